@@ -2,14 +2,25 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"expvar"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/cthulhu-platform/auth/internal/daemon"
+	"github.com/cthulhu-platform/auth/internal/mailer"
+	"github.com/cthulhu-platform/auth/internal/oauth"
 	"github.com/cthulhu-platform/auth/internal/pkg"
 	"github.com/cthulhu-platform/auth/internal/repository"
 	"github.com/cthulhu-platform/auth/internal/server"
 	"github.com/cthulhu-platform/auth/internal/service"
+	authPkg "github.com/cthulhu-platform/auth/pkg"
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
 )
 
 func main() {
@@ -20,18 +31,108 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	slog.SetDefault(logger)
 
-	repo, err := repository.NewSQLiteRepository(ctx)
+	// "migrate [up | down [n] | status]" manages the schema and exits without starting the service
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := repository.Migrate(ctx, os.Args[2:]); err != nil {
+			slog.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// PostgreSQL when POSTGRES_DSN is set, otherwise SQLite
+	repo, err := repository.NewRepository(ctx)
 	if err != nil {
 		logger.Error("Failed to create repository", "error", err)
 		os.Exit(1)
 	}
 	defer repo.Close()
 
-	svc := service.NewAuthService(repo)
+	// "role <user id | email> <user | moderator | admin>" sets a user's role and exits, e.g. to
+	// appoint the first admin
+	if len(os.Args) > 1 && os.Args[1] == "role" {
+		if err := setRole(ctx, repo, os.Args[2:]); err != nil {
+			slog.Error("Setting role failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// OAuth providers enabled by their *_CLIENT_ID / OIDC_ISSUER_URL settings
+	providers, err := oauth.NewRegistryFromEnv(ctx)
+	if err != nil {
+		logger.Error("Failed to configure OAuth providers", "error", err)
+		os.Exit(1)
+	}
+
+	// Access token signing keys; Rotate creates the first key and is re-run by the key daemon
+	rotation, err := time.ParseDuration(pkg.JWT_KEY_ROTATION_INTERVAL)
+	if err != nil {
+		logger.Error("Invalid JWT_KEY_ROTATION_INTERVAL", "value", pkg.JWT_KEY_ROTATION_INTERVAL, "error", err)
+		os.Exit(1)
+	}
+	keys, err := service.NewKeyManager(repo, pkg.JWT_SIGNING_ALG, rotation)
+	if err != nil {
+		logger.Error("Failed to configure signing keys", "error", err)
+		os.Exit(1)
+	}
+	if err := keys.Rotate(ctx); err != nil {
+		logger.Error("Failed to load signing keys", "error", err)
+		os.Exit(1)
+	}
+	keyDaemon := daemon.NewKeyDaemon(keys, pkg.SIGNING_KEY_CHECK_INTERVAL)
+	go keyDaemon.Run(ctx)
+
+	// Delivery of magic sign-in links
+	mail, err := mailer.NewFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure mailer", "error", err)
+		os.Exit(1)
+	}
+	if mail == nil {
+		logger.Warn("MAILER is not set, magic link sign-in is disabled")
+	}
+
+	svc := service.NewAuthService(repo, providers, keys, mail)
+
+	// Drop revocation entries once the revoked tokens have expired
+	revocationDaemon := daemon.NewRevocationDaemon(svc, pkg.REVOKED_TOKEN_PRUNE_INTERVAL)
+	go revocationDaemon.Run(ctx)
+
+	// Hard delete accounts once their deletion grace period has passed
+	gracePeriod, err := time.ParseDuration(pkg.ACCOUNT_DELETION_GRACE_PERIOD)
+	if err != nil {
+		logger.Error("Invalid ACCOUNT_DELETION_GRACE_PERIOD", "value", pkg.ACCOUNT_DELETION_GRACE_PERIOD, "error", err)
+		os.Exit(1)
+	}
+	accountPurgeDaemon := daemon.NewAccountPurgeDaemon(svc, pkg.ACCOUNT_PURGE_INTERVAL, gracePeriod)
+	go accountPurgeDaemon.Run(ctx)
+
+	// Delete expired OAuth sessions, device authorizations and stale refresh tokens
+	refreshRetention, err := time.ParseDuration(pkg.REFRESH_TOKEN_RETENTION)
+	if err != nil || refreshRetention < pkg.REFRESH_TOKEN_EXPIRATION {
+		logger.Error("Invalid REFRESH_TOKEN_RETENTION, must be at least the refresh token lifetime", "value", pkg.REFRESH_TOKEN_RETENTION, "min", pkg.REFRESH_TOKEN_EXPIRATION.String(), "error", err)
+		os.Exit(1)
+	}
+	maintenanceDaemon := daemon.NewMaintenanceDaemon(svc, pkg.MAINTENANCE_INTERVAL, refreshRetention)
+	go maintenanceDaemon.Run(ctx)
+
+	// expvar metrics (maintenance counts) for scraping
+	if pkg.METRICS_ADDR != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		go func() {
+			slog.Info("Serving metrics", "addr", pkg.METRICS_ADDR, "path", "/debug/vars")
+			if err := http.ListenAndServe(pkg.METRICS_ADDR, mux); err != nil {
+				slog.Error("Metrics server failed", "error", err)
+			}
+		}()
+	}
 
 	serverCfg := server.ServerConfig{
-		Host: pkg.APP_HOST,
-		Port: pkg.APP_PORT,
+		Host:     pkg.APP_HOST,
+		Port:     pkg.APP_PORT,
+		GRPCAuth: grpcauth.ConfigFromEnv(),
 	}
 
 	err = server.ListenGRPC(ctx, serverCfg, svc)
@@ -40,3 +141,24 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// setRole runs the role subcommand. It needs neither OAuth providers nor signing keys, so it
+// runs before they are configured.
+func setRole(ctx context.Context, repo repository.Repository, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: role <user id | email> <%s>", strings.Join(authPkg.Roles, " | "))
+	}
+	user, err := repo.GetUserByID(ctx, args[0])
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = repo.GetUserByEmail(ctx, args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("user %q not found: %w", args[0], err)
+	}
+	svc := service.NewAuthService(repo, nil, nil, nil)
+	if err := svc.SetUserRole(ctx, "", user.ID, args[1], authPkg.ClientInfo{}); err != nil {
+		return err
+	}
+	slog.Info("Role set", "user_id", user.ID, "email", user.Email, "role", args[1])
+	return nil
+}
@@ -3,15 +3,23 @@ module github.com/cthulhu-platform/auth
 go 1.25.6
 
 require (
+	github.com/coreos/go-oidc/v3 v3.17.0
 	github.com/cthulhu-platform/common v0.0.0
 	github.com/cthulhu-platform/proto v0.0.0
+	github.com/jackc/pgx/v5 v5.7.6
 	github.com/mattn/go-sqlite3 v1.14.33
 	github.com/spf13/viper v1.21.0
 	google.golang.org/grpc v1.78.0
 )
 
 require (
+	github.com/go-jose/go-jose/v4 v4.1.3 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	golang.org/x/crypto v0.44.0 // indirect
 	golang.org/x/net v0.47.0 // indirect
+	golang.org/x/sync v0.18.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20251029180050-ab9386a59fda // indirect
 	google.golang.org/protobuf v1.36.11 // indirect
 )
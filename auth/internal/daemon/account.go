@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cthulhu-platform/auth/internal/service"
+)
+
+// Account purge daemon that hard deletes soft deleted users once their grace period has passed
+
+type AccountPurgeDaemon struct {
+	service     service.Service
+	interval    time.Duration
+	gracePeriod time.Duration
+}
+
+func NewAccountPurgeDaemon(service service.Service, interval time.Duration, gracePeriod time.Duration) *AccountPurgeDaemon {
+	return &AccountPurgeDaemon{service: service, interval: interval, gracePeriod: gracePeriod}
+}
+
+func (d *AccountPurgeDaemon) purge(ctx context.Context) {
+	purged, err := d.service.PurgeDeletedAccounts(ctx, d.gracePeriod)
+	if err != nil {
+		slog.Error("Purging deleted accounts failed", "error", err)
+		return
+	}
+	if purged > 0 {
+		slog.Info("Purged deleted accounts", "count", purged)
+	}
+}
+
+func (d *AccountPurgeDaemon) Run(ctx context.Context) error {
+	slog.Info("Starting account purge daemon", "interval", d.interval.String(), "grace_period", d.gracePeriod.String())
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	d.purge(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.purge(ctx)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cthulhu-platform/auth/internal/service"
+)
+
+// Key daemon that rotates access token signing keys and reloads keys created by other replicas
+
+type KeyDaemon struct {
+	keys     *service.KeyManager
+	interval time.Duration
+}
+
+func NewKeyDaemon(keys *service.KeyManager, interval time.Duration) *KeyDaemon {
+	return &KeyDaemon{keys: keys, interval: interval}
+}
+
+func (d *KeyDaemon) rotate(ctx context.Context) {
+	if err := d.keys.Rotate(ctx); err != nil {
+		slog.Error("Signing key rotation failed", "error", err)
+	}
+}
+
+func (d *KeyDaemon) Run(ctx context.Context) error {
+	slog.Info("Starting key daemon", "interval", d.interval.String())
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	// Not run on startup: main rotates synchronously before serving
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.rotate(ctx)
+		}
+	}
+}
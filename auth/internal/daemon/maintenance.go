@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"time"
+
+	"github.com/cthulhu-platform/auth/internal/service"
+)
+
+// Maintenance daemon that deletes expired OAuth sessions, device authorizations, MFA challenges
+// and stale refresh tokens. Counts are logged and published under "auth_maintenance" in expvar.
+
+var maintenanceMetrics = expvar.NewMap("auth_maintenance")
+
+type MaintenanceDaemon struct {
+	service          service.Service
+	interval         time.Duration
+	refreshRetention time.Duration
+}
+
+func NewMaintenanceDaemon(service service.Service, interval time.Duration, refreshRetention time.Duration) *MaintenanceDaemon {
+	return &MaintenanceDaemon{service: service, interval: interval, refreshRetention: refreshRetention}
+}
+
+func (d *MaintenanceDaemon) cleanup(ctx context.Context) {
+	start := time.Now()
+	report, err := d.service.CleanupExpired(ctx, d.refreshRetention)
+
+	maintenanceMetrics.Add("runs", 1)
+	maintenanceMetrics.Add("oauth_sessions_deleted", report.OAuthSessions)
+	maintenanceMetrics.Add("device_authorizations_deleted", report.DeviceAuthorizations)
+	maintenanceMetrics.Add("mfa_challenges_deleted", report.MFAChallenges)
+	maintenanceMetrics.Add("magic_links_deleted", report.MagicLinks)
+	maintenanceMetrics.Add("refresh_tokens_deleted", report.RefreshTokens)
+	maintenanceMetrics.Add("sessions_deleted", report.Sessions)
+	lastRun := new(expvar.Int)
+	lastRun.Set(start.Unix())
+	maintenanceMetrics.Set("last_run", lastRun)
+
+	attrs := []any{
+		"oauth_sessions", report.OAuthSessions,
+		"device_authorizations", report.DeviceAuthorizations,
+		"mfa_challenges", report.MFAChallenges,
+		"magic_links", report.MagicLinks,
+		"refresh_tokens", report.RefreshTokens,
+		"sessions", report.Sessions,
+		"duration", time.Since(start).String(),
+	}
+	if err != nil {
+		maintenanceMetrics.Add("failures", 1)
+		slog.Error("Auth maintenance failed", append(attrs, "error", err)...)
+		return
+	}
+	if report != (service.MaintenanceReport{}) {
+		slog.Info("Auth maintenance completed", attrs...)
+	}
+}
+
+func (d *MaintenanceDaemon) Run(ctx context.Context) error {
+	slog.Info("Starting maintenance daemon", "interval", d.interval.String(), "refresh_token_retention", d.refreshRetention.String())
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	d.cleanup(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.cleanup(ctx)
+		}
+	}
+}
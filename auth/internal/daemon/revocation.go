@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cthulhu-platform/auth/internal/service"
+)
+
+// Revocation daemon that prunes revoked access tokens past their expiry
+
+type RevocationDaemon struct {
+	service  service.Service
+	interval time.Duration
+}
+
+func NewRevocationDaemon(service service.Service, interval time.Duration) *RevocationDaemon {
+	return &RevocationDaemon{service: service, interval: interval}
+}
+
+func (d *RevocationDaemon) prune(ctx context.Context) {
+	pruned, err := d.service.PruneRevokedTokens(ctx)
+	if err != nil {
+		slog.Error("Pruning revoked tokens failed", "error", err)
+		return
+	}
+	if pruned > 0 {
+		slog.Info("Pruned revoked tokens", "count", pruned)
+	}
+}
+
+func (d *RevocationDaemon) Run(ctx context.Context) error {
+	slog.Info("Starting revocation daemon", "interval", d.interval.String())
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.prune(ctx)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer is the development mailer: it logs each message, text included, and writes it to
+// dir as an .eml file when dir is set. Never use it where the log is not private, since sign-in
+// links end up in it.
+type LogMailer struct {
+	from *mail.Address
+	dir  string
+}
+
+func NewLogMailer(from *mail.Address, dir string) *LogMailer {
+	return &LogMailer{from: from, dir: dir}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	raw, err := compose(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+	slog.Info("Email (log mailer)", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	if m.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	name := filepath.Join(m.dir, fmt.Sprintf("%d.eml", now.UnixNano()))
+	if err := os.WriteFile(name, raw, 0o600); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
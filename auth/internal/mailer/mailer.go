@@ -0,0 +1,81 @@
+// Package mailer delivers the auth service's email (magic sign-in links) through SMTP, or
+// to the log and an optional directory of .eml files in development.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/cthulhu-platform/auth/internal/pkg"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewFromEnv returns the mailer selected by MAILER ("log" or "smtp"), or nil when MAILER is
+// unset: the log mailer writes working sign-in links to the logs, so it is never a default.
+func NewFromEnv() (Mailer, error) {
+	if pkg.MAILER == "" {
+		return nil, nil
+	}
+	from, err := mail.ParseAddress(pkg.MAIL_FROM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+	switch pkg.MAILER {
+	case "log":
+		return NewLogMailer(from, pkg.MAIL_DIR), nil
+	case "smtp":
+		return NewSMTPMailer(from, pkg.SMTP_HOST, pkg.SMTP_PORT, pkg.SMTP_USERNAME, pkg.SMTP_PASSWORD, pkg.SMTP_INSECURE == "true"), nil
+	}
+	return nil, fmt.Errorf("unknown MAILER %q, use log or smtp", pkg.MAILER)
+}
+
+// compose renders msg as an RFC 5322 message with a quoted-printable UTF-8 body.
+func compose(from *mail.Address, msg Message, now time.Time) ([]byte, error) {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", "<"+hex.EncodeToString(id)+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write([]byte(strings.ReplaceAll(msg.Text, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
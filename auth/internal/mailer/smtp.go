@@ -0,0 +1,103 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer submits messages to an SMTP server: implicit TLS on port 465, otherwise STARTTLS.
+// A server that does not offer STARTTLS is refused, unless it is on a loopback address or
+// insecure is set, since messages carry sign-in links. PLAIN authentication is used when a
+// username is set; net/smtp refuses it over plaintext connections to hosts other than localhost.
+type SMTPMailer struct {
+	from     *mail.Address
+	host     string
+	port     string
+	username string
+	password string
+	insecure bool
+}
+
+func NewSMTPMailer(from *mail.Address, host, port, username, password string, insecure bool) *SMTPMailer {
+	return &SMTPMailer{from: from, host: host, port: port, username: username, password: password, insecure: insecure}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	raw, err := compose(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	to, _ := mail.ParseAddress(msg.To) // validated by compose
+
+	addr := net.JoinHostPort(m.host, m.port)
+	tlsConfig := &tls.Config{ServerName: m.host}
+	var conn net.Conn
+	if m.port == "465" {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	return m.send(conn, raw, to.Address)
+}
+
+// send runs the SMTP session over conn, which is already TLS on port 465.
+func (m *SMTPMailer) send(conn net.Conn, raw []byte, to string) error {
+	addr := net.JoinHostPort(m.host, m.port)
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer c.Close()
+	if m.port != "465" {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		} else if !m.insecure && !isLoopback(m.host) {
+			return fmt.Errorf("SMTP server %s does not offer STARTTLS, set SMTP_INSECURE=true to send in plaintext", addr)
+		}
+	}
+	if m.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := c.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return fmt.Errorf("SMTP RCPT TO failed: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return c.Quit()
+}
+
+// isLoopback reports whether host is localhost or a loopback address.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
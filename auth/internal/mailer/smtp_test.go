@@ -0,0 +1,185 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cthulhu-platform/auth/internal/pkg"
+)
+
+// smtpSession is what the fake SMTP server received.
+type smtpSession struct {
+	from, rcpt string
+	data       []byte
+}
+
+// serveSMTP answers one SMTP session on a loopback listener without offering STARTTLS and
+// returns the address and the session, available once the client has disconnected.
+func serveSMTP(t *testing.T) (string, <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	done := make(chan smtpSession, 1)
+	go func() {
+		var s smtpSession
+		defer func() { done <- s }()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 test ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(cmd) {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 test")
+			case "MAIL":
+				s.from = arg
+				tp.PrintfLine("250 OK")
+			case "RCPT":
+				s.rcpt = arg
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				if s.data, err = tp.ReadDotBytes(); err != nil {
+					return
+				}
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), done
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	addr, done := serveSMTP(t)
+	host, port, _ := net.SplitHostPort(addr)
+	from := &mail.Address{Name: "Cthulhu", Address: "no-reply@example.com"}
+	text := "Sign in with this link:\nhttps://example.com/signin/email?token=abc=="
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	m := NewSMTPMailer(from, host, port, "", "", false)
+	if err := m.Send(ctx, Message{To: "ada@example.com", Subject: "Your sign-in link ✓", Text: text}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	s := <-done
+	if s.from != "FROM:<no-reply@example.com>" || s.rcpt != "TO:<ada@example.com>" {
+		t.Fatalf("envelope = %q, %q", s.from, s.rcpt)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(s.data)))
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if got := msg.Header.Get("From"); got != `"Cthulhu" <no-reply@example.com>` {
+		t.Fatalf("From = %q", got)
+	}
+	if got := msg.Header.Get("To"); got != "<ada@example.com>" {
+		t.Fatalf("To = %q", got)
+	}
+	if subject != "Your sign-in link ✓" {
+		t.Fatalf("Subject = %q", subject)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Fatalf("Message-ID = %q", id)
+	}
+	if msg.Header.Get("Content-Transfer-Encoding") != "quoted-printable" {
+		t.Fatalf("Content-Transfer-Encoding = %q", msg.Header.Get("Content-Transfer-Encoding"))
+	}
+	// DATA ends the message with a line break before the final dot
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil || strings.TrimSuffix(string(body), "\n") != text {
+		t.Fatalf("body = %q, %v, want %q", body, err, text)
+	}
+}
+
+func TestSMTPMailerStartTLSRequired(t *testing.T) {
+	from := &mail.Address{Address: "no-reply@example.com"}
+	raw, err := compose(from, Message{To: "ada@example.com", Subject: "s", Text: "t"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		host     string
+		insecure bool
+		refused  bool
+	}{
+		{name: "loopback", host: "127.0.0.1"},
+		{name: "localhost", host: "localhost"},
+		{name: "remote", host: "mail.example.com", refused: true},
+		{name: "remote, insecure", host: "mail.example.com", insecure: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, done := serveSMTP(t)
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			// send talks to the local server while checking the session against tt.host
+			m := NewSMTPMailer(from, tt.host, "587", "", "", tt.insecure)
+			err = m.send(conn, raw, "ada@example.com")
+			conn.Close()
+			s := <-done
+			if tt.refused {
+				if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+					t.Fatalf("send: err = %v, want a STARTTLS refusal", err)
+				}
+				if s.from != "" || s.data != nil {
+					t.Fatalf("refused session still sent %+v", s)
+				}
+				return
+			}
+			if err != nil || s.data == nil {
+				t.Fatalf("send: %v, session %+v", err, s)
+			}
+		})
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	defer func(mailer string) { pkg.MAILER = mailer }(pkg.MAILER)
+
+	pkg.MAILER = ""
+	if m, err := NewFromEnv(); m != nil || err != nil {
+		t.Fatalf("unset MAILER = %v, %v, want no mailer", m, err)
+	}
+	pkg.MAILER = "log"
+	if m, err := NewFromEnv(); err != nil {
+		t.Fatalf("MAILER=log: %v", err)
+	} else if _, ok := m.(*LogMailer); !ok {
+		t.Fatalf("MAILER=log = %T", m)
+	}
+	pkg.MAILER = "sendmail"
+	if _, err := NewFromEnv(); err == nil {
+		t.Fatal("unknown MAILER accepted")
+	}
+}
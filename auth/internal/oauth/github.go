@@ -0,0 +1,96 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+type githubProvider struct {
+	config *oauth2.Config
+}
+
+func NewGitHubProvider(clientID, clientSecret, redirectURI string) Provider {
+	return &githubProvider{config: &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURI,
+		Scopes:       []string{"read:user", "user:email"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://github.com/login/oauth/authorize",
+			TokenURL: "https://github.com/login/oauth/access_token",
+		},
+	}}
+}
+
+func (p *githubProvider) Name() string        { return "github" }
+func (p *githubProvider) RedirectURI() string { return p.config.RedirectURL }
+
+func (p *githubProvider) AuthCodeURL(state, codeChallenge, nonce string) string {
+	return authCodeURL(p.config, state, codeChallenge)
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, codeVerifier string) (*oauth2.Token, error) {
+	return exchange(ctx, p.config, code, codeVerifier)
+}
+
+type githubUserInfo struct {
+	ID        int    `json:"id"`
+	Login     string `json:"login"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+	Name      string `json:"name"`
+}
+
+func (p *githubProvider) UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*UserInfo, error) {
+	var githubUser githubUserInfo
+	if err := getJSON(ctx, "https://api.github.com/user", token.AccessToken, &githubUser); err != nil {
+		return nil, fmt.Errorf("failed to fetch user info: %w", err)
+	}
+
+	// The profile email is optional and says nothing about verification, the emails API does
+	email, verified, err := fetchGitHubEmail(ctx, token.AccessToken, githubUser.Email)
+	if err == nil && email != "" {
+		githubUser.Email = email
+	}
+
+	return &UserInfo{
+		OAuthUserID:   fmt.Sprintf("%d", githubUser.ID),
+		Email:         githubUser.Email,
+		EmailVerified: verified,
+		Username:      strPtr(githubUser.Login),
+		AvatarURL:     strPtr(githubUser.AvatarURL),
+	}, nil
+}
+
+// fetchGitHubEmail returns the profile email if listed, otherwise the primary one, and whether
+// GitHub verified it.
+func fetchGitHubEmail(ctx context.Context, accessToken, profileEmail string) (string, bool, error) {
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, "https://api.github.com/user/emails", accessToken, &emails); err != nil {
+		return "", false, fmt.Errorf("failed to fetch emails: %w", err)
+	}
+
+	for _, email := range emails {
+		if profileEmail != "" && strings.EqualFold(email.Email, profileEmail) {
+			return email.Email, email.Verified, nil
+		}
+	}
+	for _, email := range emails {
+		if email.Primary {
+			return email.Email, email.Verified, nil
+		}
+	}
+
+	if len(emails) > 0 {
+		return emails[0].Email, emails[0].Verified, nil
+	}
+
+	return "", false, nil
+}
@@ -0,0 +1,66 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// gitlabProvider uses the REST user API, so it works for gitlab.com and self-managed
+// instances (baseURL) without enabling GitLab's OIDC application scopes.
+type gitlabProvider struct {
+	config  *oauth2.Config
+	baseURL string
+}
+
+func NewGitLabProvider(baseURL, clientID, clientSecret, redirectURI string) Provider {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &gitlabProvider{
+		baseURL: baseURL,
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURI,
+			Scopes:       []string{"read_user"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  baseURL + "/oauth/authorize",
+				TokenURL: baseURL + "/oauth/token",
+			},
+		},
+	}
+}
+
+func (p *gitlabProvider) Name() string        { return "gitlab" }
+func (p *gitlabProvider) RedirectURI() string { return p.config.RedirectURL }
+
+func (p *gitlabProvider) AuthCodeURL(state, codeChallenge, nonce string) string {
+	return authCodeURL(p.config, state, codeChallenge)
+}
+
+func (p *gitlabProvider) Exchange(ctx context.Context, code, codeVerifier string) (*oauth2.Token, error) {
+	return exchange(ctx, p.config, code, codeVerifier)
+}
+
+type gitlabUserInfo struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	AvatarURL   string `json:"avatar_url"`
+	ConfirmedAt string `json:"confirmed_at"` // only set once the primary email is confirmed
+}
+
+func (p *gitlabProvider) UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*UserInfo, error) {
+	var gitlabUser gitlabUserInfo
+	if err := getJSON(ctx, p.baseURL+"/api/v4/user", token.AccessToken, &gitlabUser); err != nil {
+		return nil, fmt.Errorf("failed to fetch user info: %w", err)
+	}
+	return &UserInfo{
+		OAuthUserID:   fmt.Sprintf("%d", gitlabUser.ID),
+		Email:         gitlabUser.Email,
+		EmailVerified: gitlabUser.ConfirmedAt != "",
+		Username:      strPtr(gitlabUser.Username),
+		AvatarURL:     strPtr(gitlabUser.AvatarURL),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// oidcProvider signs users in with OpenID Connect. The ID token returned by the code
+// exchange is verified (signature against the issuer's JWKS, issuer, audience, expiry,
+// nonce and at_hash) and is the source of the user's identity.
+type oidcProvider struct {
+	name     string
+	provider *oidc.Provider
+	config   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	// trustEmail treats an email without an email_verified claim as verified
+	trustEmail bool
+}
+
+// NewOIDCProvider configures a provider from the issuer's discovery document
+// (issuerURL + "/.well-known/openid-configuration"). Emails are verified only when the issuer
+// says so in email_verified, unless trustEmail is set for issuers that vouch for every address
+// they return but omit the claim (e.g. company SSO).
+func NewOIDCProvider(ctx context.Context, name, issuerURL, clientID, clientSecret, redirectURI string, scopes []string, trustEmail bool) (Provider, error) {
+	// the context is kept by go-oidc for JWKS refreshes, so it must outlive startup
+	provider, err := oidc.NewProvider(httpContext(context.WithoutCancel(ctx)), issuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %w", issuerURL, err)
+	}
+	p := newOIDCProvider(name, provider, clientID, clientSecret, redirectURI, scopes)
+	p.trustEmail = trustEmail
+	return p, nil
+}
+
+// NewGoogleProvider configures Google from its published endpoints so startup does not
+// depend on fetching Google's discovery document.
+func NewGoogleProvider(clientID, clientSecret, redirectURI string) Provider {
+	provider := (&oidc.ProviderConfig{
+		IssuerURL:   "https://accounts.google.com",
+		AuthURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:    "https://oauth2.googleapis.com/token",
+		UserInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+		JWKSURL:     "https://www.googleapis.com/oauth2/v3/certs",
+		Algorithms:  []string{oidc.RS256},
+	}).NewProvider(httpContext(context.Background()))
+	return newOIDCProvider("google", provider, clientID, clientSecret, redirectURI, nil)
+}
+
+func newOIDCProvider(name string, provider *oidc.Provider, clientID, clientSecret, redirectURI string, scopes []string) *oidcProvider {
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	return &oidcProvider{
+		name:     name,
+		provider: provider,
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURI,
+			Scopes:       scopes,
+			Endpoint:     provider.Endpoint(),
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+	}
+}
+
+func (p *oidcProvider) Name() string        { return p.name }
+func (p *oidcProvider) RedirectURI() string { return p.config.RedirectURL }
+
+func (p *oidcProvider) AuthCodeURL(state, codeChallenge, nonce string) string {
+	return authCodeURL(p.config, state, codeChallenge, oidc.Nonce(nonce))
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, codeVerifier string) (*oauth2.Token, error) {
+	return exchange(ctx, p.config, code, codeVerifier)
+}
+
+type oidcClaims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     *bool  `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+	Picture           string `json:"picture"`
+}
+
+func (p *oidcProvider) UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*UserInfo, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	ctx = httpContext(ctx)
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("invalid id_token: nonce mismatch")
+	}
+	if idToken.AccessTokenHash != "" {
+		if err := idToken.VerifyAccessToken(token.AccessToken); err != nil {
+			return nil, fmt.Errorf("invalid id_token: %w", err)
+		}
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("decode id_token claims: %w", err)
+	}
+
+	// Some issuers keep profile claims out of the ID token; fall back to the userinfo endpoint
+	if claims.Email == "" && p.provider.UserInfoEndpoint() != "" {
+		info, err := p.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch user info: %w", err)
+		}
+		if info.Subject != idToken.Subject {
+			return nil, errors.New("userinfo subject does not match id_token")
+		}
+		if err := info.Claims(&claims); err != nil {
+			return nil, fmt.Errorf("decode userinfo claims: %w", err)
+		}
+	}
+
+	if claims.Email == "" {
+		return nil, errors.New("provider did not return an email address")
+	}
+
+	username := claims.PreferredUsername
+	if username == "" {
+		username = claims.Name
+	}
+	emailVerified := p.trustEmail
+	if claims.EmailVerified != nil {
+		emailVerified = *claims.EmailVerified
+	}
+	return &UserInfo{
+		OAuthUserID:   idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: emailVerified,
+		Username:      strPtr(username),
+		AvatarURL:     strPtr(claims.Picture),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeIssuer is a local OpenID Connect issuer: discovery, JWKS and a token endpoint that returns
+// an ID token with claims for any code.
+type fakeIssuer struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                f.URL,
+			"authorization_endpoint":                f.URL + "/authorize",
+			"token_endpoint":                        f.URL + "/token",
+			"jwks_uri":                              f.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, f.claims)
+		token.Header["kid"] = "test"
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// idClaims are valid ID token claims for the client "client" and nonce "nonce", overridden by
+// extra, where nil removes a claim.
+func (f *fakeIssuer) idClaims(extra jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss":   f.URL,
+		"aud":   "client",
+		"sub":   "user-1",
+		"email": "ada@example.com",
+		"nonce": "nonce",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		if v == nil {
+			delete(claims, k)
+		} else {
+			claims[k] = v
+		}
+	}
+	return claims
+}
+
+func TestOIDCProviderUserInfo(t *testing.T) {
+	ctx := context.Background()
+	issuer := newFakeIssuer(t)
+
+	tests := []struct {
+		name       string
+		trustEmail bool
+		claims     jwt.MapClaims
+		nonce      string
+		verified   bool
+		shouldFail bool
+	}{
+		{name: "email_verified missing", verified: false},
+		{name: "email_verified missing, issuer trusted", trustEmail: true, verified: true},
+		{name: "email_verified false", claims: jwt.MapClaims{"email_verified": false}, verified: false},
+		{name: "email_verified false, issuer trusted", trustEmail: true, claims: jwt.MapClaims{"email_verified": false}, verified: false},
+		{name: "email_verified true", claims: jwt.MapClaims{"email_verified": true}, verified: true},
+		{name: "nonce mismatch", claims: jwt.MapClaims{"email_verified": true}, nonce: "other", shouldFail: true},
+		{name: "nonce missing", claims: jwt.MapClaims{"email_verified": true, "nonce": nil}, shouldFail: true},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "someone-else"}, shouldFail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewOIDCProvider(ctx, "test", issuer.URL, "client", "secret", "http://localhost/callback", nil, tt.trustEmail)
+			if err != nil {
+				t.Fatalf("new provider: %v", err)
+			}
+			issuer.claims = issuer.idClaims(tt.claims)
+			nonce := tt.nonce
+			if nonce == "" {
+				nonce = "nonce"
+			}
+
+			token, err := p.Exchange(ctx, "code", "verifier")
+			if err != nil {
+				t.Fatalf("exchange: %v", err)
+			}
+			info, err := p.UserInfo(ctx, token, nonce)
+			if tt.shouldFail {
+				if err == nil {
+					t.Fatalf("user info = %+v, want an error", info)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("user info: %v", err)
+			}
+			if info.OAuthUserID != "user-1" || info.Email != "ada@example.com" || info.EmailVerified != tt.verified {
+				t.Fatalf("user info = %+v, want email verified %v", info, tt.verified)
+			}
+		})
+	}
+}
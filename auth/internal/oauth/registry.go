@@ -0,0 +1,40 @@
+package oauth
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/cthulhu-platform/auth/internal/pkg"
+)
+
+// NewRegistryFromEnv registers every provider whose client ID is configured. The generic
+// OIDC provider runs discovery here, so a wrong OIDC_ISSUER_URL fails startup.
+func NewRegistryFromEnv(ctx context.Context) (*Registry, error) {
+	var providers []Provider
+	if pkg.GITHUB_CLIENT_ID != "" {
+		providers = append(providers, NewGitHubProvider(pkg.GITHUB_CLIENT_ID, pkg.GITHUB_CLIENT_SECRET, pkg.GITHUB_REDIRECT_URI))
+	}
+	if pkg.GOOGLE_CLIENT_ID != "" {
+		providers = append(providers, NewGoogleProvider(pkg.GOOGLE_CLIENT_ID, pkg.GOOGLE_CLIENT_SECRET, pkg.GOOGLE_REDIRECT_URI))
+	}
+	if pkg.GITLAB_CLIENT_ID != "" {
+		providers = append(providers, NewGitLabProvider(pkg.GITLAB_BASE_URL, pkg.GITLAB_CLIENT_ID, pkg.GITLAB_CLIENT_SECRET, pkg.GITLAB_REDIRECT_URI))
+	}
+	if pkg.OIDC_ISSUER_URL != "" {
+		p, err := NewOIDCProvider(ctx, pkg.OIDC_PROVIDER_NAME, pkg.OIDC_ISSUER_URL,
+			pkg.OIDC_CLIENT_ID, pkg.OIDC_CLIENT_SECRET, pkg.OIDC_REDIRECT_URI, strings.Fields(pkg.OIDC_SCOPES), pkg.OIDC_TRUST_EMAIL == "true")
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	r := NewRegistry(providers...)
+	if len(providers) == 0 {
+		slog.Warn("No OAuth providers configured")
+	} else {
+		slog.Info("OAuth providers configured", "providers", r.Names())
+	}
+	return r, nil
+}
@@ -10,6 +10,59 @@ const (
 	OAUTH_SESSION_EXPIRATION_TIME = 10 * time.Minute
 	ACCESS_TOKEN_EXPIRATION       = 15 * time.Minute
 	REFRESH_TOKEN_EXPIRATION      = 7 * 24 * time.Hour
+
+	// Signing keys: a new key is published SIGNING_KEY_PREPUBLISH before it starts signing so
+	// verifiers' cached JWKS already contain it, and a superseded key stays published until the
+	// last token it signed has expired (+ SIGNING_KEY_RETIRE_GRACE for clock skew).
+	SIGNING_KEY_CHECK_INTERVAL = 10 * time.Minute
+	SIGNING_KEY_PREPUBLISH     = 1 * time.Hour
+	SIGNING_KEY_RETIRE_GRACE   = 5 * time.Minute
+	SIGNING_KEY_RELOAD_MIN     = 10 * time.Second // minimum gap between reloads triggered by an unknown kid
+
+	REVOKED_TOKEN_PRUNE_INTERVAL = 10 * time.Minute
+
+	// Device authorization grant (RFC 8628)
+	DEVICE_CODE_EXPIRATION = 10 * time.Minute
+	DEVICE_POLL_INTERVAL   = 5 * time.Second // minimum gap between polls
+	DEVICE_SLOW_DOWN_STEP  = 5 * time.Second // added to the interval when a client polls too fast
+	DEVICE_CLIENT_NAME_MAX = 64              // characters
+
+	// Personal access tokens
+	PERSONAL_ACCESS_TOKEN_MAX_PER_USER = 50
+	PERSONAL_ACCESS_TOKEN_NAME_MAX     = 100             // characters
+	PERSONAL_ACCESS_TOKEN_PREFIX_LEN   = 12              // characters of the token kept for display
+	PERSONAL_ACCESS_TOKEN_LAST_USED    = 1 * time.Minute // last_used_at is updated at most this often
+
+	SESSION_USER_AGENT_MAX = 512 // bytes of the client supplied user agent kept per session
+
+	// MFA: sign-ins of users with TOTP enabled wait for VerifyMFA for up to
+	// MFA_CHALLENGE_EXPIRATION and MFA_CHALLENGE_MAX_ATTEMPTS codes.
+	MFA_CHALLENGE_EXPIRATION   = 5 * time.Minute
+	MFA_CHALLENGE_MAX_ATTEMPTS = 5
+	MFA_RECOVERY_CODE_COUNT    = 10
+
+	// Magic links: at most MAGIC_LINK_MAX_PENDING unexpired links per email address
+	MAGIC_LINK_EXPIRATION  = 15 * time.Minute
+	MAGIC_LINK_MAX_PENDING = 3
+
+	// Account deletion: soft deleted users are hard deleted by the purge daemon once
+	// ACCOUNT_DELETION_GRACE_PERIOD has passed.
+	ACCOUNT_PURGE_INTERVAL         = 1 * time.Hour
+	ACCOUNT_PURGE_BATCH            = 100
+	ACCOUNT_DELETION_FEED_PAGE_MAX = 500 // entries returned per ListAccountDeletions call
+
+	// Maintenance: expired OAuth sessions and device authorizations are deleted, as are refresh
+	// tokens (and their ended sessions) expired or revoked more than REFRESH_TOKEN_RETENTION ago.
+	MAINTENANCE_INTERVAL = 1 * time.Hour
+
+	AUDIT_EVENT_PAGE_MAX = 200 // entries returned per ListAuditEvents call
+
+	// Organizations
+	ORGANIZATION_MAX_PER_USER  = 20  // memberships
+	ORGANIZATION_NAME_MAX      = 100 // characters
+	ORGANIZATION_SLUG_MIN      = 3
+	ORGANIZATION_SLUG_MAX      = 40
+	ORGANIZATION_RETENTION_MAX = 365 * 24 * time.Hour
 )
 
 var (
@@ -17,9 +70,56 @@ var (
 	APP_PORT = env.GetEnv("APP_PORT", "49051")
 
 	SQLITE_DB_FILE = env.GetEnv("SQLITE_DB_FILE", "auth.db")
-	JWT_SECRET_KEY = env.GetEnv("JWT_SECRET", "secret")
+	POSTGRES_DSN   = env.GetEnv("POSTGRES_DSN", "")     // if set, PostgreSQL is used instead of SQLITE_DB_FILE
+	AUTO_MIGRATE   = env.GetEnv("AUTO_MIGRATE", "true") // apply pending migrations on startup
+
+	ACCOUNT_DELETION_GRACE_PERIOD = env.GetEnv("ACCOUNT_DELETION_GRACE_PERIOD", "720h") // soft deleted users are kept this long
+
+	// Must be at least REFRESH_TOKEN_EXPIRATION: reuse of a revoked refresh token is only
+	// detected while the token is kept.
+	REFRESH_TOKEN_RETENTION = env.GetEnv("REFRESH_TOKEN_RETENTION", "168h")
+	METRICS_ADDR            = env.GetEnv("METRICS_ADDR", "") // serves expvar at /debug/vars if set, e.g. ":9090"
+
+	MFA_ISSUER = env.GetEnv("MFA_ISSUER", "Cthulhu") // account label shown in authenticator apps
+
+	// Magic link sign-in. MAILER is "log" (development only: messages, sign-in links included, are
+	// logged, and written as .eml files to MAIL_DIR if set) or "smtp"; unset turns magic links off.
+	MAGIC_LINK_URL = env.GetEnv("MAGIC_LINK_URL", "http://localhost:3000/signin/email") // client app page, gets ?token=
+	MAILER         = env.GetEnv("MAILER", "")
+	MAIL_FROM      = env.GetEnv("MAIL_FROM", "Cthulhu <no-reply@localhost>")
+	MAIL_DIR       = env.GetEnv("MAIL_DIR", "")
+	SMTP_HOST      = env.GetEnv("SMTP_HOST", "localhost")
+	SMTP_PORT      = env.GetEnv("SMTP_PORT", "587")
+	SMTP_USERNAME  = env.GetEnv("SMTP_USERNAME", "") // PLAIN auth if set, only over TLS or to localhost
+	SMTP_PASSWORD  = env.GetEnv("SMTP_PASSWORD", "")
+	SMTP_INSECURE  = env.GetEnv("SMTP_INSECURE", "false") // "true" allows servers without STARTTLS beyond loopback
+
+	DEVICE_VERIFICATION_URI = env.GetEnv("DEVICE_VERIFICATION_URI", "http://localhost:3000/device") // client app approval page
+
+	JWT_SIGNING_ALG           = env.GetEnv("JWT_SIGNING_ALG", "ES256")          // ES256 or EdDSA, used for newly generated keys
+	JWT_KEY_ROTATION_INTERVAL = env.GetEnv("JWT_KEY_ROTATION_INTERVAL", "720h") // how long each signing key signs
 
 	GITHUB_CLIENT_ID     = env.GetEnv("GITHUB_CLIENT_ID", "")
 	GITHUB_CLIENT_SECRET = env.GetEnv("GITHUB_CLIENT_SECRET", "")
 	GITHUB_REDIRECT_URI  = env.GetEnv("GITHUB_REDIRECT_URI", "")
+
+	GOOGLE_CLIENT_ID     = env.GetEnv("GOOGLE_CLIENT_ID", "")
+	GOOGLE_CLIENT_SECRET = env.GetEnv("GOOGLE_CLIENT_SECRET", "")
+	GOOGLE_REDIRECT_URI  = env.GetEnv("GOOGLE_REDIRECT_URI", "")
+
+	GITLAB_BASE_URL      = env.GetEnv("GITLAB_BASE_URL", "https://gitlab.com") // self-managed instances set their own URL
+	GITLAB_CLIENT_ID     = env.GetEnv("GITLAB_CLIENT_ID", "")
+	GITLAB_CLIENT_SECRET = env.GetEnv("GITLAB_CLIENT_SECRET", "")
+	GITLAB_REDIRECT_URI  = env.GetEnv("GITLAB_REDIRECT_URI", "")
+
+	// Generic OpenID Connect provider (e.g. company SSO), configured via discovery
+	OIDC_PROVIDER_NAME = env.GetEnv("OIDC_PROVIDER_NAME", "oidc") // :provider path segment
+	OIDC_ISSUER_URL    = env.GetEnv("OIDC_ISSUER_URL", "")
+	OIDC_CLIENT_ID     = env.GetEnv("OIDC_CLIENT_ID", "")
+	OIDC_CLIENT_SECRET = env.GetEnv("OIDC_CLIENT_SECRET", "")
+	OIDC_REDIRECT_URI  = env.GetEnv("OIDC_REDIRECT_URI", "")
+	OIDC_SCOPES        = env.GetEnv("OIDC_SCOPES", "openid email profile") // space separated
+	// "true" treats emails without an email_verified claim as verified, for issuers that vouch for
+	// every address they return; otherwise such emails are unverified
+	OIDC_TRUST_EMAIL = env.GetEnv("OIDC_TRUST_EMAIL", "false")
 )
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"os"
+
+	internalpkg "github.com/cthulhu-platform/auth/internal/pkg"
+	commonrepo "github.com/cthulhu-platform/common/pkg/repository"
+)
+
+// Numbered up/down migrations, one directory per dialect. sqlc reads the same files
+// (ignoring *.down.sql) to generate the db and pgdb packages.
+//
+//go:embed migrations
+var migrationsFS embed.FS
+
+func newMigrator(conn *sql.DB, dialect commonrepo.Dialect) (*commonrepo.Migrator, error) {
+	migrations, err := commonrepo.LoadMigrations(migrationsFS, "migrations/"+string(dialect))
+	if err != nil {
+		return nil, err
+	}
+	return commonrepo.NewMigrator(conn, dialect, migrations), nil
+}
+
+// autoMigrate applies pending migrations on startup unless AUTO_MIGRATE is disabled,
+// in which case the schema is managed with the migrate subcommand.
+func autoMigrate(ctx context.Context, conn *sql.DB, dialect commonrepo.Dialect) error {
+	if internalpkg.AUTO_MIGRATE != "true" {
+		return nil
+	}
+	m, err := newMigrator(conn, dialect)
+	if err != nil {
+		return err
+	}
+	_, err = m.Up(ctx)
+	return err
+}
+
+// Migrate runs the migrate subcommand (up, down [n], status) against the configured database.
+func Migrate(ctx context.Context, args []string) error {
+	var (
+		conn    *sql.DB
+		dialect commonrepo.Dialect
+		err     error
+	)
+	if internalpkg.POSTGRES_DSN != "" {
+		conn, err = openPostgres(ctx, internalpkg.POSTGRES_DSN)
+		dialect = commonrepo.DialectPostgres
+	} else {
+		conn, err = openSQLite(ctx)
+		dialect = commonrepo.DialectSQLite
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	m, err := newMigrator(conn, dialect)
+	if err != nil {
+		return err
+	}
+	return commonrepo.RunMigrateCommand(ctx, m, args, os.Stdout)
+}
@@ -0,0 +1,11 @@
+-- Access token signing keys, mirrors ../sqlite/0002_signing_keys.up.sql.
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid TEXT PRIMARY KEY,  -- RFC 7638 JWK thumbprint
+    algorithm TEXT NOT NULL,  -- 'ES256' or 'EdDSA'
+    private_key TEXT NOT NULL,  -- PKCS #8 PEM
+    created_at BIGINT NOT NULL,
+    activates_at BIGINT NOT NULL,  -- Unix timestamp signing starts
+    expires_at BIGINT  -- NULL while current, set once superseded
+);
+
+CREATE INDEX IF NOT EXISTS idx_signing_keys_activates ON signing_keys(activates_at);
@@ -0,0 +1,15 @@
+-- Access token revocation, mirrors ../sqlite/0003_token_revocation.up.sql.
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    jti TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at BIGINT NOT NULL,  -- the token's exp, row is pruned after it
+    revoked_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires ON revoked_access_tokens(expires_at);
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_revoked ON revoked_access_tokens(revoked_at);
+
+ALTER TABLE users ADD COLUMN tokens_valid_after BIGINT;  -- Unix timestamp, NULL if never set
+ALTER TABLE users ADD COLUMN suspended_at BIGINT;  -- NULL if not suspended
+
+CREATE INDEX IF NOT EXISTS idx_users_tokens_valid_after ON users(tokens_valid_after);
@@ -0,0 +1,6 @@
+-- Refresh token families, mirrors ../sqlite/0004_refresh_token_families.up.sql.
+ALTER TABLE refresh_tokens ADD COLUMN family_id TEXT NOT NULL DEFAULT '';
+
+UPDATE refresh_tokens SET family_id = id WHERE family_id = '';
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id, revoked_at);
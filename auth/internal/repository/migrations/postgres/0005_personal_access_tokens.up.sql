@@ -0,0 +1,15 @@
+-- Personal access tokens, mirrors ../sqlite/0005_personal_access_tokens.up.sql.
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    token_prefix TEXT NOT NULL,
+    scopes TEXT NOT NULL,  -- space separated
+    expires_at BIGINT,  -- NULL if the token never expires
+    last_used_at BIGINT,
+    created_at BIGINT NOT NULL,
+    revoked_at BIGINT  -- NULL if active
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user ON personal_access_tokens(user_id, revoked_at);
@@ -0,0 +1,14 @@
+-- Device authorization grant, mirrors ../sqlite/0006_device_authorizations.up.sql.
+CREATE TABLE IF NOT EXISTS device_authorizations (
+    device_code_hash TEXT PRIMARY KEY,
+    user_code TEXT NOT NULL UNIQUE,
+    client_name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',  -- 'pending', 'approved', 'denied'
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    poll_interval BIGINT NOT NULL,
+    last_polled_at BIGINT,
+    expires_at BIGINT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_authorizations_expires ON device_authorizations(expires_at);
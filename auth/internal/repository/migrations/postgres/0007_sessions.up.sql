@@ -0,0 +1,19 @@
+-- Sessions, mirrors ../sqlite/0007_sessions.up.sql.
+CREATE TABLE IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    last_refreshed_at BIGINT NOT NULL,
+    access_token_jti TEXT NOT NULL DEFAULT '',
+    access_token_expires_at BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id, last_refreshed_at);
+
+INSERT INTO sessions (id, user_id, created_at, last_refreshed_at)
+SELECT family_id, user_id, MIN(created_at), MAX(created_at)
+FROM refresh_tokens
+WHERE revoked_at IS NULL
+GROUP BY family_id, user_id;
@@ -0,0 +1,18 @@
+-- User identities, mirrors ../sqlite/0008_user_identities.up.sql.
+CREATE TABLE IF NOT EXISTS user_identities (
+    provider TEXT NOT NULL,
+    provider_user_id TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL DEFAULT '',
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at BIGINT NOT NULL,
+    last_login_at BIGINT,
+    PRIMARY KEY (provider, provider_user_id),
+    UNIQUE (user_id, provider)
+);
+
+INSERT INTO user_identities (provider, provider_user_id, user_id, email, created_at)
+SELECT oauth_provider, oauth_user_id, id, email, created_at
+FROM users;
+
+ALTER TABLE oauth_sessions ADD COLUMN link_user_id TEXT;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_account_deletions_pending;
+DROP TABLE IF EXISTS account_deletions;
@@ -0,0 +1,9 @@
+-- Account deletions, mirrors ../sqlite/0009_account_deletions.up.sql.
+CREATE TABLE IF NOT EXISTS account_deletions (
+    seq BIGSERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL UNIQUE,
+    deleted_at BIGINT NOT NULL,
+    purged_at BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_deletions_pending ON account_deletions(purged_at, deleted_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_audit_events_target;
+DROP INDEX IF EXISTS idx_audit_events_actor;
+DROP TABLE IF EXISTS audit_events;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,16 @@
+-- Platform roles and audit log, mirrors ../sqlite/0011_roles_audit.up.sql.
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target_type, target_id, id);
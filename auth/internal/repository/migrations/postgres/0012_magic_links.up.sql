@@ -0,0 +1,10 @@
+-- Passwordless sign-in links, mirrors ../sqlite/0012_magic_links.up.sql.
+CREATE TABLE IF NOT EXISTS magic_links (
+    token_hash TEXT PRIMARY KEY,
+    email TEXT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_magic_links_email ON magic_links(email, expires_at);
+CREATE INDEX IF NOT EXISTS idx_magic_links_expires ON magic_links(expires_at);
@@ -0,0 +1,3 @@
+-- tokens_valid_after moves to Unix milliseconds, compared with the iat_ms claim of access
+-- tokens, so a session started in the same second as "log out everywhere" stays valid.
+UPDATE users SET tokens_valid_after = tokens_valid_after * 1000 WHERE tokens_valid_after IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS oauth_sessions;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS users;
@@ -1,5 +1,5 @@
--- Enable foreign keys
-PRAGMA foreign_keys = ON;
+-- Initial schema. Foreign keys are enabled per connection via the DSN (_foreign_keys=on)
+-- since PRAGMA foreign_keys is a no-op inside the migration transaction.
 
 -- Users table: Core user identity (bare essentials)
 CREATE TABLE IF NOT EXISTS users (
@@ -45,4 +45,4 @@ CREATE TABLE IF NOT EXISTS oauth_sessions (
     created_at INTEGER NOT NULL
 );
 
-CREATE INDEX IF NOT EXISTS idx_oauth_sessions_expires ON oauth_sessions(expires_at);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_oauth_sessions_expires ON oauth_sessions(expires_at);
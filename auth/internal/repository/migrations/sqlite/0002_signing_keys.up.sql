@@ -0,0 +1,12 @@
+-- Access token signing keys (ES256 / EdDSA). A key signs from activates_at until a newer key
+-- activates, and stays in the published JWKS until expires_at so tokens it signed still verify.
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid TEXT PRIMARY KEY,  -- RFC 7638 JWK thumbprint
+    algorithm TEXT NOT NULL,  -- 'ES256' or 'EdDSA'
+    private_key TEXT NOT NULL,  -- PKCS #8 PEM
+    created_at INTEGER NOT NULL,
+    activates_at INTEGER NOT NULL,  -- Unix timestamp signing starts
+    expires_at INTEGER  -- NULL while current, set once superseded
+);
+
+CREATE INDEX IF NOT EXISTS idx_signing_keys_activates ON signing_keys(activates_at);
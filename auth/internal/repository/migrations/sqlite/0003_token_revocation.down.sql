@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_tokens_valid_after;
+ALTER TABLE users DROP COLUMN suspended_at;
+ALTER TABLE users DROP COLUMN tokens_valid_after;
+DROP INDEX IF EXISTS idx_revoked_access_tokens_revoked;
+DROP INDEX IF EXISTS idx_revoked_access_tokens_expires;
+DROP TABLE IF EXISTS revoked_access_tokens;
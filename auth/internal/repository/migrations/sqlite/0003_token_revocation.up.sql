@@ -0,0 +1,17 @@
+-- Access token revocation. Logout adds the token's jti to revoked_access_tokens (pruned once the
+-- token would have expired anyway). tokens_valid_after is a per-user watermark: tokens issued at
+-- or before it are invalid, which is how "log out everywhere", suspension and deletion take effect.
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    jti TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at INTEGER NOT NULL,  -- the token's exp, row is pruned after it
+    revoked_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires ON revoked_access_tokens(expires_at);
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_revoked ON revoked_access_tokens(revoked_at);
+
+ALTER TABLE users ADD COLUMN tokens_valid_after INTEGER;  -- Unix timestamp, NULL if never set
+ALTER TABLE users ADD COLUMN suspended_at INTEGER;  -- NULL if not suspended
+
+CREATE INDEX IF NOT EXISTS idx_users_tokens_valid_after ON users(tokens_valid_after);
@@ -0,0 +1,9 @@
+-- Refresh token families. Every refresh token minted by rotation inherits the family_id of the
+-- token it replaced (a login starts a new family). Presenting an already rotated member is treated
+-- as token theft and revokes the whole family (OAuth 2.0 Security BCP, refresh token rotation).
+ALTER TABLE refresh_tokens ADD COLUMN family_id TEXT NOT NULL DEFAULT '';
+
+-- Existing tokens each become their own family
+UPDATE refresh_tokens SET family_id = id WHERE family_id = '';
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id, revoked_at);
@@ -0,0 +1,16 @@
+-- Personal access tokens: named, scoped API keys for CLI and CI use. Stored hashed like
+-- refresh_tokens, the plaintext is only returned when the token is created.
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id TEXT PRIMARY KEY,  -- UUID
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,  -- SHA-256 hash
+    token_prefix TEXT NOT NULL,  -- first characters of the token, shown so users can tell tokens apart
+    scopes TEXT NOT NULL,  -- space separated, e.g. 'files:upload buckets:write'
+    expires_at INTEGER,  -- NULL if the token never expires
+    last_used_at INTEGER,
+    created_at INTEGER NOT NULL,
+    revoked_at INTEGER  -- NULL if active
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user ON personal_access_tokens(user_id, revoked_at);
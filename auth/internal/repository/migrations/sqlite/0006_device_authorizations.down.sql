@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_device_authorizations_expires;
+DROP TABLE IF EXISTS device_authorizations;
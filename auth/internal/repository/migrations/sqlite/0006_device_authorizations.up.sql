@@ -0,0 +1,16 @@
+-- Device authorization grant (RFC 8628): state of a headless client's sign-in, like oauth_sessions.
+-- The client polls with the device code (stored hashed) while the user approves the user code in
+-- a browser where they are signed in.
+CREATE TABLE IF NOT EXISTS device_authorizations (
+    device_code_hash TEXT PRIMARY KEY,  -- SHA-256 hash
+    user_code TEXT NOT NULL UNIQUE,  -- normalized, without the display dash
+    client_name TEXT NOT NULL,  -- shown on the approval page
+    status TEXT NOT NULL DEFAULT 'pending',  -- 'pending', 'approved', 'denied'
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,  -- set on approval
+    poll_interval INTEGER NOT NULL,  -- seconds, raised on slow_down
+    last_polled_at INTEGER,
+    expires_at INTEGER NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_authorizations_expires ON device_authorizations(expires_at);
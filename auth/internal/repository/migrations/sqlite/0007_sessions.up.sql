@@ -0,0 +1,21 @@
+-- Sessions: one row per refresh token family (the id is the family_id), i.e. per signed in
+-- device. A session is active while its family has an unrevoked, unexpired refresh token.
+CREATE TABLE IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY,  -- refresh token family_id
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',  -- as forwarded by the gateway, updated on refresh
+    created_at INTEGER NOT NULL,
+    last_refreshed_at INTEGER NOT NULL,
+    access_token_jti TEXT NOT NULL DEFAULT '',  -- latest access token, revoked with the session
+    access_token_expires_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id, last_refreshed_at);
+
+-- Families that are still active become sessions without client details
+INSERT INTO sessions (id, user_id, created_at, last_refreshed_at)
+SELECT family_id, user_id, MIN(created_at), MAX(created_at)
+FROM refresh_tokens
+WHERE revoked_at IS NULL
+GROUP BY family_id, user_id;
@@ -0,0 +1,22 @@
+-- User identities: the OAuth provider accounts linked to a user, so one person can sign in with
+-- several providers. users.oauth_provider / oauth_user_id keep the identity the account was
+-- created with (moved to another identity if that one is unlinked).
+CREATE TABLE IF NOT EXISTS user_identities (
+    provider TEXT NOT NULL,  -- 'github', 'google', etc.
+    provider_user_id TEXT NOT NULL,  -- Provider's user ID
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL DEFAULT '',  -- as reported by the provider at the last sign-in
+    email_verified BOOLEAN NOT NULL DEFAULT 0,  -- whether the provider vouched for the email
+    created_at INTEGER NOT NULL,
+    last_login_at INTEGER,
+    PRIMARY KEY (provider, provider_user_id),
+    UNIQUE (user_id, provider)  -- at most one account per provider per user
+);
+
+-- Existing users keep signing in with the identity they were created with
+INSERT INTO user_identities (provider, provider_user_id, user_id, email, created_at)
+SELECT oauth_provider, oauth_user_id, id, email, created_at
+FROM users;
+
+-- Set when an OAuth flow links an identity to this signed in user instead of signing in
+ALTER TABLE oauth_sessions ADD COLUMN link_user_id TEXT;
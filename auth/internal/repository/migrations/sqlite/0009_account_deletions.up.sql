@@ -0,0 +1,11 @@
+-- Account deletions: an append-only feed of deleted users read by the services that hold user
+-- data (lifecycle drives the filemanager cleanup from it). Rows outlive the user, so there is
+-- no foreign key. purged_at is set once the grace period has passed and the user row is gone.
+CREATE TABLE IF NOT EXISTS account_deletions (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,  -- feed cursor
+    user_id TEXT NOT NULL UNIQUE,
+    deleted_at INTEGER NOT NULL,  -- Unix timestamp of the soft delete
+    purged_at INTEGER  -- NULL until the user is hard deleted
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_deletions_pending ON account_deletions(purged_at, deleted_at);
@@ -0,0 +1,30 @@
+-- TOTP second factor. A row with enabled_at NULL is an enrollment waiting for its first code.
+-- last_used_step is the latest accepted 30 second time step, so a code cannot be replayed.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,  -- base32 TOTP secret
+    created_at INTEGER NOT NULL,
+    enabled_at INTEGER,
+    last_used_step INTEGER NOT NULL DEFAULT 0
+);
+
+-- Single use recovery codes, replaced as a set whenever MFA is enabled
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    code_hash TEXT PRIMARY KEY,  -- SHA-256 hash
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at INTEGER NOT NULL,
+    used_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user ON mfa_recovery_codes(user_id);
+
+-- Sign-ins that passed the OAuth provider and wait for the second factor (VerifyMFA)
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    token_hash TEXT PRIMARY KEY,  -- SHA-256 hash
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at INTEGER NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_expires ON mfa_challenges(expires_at);
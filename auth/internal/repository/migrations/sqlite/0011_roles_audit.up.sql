@@ -0,0 +1,19 @@
+-- Platform roles: 'user', 'moderator' (moderates buckets and users) or 'admin' (also manages
+-- roles). Access tokens carry the role in the role claim.
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+-- Audit log of moderation and administration actions, appended by the auth service and by the
+-- gateway (for actions carried out by other services). Rows are kept after their actor is purged.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_id TEXT NOT NULL,  -- user who acted, '' for operators using the service CLI
+    action TEXT NOT NULL,  -- e.g. 'user.suspend', 'user.role', 'bucket.delete'
+    target_type TEXT NOT NULL,  -- 'user', 'bucket'
+    target_id TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',  -- free text, e.g. the new role or a reason
+    ip_address TEXT NOT NULL DEFAULT '',  -- of the actor's client, as forwarded by the gateway
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target_type, target_id, id);
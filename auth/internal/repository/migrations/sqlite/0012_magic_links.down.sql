@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_magic_links_expires;
+DROP INDEX IF EXISTS idx_magic_links_email;
+DROP TABLE IF EXISTS magic_links;
@@ -0,0 +1,11 @@
+-- Passwordless sign-in links mailed by RequestMagicLink. A link is deleted when it is redeemed,
+-- which makes it single use, and expired ones are removed by the maintenance daemon.
+CREATE TABLE IF NOT EXISTS magic_links (
+    token_hash TEXT PRIMARY KEY,  -- SHA-256 hash
+    email TEXT NOT NULL,  -- lowercased address the link was sent to
+    expires_at INTEGER NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_magic_links_email ON magic_links(email, expires_at);
+CREATE INDEX IF NOT EXISTS idx_magic_links_expires ON magic_links(expires_at);
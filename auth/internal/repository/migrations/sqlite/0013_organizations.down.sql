@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_organization_members_user_id;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
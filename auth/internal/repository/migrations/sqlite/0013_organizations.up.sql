@@ -0,0 +1,24 @@
+-- Organizations: teams that share ownership of buckets. Buckets record their organization in
+-- the filemanager database (no FK constraint - cross-db).
+CREATE TABLE IF NOT EXISTS organizations (
+    id TEXT PRIMARY KEY,  -- UUID
+    slug TEXT NOT NULL UNIQUE,  -- lowercase letters, digits and dashes
+    name TEXT NOT NULL,
+    retention_seconds INTEGER NOT NULL DEFAULT 0,  -- expiry of the org's new buckets, 0 = platform default
+    quota_bytes INTEGER NOT NULL DEFAULT 0,  -- total size of the org's files, 0 = unlimited
+    created_by TEXT NOT NULL,  -- user id, kept after the user is purged
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+-- Members and their role in the organization: 'owner', 'admin' or 'member'. Admins (and owners)
+-- manage members and settings and are admins of every bucket of the organization.
+CREATE TABLE IF NOT EXISTS organization_members (
+    org_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members(user_id);
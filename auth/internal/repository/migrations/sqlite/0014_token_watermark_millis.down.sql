@@ -0,0 +1 @@
+UPDATE users SET tokens_valid_after = tokens_valid_after / 1000 WHERE tokens_valid_after IS NOT NULL;
@@ -0,0 +1,3 @@
+-- GetUserByEmail compares addresses case-insensitively, sign-in emails are lowercased but
+-- provider emails are stored as reported.
+CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users(lower(email));
@@ -0,0 +1,1111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/pgdb"
+	commonrepo "github.com/cthulhu-platform/common/pkg/repository"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresRepository implements Repository on PostgreSQL via the pgx database/sql driver.
+// sqlc generates pgdb models with the same shape as the SQLite db models, so rows are
+// converted directly and callers keep using the db package types.
+type postgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(ctx context.Context, dsn string) (*postgresRepository, error) {
+	conn, err := openPostgres(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply pending schema migrations
+	if err := autoMigrate(ctx, conn, commonrepo.DialectPostgres); err != nil {
+		log.Printf("Failed to migrate database schema: %v\n", err)
+		conn.Close()
+		return nil, err
+	}
+
+	log.Println("PostgreSQL database initialized successfully")
+
+	r := &postgresRepository{db: conn}
+	return r, nil
+}
+
+// openPostgres opens and pings the PostgreSQL connection pool without touching the schema.
+func openPostgres(ctx context.Context, dsn string) (*sql.DB, error) {
+	// create timeout context
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Open PostgreSQL connection pool
+	conn, err := sql.Open("pgx", dsn)
+	if err != nil {
+		log.Printf("Failed to open PostgreSQL database: %v\n", err)
+		return nil, err
+	}
+
+	// Ping database to ensure connection is established
+	if err := conn.PingContext(ctx); err != nil {
+		log.Printf("Failed to ping PostgreSQL database: %v\n", err)
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (r *postgresRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *postgresRepository) GetUserByOAuthID(ctx context.Context, provider string, userID string) (*db.User, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	user, err := pgdb.New(r.db).GetUserByOAuthID(ctx, pgdb.GetUserByOAuthIDParams{
+		OauthProvider: provider,
+		OauthUserID:   userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := db.User(user)
+	return &out, nil
+}
+
+func (r *postgresRepository) GetUserByID(ctx context.Context, id string) (*db.User, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	user, err := pgdb.New(r.db).GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := db.User(user)
+	return &out, nil
+}
+
+func (r *postgresRepository) GetUserByEmail(ctx context.Context, email string) (*db.User, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	user, err := pgdb.New(r.db).GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	out := db.User(user)
+	return &out, nil
+}
+
+func (r *postgresRepository) CreateUser(ctx context.Context, user *db.User) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreateUser(ctx, pgdb.CreateUserParams{
+		ID:            user.ID,
+		OauthProvider: user.OauthProvider,
+		OauthUserID:   user.OauthUserID,
+		Email:         user.Email,
+		Username:      user.Username,
+		AvatarUrl:     user.AvatarUrl,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	})
+}
+
+func (r *postgresRepository) UpdateUser(ctx context.Context, user *db.User) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).UpdateUser(ctx, pgdb.UpdateUserParams{
+		ID:        user.ID,
+		Username:  user.Username,
+		AvatarUrl: user.AvatarUrl,
+		UpdatedAt: user.UpdatedAt,
+	})
+}
+
+func (r *postgresRepository) SoftDeleteUser(ctx context.Context, id string, reason string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	q := pgdb.New(tx)
+	now := time.Now().Unix()
+	deleted, err := q.SoftDeleteUser(ctx, pgdb.SoftDeleteUserParams{
+		ID:               id,
+		DeletedAt:        sql.NullInt64{Int64: now, Valid: true},
+		UpdatedAt:        now,
+		TokensValidAfter: sql.NullInt64{Int64: time.Now().UnixMilli(), Valid: true},
+	})
+	if err != nil || deleted == 0 {
+		return false, err
+	}
+	if err := q.RevokeAllUserTokens(ctx, pgdb.RevokeAllUserTokensParams{
+		RevokedAt:     sql.NullInt64{Int64: now, Valid: true},
+		RevokedReason: sql.NullString{String: reason, Valid: true},
+		UserID:        id,
+	}); err != nil {
+		return false, err
+	}
+	if err := q.CreateAccountDeletion(ctx, pgdb.CreateAccountDeletionParams{UserID: id, DeletedAt: now}); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *postgresRepository) SetUserTokensValidAfter(ctx context.Context, id string, validAfter int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).SetUserTokensValidAfter(ctx, pgdb.SetUserTokensValidAfterParams{
+		ID:               id,
+		TokensValidAfter: sql.NullInt64{Int64: validAfter, Valid: true},
+		UpdatedAt:        time.Now().Unix(),
+	})
+}
+
+func (r *postgresRepository) SetUserSuspended(ctx context.Context, id string, suspended bool) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	now := time.Now().Unix()
+	return pgdb.New(r.db).SetUserSuspended(ctx, pgdb.SetUserSuspendedParams{
+		ID:          id,
+		SuspendedAt: sql.NullInt64{Int64: now, Valid: suspended},
+		UpdatedAt:   now,
+	})
+}
+
+func (r *postgresRepository) SetUserRole(ctx context.Context, id string, role string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).SetUserRole(ctx, pgdb.SetUserRoleParams{
+		ID:        id,
+		Role:      role,
+		UpdatedAt: time.Now().Unix(),
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) ListUserWatermarksSince(ctx context.Context, since int64) ([]db.ListUserWatermarksSinceRow, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	rows, err := pgdb.New(r.db).ListUserWatermarksSince(ctx, sql.NullInt64{Int64: since, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.ListUserWatermarksSinceRow, len(rows))
+	for i, row := range rows {
+		out[i] = db.ListUserWatermarksSinceRow(row)
+	}
+	return out, nil
+}
+
+// Account deletion operations
+
+func (r *postgresRepository) ListAccountDeletionsAfter(ctx context.Context, seq int64, limit int) ([]db.AccountDeletion, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	rows, err := pgdb.New(r.db).ListAccountDeletionsAfter(ctx, pgdb.ListAccountDeletionsAfterParams{Seq: seq, Limit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.AccountDeletion, len(rows))
+	for i, row := range rows {
+		out[i] = db.AccountDeletion(row)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) ListAccountDeletionsToPurge(ctx context.Context, deletedBefore int64, limit int) ([]db.AccountDeletion, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	rows, err := pgdb.New(r.db).ListAccountDeletionsToPurge(ctx, pgdb.ListAccountDeletionsToPurgeParams{DeletedAt: deletedBefore, Limit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.AccountDeletion, len(rows))
+	for i, row := range rows {
+		out[i] = db.AccountDeletion(row)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) PurgeUser(ctx context.Context, id string) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := pgdb.New(tx)
+	if err := q.HardDeleteUser(ctx, id); err != nil {
+		return err
+	}
+	if err := q.MarkAccountPurged(ctx, pgdb.MarkAccountPurgedParams{
+		PurgedAt: sql.NullInt64{Int64: time.Now().Unix(), Valid: true},
+		UserID:   id,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// User identity operations
+
+func (r *postgresRepository) CreateUserWithIdentity(ctx context.Context, user *db.User, identity *db.UserIdentity) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := pgdb.New(tx)
+	if err := q.CreateUser(ctx, pgdb.CreateUserParams{
+		ID:            user.ID,
+		OauthProvider: user.OauthProvider,
+		OauthUserID:   user.OauthUserID,
+		Email:         user.Email,
+		Username:      user.Username,
+		AvatarUrl:     user.AvatarUrl,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}); err != nil {
+		return err
+	}
+	if err := q.CreateUserIdentity(ctx, pgdb.CreateUserIdentityParams(*identity)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *postgresRepository) CreateUserIdentity(ctx context.Context, identity *db.UserIdentity) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreateUserIdentity(ctx, pgdb.CreateUserIdentityParams(*identity))
+}
+
+func (r *postgresRepository) GetUserIdentity(ctx context.Context, provider string, providerUserID string) (*db.UserIdentity, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	identity, err := pgdb.New(r.db).GetUserIdentity(ctx, pgdb.GetUserIdentityParams{Provider: provider, ProviderUserID: providerUserID})
+	if err != nil {
+		return nil, err
+	}
+	out := db.UserIdentity(identity)
+	return &out, nil
+}
+
+func (r *postgresRepository) ListUserIdentities(ctx context.Context, userID string) ([]db.UserIdentity, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	identities, err := pgdb.New(r.db).ListUserIdentities(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.UserIdentity, len(identities))
+	for i, identity := range identities {
+		out[i] = db.UserIdentity(identity)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) UpdateUserIdentityLogin(ctx context.Context, identity *db.UserIdentity) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).UpdateUserIdentityLogin(ctx, pgdb.UpdateUserIdentityLoginParams{
+		Provider:       identity.Provider,
+		ProviderUserID: identity.ProviderUserID,
+		Email:          identity.Email,
+		EmailVerified:  identity.EmailVerified,
+		LastLoginAt:    identity.LastLoginAt,
+	})
+}
+
+func (r *postgresRepository) DeleteUserIdentity(ctx context.Context, userID string, provider string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).DeleteUserIdentity(ctx, pgdb.DeleteUserIdentityParams{UserID: userID, Provider: provider})
+	return n > 0, err
+}
+
+func (r *postgresRepository) SetUserPrimaryIdentity(ctx context.Context, userID string, provider string, providerUserID string) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).SetUserPrimaryIdentity(ctx, pgdb.SetUserPrimaryIdentityParams{
+		ID:            userID,
+		OauthProvider: provider,
+		OauthUserID:   providerUserID,
+		UpdatedAt:     time.Now().Unix(),
+	})
+}
+
+// Refresh token operations
+func (r *postgresRepository) CreateRefreshToken(ctx context.Context, token *db.RefreshToken) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreateRefreshToken(ctx, pgdb.CreateRefreshTokenParams{
+		ID:        token.ID,
+		UserID:    token.UserID,
+		FamilyID:  token.FamilyID,
+		TokenHash: token.TokenHash,
+		ExpiresAt: token.ExpiresAt,
+		CreatedAt: token.CreatedAt,
+	})
+}
+
+func (r *postgresRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*db.RefreshToken, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	token, err := pgdb.New(r.db).GetRefreshTokenByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	out := db.RefreshToken(token)
+	return &out, nil
+}
+
+func (r *postgresRepository) RevokeRefreshToken(ctx context.Context, id string, reason string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	now := time.Now().Unix()
+	n, err := pgdb.New(r.db).RevokeRefreshToken(ctx, pgdb.RevokeRefreshTokenParams{
+		ID:            id,
+		RevokedAt:     sql.NullInt64{Int64: now, Valid: true},
+		RevokedReason: sql.NullString{String: reason, Valid: true},
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string, reason string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	now := time.Now().Unix()
+	return pgdb.New(r.db).RevokeRefreshTokenFamily(ctx, pgdb.RevokeRefreshTokenFamilyParams{
+		FamilyID:      familyID,
+		RevokedAt:     sql.NullInt64{Int64: now, Valid: true},
+		RevokedReason: sql.NullString{String: reason, Valid: true},
+	})
+}
+
+func (r *postgresRepository) RevokeAllUserTokens(ctx context.Context, userID string, reason string) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	now := time.Now().Unix()
+	return pgdb.New(r.db).RevokeAllUserTokens(ctx, pgdb.RevokeAllUserTokensParams{
+		UserID:        userID,
+		RevokedAt:     sql.NullInt64{Int64: now, Valid: true},
+		RevokedReason: sql.NullString{String: reason, Valid: true},
+	})
+}
+
+func (r *postgresRepository) DeleteStaleRefreshTokens(ctx context.Context, cutoff int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).DeleteStaleRefreshTokens(ctx, cutoff)
+}
+
+// Access token revocation operations
+
+func (r *postgresRepository) RevokeAccessToken(ctx context.Context, token *db.RevokedAccessToken) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).RevokeAccessToken(ctx, pgdb.RevokeAccessTokenParams{
+		Jti:       token.Jti,
+		UserID:    token.UserID,
+		ExpiresAt: token.ExpiresAt,
+		RevokedAt: token.RevokedAt,
+	})
+}
+
+func (r *postgresRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	_, err := pgdb.New(r.db).GetRevokedAccessToken(ctx, jti)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *postgresRepository) ListRevokedAccessTokensSince(ctx context.Context, since int64, now int64) ([]db.RevokedAccessToken, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tokens, err := pgdb.New(r.db).ListRevokedAccessTokensSince(ctx, pgdb.ListRevokedAccessTokensSinceParams{
+		RevokedAt: since,
+		ExpiresAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.RevokedAccessToken, len(tokens))
+	for i, t := range tokens {
+		out[i] = db.RevokedAccessToken(t)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) DeleteExpiredRevokedAccessTokens(ctx context.Context, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).DeleteExpiredRevokedAccessTokens(ctx, now)
+}
+
+// Personal access token operations
+
+func (r *postgresRepository) CreatePersonalAccessToken(ctx context.Context, token *db.PersonalAccessToken) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreatePersonalAccessToken(ctx, pgdb.CreatePersonalAccessTokenParams{
+		ID:          token.ID,
+		UserID:      token.UserID,
+		Name:        token.Name,
+		TokenHash:   token.TokenHash,
+		TokenPrefix: token.TokenPrefix,
+		Scopes:      token.Scopes,
+		ExpiresAt:   token.ExpiresAt,
+		CreatedAt:   token.CreatedAt,
+	})
+}
+
+func (r *postgresRepository) GetPersonalAccessTokenByHash(ctx context.Context, hash string) (*db.PersonalAccessToken, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	token, err := pgdb.New(r.db).GetPersonalAccessTokenByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	out := db.PersonalAccessToken(token)
+	return &out, nil
+}
+
+func (r *postgresRepository) ListPersonalAccessTokensByUser(ctx context.Context, userID string) ([]db.PersonalAccessToken, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tokens, err := pgdb.New(r.db).ListPersonalAccessTokensByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.PersonalAccessToken, len(tokens))
+	for i, t := range tokens {
+		out[i] = db.PersonalAccessToken(t)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) CountPersonalAccessTokensByUser(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CountPersonalAccessTokensByUser(ctx, userID)
+}
+
+func (r *postgresRepository) RevokePersonalAccessToken(ctx context.Context, id string, userID string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).RevokePersonalAccessToken(ctx, pgdb.RevokePersonalAccessTokenParams{
+		ID:        id,
+		UserID:    userID,
+		RevokedAt: sql.NullInt64{Int64: time.Now().Unix(), Valid: true},
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) TouchPersonalAccessToken(ctx context.Context, id string, now int64, staleBefore int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).TouchPersonalAccessToken(ctx, pgdb.TouchPersonalAccessTokenParams{
+		ID:          id,
+		LastUsedAt:  sql.NullInt64{Int64: now, Valid: true},
+		StaleBefore: sql.NullInt64{Int64: staleBefore, Valid: true},
+	})
+}
+
+// Session operations
+
+func (r *postgresRepository) CreateSession(ctx context.Context, session *db.Session) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreateSession(ctx, pgdb.CreateSessionParams{
+		ID:                   session.ID,
+		UserID:               session.UserID,
+		UserAgent:            session.UserAgent,
+		IpAddress:            session.IpAddress,
+		CreatedAt:            session.CreatedAt,
+		LastRefreshedAt:      session.LastRefreshedAt,
+		AccessTokenJti:       session.AccessTokenJti,
+		AccessTokenExpiresAt: session.AccessTokenExpiresAt,
+	})
+}
+
+func (r *postgresRepository) UpdateSessionRefresh(ctx context.Context, session *db.Session) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).UpdateSessionRefresh(ctx, pgdb.UpdateSessionRefreshParams{
+		ID:                   session.ID,
+		UserAgent:            session.UserAgent,
+		IpAddress:            session.IpAddress,
+		LastRefreshedAt:      session.LastRefreshedAt,
+		AccessTokenJti:       session.AccessTokenJti,
+		AccessTokenExpiresAt: session.AccessTokenExpiresAt,
+	})
+}
+
+func (r *postgresRepository) GetUserSession(ctx context.Context, id string, userID string) (*db.Session, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	session, err := pgdb.New(r.db).GetUserSession(ctx, pgdb.GetUserSessionParams{ID: id, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	out := db.Session(session)
+	return &out, nil
+}
+
+func (r *postgresRepository) ListActiveSessionsByUser(ctx context.Context, userID string, now int64) ([]db.Session, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	sessions, err := pgdb.New(r.db).ListActiveSessionsByUser(ctx, pgdb.ListActiveSessionsByUserParams{UserID: userID, Now: now})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.Session, len(sessions))
+	for i, s := range sessions {
+		out[i] = db.Session(s)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) DeleteEndedSessions(ctx context.Context, cutoff int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).DeleteEndedSessions(ctx, cutoff)
+}
+
+// OAuth session operations
+
+func (r *postgresRepository) CreateOAuthSession(ctx context.Context, session *db.OauthSession) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreateOAuthSession(ctx, pgdb.CreateOAuthSessionParams{
+		State:         session.State,
+		Provider:      session.Provider,
+		CodeVerifier:  session.CodeVerifier,
+		CodeChallenge: session.CodeChallenge,
+		RedirectUri:   session.RedirectUri,
+		ExpiresAt:     session.ExpiresAt,
+		CreatedAt:     session.CreatedAt,
+		LinkUserID:    session.LinkUserID,
+	})
+}
+
+func (r *postgresRepository) GetOAuthSession(ctx context.Context, state string) (*db.OauthSession, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	session, err := pgdb.New(r.db).GetOAuthSession(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	out := db.OauthSession(session)
+	return &out, nil
+}
+
+func (r *postgresRepository) DeleteOAuthSession(ctx context.Context, state string) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).DeleteOAuthSession(ctx, state)
+}
+
+func (r *postgresRepository) CleanupExpiredOAuthSessions(ctx context.Context, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CleanupExpiredOAuthSessions(ctx, now)
+}
+
+// Device authorization operations
+
+func (r *postgresRepository) CreateDeviceAuthorization(ctx context.Context, auth *db.DeviceAuthorization) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreateDeviceAuthorization(ctx, pgdb.CreateDeviceAuthorizationParams{
+		DeviceCodeHash: auth.DeviceCodeHash,
+		UserCode:       auth.UserCode,
+		ClientName:     auth.ClientName,
+		PollInterval:   auth.PollInterval,
+		ExpiresAt:      auth.ExpiresAt,
+		CreatedAt:      auth.CreatedAt,
+	})
+}
+
+func (r *postgresRepository) GetDeviceAuthorizationByDeviceCode(ctx context.Context, deviceCodeHash string) (*db.DeviceAuthorization, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	auth, err := pgdb.New(r.db).GetDeviceAuthorizationByDeviceCode(ctx, deviceCodeHash)
+	if err != nil {
+		return nil, err
+	}
+	out := db.DeviceAuthorization(auth)
+	return &out, nil
+}
+
+func (r *postgresRepository) GetDeviceAuthorizationByUserCode(ctx context.Context, userCode string) (*db.DeviceAuthorization, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	auth, err := pgdb.New(r.db).GetDeviceAuthorizationByUserCode(ctx, userCode)
+	if err != nil {
+		return nil, err
+	}
+	out := db.DeviceAuthorization(auth)
+	return &out, nil
+}
+
+func (r *postgresRepository) UpdateDeviceAuthorizationPoll(ctx context.Context, deviceCodeHash string, polledAt int64, interval int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).UpdateDeviceAuthorizationPoll(ctx, pgdb.UpdateDeviceAuthorizationPollParams{
+		DeviceCodeHash: deviceCodeHash,
+		LastPolledAt:   sql.NullInt64{Int64: polledAt, Valid: true},
+		PollInterval:   interval,
+	})
+}
+
+func (r *postgresRepository) DecideDeviceAuthorization(ctx context.Context, userCode string, userID string, approved bool, now int64) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	status := "denied"
+	if approved {
+		status = "approved"
+	}
+	n, err := pgdb.New(r.db).DecideDeviceAuthorization(ctx, pgdb.DecideDeviceAuthorizationParams{
+		Status:    status,
+		UserID:    sql.NullString{String: userID, Valid: true},
+		UserCode:  userCode,
+		ExpiresAt: now,
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) DeleteDeviceAuthorization(ctx context.Context, deviceCodeHash string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).DeleteDeviceAuthorization(ctx, deviceCodeHash)
+	return n > 0, err
+}
+
+func (r *postgresRepository) CleanupExpiredDeviceAuthorizations(ctx context.Context, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CleanupExpiredDeviceAuthorizations(ctx, now)
+}
+
+// MFA operations
+
+func (r *postgresRepository) GetUserMFA(ctx context.Context, userID string) (*db.UserMfa, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	mfa, err := pgdb.New(r.db).GetUserMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := db.UserMfa(mfa)
+	return &out, nil
+}
+
+func (r *postgresRepository) StartUserMFAEnrollment(ctx context.Context, userID string, secret string, now int64) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).StartUserMFAEnrollment(ctx, pgdb.StartUserMFAEnrollmentParams{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: now,
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) EnableUserMFA(ctx context.Context, userID string, step int64, now int64, recoveryCodeHashes []string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	q := pgdb.New(tx)
+	enabled, err := q.EnableUserMFA(ctx, pgdb.EnableUserMFAParams{
+		EnabledAt:    sql.NullInt64{Int64: now, Valid: true},
+		LastUsedStep: step,
+		UserID:       userID,
+	})
+	if err != nil || enabled == 0 {
+		return false, err
+	}
+	if err := q.DeleteMFARecoveryCodes(ctx, userID); err != nil {
+		return false, err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if err := q.CreateMFARecoveryCode(ctx, pgdb.CreateMFARecoveryCodeParams{
+			CodeHash:  hash,
+			UserID:    userID,
+			CreatedAt: now,
+		}); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+func (r *postgresRepository) UseUserMFAStep(ctx context.Context, userID string, step int64) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).UseUserMFAStep(ctx, pgdb.UseUserMFAStepParams{Step: step, UserID: userID})
+	return n > 0, err
+}
+
+func (r *postgresRepository) DisableUserMFA(ctx context.Context, userID string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	q := pgdb.New(tx)
+	deleted, err := q.DeleteUserMFA(ctx, userID)
+	if err != nil || deleted == 0 {
+		return false, err
+	}
+	if err := q.DeleteMFARecoveryCodes(ctx, userID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *postgresRepository) UseMFARecoveryCode(ctx context.Context, userID string, codeHash string, now int64) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).UseMFARecoveryCode(ctx, pgdb.UseMFARecoveryCodeParams{
+		UsedAt:   sql.NullInt64{Int64: now, Valid: true},
+		CodeHash: codeHash,
+		UserID:   userID,
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) CountUnusedMFARecoveryCodes(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CountUnusedMFARecoveryCodes(ctx, userID)
+}
+
+func (r *postgresRepository) CreateMFAChallenge(ctx context.Context, challenge *db.MfaChallenge) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreateMFAChallenge(ctx, pgdb.CreateMFAChallengeParams{
+		TokenHash: challenge.TokenHash,
+		UserID:    challenge.UserID,
+		ExpiresAt: challenge.ExpiresAt,
+		CreatedAt: challenge.CreatedAt,
+	})
+}
+
+func (r *postgresRepository) GetMFAChallenge(ctx context.Context, tokenHash string) (*db.MfaChallenge, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	challenge, err := pgdb.New(r.db).GetMFAChallenge(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	out := db.MfaChallenge(challenge)
+	return &out, nil
+}
+
+func (r *postgresRepository) ConsumeMFAChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int64, now int64) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).ConsumeMFAChallengeAttempt(ctx, pgdb.ConsumeMFAChallengeAttemptParams{
+		TokenHash:   tokenHash,
+		MaxAttempts: maxAttempts,
+		Now:         now,
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) DeleteMFAChallenge(ctx context.Context, tokenHash string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).DeleteMFAChallenge(ctx, tokenHash)
+	return n > 0, err
+}
+
+func (r *postgresRepository) CleanupExpiredMFAChallenges(ctx context.Context, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CleanupExpiredMFAChallenges(ctx, now)
+}
+
+// Magic link operations
+
+func (r *postgresRepository) CreateMagicLink(ctx context.Context, link *db.MagicLink) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreateMagicLink(ctx, pgdb.CreateMagicLinkParams{
+		TokenHash: link.TokenHash,
+		Email:     link.Email,
+		ExpiresAt: link.ExpiresAt,
+		CreatedAt: link.CreatedAt,
+	})
+}
+
+func (r *postgresRepository) CountPendingMagicLinks(ctx context.Context, email string, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CountPendingMagicLinks(ctx, pgdb.CountPendingMagicLinksParams{Email: email, ExpiresAt: now})
+}
+
+func (r *postgresRepository) RedeemMagicLink(ctx context.Context, tokenHash string, now int64) (string, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).RedeemMagicLink(ctx, pgdb.RedeemMagicLinkParams{TokenHash: tokenHash, ExpiresAt: now})
+}
+
+func (r *postgresRepository) CleanupExpiredMagicLinks(ctx context.Context, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CleanupExpiredMagicLinks(ctx, now)
+}
+
+// Audit log operations
+
+func (r *postgresRepository) CreateAuditEvent(ctx context.Context, event *db.AuditEvent) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreateAuditEvent(ctx, pgdb.CreateAuditEventParams{
+		ActorID:    event.ActorID,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		Details:    event.Details,
+		IpAddress:  event.IpAddress,
+		CreatedAt:  event.CreatedAt,
+	})
+}
+
+func (r *postgresRepository) ListAuditEvents(ctx context.Context, before int64, actorID, targetID, action string, limit int) ([]db.AuditEvent, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	rows, err := pgdb.New(r.db).ListAuditEvents(ctx, pgdb.ListAuditEventsParams{
+		Before:   before,
+		ActorID:  actorID,
+		TargetID: targetID,
+		Action:   action,
+		MaxRows:  int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.AuditEvent, len(rows))
+	for i, row := range rows {
+		out[i] = db.AuditEvent(row)
+	}
+	return out, nil
+}
+
+// Organization operations
+
+func (r *postgresRepository) CreateOrganization(ctx context.Context, org *db.Organization, owner *db.OrganizationMember) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := pgdb.New(tx)
+	if err := q.CreateOrganization(ctx, pgdb.CreateOrganizationParams(*org)); err != nil {
+		return err
+	}
+	if err := q.AddOrganizationMember(ctx, pgdb.AddOrganizationMemberParams(*owner)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *postgresRepository) GetOrganizationByID(ctx context.Context, id string) (*db.Organization, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	org, err := pgdb.New(r.db).GetOrganizationByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := db.Organization(org)
+	return &out, nil
+}
+
+func (r *postgresRepository) GetOrganizationBySlug(ctx context.Context, slug string) (*db.Organization, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	org, err := pgdb.New(r.db).GetOrganizationBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	out := db.Organization(org)
+	return &out, nil
+}
+
+func (r *postgresRepository) UpdateOrganization(ctx context.Context, org *db.Organization) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).UpdateOrganization(ctx, pgdb.UpdateOrganizationParams{
+		Name:             org.Name,
+		RetentionSeconds: org.RetentionSeconds,
+		QuotaBytes:       org.QuotaBytes,
+		UpdatedAt:        org.UpdatedAt,
+		ID:               org.ID,
+	})
+}
+
+func (r *postgresRepository) DeleteOrganization(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).DeleteOrganization(ctx, id)
+	return n > 0, err
+}
+
+func (r *postgresRepository) ListUserOrganizations(ctx context.Context, userID string) ([]db.ListUserOrganizationsRow, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	rows, err := pgdb.New(r.db).ListUserOrganizations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.ListUserOrganizationsRow, len(rows))
+	for i, row := range rows {
+		out[i] = db.ListUserOrganizationsRow(row)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) CountUserOrganizations(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CountUserOrganizations(ctx, userID)
+}
+
+func (r *postgresRepository) AddOrganizationMember(ctx context.Context, member *db.OrganizationMember) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).AddOrganizationMember(ctx, pgdb.AddOrganizationMemberParams(*member))
+}
+
+func (r *postgresRepository) GetOrganizationMember(ctx context.Context, orgID, userID string) (*db.OrganizationMember, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	member, err := pgdb.New(r.db).GetOrganizationMember(ctx, pgdb.GetOrganizationMemberParams{OrgID: orgID, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	out := db.OrganizationMember(member)
+	return &out, nil
+}
+
+func (r *postgresRepository) ListOrganizationMembers(ctx context.Context, orgID string) ([]db.ListOrganizationMembersRow, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	rows, err := pgdb.New(r.db).ListOrganizationMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.ListOrganizationMembersRow, len(rows))
+	for i, row := range rows {
+		out[i] = db.ListOrganizationMembersRow(row)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) SetOrganizationMemberRole(ctx context.Context, orgID, userID, role string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).SetOrganizationMemberRole(ctx, pgdb.SetOrganizationMemberRoleParams{Role: role, OrgID: orgID, UserID: userID})
+	return n > 0, err
+}
+
+func (r *postgresRepository) RemoveOrganizationMember(ctx context.Context, orgID, userID string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := pgdb.New(r.db).RemoveOrganizationMember(ctx, pgdb.RemoveOrganizationMemberParams{OrgID: orgID, UserID: userID})
+	return n > 0, err
+}
+
+func (r *postgresRepository) CountOrganizationOwners(ctx context.Context, orgID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CountOrganizationOwners(ctx, orgID)
+}
+
+func (r *postgresRepository) ListSoleOwnedOrganizations(ctx context.Context, userID string) ([]db.Organization, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	rows, err := pgdb.New(r.db).ListSoleOwnedOrganizations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.Organization, len(rows))
+	for i, row := range rows {
+		out[i] = db.Organization(row)
+	}
+	return out, nil
+}
+
+// Signing key operations
+
+func (r *postgresRepository) CreateSigningKey(ctx context.Context, key *db.SigningKey) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).CreateSigningKey(ctx, pgdb.CreateSigningKeyParams{
+		Kid:         key.Kid,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  key.PrivateKey,
+		CreatedAt:   key.CreatedAt,
+		ActivatesAt: key.ActivatesAt,
+		ExpiresAt:   key.ExpiresAt,
+	})
+}
+
+func (r *postgresRepository) ListSigningKeys(ctx context.Context) ([]db.SigningKey, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	keys, err := pgdb.New(r.db).ListSigningKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.SigningKey, len(keys))
+	for i, k := range keys {
+		out[i] = db.SigningKey(k)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) ExpireSigningKeysBefore(ctx context.Context, activatedBefore, expiresAt int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).ExpireSigningKeysBefore(ctx, pgdb.ExpireSigningKeysBeforeParams{
+		ExpiresAt:   sql.NullInt64{Int64: expiresAt, Valid: true},
+		ActivatesAt: activatedBefore,
+	})
+}
+
+func (r *postgresRepository) DeleteExpiredSigningKeys(ctx context.Context, now int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return pgdb.New(r.db).DeleteExpiredSigningKeys(ctx, sql.NullInt64{Int64: now, Valid: true})
+}
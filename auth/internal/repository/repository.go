@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 
+	internalpkg "github.com/cthulhu-platform/auth/internal/pkg"
 	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
 )
 
@@ -14,17 +15,161 @@ type Repository interface {
 	GetUserByID(ctx context.Context, id string) (*db.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*db.User, error)
 	CreateUser(ctx context.Context, user *db.User) error
+	// CreateUserWithIdentity creates a user and their first identity atomically.
+	CreateUserWithIdentity(ctx context.Context, user *db.User, identity *db.UserIdentity) error
 	UpdateUser(ctx context.Context, user *db.User) error
-	SoftDeleteUser(ctx context.Context, id string) error
+	// SoftDeleteUser also moves the user's token watermark, revokes their refresh tokens and
+	// appends the user to the account deletion feed, atomically. It reports whether the user
+	// was still active.
+	SoftDeleteUser(ctx context.Context, id string, reason string) (bool, error)
+	// SetUserTokensValidAfter sets the token watermark, in Unix milliseconds.
+	SetUserTokensValidAfter(ctx context.Context, id string, validAfter int64) error
+	SetUserSuspended(ctx context.Context, id string, suspended bool) error
+	// SetUserRole reports whether the user exists and is not deleted.
+	SetUserRole(ctx context.Context, id string, role string) (bool, error)
+	ListUserWatermarksSince(ctx context.Context, since int64) ([]db.ListUserWatermarksSinceRow, error)
+
+	// Account deletion operations
+	ListAccountDeletionsAfter(ctx context.Context, seq int64, limit int) ([]db.AccountDeletion, error)
+	ListAccountDeletionsToPurge(ctx context.Context, deletedBefore int64, limit int) ([]db.AccountDeletion, error)
+	// PurgeUser hard deletes a soft deleted user (tokens, sessions and identities cascade) and
+	// marks their feed entry purged.
+	PurgeUser(ctx context.Context, id string) error
+
+	// User identity operations
+	CreateUserIdentity(ctx context.Context, identity *db.UserIdentity) error
+	GetUserIdentity(ctx context.Context, provider string, providerUserID string) (*db.UserIdentity, error)
+	ListUserIdentities(ctx context.Context, userID string) ([]db.UserIdentity, error)
+	UpdateUserIdentityLogin(ctx context.Context, identity *db.UserIdentity) error
+	DeleteUserIdentity(ctx context.Context, userID string, provider string) (bool, error)
+	SetUserPrimaryIdentity(ctx context.Context, userID string, provider string, providerUserID string) error
 
 	// Refresh token operations
 	CreateRefreshToken(ctx context.Context, token *db.RefreshToken) error
 	GetRefreshTokenByHash(ctx context.Context, hash string) (*db.RefreshToken, error)
-	RevokeRefreshToken(ctx context.Context, id string, reason string) error
+	// RevokeRefreshToken reports whether the token was still active, so concurrent rotations of
+	// the same token cannot both succeed.
+	RevokeRefreshToken(ctx context.Context, id string, reason string) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string, reason string) (int64, error)
 	RevokeAllUserTokens(ctx context.Context, userID string, reason string) error
+	// DeleteStaleRefreshTokens deletes tokens that expired or were revoked before cutoff.
+	DeleteStaleRefreshTokens(ctx context.Context, cutoff int64) (int64, error)
+
+	// Access token revocation operations
+	RevokeAccessToken(ctx context.Context, token *db.RevokedAccessToken) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	ListRevokedAccessTokensSince(ctx context.Context, since int64, now int64) ([]db.RevokedAccessToken, error)
+	DeleteExpiredRevokedAccessTokens(ctx context.Context, now int64) (int64, error)
+
+	// Personal access token operations
+	CreatePersonalAccessToken(ctx context.Context, token *db.PersonalAccessToken) error
+	GetPersonalAccessTokenByHash(ctx context.Context, hash string) (*db.PersonalAccessToken, error)
+	ListPersonalAccessTokensByUser(ctx context.Context, userID string) ([]db.PersonalAccessToken, error)
+	CountPersonalAccessTokensByUser(ctx context.Context, userID string) (int64, error)
+	RevokePersonalAccessToken(ctx context.Context, id string, userID string) (bool, error)
+	TouchPersonalAccessToken(ctx context.Context, id string, now int64, staleBefore int64) error
+
+	// Session operations
+	CreateSession(ctx context.Context, session *db.Session) error
+	UpdateSessionRefresh(ctx context.Context, session *db.Session) error
+	GetUserSession(ctx context.Context, id string, userID string) (*db.Session, error)
+	// ListActiveSessionsByUser returns sessions whose refresh token family is still active.
+	ListActiveSessionsByUser(ctx context.Context, userID string, now int64) ([]db.Session, error)
+	// DeleteEndedSessions deletes sessions last refreshed before cutoff that have no refresh tokens left.
+	DeleteEndedSessions(ctx context.Context, cutoff int64) (int64, error)
 
 	// OAuth session operations
 	CreateOAuthSession(ctx context.Context, session *db.OauthSession) error
 	GetOAuthSession(ctx context.Context, state string) (*db.OauthSession, error)
 	DeleteOAuthSession(ctx context.Context, state string) error
+	CleanupExpiredOAuthSessions(ctx context.Context, now int64) (int64, error)
+
+	// Device authorization operations
+	CreateDeviceAuthorization(ctx context.Context, auth *db.DeviceAuthorization) error
+	GetDeviceAuthorizationByDeviceCode(ctx context.Context, deviceCodeHash string) (*db.DeviceAuthorization, error)
+	GetDeviceAuthorizationByUserCode(ctx context.Context, userCode string) (*db.DeviceAuthorization, error)
+	UpdateDeviceAuthorizationPoll(ctx context.Context, deviceCodeHash string, polledAt int64, interval int64) error
+	// DecideDeviceAuthorization approves (for userID) or denies a pending, unexpired request and
+	// reports whether it was pending.
+	DecideDeviceAuthorization(ctx context.Context, userCode string, userID string, approved bool, now int64) (bool, error)
+	DeleteDeviceAuthorization(ctx context.Context, deviceCodeHash string) (bool, error)
+	CleanupExpiredDeviceAuthorizations(ctx context.Context, now int64) (int64, error)
+
+	// MFA operations
+	GetUserMFA(ctx context.Context, userID string) (*db.UserMfa, error)
+	// StartUserMFAEnrollment stores a new pending secret, replacing an unconfirmed one. It
+	// reports false if MFA is already enabled.
+	StartUserMFAEnrollment(ctx context.Context, userID string, secret string, now int64) (bool, error)
+	// EnableUserMFA confirms a pending enrollment and replaces the user's recovery codes. It
+	// reports false if there was no pending enrollment.
+	EnableUserMFA(ctx context.Context, userID string, step int64, now int64, recoveryCodeHashes []string) (bool, error)
+	// UseUserMFAStep records a TOTP time step as used and reports false if it (or a later one) already was.
+	UseUserMFAStep(ctx context.Context, userID string, step int64) (bool, error)
+	DisableUserMFA(ctx context.Context, userID string) (bool, error)
+	UseMFARecoveryCode(ctx context.Context, userID string, codeHash string, now int64) (bool, error)
+	CountUnusedMFARecoveryCodes(ctx context.Context, userID string) (int64, error)
+	CreateMFAChallenge(ctx context.Context, challenge *db.MfaChallenge) error
+	GetMFAChallenge(ctx context.Context, tokenHash string) (*db.MfaChallenge, error)
+	// ConsumeMFAChallengeAttempt counts an attempt against an unexpired challenge and reports
+	// false once maxAttempts have been used.
+	ConsumeMFAChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int64, now int64) (bool, error)
+	DeleteMFAChallenge(ctx context.Context, tokenHash string) (bool, error)
+	CleanupExpiredMFAChallenges(ctx context.Context, now int64) (int64, error)
+
+	// Magic link operations
+	CreateMagicLink(ctx context.Context, link *db.MagicLink) error
+	CountPendingMagicLinks(ctx context.Context, email string, now int64) (int64, error)
+	// RedeemMagicLink deletes an unexpired link and returns its email, sql.ErrNoRows if there
+	// is none, so each link is redeemed at most once.
+	RedeemMagicLink(ctx context.Context, tokenHash string, now int64) (string, error)
+	CleanupExpiredMagicLinks(ctx context.Context, now int64) (int64, error)
+
+	// Audit log operations
+	CreateAuditEvent(ctx context.Context, event *db.AuditEvent) error
+	// ListAuditEvents returns events newest first, starting below the before id when it is
+	// non-zero. Empty actorID, targetID and action match any value.
+	ListAuditEvents(ctx context.Context, before int64, actorID, targetID, action string, limit int) ([]db.AuditEvent, error)
+
+	// Organization operations
+	// CreateOrganization creates the organization and its first member (the owner) in one transaction.
+	CreateOrganization(ctx context.Context, org *db.Organization, owner *db.OrganizationMember) error
+	GetOrganizationByID(ctx context.Context, id string) (*db.Organization, error)
+	GetOrganizationBySlug(ctx context.Context, slug string) (*db.Organization, error)
+	UpdateOrganization(ctx context.Context, org *db.Organization) error
+	DeleteOrganization(ctx context.Context, id string) (bool, error)
+	ListUserOrganizations(ctx context.Context, userID string) ([]db.ListUserOrganizationsRow, error)
+	CountUserOrganizations(ctx context.Context, userID string) (int64, error)
+	AddOrganizationMember(ctx context.Context, member *db.OrganizationMember) error
+	// GetOrganizationMember returns sql.ErrNoRows for deleted users, like for non-members.
+	GetOrganizationMember(ctx context.Context, orgID, userID string) (*db.OrganizationMember, error)
+	ListOrganizationMembers(ctx context.Context, orgID string) ([]db.ListOrganizationMembersRow, error)
+	SetOrganizationMemberRole(ctx context.Context, orgID, userID, role string) (bool, error)
+	RemoveOrganizationMember(ctx context.Context, orgID, userID string) (bool, error)
+	CountOrganizationOwners(ctx context.Context, orgID string) (int64, error)
+	// ListSoleOwnedOrganizations returns the organizations the user owns without another active owner.
+	ListSoleOwnedOrganizations(ctx context.Context, userID string) ([]db.Organization, error)
+
+	// Signing key operations
+	CreateSigningKey(ctx context.Context, key *db.SigningKey) error
+	ListSigningKeys(ctx context.Context) ([]db.SigningKey, error)
+	// ExpireSigningKeysBefore sets expiresAt on current keys that activated before activatedBefore.
+	ExpireSigningKeysBefore(ctx context.Context, activatedBefore, expiresAt int64) error
+	DeleteExpiredSigningKeys(ctx context.Context, now int64) error
+}
+
+// NewRepository returns the PostgreSQL repository when POSTGRES_DSN is set, otherwise the SQLite repository.
+func NewRepository(ctx context.Context) (Repository, error) {
+	// Return the concrete repositories only on success: a nil pointer in the interface is not nil
+	if internalpkg.POSTGRES_DSN != "" {
+		repo, err := NewPostgresRepository(ctx, internalpkg.POSTGRES_DSN)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+	repo, err := NewSQLiteRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
 }
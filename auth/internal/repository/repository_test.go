@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+)
+
+// repositoryTests are the behaviours both backends must share.
+var repositoryTests = []struct {
+	name string
+	run  func(t *testing.T, r Repository)
+}{
+	{"users", testUsers},
+	{"create user with identity", testCreateUserWithIdentity},
+	{"refresh tokens", testRefreshTokens},
+	{"soft delete and purge user", testSoftDeleteUser},
+	{"create organization", testCreateOrganization},
+}
+
+func TestRepository(t *testing.T) {
+	for _, tc := range repositoryTests {
+		t.Run(tc.name, func(t *testing.T) {
+			forEachBackend(t, tc.run)
+		})
+	}
+}
+
+func newTestUser(id, email string) *db.User {
+	now := time.Now().Unix()
+	return &db.User{
+		ID:            id,
+		OauthProvider: "github",
+		OauthUserID:   "gh-" + id,
+		Email:         email,
+		Username:      sql.NullString{String: id, Valid: true},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+func mustCreateUser(t *testing.T, r Repository, id, email string) *db.User {
+	t.Helper()
+	user := newTestUser(id, email)
+	if err := r.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("create user %s: %v", id, err)
+	}
+	return user
+}
+
+func testUsers(t *testing.T, r Repository) {
+	ctx := context.Background()
+	user := mustCreateUser(t, r, "u1", "ada@example.com")
+
+	got, err := r.GetUserByID(ctx, "u1")
+	if err != nil {
+		t.Fatalf("get by id: %v", err)
+	}
+	if got.Email != user.Email || got.Username != user.Username || got.Role != "user" {
+		t.Fatalf("get by id = %+v", got)
+	}
+	if got, err := r.GetUserByEmail(ctx, "ada@example.com"); err != nil || got.ID != "u1" {
+		t.Fatalf("get by email = %v, %v", got, err)
+	}
+	if got, err := r.GetUserByEmail(ctx, "Ada@Example.COM"); err != nil || got.ID != "u1" {
+		t.Fatalf("get by email in another case = %v, %v", got, err)
+	}
+	if got, err := r.GetUserByOAuthID(ctx, "github", "gh-u1"); err != nil || got.ID != "u1" {
+		t.Fatalf("get by oauth id = %v, %v", got, err)
+	}
+	if err := r.CreateUser(ctx, newTestUser("u1", "other@example.com")); err == nil {
+		t.Fatal("creating a user with a taken id succeeded")
+	}
+
+	user.Username = sql.NullString{String: "ada", Valid: true}
+	user.UpdatedAt++
+	if err := r.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got, _ := r.GetUserByID(ctx, "u1"); got.Username.String != "ada" || got.UpdatedAt != user.UpdatedAt {
+		t.Fatalf("after update = %+v", got)
+	}
+
+	if ok, err := r.SetUserRole(ctx, "u1", "moderator"); err != nil || !ok {
+		t.Fatalf("set role = %v, %v", ok, err)
+	}
+	if ok, err := r.SetUserRole(ctx, "missing", "moderator"); err != nil || ok {
+		t.Fatalf("set role of a missing user = %v, %v", ok, err)
+	}
+	if got, _ := r.GetUserByID(ctx, "u1"); got.Role != "moderator" {
+		t.Fatalf("role = %q", got.Role)
+	}
+
+	if _, err := r.GetUserByID(ctx, "missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("get missing user: err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testCreateUserWithIdentity(t *testing.T, r Repository) {
+	ctx := context.Background()
+	identity := func(userID string) *db.UserIdentity {
+		return &db.UserIdentity{
+			Provider:       "google",
+			ProviderUserID: "g-1",
+			UserID:         userID,
+			Email:          "ada@example.com",
+			EmailVerified:  true,
+			CreatedAt:      time.Now().Unix(),
+		}
+	}
+
+	// Commit: the user and the identity are both created
+	if err := r.CreateUserWithIdentity(ctx, newTestUser("u1", "ada@example.com"), identity("u1")); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := r.GetUserByID(ctx, "u1"); err != nil {
+		t.Fatalf("user after commit: %v", err)
+	}
+	if got, err := r.GetUserIdentity(ctx, "google", "g-1"); err != nil || got.UserID != "u1" || !got.EmailVerified {
+		t.Fatalf("identity after commit = %+v, %v", got, err)
+	}
+
+	// Rollback: the identity is taken, so the user must not be created either
+	if err := r.CreateUserWithIdentity(ctx, newTestUser("u2", "eve@example.com"), identity("u2")); err == nil {
+		t.Fatal("creating a second user with a taken identity succeeded")
+	}
+	if _, err := r.GetUserByID(ctx, "u2"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("user after rollback: err = %v, want sql.ErrNoRows", err)
+	}
+
+	if list, err := r.ListUserIdentities(ctx, "u1"); err != nil || len(list) != 1 {
+		t.Fatalf("identities = %v, %v", list, err)
+	}
+	if ok, err := r.DeleteUserIdentity(ctx, "u1", "google"); err != nil || !ok {
+		t.Fatalf("delete identity = %v, %v", ok, err)
+	}
+	if ok, err := r.DeleteUserIdentity(ctx, "u1", "google"); err != nil || ok {
+		t.Fatalf("delete identity again = %v, %v", ok, err)
+	}
+}
+
+func newTestRefreshToken(id, userID, familyID string) *db.RefreshToken {
+	now := time.Now().Unix()
+	return &db.RefreshToken{
+		ID:        id,
+		UserID:    userID,
+		TokenHash: "hash-" + id,
+		ExpiresAt: now + 3600,
+		CreatedAt: now,
+		FamilyID:  familyID,
+	}
+}
+
+func testRefreshTokens(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustCreateUser(t, r, "u1", "ada@example.com")
+	for _, tok := range []*db.RefreshToken{
+		newTestRefreshToken("t1", "u1", "f1"),
+		newTestRefreshToken("t2", "u1", "f1"),
+		newTestRefreshToken("t3", "u1", "f2"),
+	} {
+		if err := r.CreateRefreshToken(ctx, tok); err != nil {
+			t.Fatalf("create token %s: %v", tok.ID, err)
+		}
+	}
+
+	got, err := r.GetRefreshTokenByHash(ctx, "hash-t1")
+	if err != nil || got.ID != "t1" || got.RevokedAt.Valid {
+		t.Fatalf("get by hash = %+v, %v", got, err)
+	}
+	if ok, err := r.RevokeRefreshToken(ctx, "t1", "rotated"); err != nil || !ok {
+		t.Fatalf("revoke = %v, %v", ok, err)
+	}
+	if ok, err := r.RevokeRefreshToken(ctx, "t1", "rotated"); err != nil || ok {
+		t.Fatalf("revoke twice = %v, %v", ok, err)
+	}
+	if got, _ := r.GetRefreshTokenByHash(ctx, "hash-t1"); !got.RevokedAt.Valid || got.RevokedReason.String != "rotated" {
+		t.Fatalf("revoked token = %+v", got)
+	}
+
+	// Only the still active token of the family is revoked
+	if n, err := r.RevokeRefreshTokenFamily(ctx, "f1", "reuse"); err != nil || n != 1 {
+		t.Fatalf("revoke family = %d, %v", n, err)
+	}
+	if got, _ := r.GetRefreshTokenByHash(ctx, "hash-t3"); got.RevokedAt.Valid {
+		t.Fatal("token of another family was revoked")
+	}
+}
+
+func testSoftDeleteUser(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustCreateUser(t, r, "u1", "ada@example.com")
+	mustCreateUser(t, r, "u2", "eve@example.com")
+	if err := r.CreateRefreshToken(ctx, newTestRefreshToken("t1", "u1", "f1")); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().UnixMilli()
+	if ok, err := r.SoftDeleteUser(ctx, "u1", "account deleted"); err != nil || !ok {
+		t.Fatalf("soft delete = %v, %v", ok, err)
+	}
+	if ok, err := r.SoftDeleteUser(ctx, "u1", "account deleted"); err != nil || ok {
+		t.Fatalf("soft delete twice = %v, %v", ok, err)
+	}
+
+	// Deleted users are invisible to lookups, their tokens are revoked and the feed has them
+	if _, err := r.GetUserByID(ctx, "u1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("get deleted user: err = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := r.GetUserByEmail(ctx, "ada@example.com"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("get deleted user by email: err = %v, want sql.ErrNoRows", err)
+	}
+	if tok, _ := r.GetRefreshTokenByHash(ctx, "hash-t1"); !tok.RevokedAt.Valid || tok.RevokedReason.String != "account deleted" {
+		t.Fatalf("token of deleted user = %+v", tok)
+	}
+	// The watermark is in Unix milliseconds, like the iat_ms claim it is compared with
+	watermarks, err := r.ListUserWatermarksSince(ctx, before)
+	if err != nil || len(watermarks) != 1 || watermarks[0].ID != "u1" || watermarks[0].TokensValidAfter.Int64 > time.Now().UnixMilli() {
+		t.Fatalf("watermarks = %+v, %v", watermarks, err)
+	}
+	feed, err := r.ListAccountDeletionsAfter(ctx, 0, 10)
+	if err != nil || len(feed) != 1 || feed[0].UserID != "u1" || feed[0].PurgedAt.Valid {
+		t.Fatalf("deletion feed = %+v, %v", feed, err)
+	}
+	if _, err := r.GetUserByID(ctx, "u2"); err != nil {
+		t.Fatalf("other user: %v", err)
+	}
+
+	toPurge, err := r.ListAccountDeletionsToPurge(ctx, time.Now().Unix()+1, 10)
+	if err != nil || len(toPurge) != 1 {
+		t.Fatalf("deletions to purge = %+v, %v", toPurge, err)
+	}
+	if err := r.PurgeUser(ctx, "u1"); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if _, err := r.GetRefreshTokenByHash(ctx, "hash-t1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("token of purged user: err = %v, want sql.ErrNoRows", err)
+	}
+	if feed, _ := r.ListAccountDeletionsAfter(ctx, 0, 10); len(feed) != 1 || !feed[0].PurgedAt.Valid {
+		t.Fatalf("deletion feed after purge = %+v", feed)
+	}
+	if toPurge, _ := r.ListAccountDeletionsToPurge(ctx, time.Now().Unix()+1, 10); len(toPurge) != 0 {
+		t.Fatalf("deletions to purge after purge = %+v", toPurge)
+	}
+}
+
+func testCreateOrganization(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustCreateUser(t, r, "u1", "ada@example.com")
+	now := time.Now().Unix()
+	org := func(id, slug string) *db.Organization {
+		return &db.Organization{ID: id, Slug: slug, Name: slug, CreatedBy: "u1", CreatedAt: now, UpdatedAt: now}
+	}
+	owner := func(orgID, userID string) *db.OrganizationMember {
+		return &db.OrganizationMember{OrgID: orgID, UserID: userID, Role: "owner", CreatedAt: now}
+	}
+
+	// Commit: the organization and its owner
+	if err := r.CreateOrganization(ctx, org("o1", "acme"), owner("o1", "u1")); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if got, err := r.GetOrganizationBySlug(ctx, "acme"); err != nil || got.ID != "o1" {
+		t.Fatalf("get by slug = %+v, %v", got, err)
+	}
+	if m, err := r.GetOrganizationMember(ctx, "o1", "u1"); err != nil || m.Role != "owner" {
+		t.Fatalf("owner = %+v, %v", m, err)
+	}
+
+	// Rollback: the owner does not exist, so the organization must not either
+	if err := r.CreateOrganization(ctx, org("o2", "globex"), owner("o2", "missing")); err == nil {
+		t.Fatal("creating an organization with a missing owner succeeded")
+	}
+	if _, err := r.GetOrganizationByID(ctx, "o2"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("organization after rollback: err = %v, want sql.ErrNoRows", err)
+	}
+
+	if ok, err := r.DeleteOrganization(ctx, "o1"); err != nil || !ok {
+		t.Fatalf("delete = %v, %v", ok, err)
+	}
+	if _, err := r.GetOrganizationMember(ctx, "o1", "u1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("member of deleted organization: err = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -3,27 +3,44 @@ package repository
 import (
 	"context"
 	"database/sql"
-	_ "embed"
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	internalpkg "github.com/cthulhu-platform/auth/internal/pkg"
 	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	commonrepo "github.com/cthulhu-platform/common/pkg/repository"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed sqlc/schema.sql
-var schemaSQL string
-
 type sqliteRepository struct {
 	db *sql.DB
 }
 
 func NewSQLiteRepository(ctx context.Context) (*sqliteRepository, error) {
+	db, err := openSQLite(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply pending schema migrations
+	if err := autoMigrate(ctx, db, commonrepo.DialectSQLite); err != nil {
+		log.Printf("Failed to migrate database schema: %v\n", err)
+		db.Close()
+		return nil, err
+	}
+
+	log.Println("SQLite database initialized successfully")
+
+	r := &sqliteRepository{db: db}
+	return r, nil
+}
+
+// openSQLite opens and pings the SQLite database without touching the schema.
+func openSQLite(ctx context.Context) (*sql.DB, error) {
 	// create timeout context
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -38,8 +55,9 @@ func NewSQLiteRepository(ctx context.Context) (*sqliteRepository, error) {
 		}
 	}
 
-	// Open SQLite database connection
-	db, err := sql.Open("sqlite3", path)
+	// Open SQLite database connection. Foreign keys are a per-connection setting in SQLite,
+	// so enable them in the DSN to cover every pooled connection.
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
 	if err != nil {
 		log.Printf("Failed to open SQLite database: %v\n", err)
 		return nil, err
@@ -52,17 +70,7 @@ func NewSQLiteRepository(ctx context.Context) (*sqliteRepository, error) {
 		return nil, err
 	}
 
-	// Initialize database schema (same SQL used by sqlc for code generation)
-	if err := runSchema(ctx, db, schemaSQL); err != nil {
-		log.Printf("Failed to initialize database schema: %v\n", err)
-		db.Close()
-		return nil, err
-	}
-
-	log.Println("SQLite database initialized successfully")
-
-	r := &sqliteRepository{db: db}
-	return r, nil
+	return db, nil
 }
 
 func (r *sqliteRepository) Close() error {
@@ -129,14 +137,189 @@ func (r *sqliteRepository) UpdateUser(ctx context.Context, user *db.User) error
 	})
 }
 
-func (r *sqliteRepository) SoftDeleteUser(ctx context.Context, id string) error {
+func (r *sqliteRepository) SoftDeleteUser(ctx context.Context, id string, reason string) (bool, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	q := db.New(tx)
 	now := time.Now().Unix()
-	return db.New(r.db).SoftDeleteUser(ctx, db.SoftDeleteUserParams{
+	deleted, err := q.SoftDeleteUser(ctx, db.SoftDeleteUserParams{
+		ID:               id,
+		DeletedAt:        sql.NullInt64{Int64: now, Valid: true},
+		UpdatedAt:        now,
+		TokensValidAfter: sql.NullInt64{Int64: time.Now().UnixMilli(), Valid: true},
+	})
+	if err != nil || deleted == 0 {
+		return false, err
+	}
+	if err := q.RevokeAllUserTokens(ctx, db.RevokeAllUserTokensParams{
+		RevokedAt:     sql.NullInt64{Int64: now, Valid: true},
+		RevokedReason: sql.NullString{String: reason, Valid: true},
+		UserID:        id,
+	}); err != nil {
+		return false, err
+	}
+	if err := q.CreateAccountDeletion(ctx, db.CreateAccountDeletionParams{UserID: id, DeletedAt: now}); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *sqliteRepository) SetUserTokensValidAfter(ctx context.Context, id string, validAfter int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).SetUserTokensValidAfter(ctx, db.SetUserTokensValidAfterParams{
+		ID:               id,
+		TokensValidAfter: sql.NullInt64{Int64: validAfter, Valid: true},
+		UpdatedAt:        time.Now().Unix(),
+	})
+}
+
+func (r *sqliteRepository) SetUserSuspended(ctx context.Context, id string, suspended bool) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	now := time.Now().Unix()
+	return db.New(r.db).SetUserSuspended(ctx, db.SetUserSuspendedParams{
+		ID:          id,
+		SuspendedAt: sql.NullInt64{Int64: now, Valid: suspended},
+		UpdatedAt:   now,
+	})
+}
+
+func (r *sqliteRepository) SetUserRole(ctx context.Context, id string, role string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).SetUserRole(ctx, db.SetUserRoleParams{
 		ID:        id,
-		DeletedAt: sql.NullInt64{Int64: now, Valid: true},
-		UpdatedAt: now,
+		Role:      role,
+		UpdatedAt: time.Now().Unix(),
+	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) ListUserWatermarksSince(ctx context.Context, since int64) ([]db.ListUserWatermarksSinceRow, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListUserWatermarksSince(ctx, sql.NullInt64{Int64: since, Valid: true})
+}
+
+// Account deletion operations
+
+func (r *sqliteRepository) ListAccountDeletionsAfter(ctx context.Context, seq int64, limit int) ([]db.AccountDeletion, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListAccountDeletionsAfter(ctx, db.ListAccountDeletionsAfterParams{Seq: seq, Limit: int64(limit)})
+}
+
+func (r *sqliteRepository) ListAccountDeletionsToPurge(ctx context.Context, deletedBefore int64, limit int) ([]db.AccountDeletion, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListAccountDeletionsToPurge(ctx, db.ListAccountDeletionsToPurgeParams{DeletedAt: deletedBefore, Limit: int64(limit)})
+}
+
+func (r *sqliteRepository) PurgeUser(ctx context.Context, id string) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := db.New(tx)
+	if err := q.HardDeleteUser(ctx, id); err != nil {
+		return err
+	}
+	if err := q.MarkAccountPurged(ctx, db.MarkAccountPurgedParams{
+		PurgedAt: sql.NullInt64{Int64: time.Now().Unix(), Valid: true},
+		UserID:   id,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// User identity operations
+
+func (r *sqliteRepository) CreateUserWithIdentity(ctx context.Context, user *db.User, identity *db.UserIdentity) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := db.New(tx)
+	if err := q.CreateUser(ctx, db.CreateUserParams{
+		ID:            user.ID,
+		OauthProvider: user.OauthProvider,
+		OauthUserID:   user.OauthUserID,
+		Email:         user.Email,
+		Username:      user.Username,
+		AvatarUrl:     user.AvatarUrl,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}); err != nil {
+		return err
+	}
+	if err := q.CreateUserIdentity(ctx, db.CreateUserIdentityParams(*identity)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteRepository) CreateUserIdentity(ctx context.Context, identity *db.UserIdentity) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CreateUserIdentity(ctx, db.CreateUserIdentityParams(*identity))
+}
+
+func (r *sqliteRepository) GetUserIdentity(ctx context.Context, provider string, providerUserID string) (*db.UserIdentity, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	identity, err := db.New(r.db).GetUserIdentity(ctx, db.GetUserIdentityParams{Provider: provider, ProviderUserID: providerUserID})
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *sqliteRepository) ListUserIdentities(ctx context.Context, userID string) ([]db.UserIdentity, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListUserIdentities(ctx, userID)
+}
+
+func (r *sqliteRepository) UpdateUserIdentityLogin(ctx context.Context, identity *db.UserIdentity) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).UpdateUserIdentityLogin(ctx, db.UpdateUserIdentityLoginParams{
+		Provider:       identity.Provider,
+		ProviderUserID: identity.ProviderUserID,
+		Email:          identity.Email,
+		EmailVerified:  identity.EmailVerified,
+		LastLoginAt:    identity.LastLoginAt,
+	})
+}
+
+func (r *sqliteRepository) DeleteUserIdentity(ctx context.Context, userID string, provider string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).DeleteUserIdentity(ctx, db.DeleteUserIdentityParams{UserID: userID, Provider: provider})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) SetUserPrimaryIdentity(ctx context.Context, userID string, provider string, providerUserID string) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).SetUserPrimaryIdentity(ctx, db.SetUserPrimaryIdentityParams{
+		ID:            userID,
+		OauthProvider: provider,
+		OauthUserID:   providerUserID,
+		UpdatedAt:     time.Now().Unix(),
 	})
 }
 
@@ -147,6 +330,7 @@ func (r *sqliteRepository) CreateRefreshToken(ctx context.Context, token *db.Ref
 	return db.New(r.db).CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
 		ID:        token.ID,
 		UserID:    token.UserID,
+		FamilyID:  token.FamilyID,
 		TokenHash: token.TokenHash,
 		ExpiresAt: token.ExpiresAt,
 		CreatedAt: token.CreatedAt,
@@ -163,15 +347,27 @@ func (r *sqliteRepository) GetRefreshTokenByHash(ctx context.Context, hash strin
 	return &token, nil
 }
 
-func (r *sqliteRepository) RevokeRefreshToken(ctx context.Context, id string, reason string) error {
+func (r *sqliteRepository) RevokeRefreshToken(ctx context.Context, id string, reason string) (bool, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
 	now := time.Now().Unix()
-	return db.New(r.db).RevokeRefreshToken(ctx, db.RevokeRefreshTokenParams{
+	n, err := db.New(r.db).RevokeRefreshToken(ctx, db.RevokeRefreshTokenParams{
 		ID:            id,
 		RevokedAt:     sql.NullInt64{Int64: now, Valid: true},
 		RevokedReason: sql.NullString{String: reason, Valid: true},
 	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string, reason string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	now := time.Now().Unix()
+	return db.New(r.db).RevokeRefreshTokenFamily(ctx, db.RevokeRefreshTokenFamilyParams{
+		FamilyID:      familyID,
+		RevokedAt:     sql.NullInt64{Int64: now, Valid: true},
+		RevokedReason: sql.NullString{String: reason, Valid: true},
+	})
 }
 
 func (r *sqliteRepository) RevokeAllUserTokens(ctx context.Context, userID string, reason string) error {
@@ -185,6 +381,165 @@ func (r *sqliteRepository) RevokeAllUserTokens(ctx context.Context, userID strin
 	})
 }
 
+func (r *sqliteRepository) DeleteStaleRefreshTokens(ctx context.Context, cutoff int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).DeleteStaleRefreshTokens(ctx, cutoff)
+}
+
+// Access token revocation operations
+
+func (r *sqliteRepository) RevokeAccessToken(ctx context.Context, token *db.RevokedAccessToken) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).RevokeAccessToken(ctx, db.RevokeAccessTokenParams{
+		Jti:       token.Jti,
+		UserID:    token.UserID,
+		ExpiresAt: token.ExpiresAt,
+		RevokedAt: token.RevokedAt,
+	})
+}
+
+func (r *sqliteRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	_, err := db.New(r.db).GetRevokedAccessToken(ctx, jti)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *sqliteRepository) ListRevokedAccessTokensSince(ctx context.Context, since int64, now int64) ([]db.RevokedAccessToken, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListRevokedAccessTokensSince(ctx, db.ListRevokedAccessTokensSinceParams{
+		RevokedAt: since,
+		ExpiresAt: now,
+	})
+}
+
+func (r *sqliteRepository) DeleteExpiredRevokedAccessTokens(ctx context.Context, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).DeleteExpiredRevokedAccessTokens(ctx, now)
+}
+
+// Personal access token operations
+
+func (r *sqliteRepository) CreatePersonalAccessToken(ctx context.Context, token *db.PersonalAccessToken) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CreatePersonalAccessToken(ctx, db.CreatePersonalAccessTokenParams{
+		ID:          token.ID,
+		UserID:      token.UserID,
+		Name:        token.Name,
+		TokenHash:   token.TokenHash,
+		TokenPrefix: token.TokenPrefix,
+		Scopes:      token.Scopes,
+		ExpiresAt:   token.ExpiresAt,
+		CreatedAt:   token.CreatedAt,
+	})
+}
+
+func (r *sqliteRepository) GetPersonalAccessTokenByHash(ctx context.Context, hash string) (*db.PersonalAccessToken, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	token, err := db.New(r.db).GetPersonalAccessTokenByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *sqliteRepository) ListPersonalAccessTokensByUser(ctx context.Context, userID string) ([]db.PersonalAccessToken, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListPersonalAccessTokensByUser(ctx, userID)
+}
+
+func (r *sqliteRepository) CountPersonalAccessTokensByUser(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CountPersonalAccessTokensByUser(ctx, userID)
+}
+
+func (r *sqliteRepository) RevokePersonalAccessToken(ctx context.Context, id string, userID string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).RevokePersonalAccessToken(ctx, db.RevokePersonalAccessTokenParams{
+		ID:        id,
+		UserID:    userID,
+		RevokedAt: sql.NullInt64{Int64: time.Now().Unix(), Valid: true},
+	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) TouchPersonalAccessToken(ctx context.Context, id string, now int64, staleBefore int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).TouchPersonalAccessToken(ctx, db.TouchPersonalAccessTokenParams{
+		ID:          id,
+		LastUsedAt:  sql.NullInt64{Int64: now, Valid: true},
+		StaleBefore: sql.NullInt64{Int64: staleBefore, Valid: true},
+	})
+}
+
+// Session operations
+
+func (r *sqliteRepository) CreateSession(ctx context.Context, session *db.Session) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CreateSession(ctx, db.CreateSessionParams{
+		ID:                   session.ID,
+		UserID:               session.UserID,
+		UserAgent:            session.UserAgent,
+		IpAddress:            session.IpAddress,
+		CreatedAt:            session.CreatedAt,
+		LastRefreshedAt:      session.LastRefreshedAt,
+		AccessTokenJti:       session.AccessTokenJti,
+		AccessTokenExpiresAt: session.AccessTokenExpiresAt,
+	})
+}
+
+func (r *sqliteRepository) UpdateSessionRefresh(ctx context.Context, session *db.Session) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).UpdateSessionRefresh(ctx, db.UpdateSessionRefreshParams{
+		ID:                   session.ID,
+		UserAgent:            session.UserAgent,
+		IpAddress:            session.IpAddress,
+		LastRefreshedAt:      session.LastRefreshedAt,
+		AccessTokenJti:       session.AccessTokenJti,
+		AccessTokenExpiresAt: session.AccessTokenExpiresAt,
+	})
+}
+
+func (r *sqliteRepository) GetUserSession(ctx context.Context, id string, userID string) (*db.Session, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	session, err := db.New(r.db).GetUserSession(ctx, db.GetUserSessionParams{ID: id, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sqliteRepository) ListActiveSessionsByUser(ctx context.Context, userID string, now int64) ([]db.Session, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListActiveSessionsByUser(ctx, db.ListActiveSessionsByUserParams{UserID: userID, Now: now})
+}
+
+func (r *sqliteRepository) DeleteEndedSessions(ctx context.Context, cutoff int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).DeleteEndedSessions(ctx, cutoff)
+}
+
 // OAuth session operations
 
 func (r *sqliteRepository) CreateOAuthSession(ctx context.Context, session *db.OauthSession) error {
@@ -198,6 +553,7 @@ func (r *sqliteRepository) CreateOAuthSession(ctx context.Context, session *db.O
 		RedirectUri:   session.RedirectUri,
 		ExpiresAt:     session.ExpiresAt,
 		CreatedAt:     session.CreatedAt,
+		LinkUserID:    session.LinkUserID,
 	})
 }
 
@@ -217,24 +573,441 @@ func (r *sqliteRepository) DeleteOAuthSession(ctx context.Context, state string)
 	return db.New(r.db).DeleteOAuthSession(ctx, state)
 }
 
-// runSchema executes schema SQL statement by statement (database/sql runs one per Exec).
-func runSchema(ctx context.Context, db *sql.DB, schema string) error {
-	for _, stmt := range splitStatements(schema) {
-		if _, err := db.ExecContext(ctx, stmt); err != nil {
-			return err
-		}
+func (r *sqliteRepository) CleanupExpiredOAuthSessions(ctx context.Context, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CleanupExpiredOAuthSessions(ctx, now)
+}
+
+// Device authorization operations
+
+func (r *sqliteRepository) CreateDeviceAuthorization(ctx context.Context, auth *db.DeviceAuthorization) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CreateDeviceAuthorization(ctx, db.CreateDeviceAuthorizationParams{
+		DeviceCodeHash: auth.DeviceCodeHash,
+		UserCode:       auth.UserCode,
+		ClientName:     auth.ClientName,
+		PollInterval:   auth.PollInterval,
+		ExpiresAt:      auth.ExpiresAt,
+		CreatedAt:      auth.CreatedAt,
+	})
+}
+
+func (r *sqliteRepository) GetDeviceAuthorizationByDeviceCode(ctx context.Context, deviceCodeHash string) (*db.DeviceAuthorization, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	auth, err := db.New(r.db).GetDeviceAuthorizationByDeviceCode(ctx, deviceCodeHash)
+	if err != nil {
+		return nil, err
+	}
+	return &auth, nil
+}
+
+func (r *sqliteRepository) GetDeviceAuthorizationByUserCode(ctx context.Context, userCode string) (*db.DeviceAuthorization, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	auth, err := db.New(r.db).GetDeviceAuthorizationByUserCode(ctx, userCode)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return &auth, nil
 }
 
-func splitStatements(schema string) []string {
-	var out []string
-	for _, s := range strings.Split(schema, ";") {
-		if t := strings.TrimSpace(s); t != "" {
-			out = append(out, t)
+func (r *sqliteRepository) UpdateDeviceAuthorizationPoll(ctx context.Context, deviceCodeHash string, polledAt int64, interval int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).UpdateDeviceAuthorizationPoll(ctx, db.UpdateDeviceAuthorizationPollParams{
+		DeviceCodeHash: deviceCodeHash,
+		LastPolledAt:   sql.NullInt64{Int64: polledAt, Valid: true},
+		PollInterval:   interval,
+	})
+}
+
+func (r *sqliteRepository) DecideDeviceAuthorization(ctx context.Context, userCode string, userID string, approved bool, now int64) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	status := "denied"
+	if approved {
+		status = "approved"
+	}
+	n, err := db.New(r.db).DecideDeviceAuthorization(ctx, db.DecideDeviceAuthorizationParams{
+		Status:    status,
+		UserID:    sql.NullString{String: userID, Valid: true},
+		UserCode:  userCode,
+		ExpiresAt: now,
+	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) DeleteDeviceAuthorization(ctx context.Context, deviceCodeHash string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).DeleteDeviceAuthorization(ctx, deviceCodeHash)
+	return n > 0, err
+}
+
+func (r *sqliteRepository) CleanupExpiredDeviceAuthorizations(ctx context.Context, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CleanupExpiredDeviceAuthorizations(ctx, now)
+}
+
+// MFA operations
+
+func (r *sqliteRepository) GetUserMFA(ctx context.Context, userID string) (*db.UserMfa, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	mfa, err := db.New(r.db).GetUserMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (r *sqliteRepository) StartUserMFAEnrollment(ctx context.Context, userID string, secret string, now int64) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).StartUserMFAEnrollment(ctx, db.StartUserMFAEnrollmentParams{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: now,
+	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) EnableUserMFA(ctx context.Context, userID string, step int64, now int64, recoveryCodeHashes []string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	q := db.New(tx)
+	enabled, err := q.EnableUserMFA(ctx, db.EnableUserMFAParams{
+		EnabledAt:    sql.NullInt64{Int64: now, Valid: true},
+		LastUsedStep: step,
+		UserID:       userID,
+	})
+	if err != nil || enabled == 0 {
+		return false, err
+	}
+	if err := q.DeleteMFARecoveryCodes(ctx, userID); err != nil {
+		return false, err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if err := q.CreateMFARecoveryCode(ctx, db.CreateMFARecoveryCodeParams{
+			CodeHash:  hash,
+			UserID:    userID,
+			CreatedAt: now,
+		}); err != nil {
+			return false, err
 		}
 	}
-	return out
+	return true, tx.Commit()
+}
+
+func (r *sqliteRepository) UseUserMFAStep(ctx context.Context, userID string, step int64) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).UseUserMFAStep(ctx, db.UseUserMFAStepParams{Step: step, UserID: userID})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) DisableUserMFA(ctx context.Context, userID string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	q := db.New(tx)
+	deleted, err := q.DeleteUserMFA(ctx, userID)
+	if err != nil || deleted == 0 {
+		return false, err
+	}
+	if err := q.DeleteMFARecoveryCodes(ctx, userID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *sqliteRepository) UseMFARecoveryCode(ctx context.Context, userID string, codeHash string, now int64) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).UseMFARecoveryCode(ctx, db.UseMFARecoveryCodeParams{
+		UsedAt:   sql.NullInt64{Int64: now, Valid: true},
+		CodeHash: codeHash,
+		UserID:   userID,
+	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) CountUnusedMFARecoveryCodes(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CountUnusedMFARecoveryCodes(ctx, userID)
+}
+
+func (r *sqliteRepository) CreateMFAChallenge(ctx context.Context, challenge *db.MfaChallenge) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CreateMFAChallenge(ctx, db.CreateMFAChallengeParams{
+		TokenHash: challenge.TokenHash,
+		UserID:    challenge.UserID,
+		ExpiresAt: challenge.ExpiresAt,
+		CreatedAt: challenge.CreatedAt,
+	})
+}
+
+func (r *sqliteRepository) GetMFAChallenge(ctx context.Context, tokenHash string) (*db.MfaChallenge, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	challenge, err := db.New(r.db).GetMFAChallenge(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func (r *sqliteRepository) ConsumeMFAChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int64, now int64) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).ConsumeMFAChallengeAttempt(ctx, db.ConsumeMFAChallengeAttemptParams{
+		TokenHash:   tokenHash,
+		MaxAttempts: maxAttempts,
+		Now:         now,
+	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) DeleteMFAChallenge(ctx context.Context, tokenHash string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).DeleteMFAChallenge(ctx, tokenHash)
+	return n > 0, err
+}
+
+func (r *sqliteRepository) CleanupExpiredMFAChallenges(ctx context.Context, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CleanupExpiredMFAChallenges(ctx, now)
+}
+
+// Magic link operations
+
+func (r *sqliteRepository) CreateMagicLink(ctx context.Context, link *db.MagicLink) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CreateMagicLink(ctx, db.CreateMagicLinkParams{
+		TokenHash: link.TokenHash,
+		Email:     link.Email,
+		ExpiresAt: link.ExpiresAt,
+		CreatedAt: link.CreatedAt,
+	})
+}
+
+func (r *sqliteRepository) CountPendingMagicLinks(ctx context.Context, email string, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CountPendingMagicLinks(ctx, db.CountPendingMagicLinksParams{Email: email, ExpiresAt: now})
+}
+
+func (r *sqliteRepository) RedeemMagicLink(ctx context.Context, tokenHash string, now int64) (string, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).RedeemMagicLink(ctx, db.RedeemMagicLinkParams{TokenHash: tokenHash, ExpiresAt: now})
+}
+
+func (r *sqliteRepository) CleanupExpiredMagicLinks(ctx context.Context, now int64) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CleanupExpiredMagicLinks(ctx, now)
+}
+
+// Audit log operations
+
+func (r *sqliteRepository) CreateAuditEvent(ctx context.Context, event *db.AuditEvent) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CreateAuditEvent(ctx, db.CreateAuditEventParams{
+		ActorID:    event.ActorID,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		Details:    event.Details,
+		IpAddress:  event.IpAddress,
+		CreatedAt:  event.CreatedAt,
+	})
+}
+
+func (r *sqliteRepository) ListAuditEvents(ctx context.Context, before int64, actorID, targetID, action string, limit int) ([]db.AuditEvent, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListAuditEvents(ctx, db.ListAuditEventsParams{
+		Before:   before,
+		ActorID:  actorID,
+		TargetID: targetID,
+		Action:   action,
+		MaxRows:  int64(limit),
+	})
+}
+
+// Organization operations
+
+func (r *sqliteRepository) CreateOrganization(ctx context.Context, org *db.Organization, owner *db.OrganizationMember) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := db.New(tx)
+	if err := q.CreateOrganization(ctx, db.CreateOrganizationParams(*org)); err != nil {
+		return err
+	}
+	if err := q.AddOrganizationMember(ctx, db.AddOrganizationMemberParams(*owner)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteRepository) GetOrganizationByID(ctx context.Context, id string) (*db.Organization, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	org, err := db.New(r.db).GetOrganizationByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *sqliteRepository) GetOrganizationBySlug(ctx context.Context, slug string) (*db.Organization, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	org, err := db.New(r.db).GetOrganizationBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *sqliteRepository) UpdateOrganization(ctx context.Context, org *db.Organization) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).UpdateOrganization(ctx, db.UpdateOrganizationParams{
+		Name:             org.Name,
+		RetentionSeconds: org.RetentionSeconds,
+		QuotaBytes:       org.QuotaBytes,
+		UpdatedAt:        org.UpdatedAt,
+		ID:               org.ID,
+	})
+}
+
+func (r *sqliteRepository) DeleteOrganization(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).DeleteOrganization(ctx, id)
+	return n > 0, err
+}
+
+func (r *sqliteRepository) ListUserOrganizations(ctx context.Context, userID string) ([]db.ListUserOrganizationsRow, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListUserOrganizations(ctx, userID)
+}
+
+func (r *sqliteRepository) CountUserOrganizations(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CountUserOrganizations(ctx, userID)
+}
+
+func (r *sqliteRepository) AddOrganizationMember(ctx context.Context, member *db.OrganizationMember) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).AddOrganizationMember(ctx, db.AddOrganizationMemberParams(*member))
+}
+
+func (r *sqliteRepository) GetOrganizationMember(ctx context.Context, orgID, userID string) (*db.OrganizationMember, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	member, err := db.New(r.db).GetOrganizationMember(ctx, db.GetOrganizationMemberParams{OrgID: orgID, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *sqliteRepository) ListOrganizationMembers(ctx context.Context, orgID string) ([]db.ListOrganizationMembersRow, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListOrganizationMembers(ctx, orgID)
+}
+
+func (r *sqliteRepository) SetOrganizationMemberRole(ctx context.Context, orgID, userID, role string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).SetOrganizationMemberRole(ctx, db.SetOrganizationMemberRoleParams{Role: role, OrgID: orgID, UserID: userID})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) RemoveOrganizationMember(ctx context.Context, orgID, userID string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := db.New(r.db).RemoveOrganizationMember(ctx, db.RemoveOrganizationMemberParams{OrgID: orgID, UserID: userID})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) CountOrganizationOwners(ctx context.Context, orgID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CountOrganizationOwners(ctx, orgID)
+}
+
+func (r *sqliteRepository) ListSoleOwnedOrganizations(ctx context.Context, userID string) ([]db.Organization, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListSoleOwnedOrganizations(ctx, userID)
+}
+
+// Signing key operations
+
+func (r *sqliteRepository) CreateSigningKey(ctx context.Context, key *db.SigningKey) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).CreateSigningKey(ctx, db.CreateSigningKeyParams{
+		Kid:         key.Kid,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  key.PrivateKey,
+		CreatedAt:   key.CreatedAt,
+		ActivatesAt: key.ActivatesAt,
+		ExpiresAt:   key.ExpiresAt,
+	})
+}
+
+func (r *sqliteRepository) ListSigningKeys(ctx context.Context) ([]db.SigningKey, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ListSigningKeys(ctx)
+}
+
+func (r *sqliteRepository) ExpireSigningKeysBefore(ctx context.Context, activatedBefore, expiresAt int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).ExpireSigningKeysBefore(ctx, db.ExpireSigningKeysBeforeParams{
+		ExpiresAt:   sql.NullInt64{Int64: expiresAt, Valid: true},
+		ActivatesAt: activatedBefore,
+	})
+}
+
+func (r *sqliteRepository) DeleteExpiredSigningKeys(ctx context.Context, now int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return db.New(r.db).DeleteExpiredSigningKeys(ctx, sql.NullInt64{Int64: now, Valid: true})
 }
 
 func defaultTimeoutContext() (context.Context, context.CancelFunc) {
@@ -0,0 +1,470 @@
+-- PostgreSQL variant of ../query.sql; keep both files in sync so the backends stay interchangeable.
+
+-- name: GetUserByOAuthID :one
+SELECT * FROM users
+WHERE oauth_provider = $1 AND oauth_user_id = $2 AND deleted_at IS NULL
+LIMIT 1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1 AND deleted_at IS NULL
+LIMIT 1;
+
+-- name: GetUserByEmail :one
+-- Case-insensitive, served by idx_users_email_lower. Addresses stored before sign-in emails
+-- were normalized may differ only in case, the oldest account wins.
+SELECT * FROM users
+WHERE lower(email) = lower(CAST($1 AS TEXT)) AND deleted_at IS NULL
+ORDER BY created_at, id
+LIMIT 1;
+
+-- name: CreateUser :exec
+INSERT INTO users (
+    id, oauth_provider, oauth_user_id, email, username, avatar_url,
+    created_at, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: UpdateUser :exec
+UPDATE users
+SET username = $1, avatar_url = $2, updated_at = $3
+WHERE id = $4 AND deleted_at IS NULL;
+
+-- name: SoftDeleteUser :execrows
+UPDATE users
+SET deleted_at = $1, updated_at = $2, tokens_valid_after = $3
+WHERE id = $4 AND deleted_at IS NULL;
+
+-- name: SetUserTokensValidAfter :exec
+UPDATE users
+SET tokens_valid_after = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: SetUserSuspended :exec
+UPDATE users
+SET suspended_at = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET role = $1, updated_at = $2
+WHERE id = $3 AND deleted_at IS NULL;
+
+-- name: ListUserWatermarksSince :many
+SELECT id, tokens_valid_after FROM users
+WHERE tokens_valid_after >= $1;
+
+-- name: HardDeleteUser :exec
+DELETE FROM users
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: CreateAccountDeletion :exec
+INSERT INTO account_deletions (user_id, deleted_at)
+VALUES ($1, $2);
+
+-- name: ListAccountDeletionsAfter :many
+SELECT * FROM account_deletions
+WHERE seq > $1
+ORDER BY seq
+LIMIT $2;
+
+-- name: ListAccountDeletionsToPurge :many
+SELECT * FROM account_deletions
+WHERE purged_at IS NULL AND deleted_at <= $1
+ORDER BY seq
+LIMIT $2;
+
+-- name: MarkAccountPurged :exec
+UPDATE account_deletions
+SET purged_at = $1
+WHERE user_id = $2;
+
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (
+    provider, provider_user_id, user_id, email, email_verified, created_at, last_login_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities
+WHERE provider = $1 AND provider_user_id = $2
+LIMIT 1;
+
+-- name: ListUserIdentities :many
+SELECT * FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: UpdateUserIdentityLogin :exec
+UPDATE user_identities
+SET email = $1, email_verified = $2, last_login_at = $3
+WHERE provider = $4 AND provider_user_id = $5;
+
+-- name: DeleteUserIdentity :execrows
+DELETE FROM user_identities
+WHERE user_id = $1 AND provider = $2;
+
+-- name: SetUserPrimaryIdentity :exec
+UPDATE users
+SET oauth_provider = $1, oauth_user_id = $2, updated_at = $3
+WHERE id = $4;
+
+-- name: CreateRefreshToken :exec
+INSERT INTO refresh_tokens (
+    id, user_id, family_id, token_hash, expires_at, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
+
+-- name: GetRefreshTokenByHash :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1
+LIMIT 1;
+
+-- name: RevokeRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = $1, revoked_reason = $2
+WHERE id = $3 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = $1, revoked_reason = $2
+WHERE family_id = $3 AND revoked_at IS NULL;
+
+-- name: RevokeAllUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = $1, revoked_reason = $2
+WHERE user_id = $3 AND revoked_at IS NULL;
+
+-- name: CreateSession :exec
+INSERT INTO sessions (
+    id, user_id, user_agent, ip_address, created_at, last_refreshed_at, access_token_jti, access_token_expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: UpdateSessionRefresh :exec
+UPDATE sessions
+SET user_agent = $1, ip_address = $2, last_refreshed_at = $3, access_token_jti = $4, access_token_expires_at = $5
+WHERE id = $6;
+
+-- name: GetUserSession :one
+SELECT * FROM sessions
+WHERE id = $1 AND user_id = $2
+LIMIT 1;
+
+-- name: ListActiveSessionsByUser :many
+SELECT s.* FROM sessions s
+WHERE s.user_id = sqlc.arg(user_id) AND EXISTS (
+    SELECT 1 FROM refresh_tokens rt
+    WHERE rt.family_id = s.id AND rt.revoked_at IS NULL AND rt.expires_at > sqlc.arg(now)
+)
+ORDER BY s.last_refreshed_at DESC;
+
+-- name: DeleteStaleRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE expires_at < sqlc.arg(cutoff) OR revoked_at < sqlc.arg(cutoff);
+
+-- name: DeleteEndedSessions :execrows
+DELETE FROM sessions
+WHERE last_refreshed_at < sqlc.arg(cutoff) AND NOT EXISTS (
+    SELECT 1 FROM refresh_tokens rt
+    WHERE rt.family_id = sessions.id
+);
+
+-- name: CreateOAuthSession :exec
+INSERT INTO oauth_sessions (
+    state, provider, code_verifier, code_challenge, redirect_uri,
+    expires_at, created_at, link_user_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: GetOAuthSession :one
+SELECT * FROM oauth_sessions
+WHERE state = $1
+LIMIT 1;
+
+-- name: DeleteOAuthSession :exec
+DELETE FROM oauth_sessions
+WHERE state = $1;
+
+-- name: CleanupExpiredOAuthSessions :execrows
+DELETE FROM oauth_sessions
+WHERE expires_at < $1;
+
+-- name: CreateSigningKey :exec
+INSERT INTO signing_keys (
+    kid, algorithm, private_key, created_at, activates_at, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
+
+-- name: ListSigningKeys :many
+SELECT * FROM signing_keys
+ORDER BY activates_at ASC;
+
+-- name: ExpireSigningKeysBefore :exec
+UPDATE signing_keys
+SET expires_at = $1
+WHERE activates_at < $2 AND expires_at IS NULL;
+
+-- name: DeleteExpiredSigningKeys :exec
+DELETE FROM signing_keys
+WHERE expires_at IS NOT NULL AND expires_at < $1;
+
+
+-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens (
+    jti, user_id, expires_at, revoked_at
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT(jti) DO NOTHING;
+
+-- name: GetRevokedAccessToken :one
+SELECT * FROM revoked_access_tokens
+WHERE jti = $1
+LIMIT 1;
+
+-- name: ListRevokedAccessTokensSince :many
+SELECT * FROM revoked_access_tokens
+WHERE revoked_at >= $1 AND expires_at >= $2;
+
+-- name: DeleteExpiredRevokedAccessTokens :execrows
+DELETE FROM revoked_access_tokens
+WHERE expires_at < $1;
+
+-- name: CreatePersonalAccessToken :exec
+INSERT INTO personal_access_tokens (
+    id, user_id, name, token_hash, token_prefix, scopes, expires_at, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT * FROM personal_access_tokens
+WHERE token_hash = $1 AND revoked_at IS NULL
+LIMIT 1;
+
+-- name: ListPersonalAccessTokensByUser :many
+SELECT * FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: CountPersonalAccessTokensByUser :one
+SELECT COUNT(*) FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = $1
+WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = sqlc.arg(last_used_at)
+WHERE id = sqlc.arg(id) AND (last_used_at IS NULL OR last_used_at < sqlc.arg(stale_before));
+
+-- name: CreateDeviceAuthorization :exec
+INSERT INTO device_authorizations (
+    device_code_hash, user_code, client_name, poll_interval, expires_at, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
+
+-- name: GetDeviceAuthorizationByDeviceCode :one
+SELECT * FROM device_authorizations
+WHERE device_code_hash = $1
+LIMIT 1;
+
+-- name: GetDeviceAuthorizationByUserCode :one
+SELECT * FROM device_authorizations
+WHERE user_code = $1
+LIMIT 1;
+
+-- name: UpdateDeviceAuthorizationPoll :exec
+UPDATE device_authorizations
+SET last_polled_at = $1, poll_interval = $2
+WHERE device_code_hash = $3;
+
+-- name: DecideDeviceAuthorization :execrows
+UPDATE device_authorizations
+SET status = $1, user_id = $2
+WHERE user_code = $3 AND status = 'pending' AND expires_at >= $4;
+
+-- name: DeleteDeviceAuthorization :execrows
+DELETE FROM device_authorizations
+WHERE device_code_hash = $1;
+
+-- name: CleanupExpiredDeviceAuthorizations :execrows
+DELETE FROM device_authorizations
+WHERE expires_at < $1;
+
+-- name: GetUserMFA :one
+SELECT * FROM user_mfa
+WHERE user_id = $1
+LIMIT 1;
+
+-- name: StartUserMFAEnrollment :execrows
+INSERT INTO user_mfa (user_id, secret, created_at)
+VALUES (sqlc.arg(user_id), sqlc.arg(secret), sqlc.arg(created_at))
+ON CONFLICT (user_id) DO UPDATE
+SET secret = excluded.secret, created_at = excluded.created_at, last_used_step = 0
+WHERE user_mfa.enabled_at IS NULL;
+
+-- name: EnableUserMFA :execrows
+UPDATE user_mfa
+SET enabled_at = $1, last_used_step = $2
+WHERE user_id = $3 AND enabled_at IS NULL;
+
+-- name: UseUserMFAStep :execrows
+UPDATE user_mfa
+SET last_used_step = sqlc.arg(step)
+WHERE user_id = sqlc.arg(user_id) AND last_used_step < sqlc.arg(step);
+
+-- name: DeleteUserMFA :execrows
+DELETE FROM user_mfa
+WHERE user_id = $1;
+
+-- name: CreateMFARecoveryCode :exec
+INSERT INTO mfa_recovery_codes (code_hash, user_id, created_at)
+VALUES ($1, $2, $3);
+
+-- name: UseMFARecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = $1
+WHERE code_hash = $2 AND user_id = $3 AND used_at IS NULL;
+
+-- name: CountUnusedMFARecoveryCodes :one
+SELECT COUNT(*) FROM mfa_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (token_hash, user_id, expires_at, created_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetMFAChallenge :one
+SELECT * FROM mfa_challenges
+WHERE token_hash = $1
+LIMIT 1;
+
+-- name: ConsumeMFAChallengeAttempt :execrows
+UPDATE mfa_challenges
+SET attempts = attempts + 1
+WHERE token_hash = sqlc.arg(token_hash) AND attempts < sqlc.arg(max_attempts) AND expires_at >= sqlc.arg(now);
+
+-- name: DeleteMFAChallenge :execrows
+DELETE FROM mfa_challenges
+WHERE token_hash = $1;
+
+-- name: CleanupExpiredMFAChallenges :execrows
+DELETE FROM mfa_challenges
+WHERE expires_at < $1;
+
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (actor_id, action, target_type, target_id, details, ip_address, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE (sqlc.arg(before) = 0 OR id < sqlc.arg(before))
+    AND (sqlc.arg(actor_id) = '' OR actor_id = sqlc.arg(actor_id))
+    AND (sqlc.arg(target_id) = '' OR target_id = sqlc.arg(target_id))
+    AND (sqlc.arg(action) = '' OR action = sqlc.arg(action))
+ORDER BY id DESC
+LIMIT sqlc.arg(max_rows);
+
+-- name: CreateMagicLink :exec
+INSERT INTO magic_links (token_hash, email, expires_at, created_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: CountPendingMagicLinks :one
+SELECT COUNT(*) FROM magic_links
+WHERE email = $1 AND expires_at > $2;
+
+-- name: RedeemMagicLink :one
+DELETE FROM magic_links
+WHERE token_hash = $1 AND expires_at > $2
+RETURNING email;
+
+-- name: CleanupExpiredMagicLinks :execrows
+DELETE FROM magic_links WHERE expires_at <= $1;
+
+-- name: CreateOrganization :exec
+INSERT INTO organizations (id, slug, name, retention_seconds, quota_bytes, created_by, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetOrganizationByID :one
+SELECT * FROM organizations
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetOrganizationBySlug :one
+SELECT * FROM organizations
+WHERE slug = $1
+LIMIT 1;
+
+-- name: UpdateOrganization :exec
+UPDATE organizations
+SET name = $1, retention_seconds = $2, quota_bytes = $3, updated_at = $4
+WHERE id = $5;
+
+-- name: DeleteOrganization :execrows
+DELETE FROM organizations WHERE id = $1;
+
+-- name: ListUserOrganizations :many
+SELECT o.*, m.role FROM organizations o
+INNER JOIN organization_members m ON m.org_id = o.id
+WHERE m.user_id = $1
+ORDER BY o.name, o.id;
+
+-- name: AddOrganizationMember :exec
+INSERT INTO organization_members (org_id, user_id, role, created_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetOrganizationMember :one
+SELECT m.* FROM organization_members m
+INNER JOIN users u ON u.id = m.user_id
+WHERE m.org_id = $1 AND m.user_id = $2 AND u.deleted_at IS NULL
+LIMIT 1;
+
+-- name: ListOrganizationMembers :many
+SELECT m.user_id, m.role, m.created_at, u.email, u.username, u.avatar_url
+FROM organization_members m
+INNER JOIN users u ON u.id = m.user_id
+WHERE m.org_id = $1 AND u.deleted_at IS NULL
+ORDER BY m.created_at, m.user_id;
+
+-- name: CountUserOrganizations :one
+SELECT COUNT(*) FROM organization_members
+WHERE user_id = $1;
+
+-- name: SetOrganizationMemberRole :execrows
+UPDATE organization_members SET role = $1
+WHERE org_id = $2 AND user_id = $3;
+
+-- name: RemoveOrganizationMember :execrows
+DELETE FROM organization_members
+WHERE org_id = $1 AND user_id = $2;
+
+-- name: CountOrganizationOwners :one
+SELECT COUNT(*) FROM organization_members m
+INNER JOIN users u ON u.id = m.user_id
+WHERE m.org_id = $1 AND m.role = 'owner' AND u.deleted_at IS NULL;
+
+-- name: ListSoleOwnedOrganizations :many
+SELECT o.* FROM organizations o
+INNER JOIN organization_members m ON m.org_id = o.id
+WHERE m.user_id = $1 AND m.role = 'owner' AND NOT EXISTS (
+    SELECT 1 FROM organization_members other
+    INNER JOIN users u ON u.id = other.user_id
+    WHERE other.org_id = o.id AND other.user_id <> m.user_id AND other.role = 'owner' AND u.deleted_at IS NULL
+)
+ORDER BY o.name, o.id;
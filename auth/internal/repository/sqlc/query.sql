@@ -9,8 +9,11 @@ WHERE id = ? AND deleted_at IS NULL
 LIMIT 1;
 
 -- name: GetUserByEmail :one
+-- Case-insensitive, served by idx_users_email_lower. Addresses stored before sign-in emails
+-- were normalized may differ only in case, the oldest account wins.
 SELECT * FROM users
-WHERE email = ? AND deleted_at IS NULL
+WHERE lower(email) = lower(CAST(? AS TEXT)) AND deleted_at IS NULL
+ORDER BY created_at, id
 LIMIT 1;
 
 -- name: CreateUser :exec
@@ -26,39 +29,155 @@ UPDATE users
 SET username = ?, avatar_url = ?, updated_at = ?
 WHERE id = ? AND deleted_at IS NULL;
 
--- name: SoftDeleteUser :exec
+-- name: SoftDeleteUser :execrows
 UPDATE users
-SET deleted_at = ?, updated_at = ?
+SET deleted_at = ?, updated_at = ?, tokens_valid_after = ?
+WHERE id = ? AND deleted_at IS NULL;
+
+-- name: SetUserTokensValidAfter :exec
+UPDATE users
+SET tokens_valid_after = ?, updated_at = ?
+WHERE id = ?;
+
+-- name: SetUserSuspended :exec
+UPDATE users
+SET suspended_at = ?, updated_at = ?
+WHERE id = ?;
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET role = ?, updated_at = ?
+WHERE id = ? AND deleted_at IS NULL;
+
+-- name: ListUserWatermarksSince :many
+SELECT id, tokens_valid_after FROM users
+WHERE tokens_valid_after >= ?;
+
+-- name: HardDeleteUser :exec
+DELETE FROM users
+WHERE id = ? AND deleted_at IS NOT NULL;
+
+-- name: CreateAccountDeletion :exec
+INSERT INTO account_deletions (user_id, deleted_at)
+VALUES (?, ?);
+
+-- name: ListAccountDeletionsAfter :many
+SELECT * FROM account_deletions
+WHERE seq > ?
+ORDER BY seq
+LIMIT ?;
+
+-- name: ListAccountDeletionsToPurge :many
+SELECT * FROM account_deletions
+WHERE purged_at IS NULL AND deleted_at <= ?
+ORDER BY seq
+LIMIT ?;
+
+-- name: MarkAccountPurged :exec
+UPDATE account_deletions
+SET purged_at = ?
+WHERE user_id = ?;
+
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (
+    provider, provider_user_id, user_id, email, email_verified, created_at, last_login_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?
+);
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities
+WHERE provider = ? AND provider_user_id = ?
+LIMIT 1;
+
+-- name: ListUserIdentities :many
+SELECT * FROM user_identities
+WHERE user_id = ?
+ORDER BY created_at;
+
+-- name: UpdateUserIdentityLogin :exec
+UPDATE user_identities
+SET email = ?, email_verified = ?, last_login_at = ?
+WHERE provider = ? AND provider_user_id = ?;
+
+-- name: DeleteUserIdentity :execrows
+DELETE FROM user_identities
+WHERE user_id = ? AND provider = ?;
+
+-- name: SetUserPrimaryIdentity :exec
+UPDATE users
+SET oauth_provider = ?, oauth_user_id = ?, updated_at = ?
 WHERE id = ?;
 
 -- name: CreateRefreshToken :exec
 INSERT INTO refresh_tokens (
-    id, user_id, token_hash, expires_at, created_at
+    id, user_id, family_id, token_hash, expires_at, created_at
 ) VALUES (
-    ?, ?, ?, ?, ?
+    ?, ?, ?, ?, ?, ?
 );
 
 -- name: GetRefreshTokenByHash :one
 SELECT * FROM refresh_tokens
-WHERE token_hash = ? AND revoked_at IS NULL
+WHERE token_hash = ?
 LIMIT 1;
 
--- name: RevokeRefreshToken :exec
+-- name: RevokeRefreshToken :execrows
 UPDATE refresh_tokens
 SET revoked_at = ?, revoked_reason = ?
-WHERE id = ?;
+WHERE id = ? AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = ?, revoked_reason = ?
+WHERE family_id = ? AND revoked_at IS NULL;
 
 -- name: RevokeAllUserTokens :exec
 UPDATE refresh_tokens
 SET revoked_at = ?, revoked_reason = ?
 WHERE user_id = ? AND revoked_at IS NULL;
 
+-- name: CreateSession :exec
+INSERT INTO sessions (
+    id, user_id, user_agent, ip_address, created_at, last_refreshed_at, access_token_jti, access_token_expires_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?
+);
+
+-- name: UpdateSessionRefresh :exec
+UPDATE sessions
+SET user_agent = ?, ip_address = ?, last_refreshed_at = ?, access_token_jti = ?, access_token_expires_at = ?
+WHERE id = ?;
+
+-- name: GetUserSession :one
+SELECT * FROM sessions
+WHERE id = ? AND user_id = ?
+LIMIT 1;
+
+-- name: ListActiveSessionsByUser :many
+SELECT s.* FROM sessions s
+WHERE s.user_id = sqlc.arg(user_id) AND EXISTS (
+    SELECT 1 FROM refresh_tokens rt
+    WHERE rt.family_id = s.id AND rt.revoked_at IS NULL AND rt.expires_at > sqlc.arg(now)
+)
+ORDER BY s.last_refreshed_at DESC;
+
+-- name: DeleteStaleRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE expires_at < sqlc.arg(cutoff) OR revoked_at < sqlc.arg(cutoff);
+
+-- name: DeleteEndedSessions :execrows
+DELETE FROM sessions
+WHERE last_refreshed_at < sqlc.arg(cutoff) AND NOT EXISTS (
+    SELECT 1 FROM refresh_tokens rt
+    WHERE rt.family_id = sessions.id
+);
+
 -- name: CreateOAuthSession :exec
 INSERT INTO oauth_sessions (
     state, provider, code_verifier, code_challenge, redirect_uri,
-    expires_at, created_at
+    expires_at, created_at, link_user_id
 ) VALUES (
-    ?, ?, ?, ?, ?, ?, ?
+    ?, ?, ?, ?, ?, ?, ?, ?
 );
 
 -- name: GetOAuthSession :one
@@ -70,6 +189,280 @@ LIMIT 1;
 DELETE FROM oauth_sessions
 WHERE state = ?;
 
--- name: CleanupExpiredOAuthSessions :exec
+-- name: CleanupExpiredOAuthSessions :execrows
 DELETE FROM oauth_sessions
-WHERE expires_at < ?;
\ No newline at end of file
+WHERE expires_at < ?;
+
+-- name: CreateSigningKey :exec
+INSERT INTO signing_keys (
+    kid, algorithm, private_key, created_at, activates_at, expires_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+);
+
+-- name: ListSigningKeys :many
+SELECT * FROM signing_keys
+ORDER BY activates_at ASC;
+
+-- name: ExpireSigningKeysBefore :exec
+UPDATE signing_keys
+SET expires_at = ?
+WHERE activates_at < ? AND expires_at IS NULL;
+
+-- name: DeleteExpiredSigningKeys :exec
+DELETE FROM signing_keys
+WHERE expires_at IS NOT NULL AND expires_at < ?;
+
+
+-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens (
+    jti, user_id, expires_at, revoked_at
+) VALUES (
+    ?, ?, ?, ?
+)
+ON CONFLICT(jti) DO NOTHING;
+
+-- name: GetRevokedAccessToken :one
+SELECT * FROM revoked_access_tokens
+WHERE jti = ?
+LIMIT 1;
+
+-- name: ListRevokedAccessTokensSince :many
+SELECT * FROM revoked_access_tokens
+WHERE revoked_at >= ? AND expires_at >= ?;
+
+-- name: DeleteExpiredRevokedAccessTokens :execrows
+DELETE FROM revoked_access_tokens
+WHERE expires_at < ?;
+
+-- name: CreatePersonalAccessToken :exec
+INSERT INTO personal_access_tokens (
+    id, user_id, name, token_hash, token_prefix, scopes, expires_at, created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?
+);
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT * FROM personal_access_tokens
+WHERE token_hash = ? AND revoked_at IS NULL
+LIMIT 1;
+
+-- name: ListPersonalAccessTokensByUser :many
+SELECT * FROM personal_access_tokens
+WHERE user_id = ? AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: CountPersonalAccessTokensByUser :one
+SELECT COUNT(*) FROM personal_access_tokens
+WHERE user_id = ? AND revoked_at IS NULL;
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = ?
+WHERE id = ? AND user_id = ? AND revoked_at IS NULL;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = sqlc.arg(last_used_at)
+WHERE id = sqlc.arg(id) AND (last_used_at IS NULL OR last_used_at < sqlc.arg(stale_before));
+
+-- name: CreateDeviceAuthorization :exec
+INSERT INTO device_authorizations (
+    device_code_hash, user_code, client_name, poll_interval, expires_at, created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+);
+
+-- name: GetDeviceAuthorizationByDeviceCode :one
+SELECT * FROM device_authorizations
+WHERE device_code_hash = ?
+LIMIT 1;
+
+-- name: GetDeviceAuthorizationByUserCode :one
+SELECT * FROM device_authorizations
+WHERE user_code = ?
+LIMIT 1;
+
+-- name: UpdateDeviceAuthorizationPoll :exec
+UPDATE device_authorizations
+SET last_polled_at = ?, poll_interval = ?
+WHERE device_code_hash = ?;
+
+-- name: DecideDeviceAuthorization :execrows
+UPDATE device_authorizations
+SET status = ?, user_id = ?
+WHERE user_code = ? AND status = 'pending' AND expires_at >= ?;
+
+-- name: DeleteDeviceAuthorization :execrows
+DELETE FROM device_authorizations
+WHERE device_code_hash = ?;
+
+-- name: CleanupExpiredDeviceAuthorizations :execrows
+DELETE FROM device_authorizations
+WHERE expires_at < ?;
+
+-- name: GetUserMFA :one
+SELECT * FROM user_mfa
+WHERE user_id = ?
+LIMIT 1;
+
+-- name: StartUserMFAEnrollment :execrows
+INSERT INTO user_mfa (user_id, secret, created_at)
+VALUES (sqlc.arg(user_id), sqlc.arg(secret), sqlc.arg(created_at))
+ON CONFLICT (user_id) DO UPDATE
+SET secret = excluded.secret, created_at = excluded.created_at, last_used_step = 0
+WHERE user_mfa.enabled_at IS NULL;
+
+-- name: EnableUserMFA :execrows
+UPDATE user_mfa
+SET enabled_at = ?, last_used_step = ?
+WHERE user_id = ? AND enabled_at IS NULL;
+
+-- name: UseUserMFAStep :execrows
+UPDATE user_mfa
+SET last_used_step = sqlc.arg(step)
+WHERE user_id = sqlc.arg(user_id) AND last_used_step < sqlc.arg(step);
+
+-- name: DeleteUserMFA :execrows
+DELETE FROM user_mfa
+WHERE user_id = ?;
+
+-- name: CreateMFARecoveryCode :exec
+INSERT INTO mfa_recovery_codes (code_hash, user_id, created_at)
+VALUES (?, ?, ?);
+
+-- name: UseMFARecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = ?
+WHERE code_hash = ? AND user_id = ? AND used_at IS NULL;
+
+-- name: CountUnusedMFARecoveryCodes :one
+SELECT COUNT(*) FROM mfa_recovery_codes
+WHERE user_id = ? AND used_at IS NULL;
+
+-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = ?;
+
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (token_hash, user_id, expires_at, created_at)
+VALUES (?, ?, ?, ?);
+
+-- name: GetMFAChallenge :one
+SELECT * FROM mfa_challenges
+WHERE token_hash = ?
+LIMIT 1;
+
+-- name: ConsumeMFAChallengeAttempt :execrows
+UPDATE mfa_challenges
+SET attempts = attempts + 1
+WHERE token_hash = sqlc.arg(token_hash) AND attempts < sqlc.arg(max_attempts) AND expires_at >= sqlc.arg(now);
+
+-- name: DeleteMFAChallenge :execrows
+DELETE FROM mfa_challenges
+WHERE token_hash = ?;
+
+-- name: CleanupExpiredMFAChallenges :execrows
+DELETE FROM mfa_challenges
+WHERE expires_at < ?;
+
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (actor_id, action, target_type, target_id, details, ip_address, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE (sqlc.arg(before) = 0 OR id < sqlc.arg(before))
+    AND (sqlc.arg(actor_id) = '' OR actor_id = sqlc.arg(actor_id))
+    AND (sqlc.arg(target_id) = '' OR target_id = sqlc.arg(target_id))
+    AND (sqlc.arg(action) = '' OR action = sqlc.arg(action))
+ORDER BY id DESC
+LIMIT sqlc.arg(max_rows);
+
+-- name: CreateMagicLink :exec
+INSERT INTO magic_links (token_hash, email, expires_at, created_at)
+VALUES (?, ?, ?, ?);
+
+-- name: CountPendingMagicLinks :one
+SELECT COUNT(*) FROM magic_links
+WHERE email = ? AND expires_at > ?;
+
+-- name: RedeemMagicLink :one
+DELETE FROM magic_links
+WHERE token_hash = ? AND expires_at > ?
+RETURNING email;
+
+-- name: CleanupExpiredMagicLinks :execrows
+DELETE FROM magic_links WHERE expires_at <= ?;
+
+-- name: CreateOrganization :exec
+INSERT INTO organizations (id, slug, name, retention_seconds, quota_bytes, created_by, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetOrganizationByID :one
+SELECT * FROM organizations
+WHERE id = ?
+LIMIT 1;
+
+-- name: GetOrganizationBySlug :one
+SELECT * FROM organizations
+WHERE slug = ?
+LIMIT 1;
+
+-- name: UpdateOrganization :exec
+UPDATE organizations
+SET name = ?, retention_seconds = ?, quota_bytes = ?, updated_at = ?
+WHERE id = ?;
+
+-- name: DeleteOrganization :execrows
+DELETE FROM organizations WHERE id = ?;
+
+-- name: ListUserOrganizations :many
+SELECT o.*, m.role FROM organizations o
+INNER JOIN organization_members m ON m.org_id = o.id
+WHERE m.user_id = ?
+ORDER BY o.name, o.id;
+
+-- name: AddOrganizationMember :exec
+INSERT INTO organization_members (org_id, user_id, role, created_at)
+VALUES (?, ?, ?, ?);
+
+-- name: GetOrganizationMember :one
+SELECT m.* FROM organization_members m
+INNER JOIN users u ON u.id = m.user_id
+WHERE m.org_id = ? AND m.user_id = ? AND u.deleted_at IS NULL
+LIMIT 1;
+
+-- name: ListOrganizationMembers :many
+SELECT m.user_id, m.role, m.created_at, u.email, u.username, u.avatar_url
+FROM organization_members m
+INNER JOIN users u ON u.id = m.user_id
+WHERE m.org_id = ? AND u.deleted_at IS NULL
+ORDER BY m.created_at, m.user_id;
+
+-- name: CountUserOrganizations :one
+SELECT COUNT(*) FROM organization_members
+WHERE user_id = ?;
+
+-- name: SetOrganizationMemberRole :execrows
+UPDATE organization_members SET role = ?
+WHERE org_id = ? AND user_id = ?;
+
+-- name: RemoveOrganizationMember :execrows
+DELETE FROM organization_members
+WHERE org_id = ? AND user_id = ?;
+
+-- name: CountOrganizationOwners :one
+SELECT COUNT(*) FROM organization_members m
+INNER JOIN users u ON u.id = m.user_id
+WHERE m.org_id = ? AND m.role = 'owner' AND u.deleted_at IS NULL;
+
+-- name: ListSoleOwnedOrganizations :many
+SELECT o.* FROM organizations o
+INNER JOIN organization_members m ON m.org_id = o.id
+WHERE m.user_id = ? AND m.role = 'owner' AND NOT EXISTS (
+    SELECT 1 FROM organization_members other
+    INNER JOIN users u ON u.id = other.user_id
+    WHERE other.org_id = o.id AND other.user_id <> m.user_id AND other.role = 'owner' AND u.deleted_at IS NULL
+)
+ORDER BY o.name, o.id;
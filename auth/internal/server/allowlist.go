@@ -0,0 +1,63 @@
+package server
+
+import (
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
+	pb "github.com/cthulhu-platform/proto/pkg/auth"
+)
+
+var gatewayOnly = []string{grpcauth.Gateway}
+
+// allowlist names the services that may call each RPC when gRPC authentication is enabled.
+// Everything user-facing goes through the gateway; filemanager checks organization membership and
+// lifecycle follows account deletions.
+var allowlist = grpcauth.Allowlist{
+	pb.AuthService_InitiateOAuth_FullMethodName:               gatewayOnly,
+	pb.AuthService_HandleOAuthCallback_FullMethodName:         gatewayOnly,
+	pb.AuthService_ValidateToken_FullMethodName:               gatewayOnly,
+	pb.AuthService_RefreshToken_FullMethodName:                gatewayOnly,
+	pb.AuthService_Logout_FullMethodName:                      gatewayOnly,
+	pb.AuthService_GetJWKS_FullMethodName:                     gatewayOnly,
+	pb.AuthService_LogoutAll_FullMethodName:                   gatewayOnly,
+	pb.AuthService_SetUserSuspended_FullMethodName:            gatewayOnly,
+	pb.AuthService_GetRevocations_FullMethodName:              gatewayOnly,
+	pb.AuthService_CreatePersonalAccessToken_FullMethodName:   gatewayOnly,
+	pb.AuthService_ListPersonalAccessTokens_FullMethodName:    gatewayOnly,
+	pb.AuthService_RevokePersonalAccessToken_FullMethodName:   gatewayOnly,
+	pb.AuthService_ValidatePersonalAccessToken_FullMethodName: gatewayOnly,
+	pb.AuthService_StartDeviceAuthorization_FullMethodName:    gatewayOnly,
+	pb.AuthService_PollDeviceAuthorization_FullMethodName:     gatewayOnly,
+	pb.AuthService_GetDeviceAuthorization_FullMethodName:      gatewayOnly,
+	pb.AuthService_ApproveDeviceAuthorization_FullMethodName:  gatewayOnly,
+	pb.AuthService_ListSessions_FullMethodName:                gatewayOnly,
+	pb.AuthService_RevokeSession_FullMethodName:               gatewayOnly,
+	pb.AuthService_StartLinkIdentity_FullMethodName:           gatewayOnly,
+	pb.AuthService_LinkIdentity_FullMethodName:                gatewayOnly,
+	pb.AuthService_ListIdentities_FullMethodName:              gatewayOnly,
+	pb.AuthService_UnlinkIdentity_FullMethodName:              gatewayOnly,
+	pb.AuthService_DeleteAccount_FullMethodName:               gatewayOnly,
+	pb.AuthService_StartMFAEnrollment_FullMethodName:          gatewayOnly,
+	pb.AuthService_ConfirmMFAEnrollment_FullMethodName:        gatewayOnly,
+	pb.AuthService_DisableMFA_FullMethodName:                  gatewayOnly,
+	pb.AuthService_GetMFAStatus_FullMethodName:                gatewayOnly,
+	pb.AuthService_VerifyMFA_FullMethodName:                   gatewayOnly,
+	pb.AuthService_SetUserRole_FullMethodName:                 gatewayOnly,
+	pb.AuthService_RecordAuditEvent_FullMethodName:            gatewayOnly,
+	pb.AuthService_ListAuditEvents_FullMethodName:             gatewayOnly,
+	pb.AuthService_NotifyAbuseReporter_FullMethodName:         gatewayOnly,
+	pb.AuthService_RequestMagicLink_FullMethodName:            gatewayOnly,
+	pb.AuthService_RedeemMagicLink_FullMethodName:             gatewayOnly,
+	pb.AuthService_CreateOrganization_FullMethodName:          gatewayOnly,
+	pb.AuthService_ListOrganizations_FullMethodName:           gatewayOnly,
+	pb.AuthService_GetOrganization_FullMethodName:             gatewayOnly,
+	pb.AuthService_UpdateOrganization_FullMethodName:          gatewayOnly,
+	pb.AuthService_DeleteOrganization_FullMethodName:          gatewayOnly,
+	pb.AuthService_ListOrganizationMembers_FullMethodName:     gatewayOnly,
+	pb.AuthService_AddOrganizationMember_FullMethodName:       gatewayOnly,
+	pb.AuthService_SetOrganizationMemberRole_FullMethodName:   gatewayOnly,
+	pb.AuthService_RemoveOrganizationMember_FullMethodName:    gatewayOnly,
+	pb.AuthService_ListAccountDeletions_FullMethodName:        {grpcauth.Lifecycle},
+	pb.AuthService_GetOrganizationMembership_FullMethodName:   {grpcauth.Gateway, grpcauth.Filemanager},
+
+	"/grpc.reflection.v1.ServerReflection/":      {grpcauth.Operator},
+	"/grpc.reflection.v1alpha.ServerReflection/": {grpcauth.Operator},
+}
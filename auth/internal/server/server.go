@@ -2,16 +2,20 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"time"
 
 	"github.com/cthulhu-platform/auth/internal/service"
 	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
 	"github.com/cthulhu-platform/common/pkg/strings"
 	pb "github.com/cthulhu-platform/proto/pkg/auth"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
@@ -22,8 +26,9 @@ type grpcServer struct {
 }
 
 type ServerConfig struct {
-	Host string
-	Port string
+	Host     string
+	Port     string
+	GRPCAuth grpcauth.Config
 }
 
 func ListenGRPC(ctx context.Context, cfg ServerConfig, svc service.Service) error {
@@ -32,7 +37,15 @@ func ListenGRPC(ctx context.Context, cfg ServerConfig, svc service.Service) erro
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
-	srv := grpc.NewServer()
+	opts, err := cfg.GRPCAuth.ServerOptions(allowlist)
+	if err != nil {
+		return fmt.Errorf("failed to load gRPC credentials: %v", err)
+	}
+	if !cfg.GRPCAuth.Enabled() {
+		slog.Warn("gRPC service authentication is disabled, any caller is accepted; set GRPC_TLS_CERT_FILE, GRPC_TLS_KEY_FILE and GRPC_TLS_CA_FILE")
+	}
+
+	srv := grpc.NewServer(opts...)
 	pb.RegisterAuthServiceServer(srv, &grpcServer{service: svc})
 
 	reflection.Register(srv)
@@ -51,12 +64,15 @@ func (s *grpcServer) InitiateOAuth(ctx context.Context, req *pb.InitiateOAuthReq
 }
 
 func (s *grpcServer) HandleOAuthCallback(ctx context.Context, req *pb.HandleOAuthCallbackRequest) (*pb.HandleOAuthCallbackResponse, error) {
-	res, err := s.service.HandleOAuthCallback(ctx, req.GetProvider(), req.GetCode(), req.GetState())
+	res, err := s.service.HandleOAuthCallback(ctx, req.GetProvider(), req.GetCode(), req.GetState(), clientInfo(ctx))
 	if err != nil {
 		slog.Error("Failed to handle OAuth callback", "error", err)
 		return nil, status.Errorf(codes.Internal, "handle OAuth callback: %v", err)
 	}
-	slog.Info("OAuth callback handled for provider", "provider", req.GetProvider())
+	slog.Info("OAuth callback handled for provider", "provider", req.GetProvider(), "mfa_required", res.MFAToken != "")
+	if res.MFAToken != "" {
+		return &pb.HandleOAuthCallbackResponse{MfaToken: res.MFAToken, MfaExpiresIn: res.MFAExpiresIn}, nil
+	}
 	return &pb.HandleOAuthCallbackResponse{
 		AccessToken:  res.AccessToken,
 		RefreshToken: res.RefreshToken,
@@ -75,7 +91,7 @@ func (s *grpcServer) ValidateToken(ctx context.Context, req *pb.ValidateTokenReq
 }
 
 func (s *grpcServer) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
-	res, err := s.service.RefreshToken(ctx, req.GetRefreshToken())
+	res, err := s.service.RefreshToken(ctx, req.GetRefreshToken(), clientInfo(ctx))
 	if err != nil {
 		slog.Error("Failed to refresh token", "error", err)
 		return nil, status.Errorf(codes.Internal, "refresh token: %v", err)
@@ -97,6 +113,537 @@ func (s *grpcServer) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.Log
 	return &pb.LogoutResponse{Success: true}, nil
 }
 
+func (s *grpcServer) GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*pb.GetJWKSResponse, error) {
+	jwks, err := s.service.GetJWKS(ctx)
+	if err != nil {
+		slog.Error("Failed to get JWKS", "error", err)
+		return nil, status.Errorf(codes.Internal, "get JWKS: %v", err)
+	}
+	out := &pb.GetJWKSResponse{Keys: make([]*pb.JSONWebKey, 0, len(jwks.Keys))}
+	for _, k := range jwks.Keys {
+		key := &pb.JSONWebKey{Kty: k.Kty, Kid: k.Kid, Use: k.Use, Alg: k.Alg, Crv: k.Crv, X: k.X}
+		if k.Y != "" {
+			key.Y = &k.Y
+		}
+		out.Keys = append(out.Keys, key)
+	}
+	return out, nil
+}
+
+func (s *grpcServer) LogoutAll(ctx context.Context, req *pb.LogoutAllRequest) (*pb.LogoutAllResponse, error) {
+	err := s.service.LogoutAll(ctx, req.GetAccessToken())
+	if err != nil {
+		slog.Error("Failed to logout everywhere", "error", err)
+		return nil, status.Errorf(codes.Internal, "logout all: %v", err)
+	}
+	slog.Info("All sessions revoked", "access_token", strings.TruncateString(req.GetAccessToken(), 4))
+	return &pb.LogoutAllResponse{Success: true}, nil
+}
+
+func (s *grpcServer) SetUserSuspended(ctx context.Context, req *pb.SetUserSuspendedRequest) (*pb.SetUserSuspendedResponse, error) {
+	err := s.service.SetUserSuspended(ctx, req.GetActorId(), req.GetUserId(), req.GetSuspended(), clientInfo(ctx))
+	if err != nil {
+		slog.Error("Failed to set user suspension", "error", err)
+		return nil, status.Errorf(codes.Internal, "set user suspended: %v", err)
+	}
+	slog.Info("User suspension updated", "user_id", strings.TruncateString(req.GetUserId(), 4), "suspended", req.GetSuspended())
+	return &pb.SetUserSuspendedResponse{Success: true}, nil
+}
+
+func (s *grpcServer) GetRevocations(ctx context.Context, req *pb.GetRevocationsRequest) (*pb.GetRevocationsResponse, error) {
+	res, err := s.service.GetRevocations(ctx, req.GetSince())
+	if err != nil {
+		slog.Error("Failed to get revocations", "error", err)
+		return nil, status.Errorf(codes.Internal, "get revocations: %v", err)
+	}
+	out := &pb.GetRevocationsResponse{
+		Tokens:     make([]*pb.RevokedAccessToken, 0, len(res.Tokens)),
+		Watermarks: make([]*pb.UserTokenWatermark, 0, len(res.Watermarks)),
+		Cursor:     res.Cursor,
+	}
+	for _, t := range res.Tokens {
+		out.Tokens = append(out.Tokens, &pb.RevokedAccessToken{Jti: t.JTI, ExpiresAt: t.ExpiresAt})
+	}
+	for _, w := range res.Watermarks {
+		out.Watermarks = append(out.Watermarks, &pb.UserTokenWatermark{UserId: w.UserID, ValidAfter: w.ValidAfter, ExpiresAt: w.ExpiresAt})
+	}
+	return out, nil
+}
+
+func (s *grpcServer) CreatePersonalAccessToken(ctx context.Context, req *pb.CreatePersonalAccessTokenRequest) (*pb.CreatePersonalAccessTokenResponse, error) {
+	res, err := s.service.CreatePersonalAccessToken(ctx, req.GetAccessToken(), req.GetName(), req.GetScopes(), time.Duration(req.GetExpiresIn())*time.Second)
+	if err != nil {
+		slog.Error("Failed to create personal access token", "error", err)
+		return nil, status.Errorf(codes.Internal, "create personal access token: %v", err)
+	}
+	slog.Info("Personal access token created", "id", res.ID, "scopes", res.Scopes)
+	return &pb.CreatePersonalAccessTokenResponse{Token: res.Token, Details: personalAccessTokenToPB(res.PersonalAccessToken)}, nil
+}
+
+func (s *grpcServer) ListPersonalAccessTokens(ctx context.Context, req *pb.ListPersonalAccessTokensRequest) (*pb.ListPersonalAccessTokensResponse, error) {
+	tokens, err := s.service.ListPersonalAccessTokens(ctx, req.GetAccessToken())
+	if err != nil {
+		slog.Error("Failed to list personal access tokens", "error", err)
+		return nil, status.Errorf(codes.Internal, "list personal access tokens: %v", err)
+	}
+	out := &pb.ListPersonalAccessTokensResponse{Tokens: make([]*pb.PersonalAccessToken, 0, len(tokens))}
+	for _, t := range tokens {
+		out.Tokens = append(out.Tokens, personalAccessTokenToPB(t))
+	}
+	return out, nil
+}
+
+func (s *grpcServer) RevokePersonalAccessToken(ctx context.Context, req *pb.RevokePersonalAccessTokenRequest) (*pb.RevokePersonalAccessTokenResponse, error) {
+	err := s.service.RevokePersonalAccessToken(ctx, req.GetAccessToken(), req.GetId())
+	if err != nil {
+		slog.Error("Failed to revoke personal access token", "error", err)
+		return nil, status.Errorf(codes.Internal, "revoke personal access token: %v", err)
+	}
+	slog.Info("Personal access token revoked", "id", req.GetId())
+	return &pb.RevokePersonalAccessTokenResponse{Success: true}, nil
+}
+
+func (s *grpcServer) ValidatePersonalAccessToken(ctx context.Context, req *pb.ValidatePersonalAccessTokenRequest) (*pb.ValidatePersonalAccessTokenResponse, error) {
+	user, scopes, err := s.service.ValidatePersonalAccessToken(ctx, req.GetToken())
+	if err != nil {
+		slog.Error("Failed to validate personal access token", "error", err)
+		return nil, status.Errorf(codes.Internal, "validate personal access token: %v", err)
+	}
+	return &pb.ValidatePersonalAccessTokenResponse{User: userInfoToPB(user), Scopes: scopes}, nil
+}
+
+func (s *grpcServer) StartDeviceAuthorization(ctx context.Context, req *pb.StartDeviceAuthorizationRequest) (*pb.StartDeviceAuthorizationResponse, error) {
+	res, err := s.service.StartDeviceAuthorization(ctx, req.GetClientName())
+	if err != nil {
+		slog.Error("Failed to start device authorization", "error", err)
+		return nil, status.Errorf(codes.Internal, "start device authorization: %v", err)
+	}
+	slog.Info("Device authorization started", "client_name", req.GetClientName())
+	return &pb.StartDeviceAuthorizationResponse{
+		DeviceCode:              res.DeviceCode,
+		UserCode:                res.UserCode,
+		VerificationUri:         res.VerificationURI,
+		VerificationUriComplete: res.VerificationURIComplete,
+		ExpiresIn:               res.ExpiresIn,
+		Interval:                res.Interval,
+	}, nil
+}
+
+// PollDeviceAuthorization reports pending, denied and expired requests in the error field rather
+// than as gRPC errors, so callers can return them as RFC 8628 token errors.
+func (s *grpcServer) PollDeviceAuthorization(ctx context.Context, req *pb.PollDeviceAuthorizationRequest) (*pb.PollDeviceAuthorizationResponse, error) {
+	res, err := s.service.PollDeviceAuthorization(ctx, req.GetDeviceCode(), clientInfo(ctx))
+	var flowErr *pkg.DeviceFlowError
+	if errors.As(err, &flowErr) {
+		return &pb.PollDeviceAuthorizationResponse{Error: flowErr.Code}, nil
+	}
+	if err != nil {
+		slog.Error("Failed to poll device authorization", "error", err)
+		return nil, status.Errorf(codes.Internal, "poll device authorization: %v", err)
+	}
+	slog.Info("Device authorized", "user_id", strings.TruncateString(res.User.ID, 4))
+	return &pb.PollDeviceAuthorizationResponse{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		User:         userInfoToPB(res.User),
+	}, nil
+}
+
+func (s *grpcServer) GetDeviceAuthorization(ctx context.Context, req *pb.GetDeviceAuthorizationRequest) (*pb.GetDeviceAuthorizationResponse, error) {
+	res, err := s.service.GetDeviceAuthorization(ctx, req.GetAccessToken(), req.GetUserCode())
+	if err != nil {
+		slog.Error("Failed to get device authorization", "error", err)
+		return nil, status.Errorf(codes.Internal, "get device authorization: %v", err)
+	}
+	return &pb.GetDeviceAuthorizationResponse{
+		UserCode:   res.UserCode,
+		ClientName: res.ClientName,
+		CreatedAt:  res.CreatedAt,
+		ExpiresAt:  res.ExpiresAt,
+	}, nil
+}
+
+func (s *grpcServer) ApproveDeviceAuthorization(ctx context.Context, req *pb.ApproveDeviceAuthorizationRequest) (*pb.ApproveDeviceAuthorizationResponse, error) {
+	err := s.service.ApproveDeviceAuthorization(ctx, req.GetAccessToken(), req.GetUserCode(), req.GetApprove())
+	if err != nil {
+		slog.Error("Failed to decide device authorization", "error", err)
+		return nil, status.Errorf(codes.Internal, "approve device authorization: %v", err)
+	}
+	slog.Info("Device authorization decided", "approved", req.GetApprove())
+	return &pb.ApproveDeviceAuthorizationResponse{Success: true}, nil
+}
+
+func (s *grpcServer) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	sessions, err := s.service.ListSessions(ctx, req.GetAccessToken())
+	if err != nil {
+		slog.Error("Failed to list sessions", "error", err)
+		return nil, status.Errorf(codes.Internal, "list sessions: %v", err)
+	}
+	out := &pb.ListSessionsResponse{Sessions: make([]*pb.Session, 0, len(sessions))}
+	for _, sess := range sessions {
+		out.Sessions = append(out.Sessions, &pb.Session{
+			Id:              sess.ID,
+			UserAgent:       sess.UserAgent,
+			IpAddress:       sess.IPAddress,
+			CreatedAt:       sess.CreatedAt,
+			LastRefreshedAt: sess.LastRefreshedAt,
+			Current:         sess.Current,
+		})
+	}
+	return out, nil
+}
+
+func (s *grpcServer) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*pb.RevokeSessionResponse, error) {
+	err := s.service.RevokeSession(ctx, req.GetAccessToken(), req.GetId())
+	if err != nil {
+		slog.Error("Failed to revoke session", "error", err)
+		return nil, status.Errorf(codes.Internal, "revoke session: %v", err)
+	}
+	slog.Info("Session revoked", "id", req.GetId())
+	return &pb.RevokeSessionResponse{Success: true}, nil
+}
+
+func (s *grpcServer) StartLinkIdentity(ctx context.Context, req *pb.StartLinkIdentityRequest) (*pb.StartLinkIdentityResponse, error) {
+	redirectURL, err := s.service.StartLinkIdentity(ctx, req.GetAccessToken(), req.GetProvider())
+	if err != nil {
+		slog.Error("Failed to start identity link", "error", err)
+		return nil, status.Errorf(codes.Internal, "start link identity: %v", err)
+	}
+	return &pb.StartLinkIdentityResponse{RedirectUrl: redirectURL}, nil
+}
+
+func (s *grpcServer) LinkIdentity(ctx context.Context, req *pb.LinkIdentityRequest) (*pb.LinkIdentityResponse, error) {
+	identity, err := s.service.LinkIdentity(ctx, req.GetAccessToken(), req.GetProvider(), req.GetCode(), req.GetState())
+	if err != nil {
+		slog.Error("Failed to link identity", "error", err)
+		return nil, status.Errorf(codes.Internal, "link identity: %v", err)
+	}
+	slog.Info("Identity linked", "provider", req.GetProvider())
+	return &pb.LinkIdentityResponse{Identity: identityToPB(*identity)}, nil
+}
+
+func (s *grpcServer) ListIdentities(ctx context.Context, req *pb.ListIdentitiesRequest) (*pb.ListIdentitiesResponse, error) {
+	identities, err := s.service.ListIdentities(ctx, req.GetAccessToken())
+	if err != nil {
+		slog.Error("Failed to list identities", "error", err)
+		return nil, status.Errorf(codes.Internal, "list identities: %v", err)
+	}
+	out := &pb.ListIdentitiesResponse{Identities: make([]*pb.Identity, 0, len(identities))}
+	for _, identity := range identities {
+		out.Identities = append(out.Identities, identityToPB(identity))
+	}
+	return out, nil
+}
+
+func (s *grpcServer) UnlinkIdentity(ctx context.Context, req *pb.UnlinkIdentityRequest) (*pb.UnlinkIdentityResponse, error) {
+	err := s.service.UnlinkIdentity(ctx, req.GetAccessToken(), req.GetProvider())
+	if err != nil {
+		slog.Error("Failed to unlink identity", "error", err)
+		return nil, status.Errorf(codes.Internal, "unlink identity: %v", err)
+	}
+	slog.Info("Identity unlinked", "provider", req.GetProvider())
+	return &pb.UnlinkIdentityResponse{Success: true}, nil
+}
+
+func (s *grpcServer) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRequest) (*pb.DeleteAccountResponse, error) {
+	err := s.service.DeleteAccount(ctx, req.GetAccessToken())
+	if err != nil {
+		slog.Error("Failed to delete account", "error", err)
+		return nil, status.Errorf(codes.Internal, "delete account: %v", err)
+	}
+	slog.Info("Account deleted")
+	return &pb.DeleteAccountResponse{Success: true}, nil
+}
+
+func (s *grpcServer) ListAccountDeletions(ctx context.Context, req *pb.ListAccountDeletionsRequest) (*pb.ListAccountDeletionsResponse, error) {
+	deletions, err := s.service.ListAccountDeletions(ctx, req.GetAfter(), int(req.GetLimit()))
+	if err != nil {
+		slog.Error("Failed to list account deletions", "error", err)
+		return nil, status.Errorf(codes.Internal, "list account deletions: %v", err)
+	}
+	out := &pb.ListAccountDeletionsResponse{Deletions: make([]*pb.AccountDeletion, 0, len(deletions))}
+	for _, d := range deletions {
+		out.Deletions = append(out.Deletions, &pb.AccountDeletion{Seq: d.Seq, UserId: d.UserID, DeletedAt: d.DeletedAt})
+	}
+	return out, nil
+}
+
+func (s *grpcServer) StartMFAEnrollment(ctx context.Context, req *pb.StartMFAEnrollmentRequest) (*pb.StartMFAEnrollmentResponse, error) {
+	enrollment, err := s.service.StartMFAEnrollment(ctx, req.GetAccessToken())
+	if err != nil {
+		slog.Error("Failed to start MFA enrollment", "error", err)
+		return nil, status.Errorf(codes.Internal, "start MFA enrollment: %v", err)
+	}
+	return &pb.StartMFAEnrollmentResponse{Secret: enrollment.Secret, OtpauthUri: enrollment.OTPAuthURI}, nil
+}
+
+func (s *grpcServer) ConfirmMFAEnrollment(ctx context.Context, req *pb.ConfirmMFAEnrollmentRequest) (*pb.ConfirmMFAEnrollmentResponse, error) {
+	recoveryCodes, err := s.service.ConfirmMFAEnrollment(ctx, req.GetAccessToken(), req.GetCode())
+	if err != nil {
+		slog.Error("Failed to confirm MFA enrollment", "error", err)
+		return nil, status.Errorf(codes.Internal, "confirm MFA enrollment: %v", err)
+	}
+	slog.Info("MFA enabled")
+	return &pb.ConfirmMFAEnrollmentResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (s *grpcServer) DisableMFA(ctx context.Context, req *pb.DisableMFARequest) (*pb.DisableMFAResponse, error) {
+	err := s.service.DisableMFA(ctx, req.GetAccessToken(), req.GetCode())
+	if err != nil {
+		slog.Error("Failed to disable MFA", "error", err)
+		return nil, status.Errorf(codes.Internal, "disable MFA: %v", err)
+	}
+	slog.Info("MFA disabled")
+	return &pb.DisableMFAResponse{Success: true}, nil
+}
+
+func (s *grpcServer) GetMFAStatus(ctx context.Context, req *pb.GetMFAStatusRequest) (*pb.GetMFAStatusResponse, error) {
+	mfaStatus, err := s.service.GetMFAStatus(ctx, req.GetAccessToken())
+	if err != nil {
+		slog.Error("Failed to get MFA status", "error", err)
+		return nil, status.Errorf(codes.Internal, "get MFA status: %v", err)
+	}
+	return &pb.GetMFAStatusResponse{Enabled: mfaStatus.Enabled, RecoveryCodesRemaining: mfaStatus.RecoveryCodesRemaining}, nil
+}
+
+func (s *grpcServer) VerifyMFA(ctx context.Context, req *pb.VerifyMFARequest) (*pb.VerifyMFAResponse, error) {
+	res, err := s.service.VerifyMFA(ctx, req.GetMfaToken(), req.GetCode(), clientInfo(ctx))
+	if err != nil {
+		slog.Error("Failed to verify MFA", "error", err)
+		return nil, status.Errorf(codes.Internal, "verify MFA: %v", err)
+	}
+	slog.Info("MFA verified", "user_id", strings.TruncateString(res.User.ID, 4))
+	return &pb.VerifyMFAResponse{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		User:         userInfoToPB(res.User),
+	}, nil
+}
+
+func (s *grpcServer) NotifyAbuseReporter(ctx context.Context, req *pb.NotifyAbuseReporterRequest) (*pb.NotifyAbuseReporterResponse, error) {
+	sent, err := s.service.NotifyAbuseReporter(ctx, req.GetUserId(), req.GetEmail(), req.GetReportId(), req.GetBucketId(), req.GetOutcome())
+	if err != nil {
+		slog.Error("Failed to notify abuse reporter", "report_id", req.GetReportId(), "error", err)
+		return nil, status.Errorf(codes.Internal, "notify abuse reporter: %v", err)
+	}
+	return &pb.NotifyAbuseReporterResponse{Success: sent}, nil
+}
+
+func (s *grpcServer) RequestMagicLink(ctx context.Context, req *pb.RequestMagicLinkRequest) (*pb.RequestMagicLinkResponse, error) {
+	if err := s.service.RequestMagicLink(ctx, req.GetEmail()); err != nil {
+		slog.Error("Failed to request magic link", "error", err)
+		return nil, status.Errorf(codes.Internal, "request magic link: %v", err)
+	}
+	return &pb.RequestMagicLinkResponse{Success: true}, nil
+}
+
+func (s *grpcServer) RedeemMagicLink(ctx context.Context, req *pb.RedeemMagicLinkRequest) (*pb.RedeemMagicLinkResponse, error) {
+	res, err := s.service.RedeemMagicLink(ctx, req.GetToken(), clientInfo(ctx))
+	if err != nil {
+		slog.Error("Failed to redeem magic link", "error", err)
+		return nil, status.Errorf(codes.Internal, "redeem magic link: %v", err)
+	}
+	slog.Info("Magic link redeemed", "mfa_required", res.MFAToken != "")
+	if res.MFAToken != "" {
+		return &pb.RedeemMagicLinkResponse{MfaToken: res.MFAToken, MfaExpiresIn: res.MFAExpiresIn}, nil
+	}
+	return &pb.RedeemMagicLinkResponse{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		User:         userInfoToPB(res.User),
+	}, nil
+}
+
+func (s *grpcServer) SetUserRole(ctx context.Context, req *pb.SetUserRoleRequest) (*pb.SetUserRoleResponse, error) {
+	err := s.service.SetUserRole(ctx, req.GetActorId(), req.GetUserId(), req.GetRole(), clientInfo(ctx))
+	if err != nil {
+		slog.Error("Failed to set user role", "error", err)
+		return nil, status.Errorf(codes.Internal, "set user role: %v", err)
+	}
+	slog.Info("User role updated", "user_id", strings.TruncateString(req.GetUserId(), 4), "role", req.GetRole())
+	return &pb.SetUserRoleResponse{Success: true}, nil
+}
+
+func (s *grpcServer) RecordAuditEvent(ctx context.Context, req *pb.RecordAuditEventRequest) (*pb.RecordAuditEventResponse, error) {
+	e := req.GetEvent()
+	err := s.service.RecordAuditEvent(ctx, pkg.AuditEvent{
+		ActorID:    e.GetActorId(),
+		Action:     e.GetAction(),
+		TargetType: e.GetTargetType(),
+		TargetID:   e.GetTargetId(),
+		Details:    e.GetDetails(),
+	}, clientInfo(ctx))
+	if err != nil {
+		slog.Error("Failed to record audit event", "error", err)
+		return nil, status.Errorf(codes.Internal, "record audit event: %v", err)
+	}
+	return &pb.RecordAuditEventResponse{Success: true}, nil
+}
+
+func (s *grpcServer) ListAuditEvents(ctx context.Context, req *pb.ListAuditEventsRequest) (*pb.ListAuditEventsResponse, error) {
+	events, err := s.service.ListAuditEvents(ctx, req.GetBefore(), req.GetActorId(), req.GetTargetId(), req.GetAction(), int(req.GetLimit()))
+	if err != nil {
+		slog.Error("Failed to list audit events", "error", err)
+		return nil, status.Errorf(codes.Internal, "list audit events: %v", err)
+	}
+	out := &pb.ListAuditEventsResponse{Events: make([]*pb.AuditEvent, 0, len(events))}
+	for _, e := range events {
+		out.Events = append(out.Events, &pb.AuditEvent{
+			Id:         e.ID,
+			ActorId:    e.ActorID,
+			Action:     e.Action,
+			TargetType: e.TargetType,
+			TargetId:   e.TargetID,
+			Details:    e.Details,
+			IpAddress:  e.IPAddress,
+			CreatedAt:  e.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+func (s *grpcServer) CreateOrganization(ctx context.Context, req *pb.CreateOrganizationRequest) (*pb.CreateOrganizationResponse, error) {
+	org, err := s.service.CreateOrganization(ctx, req.GetAccessToken(), req.GetName(), req.GetSlug())
+	if err != nil {
+		slog.Error("Failed to create organization", "error", err)
+		return nil, status.Errorf(codes.Internal, "create organization: %v", err)
+	}
+	slog.Info("Organization created", "org_id", strings.TruncateString(org.ID, 4))
+	return &pb.CreateOrganizationResponse{Organization: organizationToPB(org)}, nil
+}
+
+func (s *grpcServer) ListOrganizations(ctx context.Context, req *pb.ListOrganizationsRequest) (*pb.ListOrganizationsResponse, error) {
+	orgs, err := s.service.ListOrganizations(ctx, req.GetAccessToken())
+	if err != nil {
+		slog.Error("Failed to list organizations", "error", err)
+		return nil, status.Errorf(codes.Internal, "list organizations: %v", err)
+	}
+	out := &pb.ListOrganizationsResponse{Organizations: make([]*pb.Organization, 0, len(orgs))}
+	for i := range orgs {
+		out.Organizations = append(out.Organizations, organizationToPB(&orgs[i]))
+	}
+	return out, nil
+}
+
+func (s *grpcServer) GetOrganization(ctx context.Context, req *pb.GetOrganizationRequest) (*pb.GetOrganizationResponse, error) {
+	org, err := s.service.GetOrganization(ctx, req.GetAccessToken(), req.GetOrgId())
+	if err != nil {
+		slog.Error("Failed to get organization", "error", err)
+		return nil, status.Errorf(codes.Internal, "get organization: %v", err)
+	}
+	return &pb.GetOrganizationResponse{Organization: organizationToPB(org)}, nil
+}
+
+func (s *grpcServer) UpdateOrganization(ctx context.Context, req *pb.UpdateOrganizationRequest) (*pb.UpdateOrganizationResponse, error) {
+	org, err := s.service.UpdateOrganization(ctx, req.GetAccessToken(), req.GetOrgId(), pkg.OrganizationUpdate{
+		Name:             req.Name,
+		RetentionSeconds: req.RetentionSeconds,
+		QuotaBytes:       req.QuotaBytes,
+	})
+	if err != nil {
+		slog.Error("Failed to update organization", "error", err)
+		return nil, status.Errorf(codes.Internal, "update organization: %v", err)
+	}
+	return &pb.UpdateOrganizationResponse{Organization: organizationToPB(org)}, nil
+}
+
+func (s *grpcServer) DeleteOrganization(ctx context.Context, req *pb.DeleteOrganizationRequest) (*pb.DeleteOrganizationResponse, error) {
+	if err := s.service.DeleteOrganization(ctx, req.GetAccessToken(), req.GetOrgId()); err != nil {
+		slog.Error("Failed to delete organization", "error", err)
+		return nil, status.Errorf(codes.Internal, "delete organization: %v", err)
+	}
+	slog.Info("Organization deleted", "org_id", strings.TruncateString(req.GetOrgId(), 4))
+	return &pb.DeleteOrganizationResponse{Success: true}, nil
+}
+
+func (s *grpcServer) ListOrganizationMembers(ctx context.Context, req *pb.ListOrganizationMembersRequest) (*pb.ListOrganizationMembersResponse, error) {
+	members, err := s.service.ListOrganizationMembers(ctx, req.GetAccessToken(), req.GetOrgId())
+	if err != nil {
+		slog.Error("Failed to list organization members", "error", err)
+		return nil, status.Errorf(codes.Internal, "list organization members: %v", err)
+	}
+	out := &pb.ListOrganizationMembersResponse{Members: make([]*pb.OrganizationMember, 0, len(members))}
+	for _, m := range members {
+		out.Members = append(out.Members, organizationMemberToPB(m))
+	}
+	return out, nil
+}
+
+func (s *grpcServer) AddOrganizationMember(ctx context.Context, req *pb.AddOrganizationMemberRequest) (*pb.AddOrganizationMemberResponse, error) {
+	member, err := s.service.AddOrganizationMember(ctx, req.GetAccessToken(), req.GetOrgId(), req.GetEmail(), req.GetRole())
+	if err != nil {
+		slog.Error("Failed to add organization member", "error", err)
+		return nil, status.Errorf(codes.Internal, "add organization member: %v", err)
+	}
+	slog.Info("Organization member added", "org_id", strings.TruncateString(req.GetOrgId(), 4), "role", member.Role)
+	return &pb.AddOrganizationMemberResponse{Member: organizationMemberToPB(*member)}, nil
+}
+
+func (s *grpcServer) SetOrganizationMemberRole(ctx context.Context, req *pb.SetOrganizationMemberRoleRequest) (*pb.SetOrganizationMemberRoleResponse, error) {
+	err := s.service.SetOrganizationMemberRole(ctx, req.GetAccessToken(), req.GetOrgId(), req.GetUserId(), req.GetRole())
+	if err != nil {
+		slog.Error("Failed to set organization member role", "error", err)
+		return nil, status.Errorf(codes.Internal, "set organization member role: %v", err)
+	}
+	return &pb.SetOrganizationMemberRoleResponse{Success: true}, nil
+}
+
+func (s *grpcServer) RemoveOrganizationMember(ctx context.Context, req *pb.RemoveOrganizationMemberRequest) (*pb.RemoveOrganizationMemberResponse, error) {
+	err := s.service.RemoveOrganizationMember(ctx, req.GetAccessToken(), req.GetOrgId(), req.GetUserId())
+	if err != nil {
+		slog.Error("Failed to remove organization member", "error", err)
+		return nil, status.Errorf(codes.Internal, "remove organization member: %v", err)
+	}
+	return &pb.RemoveOrganizationMemberResponse{Success: true}, nil
+}
+
+func (s *grpcServer) GetOrganizationMembership(ctx context.Context, req *pb.GetOrganizationMembershipRequest) (*pb.GetOrganizationMembershipResponse, error) {
+	org, err := s.service.GetOrganizationMembership(ctx, req.GetOrgId(), req.GetUserId())
+	if err != nil {
+		slog.Error("Failed to get organization membership", "error", err)
+		return nil, status.Errorf(codes.Internal, "get organization membership: %v", err)
+	}
+	return &pb.GetOrganizationMembershipResponse{Organization: organizationToPB(org)}, nil
+}
+
+// clientInfo reads the end user's client details forwarded by the gateway.
+func clientInfo(ctx context.Context) pkg.ClientInfo {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	return pkg.ClientInfo{
+		IPAddress: first(pkg.MetadataClientIP),
+		UserAgent: first(pkg.MetadataClientUserAgent),
+	}
+}
+
+func personalAccessTokenToPB(t pkg.PersonalAccessToken) *pb.PersonalAccessToken {
+	return &pb.PersonalAccessToken{
+		Id:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     t.Scopes,
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
+
+func identityToPB(i pkg.Identity) *pb.Identity {
+	return &pb.Identity{
+		Provider:      i.Provider,
+		Email:         i.Email,
+		EmailVerified: i.EmailVerified,
+		Primary:       i.Primary,
+		CreatedAt:     i.CreatedAt,
+		LastLoginAt:   i.LastLoginAt,
+	}
+}
+
 func userInfoToPB(u *pkg.UserInfo) *pb.UserInfo {
 	if u == nil {
 		return nil
@@ -106,5 +653,30 @@ func userInfoToPB(u *pkg.UserInfo) *pb.UserInfo {
 		Email:     u.Email,
 		Username:  u.Username,
 		AvatarUrl: u.AvatarUrl,
+		Role:      u.Role,
+	}
+}
+
+func organizationToPB(o *pkg.Organization) *pb.Organization {
+	return &pb.Organization{
+		Id:               o.ID,
+		Slug:             o.Slug,
+		Name:             o.Name,
+		RetentionSeconds: o.RetentionSeconds,
+		QuotaBytes:       o.QuotaBytes,
+		Role:             o.Role,
+		CreatedAt:        o.CreatedAt,
+		UpdatedAt:        o.UpdatedAt,
+	}
+}
+
+func organizationMemberToPB(m pkg.OrganizationMember) *pb.OrganizationMember {
+	return &pb.OrganizationMember{
+		UserId:    m.UserID,
+		Email:     m.Email,
+		Username:  m.Username,
+		AvatarUrl: m.AvatarUrl,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt,
 	}
 }
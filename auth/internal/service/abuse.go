@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cthulhu-platform/auth/internal/mailer"
+	"github.com/cthulhu-platform/auth/pkg"
+)
+
+var errInvalidOutcome = errors.New("invalid abuse report outcome")
+
+// abuseReportMessages are the subject and body of the email for each report outcome; the body is
+// formatted with the report and bucket ids.
+var abuseReportMessages = map[string][2]string{
+	pkg.AbuseReportReceived: {
+		"We received your report",
+		"Thank you for reporting bucket %[2]s. Your report #%[1]d is in our moderation queue, and we will " +
+			"email you again once a moderator has reviewed it.\n",
+	},
+	pkg.AbuseReportActioned: {
+		"We removed the content you reported",
+		"A moderator reviewed your report #%[1]d and removed bucket %[2]s. Its files can no longer be " +
+			"downloaded or uploaded again. Thank you for helping keep Cthulhu safe.\n",
+	},
+	pkg.AbuseReportDismissed: {
+		"We reviewed your report",
+		"A moderator reviewed your report #%[1]d about bucket %[2]s and found that it does not break our " +
+			"rules, so no action was taken. Thank you for letting us know.\n",
+	},
+}
+
+// NotifyAbuseReporter emails a reporter about their report, at the account's address for a signed
+// in reporter and at email otherwise. Receipts only go to signed in reporters, since email is not
+// verified. It returns false without an address or a mailer.
+func (s *authService) NotifyAbuseReporter(ctx context.Context, userID, email string, reportID int64, bucketID, outcome string) (bool, error) {
+	msg, ok := abuseReportMessages[outcome]
+	if !ok {
+		return false, errInvalidOutcome
+	}
+	if s.mailer == nil || (outcome == pkg.AbuseReportReceived && userID == "") {
+		return false, nil
+	}
+	if userID != "" {
+		user, err := s.repo.GetUserByID(ctx, userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("failed to get reporter: %w", err)
+		}
+		if err == nil {
+			email = user.Email
+		}
+	}
+	if email == "" {
+		return false, nil
+	}
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: msg[0],
+		Text:    fmt.Sprintf(msg[1], reportID, bucketID),
+	}); err != nil {
+		return false, fmt.Errorf("failed to send abuse report email: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/pkg"
+)
+
+var errAccountDeleted = errors.New("this account has been deleted")
+
+// DeleteAccount deletes the access token's user. The account is soft deleted at once: all of
+// its tokens stop working and it is appended to the account deletion feed, from which the
+// services holding user data clean up. PurgeDeletedAccounts removes it for good after the grace period.
+func (s *authService) DeleteAccount(ctx context.Context, accessToken string) error {
+	_, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	// Organizations would be left without an owner
+	owned, err := s.repo.ListSoleOwnedOrganizations(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list owned organizations: %w", err)
+	}
+	if len(owned) > 0 {
+		slugs := make([]string, 0, len(owned))
+		for _, org := range owned {
+			slugs = append(slugs, org.Slug)
+		}
+		return fmt.Errorf("you are the only owner of %s, make another member an owner or delete the organization first", strings.Join(slugs, ", "))
+	}
+	deleted, err := s.repo.SoftDeleteUser(ctx, user.ID, "account_deleted")
+	if err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+	if !deleted {
+		return errAccountDeleted
+	}
+	securityEvent("account_deleted", "user_id", user.ID)
+	return nil
+}
+
+// ListAccountDeletions returns up to limit entries of the account deletion feed after seq.
+func (s *authService) ListAccountDeletions(ctx context.Context, after int64, limit int) ([]pkg.AccountDeletion, error) {
+	if limit <= 0 || limit > localPkg.ACCOUNT_DELETION_FEED_PAGE_MAX {
+		limit = localPkg.ACCOUNT_DELETION_FEED_PAGE_MAX
+	}
+	rows, err := s.repo.ListAccountDeletionsAfter(ctx, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account deletions: %w", err)
+	}
+	out := make([]pkg.AccountDeletion, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, pkg.AccountDeletion{Seq: row.Seq, UserID: row.UserID, DeletedAt: row.DeletedAt})
+	}
+	return out, nil
+}
+
+// PurgeDeletedAccounts hard deletes users soft deleted more than gracePeriod ago. Their feed
+// entries are kept (marked purged) for services that have not caught up yet.
+func (s *authService) PurgeDeletedAccounts(ctx context.Context, gracePeriod time.Duration) (purged int, err error) {
+	due, err := s.repo.ListAccountDeletionsToPurge(ctx, time.Now().Add(-gracePeriod).Unix(), localPkg.ACCOUNT_PURGE_BATCH)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list accounts to purge: %w", err)
+	}
+	for _, d := range due {
+		if err := s.repo.PurgeUser(ctx, d.UserID); err != nil {
+			slog.Warn("Failed to purge deleted account", "user_id", d.UserID, "error", err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
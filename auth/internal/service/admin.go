@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/pkg"
+)
+
+var (
+	errInvalidRole   = errors.New("invalid role")
+	errUserNotFound  = errors.New("user not found")
+	errNotPermitted  = errors.New("not permitted")
+	errOwnRole       = errors.New("cannot change your own role")
+	errInvalidAction = errors.New("invalid audit event")
+)
+
+// Administrative calls take the id of the acting user, whose current role (not the one in their
+// access token) is checked. An empty actor id is an operator using the service CLI or another
+// trusted service, and is not checked.
+
+// SetUserRole gives a user a platform role; only admins can, and not for themselves. A demoted
+// user is logged out everywhere, since their access tokens carry the old role.
+func (s *authService) SetUserRole(ctx context.Context, actorID, userID, role string, client pkg.ClientInfo) error {
+	if pkg.RoleRank(role) < 0 || role == "" {
+		return errInvalidRole
+	}
+	if actorID != "" {
+		if actorID == userID {
+			return errOwnRole
+		}
+		if _, err := s.actorWithRole(ctx, actorID, pkg.RoleAdmin); err != nil {
+			return err
+		}
+	}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Role == role {
+		return nil
+	}
+	updated, err := s.repo.SetUserRole(ctx, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to set role: %w", err)
+	}
+	if !updated {
+		return errUserNotFound
+	}
+	if pkg.RoleRank(role) < pkg.RoleRank(user.Role) {
+		if err := s.killSessions(ctx, userID, "role_changed"); err != nil {
+			return err
+		}
+	}
+	securityEvent("role_changed", "user_id", userID, "actor_id", actorID, "from", user.Role, "to", role)
+	s.audit(ctx, actorID, pkg.AuditUserRole, pkg.AuditTargetUser, userID, user.Role+" -> "+role, client)
+	return nil
+}
+
+// SetUserSuspended suspends (ending all sessions) or reinstates a user. Moderators and admins
+// can suspend users whose role is below their own.
+func (s *authService) SetUserSuspended(ctx context.Context, actorID, userID string, suspended bool, client pkg.ClientInfo) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if actorID != "" {
+		actor, err := s.actorWithRole(ctx, actorID, pkg.RoleModerator)
+		if err != nil {
+			return err
+		}
+		if pkg.RoleRank(actor.Role) <= pkg.RoleRank(user.Role) {
+			return errNotPermitted
+		}
+	}
+	if suspended {
+		// sessions first: if suspending fails afterwards the user is still logged out
+		if err := s.killSessions(ctx, userID, "user_suspended"); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.SetUserSuspended(ctx, userID, suspended); err != nil {
+		return err
+	}
+	action := pkg.AuditUserUnsuspend
+	if suspended {
+		action = pkg.AuditUserSuspend
+	}
+	s.audit(ctx, actorID, action, pkg.AuditTargetUser, userID, "", client)
+	return nil
+}
+
+// RecordAuditEvent appends an action carried out by another service, such as a bucket deletion
+// by the gateway, to the audit log. The IP address is taken from client.
+func (s *authService) RecordAuditEvent(ctx context.Context, event pkg.AuditEvent, client pkg.ClientInfo) error {
+	if event.Action == "" || event.TargetType == "" || event.TargetID == "" {
+		return errInvalidAction
+	}
+	if err := s.createAuditEvent(ctx, event.ActorID, event.Action, event.TargetType, event.TargetID, event.Details, client); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEvents returns up to limit events newest first, below the before id when it is
+// non-zero. Empty filters match any value.
+func (s *authService) ListAuditEvents(ctx context.Context, before int64, actorID, targetID, action string, limit int) ([]pkg.AuditEvent, error) {
+	if limit <= 0 || limit > localPkg.AUDIT_EVENT_PAGE_MAX {
+		limit = localPkg.AUDIT_EVENT_PAGE_MAX
+	}
+	rows, err := s.repo.ListAuditEvents(ctx, before, actorID, targetID, action, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	out := make([]pkg.AuditEvent, 0, len(rows))
+	for _, e := range rows {
+		out = append(out, pkg.AuditEvent{
+			ID:         e.ID,
+			ActorID:    e.ActorID,
+			Action:     e.Action,
+			TargetType: e.TargetType,
+			TargetID:   e.TargetID,
+			Details:    e.Details,
+			IPAddress:  e.IpAddress,
+			CreatedAt:  e.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// actorWithRole returns the acting user if they are active and hold at least role min.
+func (s *authService) actorWithRole(ctx context.Context, actorID string, min string) (*db.User, error) {
+	actor, err := s.repo.GetUserByID(ctx, actorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errNotPermitted
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get actor: %w", err)
+	}
+	if actor.SuspendedAt.Valid || !pkg.HasRole(actor.Role, min) {
+		return nil, errNotPermitted
+	}
+	return actor, nil
+}
+
+// audit records an action the service has already carried out. A failure is logged rather than
+// returned, so the caller does not report a completed action as failed.
+func (s *authService) audit(ctx context.Context, actorID, action, targetType, targetID, details string, client pkg.ClientInfo) {
+	if err := s.createAuditEvent(ctx, actorID, action, targetType, targetID, details, client); err != nil {
+		slog.Error("Failed to record audit event", "action", action, "target_id", targetID, "error", err)
+	}
+}
+
+func (s *authService) createAuditEvent(ctx context.Context, actorID, action, targetType, targetID, details string, client pkg.ClientInfo) error {
+	return s.repo.CreateAuditEvent(ctx, &db.AuditEvent{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		IpAddress:  cleanClientInfo(client).IPAddress,
+		CreatedAt:  time.Now().Unix(),
+	})
+}
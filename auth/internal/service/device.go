@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/pkg"
+)
+
+// userCodeAlphabet has no vowels (no accidental words) and no easily confused characters,
+// as recommended by RFC 8628 section 6.1. Eight characters give ~34 bits of entropy.
+const (
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+var errInvalidUserCode = errors.New("invalid or expired user code")
+
+// StartDeviceAuthorization begins a device authorization grant for a client without a browser.
+func (s *authService) StartDeviceAuthorization(ctx context.Context, clientName string) (*pkg.DeviceAuthorization, error) {
+	clientName = strings.TrimSpace(clientName)
+	if clientName == "" {
+		clientName = "Unknown device"
+	}
+	if utf8.RuneCountInString(clientName) > localPkg.DEVICE_CLIENT_NAME_MAX || strings.ContainsFunc(clientName, unicode.IsControl) {
+		return nil, fmt.Errorf("invalid client name")
+	}
+
+	deviceCode, deviceCodeHash, err := generateRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate device code: %w", err)
+	}
+	now := time.Now()
+	interval := int64(localPkg.DEVICE_POLL_INTERVAL.Seconds())
+	expiresAt := now.Add(localPkg.DEVICE_CODE_EXPIRATION)
+
+	// user codes are short, so retry on the rare collision with a pending one
+	var userCode string
+	for attempt := 0; ; attempt++ {
+		userCode, err = generateUserCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate user code: %w", err)
+		}
+		err = s.repo.CreateDeviceAuthorization(ctx, &db.DeviceAuthorization{
+			DeviceCodeHash: deviceCodeHash,
+			UserCode:       userCode,
+			ClientName:     clientName,
+			PollInterval:   interval,
+			ExpiresAt:      expiresAt.Unix(),
+			CreatedAt:      now.Unix(),
+		})
+		if err == nil {
+			break
+		}
+		if attempt == 2 {
+			return nil, fmt.Errorf("failed to store device authorization: %w", err)
+		}
+	}
+
+	display := formatUserCode(userCode)
+	return &pkg.DeviceAuthorization{
+		DeviceCode:              deviceCode,
+		UserCode:                display,
+		VerificationURI:         localPkg.DEVICE_VERIFICATION_URI,
+		VerificationURIComplete: localPkg.DEVICE_VERIFICATION_URI + "?user_code=" + url.QueryEscape(display),
+		ExpiresIn:               int64(localPkg.DEVICE_CODE_EXPIRATION.Seconds()),
+		Interval:                interval,
+	}, nil
+}
+
+// PollDeviceAuthorization is the device access token request. Until the user decides it fails
+// with a *pkg.DeviceFlowError; once approved it returns tokens exactly once, on a new session.
+func (s *authService) PollDeviceAuthorization(ctx context.Context, deviceCode string, client pkg.ClientInfo) (*pkg.AuthResponse, error) {
+	hash := sha256Hex(deviceCode)
+	auth, err := s.repo.GetDeviceAuthorizationByDeviceCode(ctx, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &pkg.DeviceFlowError{Code: pkg.DeviceErrorInvalidGrant}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device authorization: %w", err)
+	}
+
+	now := time.Now().Unix()
+	if now > auth.ExpiresAt {
+		_, _ = s.repo.DeleteDeviceAuthorization(ctx, hash)
+		return nil, &pkg.DeviceFlowError{Code: pkg.DeviceErrorExpiredToken}
+	}
+	interval := auth.PollInterval
+	tooFast := auth.LastPolledAt.Valid && now-auth.LastPolledAt.Int64 < interval
+	if tooFast {
+		interval += int64(localPkg.DEVICE_SLOW_DOWN_STEP.Seconds())
+	}
+	if err := s.repo.UpdateDeviceAuthorizationPoll(ctx, hash, now, interval); err != nil {
+		return nil, fmt.Errorf("failed to record poll: %w", err)
+	}
+	if tooFast {
+		return nil, &pkg.DeviceFlowError{Code: pkg.DeviceErrorSlowDown}
+	}
+
+	switch auth.Status {
+	case "pending":
+		return nil, &pkg.DeviceFlowError{Code: pkg.DeviceErrorAuthorizationPending}
+	case "denied":
+		_, _ = s.repo.DeleteDeviceAuthorization(ctx, hash)
+		return nil, &pkg.DeviceFlowError{Code: pkg.DeviceErrorAccessDenied}
+	}
+
+	// Deleting claims the approval, so concurrent polls cannot both receive tokens
+	claimed, err := s.repo.DeleteDeviceAuthorization(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim device authorization: %w", err)
+	}
+	if !claimed {
+		return nil, &pkg.DeviceFlowError{Code: pkg.DeviceErrorInvalidGrant}
+	}
+	user, err := s.repo.GetUserByID(ctx, auth.UserID.String)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.SuspendedAt.Valid {
+		return nil, &pkg.DeviceFlowError{Code: pkg.DeviceErrorAccessDenied}
+	}
+
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &pkg.AuthResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		User:         userToUserInfo(user),
+	}, nil
+}
+
+// GetDeviceAuthorization describes a pending request so the user can check it before approving.
+func (s *authService) GetDeviceAuthorization(ctx context.Context, accessToken string, userCode string) (*pkg.PendingDeviceAuthorization, error) {
+	if _, _, err := s.validateActiveToken(ctx, accessToken); err != nil {
+		return nil, err
+	}
+	auth, err := s.repo.GetDeviceAuthorizationByUserCode(ctx, normalizeUserCode(userCode))
+	if err != nil || auth.Status != "pending" || time.Now().Unix() > auth.ExpiresAt {
+		return nil, errInvalidUserCode
+	}
+	return &pkg.PendingDeviceAuthorization{
+		UserCode:   formatUserCode(auth.UserCode),
+		ClientName: auth.ClientName,
+		CreatedAt:  auth.CreatedAt,
+		ExpiresAt:  auth.ExpiresAt,
+	}, nil
+}
+
+// ApproveDeviceAuthorization approves (signing the device in as the access token's user) or
+// denies a pending request.
+func (s *authService) ApproveDeviceAuthorization(ctx context.Context, accessToken string, userCode string, approve bool) error {
+	claims, _, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	decided, err := s.repo.DecideDeviceAuthorization(ctx, normalizeUserCode(userCode), claims.UserID, approve, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to update device authorization: %w", err)
+	}
+	if !decided {
+		return errInvalidUserCode
+	}
+	return nil
+}
+
+func generateUserCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	b := make([]byte, userCodeLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// normalizeUserCode makes typed codes case-insensitive and drops dashes and spaces.
+func normalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, code)
+}
+
+// formatUserCode renders a stored code as XXXX-XXXX.
+func formatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cthulhu-platform/auth/internal/oauth"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/google/uuid"
+)
+
+var (
+	errIdentityNotFound = errors.New("no account of this provider is linked")
+	errLastIdentity     = errors.New("cannot unlink the only sign-in method of an account")
+)
+
+// signInIdentity resolves the user of a provider identity, creating the user on first sign-in.
+// Identities are never attached to an existing account by email: a user must link them from a
+// signed in session, otherwise whoever controls a provider account with a matching (possibly
+// unverified) email could take the account over. Magic links are the exception, see
+// signInEmail.
+func (s *authService) signInIdentity(ctx context.Context, provider string, info *oauth.UserInfo) (*db.User, error) {
+	now := time.Now().Unix()
+	identity, err := s.repo.GetUserIdentity(ctx, provider, info.OAuthUserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check existing identity: %w", err)
+	}
+
+	if identity != nil {
+		user, err := s.repo.GetUserByID(ctx, identity.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// soft deleted, the identity goes away with the user once the account is purged
+			return nil, errAccountDeleted
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user.SuspendedAt.Valid {
+			return nil, errUserSuspended
+		}
+		// the profile follows the identity the account was created with
+		if provider == user.OauthProvider {
+			if err := s.repo.UpdateUser(ctx, &db.User{
+				ID:        user.ID,
+				Username:  ptrToNullString(info.Username),
+				AvatarUrl: ptrToNullString(info.AvatarURL),
+				UpdatedAt: now,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to update user: %w", err)
+			}
+			user.Username = ptrToNullString(info.Username)
+			user.AvatarUrl = ptrToNullString(info.AvatarURL)
+			user.UpdatedAt = now
+		}
+		if err := s.repo.UpdateUserIdentityLogin(ctx, &db.UserIdentity{
+			Provider:       provider,
+			ProviderUserID: info.OAuthUserID,
+			Email:          info.Email,
+			EmailVerified:  info.EmailVerified,
+			LastLoginAt:    sql.NullInt64{Int64: now, Valid: true},
+		}); err != nil {
+			return nil, fmt.Errorf("failed to update identity: %w", err)
+		}
+		return user, nil
+	}
+
+	if info.Email == "" {
+		return nil, fmt.Errorf("provider did not return an email address")
+	}
+	if !info.EmailVerified {
+		return nil, fmt.Errorf("the email address of this %s account is not verified, verify it with %s or link the account from an existing one", provider, provider)
+	}
+	existing, err := s.repo.GetUserByEmail(ctx, info.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check existing user: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("an account with this email already exists, sign in with a provider linked to it and link %s from your account", provider)
+	}
+
+	user := &db.User{
+		ID:            uuid.New().String(),
+		OauthProvider: provider,
+		OauthUserID:   info.OAuthUserID,
+		Email:         info.Email,
+		Username:      ptrToNullString(info.Username),
+		AvatarUrl:     ptrToNullString(info.AvatarURL),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.repo.CreateUserWithIdentity(ctx, user, &db.UserIdentity{
+		Provider:       provider,
+		ProviderUserID: info.OAuthUserID,
+		UserID:         user.ID,
+		Email:          info.Email,
+		EmailVerified:  info.EmailVerified,
+		CreatedAt:      now,
+		LastLoginAt:    sql.NullInt64{Int64: now, Valid: true},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// StartLinkIdentity begins an OAuth flow that links a provider account to the access token's
+// user; it is finished by LinkIdentity with the callback's code and state.
+func (s *authService) StartLinkIdentity(ctx context.Context, accessToken string, provider string) (string, error) {
+	claims, _, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return "", err
+	}
+	return s.beginOAuth(ctx, provider, claims.UserID)
+}
+
+// LinkIdentity finishes a flow started by StartLinkIdentity. The OAuth session must belong to
+// the same user, so a callback cannot be replayed into someone else's account.
+func (s *authService) LinkIdentity(ctx context.Context, accessToken string, provider string, code string, state string) (*pkg.Identity, error) {
+	claims, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.completeOAuth(ctx, provider, code, state, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetUserIdentity(ctx, provider, info.OAuthUserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check existing identity: %w", err)
+	}
+	if existing != nil {
+		if existing.UserID != user.ID {
+			return nil, fmt.Errorf("this %s account is already linked to another user", provider)
+		}
+		return identityToPkg(existing, user), nil
+	}
+	linked, err := s.repo.ListUserIdentities(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	for _, identity := range linked {
+		if identity.Provider == provider {
+			return nil, fmt.Errorf("another %s account is already linked, unlink it first", provider)
+		}
+	}
+
+	identity := &db.UserIdentity{
+		Provider:       provider,
+		ProviderUserID: info.OAuthUserID,
+		UserID:         user.ID,
+		Email:          info.Email,
+		EmailVerified:  info.EmailVerified,
+		CreatedAt:      time.Now().Unix(),
+	}
+	if err := s.repo.CreateUserIdentity(ctx, identity); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+	securityEvent("identity_linked", "user_id", user.ID, "provider", provider)
+	return identityToPkg(identity, user), nil
+}
+
+// ListIdentities returns the provider accounts linked to the access token's user.
+func (s *authService) ListIdentities(ctx context.Context, accessToken string) ([]pkg.Identity, error) {
+	_, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListUserIdentities(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	out := make([]pkg.Identity, 0, len(rows))
+	for i := range rows {
+		out = append(out, *identityToPkg(&rows[i], user))
+	}
+	return out, nil
+}
+
+// UnlinkIdentity removes a provider account from the access token's user. The last identity
+// cannot be removed; if the account was created with the removed one, another takes its place.
+func (s *authService) UnlinkIdentity(ctx context.Context, accessToken string, provider string) error {
+	_, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	linked, err := s.repo.ListUserIdentities(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list identities: %w", err)
+	}
+	var remaining []db.UserIdentity
+	found := false
+	for _, identity := range linked {
+		if identity.Provider == provider {
+			found = true
+			continue
+		}
+		remaining = append(remaining, identity)
+	}
+	if !found {
+		return errIdentityNotFound
+	}
+	if len(remaining) == 0 {
+		return errLastIdentity
+	}
+
+	deleted, err := s.repo.DeleteUserIdentity(ctx, user.ID, provider)
+	if err != nil {
+		return fmt.Errorf("failed to unlink identity: %w", err)
+	}
+	if !deleted {
+		return errIdentityNotFound
+	}
+	if user.OauthProvider == provider {
+		if err := s.repo.SetUserPrimaryIdentity(ctx, user.ID, remaining[0].Provider, remaining[0].ProviderUserID); err != nil {
+			return fmt.Errorf("failed to update primary identity: %w", err)
+		}
+	}
+	securityEvent("identity_unlinked", "user_id", user.ID, "provider", provider)
+	return nil
+}
+
+func identityToPkg(identity *db.UserIdentity, user *db.User) *pkg.Identity {
+	return &pkg.Identity{
+		Provider:      identity.Provider,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Primary:       identity.Provider == user.OauthProvider && identity.ProviderUserID == user.OauthUserID,
+		CreatedAt:     identity.CreatedAt,
+		LastLoginAt:   identity.LastLoginAt.Int64,
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
@@ -10,33 +11,55 @@ import (
 	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
 	"github.com/cthulhu-platform/auth/pkg"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-// GenerateAccessToken creates a JWT with user claims.
-func generateAccessToken(userID, email, provider string) (string, error) {
-	secret := localPkg.JWT_SECRET_KEY
-	claims := pkg.Claims{
-		UserID:   userID,
-		Email:    email,
-		Provider: provider,
+// generateAccessToken creates a JWT with user claims for a session, signed by the active key and
+// tagged with its kid. The claims are returned so callers can record the jti.
+func (m *KeyManager) generateAccessToken(userID, email, provider, role, sessionID string) (string, *pkg.Claims, error) {
+	key, err := m.active()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	claims := &pkg.Claims{
+		UserID:     userID,
+		Email:      email,
+		Provider:   provider,
+		SessionID:  sessionID,
+		IssuedAtMs: now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(localPkg.ACCESS_TOKEN_EXPIRATION)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(localPkg.ACCESS_TOKEN_EXPIRATION)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return tok.SignedString([]byte(secret))
+	if role != pkg.RoleUser {
+		claims.Role = role
+	}
+	tok := jwt.NewWithClaims(key.method, claims)
+	tok.Header["kid"] = key.kid
+	signed, err := tok.SignedString(key.private)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
-// validateAccessToken parses and verifies the JWT, returns claims or error.
-func validateAccessToken(tokenString string) (*pkg.Claims, error) {
-	secret := localPkg.JWT_SECRET_KEY
+// validateAccessToken parses and verifies the JWT against the key named by its kid, returns claims or error.
+func (m *KeyManager) validateAccessToken(ctx context.Context, tokenString string) (*pkg.Claims, error) {
 	tok, err := jwt.ParseWithClaims(tokenString, &pkg.Claims{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := t.Header["kid"].(string)
+		key, err := m.lookup(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != key.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 		}
-		return []byte(secret), nil
-	})
+		return key.private.Public(), nil
+	}, jwt.WithValidMethods(pkg.AccessTokenAlgorithms))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/internal/repository"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is a parsed row of signing_keys.
+type signingKey struct {
+	kid         string
+	method      jwt.SigningMethod
+	private     crypto.Signer
+	jwk         pkg.JWK
+	activatesAt int64
+	expiresAt   int64 // 0 while current
+}
+
+func (k *signingKey) published(now int64) bool {
+	return k.expiresAt == 0 || now <= k.expiresAt
+}
+
+// KeyManager owns the access token signing keys. Keys live in the signing_keys table so every
+// replica signs with, and publishes, the same set; each replica caches them in memory and
+// reloads on Rotate and when it sees a kid it does not know.
+type KeyManager struct {
+	repo     repository.Repository
+	alg      string
+	rotation time.Duration
+
+	mu         sync.RWMutex
+	keys       []*signingKey // ordered by activatesAt
+	lastReload time.Time
+}
+
+func NewKeyManager(repo repository.Repository, alg string, rotation time.Duration) (*KeyManager, error) {
+	if alg != jwt.SigningMethodES256.Alg() && alg != jwt.SigningMethodEdDSA.Alg() {
+		return nil, fmt.Errorf("unsupported signing algorithm %q (expected ES256 or EdDSA)", alg)
+	}
+	if rotation <= localPkg.SIGNING_KEY_PREPUBLISH {
+		return nil, fmt.Errorf("key rotation interval %s must be longer than %s", rotation, localPkg.SIGNING_KEY_PREPUBLISH)
+	}
+	return &KeyManager{repo: repo, alg: alg, rotation: rotation}, nil
+}
+
+// Rotate creates the first key, schedules the next key SIGNING_KEY_PREPUBLISH before the
+// current one is due, sets the expiry of superseded keys, drops expired keys and reloads.
+func (m *KeyManager) Rotate(ctx context.Context) error {
+	now := time.Now()
+	rows, err := m.repo.ListSigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("list signing keys: %w", err)
+	}
+
+	var current, newest *db.SigningKey
+	for i := range rows {
+		if rows[i].ActivatesAt <= now.Unix() {
+			current = &rows[i]
+		}
+		newest = &rows[i]
+	}
+
+	switch {
+	case newest == nil:
+		if err := m.createKey(ctx, now, now); err != nil {
+			return err
+		}
+	case newest == current && now.Unix() >= current.ActivatesAt+int64((m.rotation-localPkg.SIGNING_KEY_PREPUBLISH).Seconds()):
+		activatesAt := time.Unix(current.ActivatesAt, 0).Add(m.rotation)
+		if earliest := now.Add(localPkg.SIGNING_KEY_PREPUBLISH); activatesAt.Before(earliest) {
+			activatesAt = earliest
+		}
+		if err := m.createKey(ctx, now, activatesAt); err != nil {
+			return err
+		}
+	}
+
+	if current != nil {
+		// Tokens signed by older keys were issued before current took over
+		expiresAt := time.Unix(current.ActivatesAt, 0).Add(localPkg.ACCESS_TOKEN_EXPIRATION + localPkg.SIGNING_KEY_RETIRE_GRACE)
+		if err := m.repo.ExpireSigningKeysBefore(ctx, current.ActivatesAt, expiresAt.Unix()); err != nil {
+			return fmt.Errorf("expire superseded signing keys: %w", err)
+		}
+	}
+	if err := m.repo.DeleteExpiredSigningKeys(ctx, now.Unix()); err != nil {
+		return fmt.Errorf("delete expired signing keys: %w", err)
+	}
+	return m.reload(ctx)
+}
+
+func (m *KeyManager) createKey(ctx context.Context, now, activatesAt time.Time) error {
+	var private crypto.Signer
+	var err error
+	switch m.alg {
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		return fmt.Errorf("generate signing key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return fmt.Errorf("encode signing key: %w", err)
+	}
+	jwk, err := pkg.NewJWK(private.Public())
+	if err != nil {
+		return err
+	}
+
+	kid := jwk.Thumbprint()
+	if err := m.repo.CreateSigningKey(ctx, &db.SigningKey{
+		Kid:         kid,
+		Algorithm:   m.alg,
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		CreatedAt:   now.Unix(),
+		ActivatesAt: activatesAt.Unix(),
+	}); err != nil {
+		return fmt.Errorf("store signing key: %w", err)
+	}
+	slog.Info("Signing key created", "kid", kid, "alg", m.alg, "activates_at", activatesAt.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// reload replaces the in-memory keys with the signing_keys table.
+func (m *KeyManager) reload(ctx context.Context) error {
+	rows, err := m.repo.ListSigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("list signing keys: %w", err)
+	}
+	keys := make([]*signingKey, 0, len(rows))
+	for _, row := range rows {
+		k, err := parseSigningKey(row)
+		if err != nil {
+			slog.Error("Skipping unreadable signing key", "kid", row.Kid, "error", err)
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.lastReload = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+func parseSigningKey(row db.SigningKey) (*signingKey, error) {
+	block, _ := pem.Decode([]byte(row.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+	method := jwt.GetSigningMethod(row.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported algorithm %q", row.Algorithm)
+	}
+	jwk, err := pkg.NewJWK(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Alg != row.Algorithm {
+		return nil, fmt.Errorf("key type does not match algorithm %q", row.Algorithm)
+	}
+	jwk.Kid = row.Kid
+	k := &signingKey{
+		kid:         row.Kid,
+		method:      method,
+		private:     private,
+		jwk:         jwk,
+		activatesAt: row.ActivatesAt,
+	}
+	if row.ExpiresAt.Valid {
+		k.expiresAt = row.ExpiresAt.Int64
+	}
+	return k, nil
+}
+
+// active returns the newest key that has started signing.
+func (m *KeyManager) active() (*signingKey, error) {
+	now := time.Now().Unix()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		if m.keys[i].activatesAt <= now {
+			return m.keys[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no active signing key")
+}
+
+// lookup returns the published key with the given kid, reloading once (rate limited) on a miss
+// so tokens signed by a key another replica just created still verify.
+func (m *KeyManager) lookup(ctx context.Context, kid string) (*signingKey, error) {
+	if k := m.find(kid); k != nil {
+		return k, nil
+	}
+	m.mu.RLock()
+	recent := time.Since(m.lastReload) < localPkg.SIGNING_KEY_RELOAD_MIN
+	m.mu.RUnlock()
+	if !recent {
+		if err := m.reload(ctx); err != nil {
+			return nil, err
+		}
+		if k := m.find(kid); k != nil {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (m *KeyManager) find(kid string) *signingKey {
+	now := time.Now().Unix()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, k := range m.keys {
+		if k.kid == kid && k.published(now) {
+			return k
+		}
+	}
+	return nil
+}
+
+// JWKS returns the public keys verifiers should accept: the active key, keys scheduled to
+// activate and superseded keys whose tokens may still be valid.
+func (m *KeyManager) JWKS() *pkg.JWKS {
+	now := time.Now().Unix()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := &pkg.JWKS{Keys: make([]pkg.JWK, 0, len(m.keys))}
+	for _, k := range m.keys {
+		if k.published(now) {
+			out.Keys = append(out.Keys, k.jwk)
+		}
+	}
+	return out
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cthulhu-platform/auth/internal/mailer"
+	"github.com/cthulhu-platform/auth/internal/oauth"
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/pkg"
+)
+
+var (
+	errInvalidEmail       = errors.New("invalid email address")
+	errTooManyMagicLinks  = errors.New("too many sign-in links requested, use one already sent or try again later")
+	errInvalidMagicLink   = errors.New("invalid or expired sign-in link")
+	errMagicLinksDisabled = errors.New("email sign-in is not configured")
+)
+
+// RequestMagicLink emails a single-use sign-in link to the address. It succeeds whether or not
+// an account uses the address, so it cannot be used to find out.
+func (s *authService) RequestMagicLink(ctx context.Context, email string) error {
+	if s.mailer == nil {
+		return errMagicLinksDisabled
+	}
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	pending, err := s.repo.CountPendingMagicLinks(ctx, email, now.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to count pending magic links: %w", err)
+	}
+	if pending >= localPkg.MAGIC_LINK_MAX_PENDING {
+		return errTooManyMagicLinks
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("failed to generate magic link: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	link, err := url.Parse(localPkg.MAGIC_LINK_URL)
+	if err != nil {
+		return fmt.Errorf("invalid MAGIC_LINK_URL: %w", err)
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	if err := s.repo.CreateMagicLink(ctx, &db.MagicLink{
+		TokenHash: sha256Hex(token),
+		Email:     email,
+		ExpiresAt: now.Add(localPkg.MAGIC_LINK_EXPIRATION).Unix(),
+		CreatedAt: now.Unix(),
+	}); err != nil {
+		return fmt.Errorf("failed to store magic link: %w", err)
+	}
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Your Cthulhu sign-in link",
+		Text: fmt.Sprintf("Sign in to Cthulhu with this link:\n\n%s\n\n"+
+			"It works once and expires in %d minutes. If you did not ask to sign in, ignore this email.\n",
+			link.String(), int(localPkg.MAGIC_LINK_EXPIRATION.Minutes())),
+	}); err != nil {
+		return fmt.Errorf("failed to send magic link: %w", err)
+	}
+	return nil
+}
+
+// RedeemMagicLink consumes a link sent by RequestMagicLink and signs its address in like
+// HandleOAuthCallback, creating the account on first use.
+func (s *authService) RedeemMagicLink(ctx context.Context, token string, client pkg.ClientInfo) (*pkg.AuthResponse, error) {
+	email, err := s.repo.RedeemMagicLink(ctx, sha256Hex(token), time.Now().Unix())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errInvalidMagicLink
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem magic link: %w", err)
+	}
+	user, err := s.signInEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	return s.finishSignIn(ctx, user, client)
+}
+
+// signInEmail resolves the user of a redeemed magic link. Unlike provider identities, an email
+// identity is attached to an existing account with the same address, but only when one of the
+// account's identities recorded that address as verified: users.email is whatever a provider
+// returned at sign-up, and trusting it would hand the mailbox owner an account someone else
+// created with their address (and can still sign in to).
+func (s *authService) signInEmail(ctx context.Context, email string) (*db.User, error) {
+	info := &oauth.UserInfo{OAuthUserID: email, Email: email, EmailVerified: true}
+	identity, err := s.repo.GetUserIdentity(ctx, pkg.ProviderEmail, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check existing identity: %w", err)
+	}
+	if identity != nil {
+		return s.signInIdentity(ctx, pkg.ProviderEmail, info)
+	}
+
+	existing, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check existing user: %w", err)
+	}
+	if existing == nil {
+		return s.signInIdentity(ctx, pkg.ProviderEmail, info)
+	}
+	if existing.SuspendedAt.Valid {
+		return nil, errUserSuspended
+	}
+	verified, err := s.hasVerifiedEmail(ctx, existing.ID, email)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, errors.New("an account with this email already exists, sign in with a provider linked to it")
+	}
+	now := time.Now().Unix()
+	if err := s.repo.CreateUserIdentity(ctx, &db.UserIdentity{
+		Provider:       pkg.ProviderEmail,
+		ProviderUserID: email,
+		UserID:         existing.ID,
+		Email:          email,
+		EmailVerified:  true,
+		CreatedAt:      now,
+		LastLoginAt:    sql.NullInt64{Int64: now, Valid: true},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+	securityEvent("identity_linked", "user_id", existing.ID, "provider", pkg.ProviderEmail)
+	return existing, nil
+}
+
+// hasVerifiedEmail reports whether one of the user's identities vouched for email.
+func (s *authService) hasVerifiedEmail(ctx context.Context, userID, email string) (bool, error) {
+	identities, err := s.repo.ListUserIdentities(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to list identities: %w", err)
+	}
+	for _, identity := range identities {
+		if identity.EmailVerified && strings.EqualFold(identity.Email, email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// normalizeEmail accepts a bare address (no display name) and lowercases it.
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email || len(email) > 254 {
+		return "", errInvalidEmail
+	}
+	return strings.ToLower(email), nil
+}
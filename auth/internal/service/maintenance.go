@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaintenanceReport counts the rows removed by one CleanupExpired run.
+type MaintenanceReport struct {
+	OAuthSessions        int64
+	DeviceAuthorizations int64
+	MFAChallenges        int64
+	MagicLinks           int64
+	RefreshTokens        int64
+	Sessions             int64
+}
+
+// CleanupExpired deletes OAuth sessions, device authorizations, MFA challenges and magic links
+// past their expiry, refresh tokens that expired or were revoked more than refreshRetention ago, and the
+// sessions left without refresh tokens. A failing step does not stop the others; the report counts what was
+// deleted and the errors are joined.
+func (s *authService) CleanupExpired(ctx context.Context, refreshRetention time.Duration) (MaintenanceReport, error) {
+	var report MaintenanceReport
+	var errs []error
+	now := time.Now()
+	cutoff := now.Add(-refreshRetention).Unix()
+
+	n, err := s.repo.CleanupExpiredOAuthSessions(ctx, now.Unix())
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to clean up oauth sessions: %w", err))
+	}
+	report.OAuthSessions = n
+
+	n, err = s.repo.CleanupExpiredDeviceAuthorizations(ctx, now.Unix())
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to clean up device authorizations: %w", err))
+	}
+	report.DeviceAuthorizations = n
+
+	n, err = s.repo.CleanupExpiredMFAChallenges(ctx, now.Unix())
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to clean up MFA challenges: %w", err))
+	}
+	report.MFAChallenges = n
+
+	n, err = s.repo.CleanupExpiredMagicLinks(ctx, now.Unix())
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to clean up magic links: %w", err))
+	}
+	report.MagicLinks = n
+
+	n, err = s.repo.DeleteStaleRefreshTokens(ctx, cutoff)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to delete stale refresh tokens: %w", err))
+	}
+	report.RefreshTokens = n
+
+	// Sessions go after their tokens, with the same cutoff so a session refreshed within the
+	// retention window stays listed
+	n, err = s.repo.DeleteEndedSessions(ctx, cutoff)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to delete ended sessions: %w", err))
+	}
+	report.Sessions = n
+
+	return report, errors.Join(errs...)
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/pkg"
+)
+
+var (
+	errMFAAlreadyEnabled   = errors.New("MFA is already enabled")
+	errMFANotEnabled       = errors.New("MFA is not enabled")
+	errMFANotPending       = errors.New("no MFA enrollment in progress")
+	errInvalidMFACode      = errors.New("invalid MFA code")
+	errInvalidMFAChallenge = errors.New("invalid or expired MFA challenge")
+)
+
+// StartMFAEnrollment creates a TOTP secret for the access token's user. MFA is only enabled
+// once ConfirmMFAEnrollment sees a code generated from it; starting again replaces the secret.
+func (s *authService) StartMFAEnrollment(ctx context.Context, accessToken string) (*pkg.MFAEnrollment, error) {
+	_, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate MFA secret: %w", err)
+	}
+	started, err := s.repo.StartUserMFAEnrollment(ctx, user.ID, secret, time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to start MFA enrollment: %w", err)
+	}
+	if !started {
+		return nil, errMFAAlreadyEnabled
+	}
+	return &pkg.MFAEnrollment{
+		Secret:     secret,
+		OTPAuthURI: totpURI(localPkg.MFA_ISSUER, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment enables MFA with a TOTP code from the pending secret and returns a new
+// set of recovery codes, which are only stored hashed.
+func (s *authService) ConfirmMFAEnrollment(ctx context.Context, accessToken string, code string) ([]string, error) {
+	_, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := s.repo.GetUserMFA(ctx, user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errMFANotPending
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MFA: %w", err)
+	}
+	if mfa.EnabledAt.Valid {
+		return nil, errMFAAlreadyEnabled
+	}
+	step, ok := verifyTOTP(mfa.Secret, normalizeMFACode(code), time.Now())
+	if !ok {
+		return nil, errInvalidMFACode
+	}
+
+	codes := make([]string, localPkg.MFA_RECOVERY_CODE_COUNT)
+	hashes := make([]string, len(codes))
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		hashes[i] = sha256Hex(normalizeUserCode(codes[i]))
+	}
+	enabled, err := s.repo.EnableUserMFA(ctx, user.ID, step, time.Now().Unix(), hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable MFA: %w", err)
+	}
+	if !enabled {
+		return nil, errMFANotPending
+	}
+	securityEvent("mfa_enabled", "user_id", user.ID)
+	return codes, nil
+}
+
+// DisableMFA turns MFA off for the access token's user, who must present a TOTP or recovery code.
+func (s *authService) DisableMFA(ctx context.Context, accessToken string, code string) error {
+	_, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	mfa, err := s.enabledMFA(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if mfa == nil {
+		return errMFANotEnabled
+	}
+	if err := s.checkMFACode(ctx, mfa, code); err != nil {
+		return err
+	}
+	if _, err := s.repo.DisableUserMFA(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+	securityEvent("mfa_disabled", "user_id", user.ID)
+	return nil
+}
+
+func (s *authService) GetMFAStatus(ctx context.Context, accessToken string) (*pkg.MFAStatus, error) {
+	_, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := s.enabledMFA(ctx, user.ID)
+	if err != nil || mfa == nil {
+		return &pkg.MFAStatus{}, err
+	}
+	remaining, err := s.repo.CountUnusedMFARecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return &pkg.MFAStatus{Enabled: true, RecoveryCodesRemaining: remaining}, nil
+}
+
+// VerifyMFA finishes a sign-in HandleOAuthCallback left waiting for the second factor. A
+// challenge allows MFA_CHALLENGE_MAX_ATTEMPTS codes and starts at most one session.
+func (s *authService) VerifyMFA(ctx context.Context, mfaToken string, code string, client pkg.ClientInfo) (*pkg.AuthResponse, error) {
+	hash := sha256Hex(mfaToken)
+	challenge, err := s.repo.GetMFAChallenge(ctx, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MFA challenge: %w", err)
+	}
+	allowed, err := s.repo.ConsumeMFAChallengeAttempt(ctx, hash, localPkg.MFA_CHALLENGE_MAX_ATTEMPTS, time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update MFA challenge: %w", err)
+	}
+	if !allowed {
+		_, _ = s.repo.DeleteMFAChallenge(ctx, hash)
+		return nil, errInvalidMFAChallenge
+	}
+
+	mfa, err := s.enabledMFA(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		// disabled since the challenge was issued, sign in again
+		_, _ = s.repo.DeleteMFAChallenge(ctx, hash)
+		return nil, errInvalidMFAChallenge
+	}
+	if err := s.checkMFACode(ctx, mfa, code); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			securityEvent("mfa_code_rejected", "user_id", challenge.UserID, "attempt", challenge.Attempts+1)
+		}
+		return nil, err
+	}
+	consumed, err := s.repo.DeleteMFAChallenge(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete MFA challenge: %w", err)
+	}
+	if !consumed {
+		// completed by a concurrent call
+		return nil, errInvalidMFAChallenge
+	}
+
+	user, err := s.repo.GetUserByID(ctx, challenge.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errAccountDeleted
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.SuspendedAt.Valid {
+		return nil, errUserSuspended
+	}
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &pkg.AuthResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		User:         userToUserInfo(user),
+	}, nil
+}
+
+// startMFAChallenge holds a sign-in until VerifyMFA and returns the token naming it.
+func (s *authService) startMFAChallenge(ctx context.Context, user *db.User) (*pkg.AuthResponse, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate MFA token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	now := time.Now()
+	if err := s.repo.CreateMFAChallenge(ctx, &db.MfaChallenge{
+		TokenHash: sha256Hex(token),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(localPkg.MFA_CHALLENGE_EXPIRATION).Unix(),
+		CreatedAt: now.Unix(),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store MFA challenge: %w", err)
+	}
+	return &pkg.AuthResponse{
+		MFAToken:     token,
+		MFAExpiresIn: int64(localPkg.MFA_CHALLENGE_EXPIRATION.Seconds()),
+	}, nil
+}
+
+// enabledMFA returns the user's MFA settings, or nil if MFA is not enabled.
+func (s *authService) enabledMFA(ctx context.Context, userID string) (*db.UserMfa, error) {
+	mfa, err := s.repo.GetUserMFA(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MFA: %w", err)
+	}
+	if !mfa.EnabledAt.Valid {
+		return nil, nil
+	}
+	return mfa, nil
+}
+
+// checkMFACode accepts a TOTP code whose time step has not been used yet, or an unused
+// recovery code, and marks it used.
+func (s *authService) checkMFACode(ctx context.Context, mfa *db.UserMfa, code string) error {
+	code = normalizeMFACode(code)
+	if isTOTPCode(code) {
+		step, ok := verifyTOTP(mfa.Secret, code, time.Now())
+		if !ok {
+			return errInvalidMFACode
+		}
+		fresh, err := s.repo.UseUserMFAStep(ctx, mfa.UserID, step)
+		if err != nil {
+			return fmt.Errorf("failed to record MFA code use: %w", err)
+		}
+		if !fresh {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.repo.UseMFARecoveryCode(ctx, mfa.UserID, sha256Hex(normalizeUserCode(code)), time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if !used {
+		return errInvalidMFACode
+	}
+	securityEvent("mfa_recovery_code_used", "user_id", mfa.UserID)
+	return nil
+}
+
+// normalizeMFACode drops the spaces authenticator apps show in codes.
+func normalizeMFACode(code string) string {
+	return strings.Join(strings.Fields(code), "")
+}
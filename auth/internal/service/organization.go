@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/google/uuid"
+)
+
+var (
+	errOrganizationNotFound = errors.New("organization not found")
+	errInvalidOrgRole       = errors.New("role must be one of member, admin, owner")
+	errLastOwner            = errors.New("an organization needs an owner, make another member an owner first")
+	errAlreadyMember        = errors.New("user is already a member of this organization")
+	errMemberNotFound       = errors.New("member not found")
+)
+
+// Organization calls take the caller's access token and check their membership; members who
+// are not admins can only read. Non-members get errOrganizationNotFound, so they cannot tell
+// whether an organization exists.
+
+// CreateOrganization creates an organization owned by the access token's user. An empty slug is
+// derived from the name.
+func (s *authService) CreateOrganization(ctx context.Context, accessToken string, name string, slug string) (*pkg.Organization, error) {
+	_, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	name, err = cleanOrganizationName(name)
+	if err != nil {
+		return nil, err
+	}
+	if slug == "" {
+		slug = slugify(name)
+	}
+	if err := validateSlug(slug); err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.GetOrganizationBySlug(ctx, slug)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check slug: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("the slug %q is taken", slug)
+	}
+	count, err := s.repo.CountUserOrganizations(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count organizations: %w", err)
+	}
+	if count >= localPkg.ORGANIZATION_MAX_PER_USER {
+		return nil, fmt.Errorf("organization limit reached (%d)", localPkg.ORGANIZATION_MAX_PER_USER)
+	}
+
+	now := time.Now().Unix()
+	org := &db.Organization{
+		ID:        uuid.New().String(),
+		Slug:      slug,
+		Name:      name,
+		CreatedBy: user.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.CreateOrganization(ctx, org, &db.OrganizationMember{
+		OrgID:     org.ID,
+		UserID:    user.ID,
+		Role:      pkg.OrgRoleOwner,
+		CreatedAt: now,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	return organizationToPkg(org, pkg.OrgRoleOwner), nil
+}
+
+// ListOrganizations returns the organizations of the access token's user with their role.
+func (s *authService) ListOrganizations(ctx context.Context, accessToken string) ([]pkg.Organization, error) {
+	_, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListUserOrganizations(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	out := make([]pkg.Organization, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, pkg.Organization{
+			ID:               row.ID,
+			Slug:             row.Slug,
+			Name:             row.Name,
+			RetentionSeconds: row.RetentionSeconds,
+			QuotaBytes:       row.QuotaBytes,
+			Role:             row.Role,
+			CreatedAt:        row.CreatedAt,
+			UpdatedAt:        row.UpdatedAt,
+		})
+	}
+	return out, nil
+}
+
+func (s *authService) GetOrganization(ctx context.Context, accessToken string, orgID string) (*pkg.Organization, error) {
+	org, member, err := s.organizationAs(ctx, accessToken, orgID, pkg.OrgRoleMember)
+	if err != nil {
+		return nil, err
+	}
+	return organizationToPkg(org, member.Role), nil
+}
+
+// UpdateOrganization changes the name, retention or quota of an organization (admins).
+func (s *authService) UpdateOrganization(ctx context.Context, accessToken string, orgID string, update pkg.OrganizationUpdate) (*pkg.Organization, error) {
+	org, member, err := s.organizationAs(ctx, accessToken, orgID, pkg.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if update.Name != nil {
+		if org.Name, err = cleanOrganizationName(*update.Name); err != nil {
+			return nil, err
+		}
+	}
+	if update.RetentionSeconds != nil {
+		r := *update.RetentionSeconds
+		if r < 0 || r > int64(localPkg.ORGANIZATION_RETENTION_MAX.Seconds()) {
+			return nil, fmt.Errorf("retention must be between 0 (platform default) and %d seconds", int64(localPkg.ORGANIZATION_RETENTION_MAX.Seconds()))
+		}
+		org.RetentionSeconds = r
+	}
+	if update.QuotaBytes != nil {
+		if *update.QuotaBytes < 0 {
+			return nil, fmt.Errorf("quota must not be negative")
+		}
+		org.QuotaBytes = *update.QuotaBytes
+	}
+	org.UpdatedAt = time.Now().Unix()
+	if err := s.repo.UpdateOrganization(ctx, org); err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+	return organizationToPkg(org, member.Role), nil
+}
+
+// DeleteOrganization deletes an organization and its memberships (owners). Its buckets are
+// released by the caller (the gateway) through the filemanager.
+func (s *authService) DeleteOrganization(ctx context.Context, accessToken string, orgID string) error {
+	if _, _, err := s.organizationAs(ctx, accessToken, orgID, pkg.OrgRoleOwner); err != nil {
+		return err
+	}
+	deleted, err := s.repo.DeleteOrganization(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	if !deleted {
+		return errOrganizationNotFound
+	}
+	return nil
+}
+
+func (s *authService) ListOrganizationMembers(ctx context.Context, accessToken string, orgID string) ([]pkg.OrganizationMember, error) {
+	if _, _, err := s.organizationAs(ctx, accessToken, orgID, pkg.OrgRoleMember); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListOrganizationMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	out := make([]pkg.OrganizationMember, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, pkg.OrganizationMember{
+			UserID:    row.UserID,
+			Email:     row.Email,
+			Username:  row.Username.String,
+			AvatarUrl: row.AvatarUrl.String,
+			Role:      row.Role,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// AddOrganizationMember adds the user with the given email (admins, with a role up to their own).
+func (s *authService) AddOrganizationMember(ctx context.Context, accessToken string, orgID string, email string, role string) (*pkg.OrganizationMember, error) {
+	_, actor, err := s.organizationAs(ctx, accessToken, orgID, pkg.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.OrgRoleRank(role) < 0 {
+		return nil, errInvalidOrgRole
+	}
+	if pkg.OrgRoleRank(role) > pkg.OrgRoleRank(actor.Role) {
+		return nil, errNotPermitted
+	}
+	user, err := s.repo.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no user with this email, they need to sign in once first")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	existing, err := s.repo.GetOrganizationMember(ctx, orgID, user.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check membership: %w", err)
+	}
+	if existing != nil {
+		return nil, errAlreadyMember
+	}
+	count, err := s.repo.CountUserOrganizations(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count organizations: %w", err)
+	}
+	if count >= localPkg.ORGANIZATION_MAX_PER_USER {
+		return nil, fmt.Errorf("this user has reached the organization limit (%d)", localPkg.ORGANIZATION_MAX_PER_USER)
+	}
+
+	member := &db.OrganizationMember{OrgID: orgID, UserID: user.ID, Role: role, CreatedAt: time.Now().Unix()}
+	if err := s.repo.AddOrganizationMember(ctx, member); err != nil {
+		return nil, fmt.Errorf("failed to add member: %w", err)
+	}
+	return &pkg.OrganizationMember{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Username:  user.Username.String,
+		AvatarUrl: user.AvatarUrl.String,
+		Role:      role,
+		CreatedAt: member.CreatedAt,
+	}, nil
+}
+
+// SetOrganizationMemberRole changes a member's role. Admins can change the role of members
+// below them (and their own) to a role up to theirs; owners can change anyone's.
+func (s *authService) SetOrganizationMemberRole(ctx context.Context, accessToken string, orgID string, userID string, role string) error {
+	_, actor, err := s.organizationAs(ctx, accessToken, orgID, pkg.OrgRoleAdmin)
+	if err != nil {
+		return err
+	}
+	if pkg.OrgRoleRank(role) < 0 {
+		return errInvalidOrgRole
+	}
+	target, err := s.manageableMember(ctx, actor, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if pkg.OrgRoleRank(role) > pkg.OrgRoleRank(actor.Role) {
+		return errNotPermitted
+	}
+	if target.Role == role {
+		return nil
+	}
+	if target.Role == pkg.OrgRoleOwner {
+		if err := s.ensureAnotherOwner(ctx, orgID); err != nil {
+			return err
+		}
+	}
+	updated, err := s.repo.SetOrganizationMemberRole(ctx, orgID, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to set role: %w", err)
+	}
+	if !updated {
+		return errMemberNotFound
+	}
+	return nil
+}
+
+// RemoveOrganizationMember removes a member (admins, for members below them) or lets the caller
+// leave. The last owner cannot leave.
+func (s *authService) RemoveOrganizationMember(ctx context.Context, accessToken string, orgID string, userID string) error {
+	_, actor, err := s.organizationAs(ctx, accessToken, orgID, pkg.OrgRoleMember)
+	if err != nil {
+		return err
+	}
+	if actor.UserID != userID && pkg.OrgRoleRank(actor.Role) < pkg.OrgRoleRank(pkg.OrgRoleAdmin) {
+		return errNotPermitted
+	}
+	target, err := s.manageableMember(ctx, actor, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if target.Role == pkg.OrgRoleOwner {
+		if err := s.ensureAnotherOwner(ctx, orgID); err != nil {
+			return err
+		}
+	}
+	removed, err := s.repo.RemoveOrganizationMember(ctx, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	if !removed {
+		return errMemberNotFound
+	}
+	return nil
+}
+
+// GetOrganizationMembership returns an organization with the role of userID, empty if they are
+// not a member. It is for other services (the filemanager and gateway), which pass a user id they
+// have already authenticated.
+func (s *authService) GetOrganizationMembership(ctx context.Context, orgID string, userID string) (*pkg.Organization, error) {
+	org, err := s.repo.GetOrganizationByID(ctx, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errOrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	role := ""
+	if userID != "" {
+		member, err := s.repo.GetOrganizationMember(ctx, orgID, userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get membership: %w", err)
+		}
+		if member != nil {
+			role = member.Role
+		}
+	}
+	return organizationToPkg(org, role), nil
+}
+
+// organizationAs returns an organization and the access token's user's membership, which must
+// have at least role min.
+func (s *authService) organizationAs(ctx context.Context, accessToken string, orgID string, min string) (*db.Organization, *db.OrganizationMember, error) {
+	_, user, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.repo.GetOrganizationMember(ctx, orgID, user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, errOrganizationNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+	if pkg.OrgRoleRank(member.Role) < pkg.OrgRoleRank(min) {
+		return nil, nil, errNotPermitted
+	}
+	org, err := s.repo.GetOrganizationByID(ctx, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, errOrganizationNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return org, member, nil
+}
+
+// manageableMember returns the member userID if actor may change or remove them: themselves,
+// members below the actor's role, or anyone for owners.
+func (s *authService) manageableMember(ctx context.Context, actor *db.OrganizationMember, orgID string, userID string) (*db.OrganizationMember, error) {
+	target, err := s.repo.GetOrganizationMember(ctx, orgID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errMemberNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member: %w", err)
+	}
+	if target.UserID != actor.UserID && actor.Role != pkg.OrgRoleOwner &&
+		pkg.OrgRoleRank(target.Role) >= pkg.OrgRoleRank(actor.Role) {
+		return nil, errNotPermitted
+	}
+	return target, nil
+}
+
+func (s *authService) ensureAnotherOwner(ctx context.Context, orgID string) error {
+	owners, err := s.repo.CountOrganizationOwners(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to count owners: %w", err)
+	}
+	if owners <= 1 {
+		return errLastOwner
+	}
+	return nil
+}
+
+func organizationToPkg(org *db.Organization, role string) *pkg.Organization {
+	return &pkg.Organization{
+		ID:               org.ID,
+		Slug:             org.Slug,
+		Name:             org.Name,
+		RetentionSeconds: org.RetentionSeconds,
+		QuotaBytes:       org.QuotaBytes,
+		Role:             role,
+		CreatedAt:        org.CreatedAt,
+		UpdatedAt:        org.UpdatedAt,
+	}
+}
+
+func cleanOrganizationName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("organization name is required")
+	}
+	if utf8.RuneCountInString(name) > localPkg.ORGANIZATION_NAME_MAX {
+		return "", fmt.Errorf("organization name must be at most %d characters", localPkg.ORGANIZATION_NAME_MAX)
+	}
+	if strings.ContainsFunc(name, unicode.IsControl) {
+		return "", fmt.Errorf("organization name must not contain control characters")
+	}
+	return name, nil
+}
+
+// slugify lowercases name and joins its runs of ASCII letters and digits with dashes.
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	slug := b.String()
+	if len(slug) > localPkg.ORGANIZATION_SLUG_MAX {
+		slug = strings.TrimRight(slug[:localPkg.ORGANIZATION_SLUG_MAX], "-")
+	}
+	return slug
+}
+
+func validateSlug(slug string) error {
+	if len(slug) < localPkg.ORGANIZATION_SLUG_MIN || len(slug) > localPkg.ORGANIZATION_SLUG_MAX {
+		return fmt.Errorf("slug must be %d to %d characters", localPkg.ORGANIZATION_SLUG_MIN, localPkg.ORGANIZATION_SLUG_MAX)
+	}
+	for i, r := range slug {
+		ok := (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || (r == '-' && i > 0 && i < len(slug)-1)
+		if !ok {
+			return fmt.Errorf("slug must be lowercase letters, digits and inner dashes")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/google/uuid"
+)
+
+var errInvalidPersonalAccessToken = errors.New("invalid or expired personal access token")
+
+// CreatePersonalAccessToken issues a named token with the given scopes for the access token's
+// user. A zero expiresIn creates a token that does not expire. The plaintext is only returned here.
+func (s *authService) CreatePersonalAccessToken(ctx context.Context, accessToken string, name string, scopes []string, expiresIn time.Duration) (*pkg.CreatedPersonalAccessToken, error) {
+	claims, _, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	name, err = cleanTokenName(name)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err = normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	if expiresIn < 0 {
+		return nil, fmt.Errorf("expiry must not be negative")
+	}
+
+	count, err := s.repo.CountPersonalAccessTokensByUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count personal access tokens: %w", err)
+	}
+	if count >= localPkg.PERSONAL_ACCESS_TOKEN_MAX_PER_USER {
+		return nil, fmt.Errorf("personal access token limit reached (%d)", localPkg.PERSONAL_ACCESS_TOKEN_MAX_PER_USER)
+	}
+
+	plain, hash, err := generatePersonalAccessToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate personal access token: %w", err)
+	}
+	now := time.Now()
+	row := &db.PersonalAccessToken{
+		ID:          uuid.New().String(),
+		UserID:      claims.UserID,
+		Name:        name,
+		TokenHash:   hash,
+		TokenPrefix: plain[:localPkg.PERSONAL_ACCESS_TOKEN_PREFIX_LEN],
+		Scopes:      strings.Join(scopes, " "),
+		CreatedAt:   now.Unix(),
+	}
+	if expiresIn > 0 {
+		row.ExpiresAt = sql.NullInt64{Int64: now.Add(expiresIn).Unix(), Valid: true}
+	}
+	if err := s.repo.CreatePersonalAccessToken(ctx, row); err != nil {
+		return nil, fmt.Errorf("failed to store personal access token: %w", err)
+	}
+	return &pkg.CreatedPersonalAccessToken{Token: plain, PersonalAccessToken: personalAccessTokenToPkg(row)}, nil
+}
+
+// ListPersonalAccessTokens returns the access token's user's active tokens, newest first.
+func (s *authService) ListPersonalAccessTokens(ctx context.Context, accessToken string) ([]pkg.PersonalAccessToken, error) {
+	claims, _, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListPersonalAccessTokensByUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personal access tokens: %w", err)
+	}
+	out := make([]pkg.PersonalAccessToken, 0, len(rows))
+	for i := range rows {
+		out = append(out, personalAccessTokenToPkg(&rows[i]))
+	}
+	return out, nil
+}
+
+func (s *authService) RevokePersonalAccessToken(ctx context.Context, accessToken string, id string) error {
+	claims, _, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	revoked, err := s.repo.RevokePersonalAccessToken(ctx, id, claims.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke personal access token: %w", err)
+	}
+	if !revoked {
+		return fmt.Errorf("personal access token not found")
+	}
+	return nil
+}
+
+// ValidatePersonalAccessToken resolves a personal access token to its user and scopes and
+// records when it was last used.
+func (s *authService) ValidatePersonalAccessToken(ctx context.Context, token string) (*pkg.UserInfo, []string, error) {
+	if !strings.HasPrefix(token, pkg.PersonalAccessTokenPrefix) {
+		return nil, nil, errInvalidPersonalAccessToken
+	}
+	row, err := s.repo.GetPersonalAccessTokenByHash(ctx, sha256Hex(token))
+	if err != nil {
+		return nil, nil, errInvalidPersonalAccessToken
+	}
+	now := time.Now()
+	if row.ExpiresAt.Valid && now.Unix() > row.ExpiresAt.Int64 {
+		return nil, nil, errInvalidPersonalAccessToken
+	}
+	user, err := s.repo.GetUserByID(ctx, row.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("user not found: %w", err)
+	}
+	if user.SuspendedAt.Valid {
+		return nil, nil, errUserSuspended
+	}
+
+	staleBefore := now.Add(-localPkg.PERSONAL_ACCESS_TOKEN_LAST_USED).Unix()
+	if err := s.repo.TouchPersonalAccessToken(ctx, row.ID, now.Unix(), staleBefore); err != nil {
+		return nil, nil, fmt.Errorf("failed to record token use: %w", err)
+	}
+	return userToUserInfo(user), strings.Fields(row.Scopes), nil
+}
+
+// generatePersonalAccessToken returns a prefixed random token and the SHA-256 hash that is stored.
+func generatePersonalAccessToken() (plain, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	plain = pkg.PersonalAccessTokenPrefix + hex.EncodeToString(b)
+	return plain, sha256Hex(plain), nil
+}
+
+func cleanTokenName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("token name is required")
+	}
+	if utf8.RuneCountInString(name) > localPkg.PERSONAL_ACCESS_TOKEN_NAME_MAX {
+		return "", fmt.Errorf("token name must be at most %d characters", localPkg.PERSONAL_ACCESS_TOKEN_NAME_MAX)
+	}
+	if strings.ContainsFunc(name, unicode.IsControl) {
+		return "", fmt.Errorf("token name must not contain control characters")
+	}
+	return name, nil
+}
+
+// normalizeScopes validates the requested scopes and returns them sorted without duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	out := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(pkg.PersonalAccessTokenScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		out = append(out, scope)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+func personalAccessTokenToPkg(t *db.PersonalAccessToken) pkg.PersonalAccessToken {
+	return pkg.PersonalAccessToken{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.TokenPrefix,
+		Scopes:     strings.Fields(t.Scopes),
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt.Int64,
+		LastUsedAt: t.LastUsedAt.Int64,
+	}
+}
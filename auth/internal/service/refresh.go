@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/google/uuid"
+)
+
+var errRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+
+// issueRefreshToken stores a new refresh token in familyID, the id of the session it belongs to.
+func (s *authService) issueRefreshToken(ctx context.Context, userID, familyID string) (string, error) {
+	plain, hash, err := generateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	now := time.Now()
+	if err := s.repo.CreateRefreshToken(ctx, &db.RefreshToken{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(localPkg.REFRESH_TOKEN_EXPIRATION).Unix(),
+		CreatedAt: now.Unix(),
+	}); err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return plain, nil
+}
+
+// rejectRevokedRefreshToken handles a refresh token that is no longer active. A token that
+// was already rotated is only presented again if it leaked: either the attacker or the
+// legitimate client holds its successor, and we cannot tell which, so the session is ended:
+// the whole family is revoked along with its latest access token (OAuth 2.0 Security BCP,
+// refresh token rotation).
+func (s *authService) rejectRevokedRefreshToken(ctx context.Context, token *db.RefreshToken) error {
+	if token.RevokedReason.String != "token_refreshed" {
+		return fmt.Errorf("refresh token has been revoked")
+	}
+	var revoked bool
+	session, err := s.repo.GetUserSession(ctx, token.FamilyID, token.UserID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// Families that ended before sessions were recorded have no access token to revoke
+		n, err := s.repo.RevokeRefreshTokenFamily(ctx, token.FamilyID, "reuse_detected")
+		if err != nil {
+			return fmt.Errorf("failed to revoke token family: %w", err)
+		}
+		revoked = n > 0
+	case err != nil:
+		return fmt.Errorf("failed to get session: %w", err)
+	default:
+		if revoked, err = s.endSession(ctx, session, "reuse_detected"); err != nil {
+			return err
+		}
+	}
+	securityEvent("refresh_token_reuse",
+		"user_id", token.UserID,
+		"family_id", token.FamilyID,
+		"token_id", token.ID,
+		"revoked", revoked,
+	)
+	return errRefreshTokenReused
+}
+
+// securityEvent records a security relevant event as a structured warning so it can be
+// alerted on.
+func securityEvent(event string, attrs ...any) {
+	slog.Warn("Security event", append([]any{"event", event}, attrs...)...)
+}
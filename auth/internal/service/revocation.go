@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/pkg"
+)
+
+var (
+	errTokenRevoked  = errors.New("token has been revoked")
+	errUserSuspended = errors.New("user is suspended")
+)
+
+// validateActiveToken verifies the token and that it has not been revoked: its jti is not on
+// the revocation list, it was issued after the user's watermark and the user is active.
+func (s *authService) validateActiveToken(ctx context.Context, token string) (*pkg.Claims, *db.User, error) {
+	claims, err := s.keys.validateAccessToken(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if claims.ID == "" || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		return nil, nil, fmt.Errorf("invalid token: missing jti, iat or exp")
+	}
+	revoked, err := s.repo.IsAccessTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check revocation: %w", err)
+	}
+	if revoked {
+		return nil, nil, errTokenRevoked
+	}
+	user, err := s.repo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("user not found: %w", err)
+	}
+	if user.TokensValidAfter.Valid && claims.IssuedAtUnixMilli() <= user.TokensValidAfter.Int64 {
+		return nil, nil, errTokenRevoked
+	}
+	if user.SuspendedAt.Valid {
+		return nil, nil, errUserSuspended
+	}
+	return claims, user, nil
+}
+
+// killSessions invalidates every access token issued so far (watermark) and every refresh token.
+// The watermark has millisecond precision, so a session started right after stays valid.
+func (s *authService) killSessions(ctx context.Context, userID string, reason string) error {
+	if err := s.repo.SetUserTokensValidAfter(ctx, userID, time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("failed to set token watermark: %w", err)
+	}
+	if err := s.repo.RevokeAllUserTokens(ctx, userID, reason); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// LogoutAll logs the token's user out everywhere.
+func (s *authService) LogoutAll(ctx context.Context, accessToken string) error {
+	claims, _, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	return s.killSessions(ctx, claims.UserID, "user_logout_all")
+}
+
+// GetRevocations returns revocations recorded at or after since. Watermarks older than the
+// access token lifetime cannot affect a valid token and are left out.
+func (s *authService) GetRevocations(ctx context.Context, since int64) (*pkg.Revocations, error) {
+	now := time.Now().Unix()
+	tokens, err := s.repo.ListRevokedAccessTokensSince(ctx, since, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revoked tokens: %w", err)
+	}
+	watermarkSince := max(since, now-int64(localPkg.ACCESS_TOKEN_EXPIRATION.Seconds()))
+	watermarks, err := s.repo.ListUserWatermarksSince(ctx, watermarkSince*1000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list token watermarks: %w", err)
+	}
+
+	out := &pkg.Revocations{
+		Tokens:     make([]pkg.RevokedToken, 0, len(tokens)),
+		Watermarks: make([]pkg.TokenWatermark, 0, len(watermarks)),
+		Cursor:     now,
+	}
+	for _, t := range tokens {
+		out.Tokens = append(out.Tokens, pkg.RevokedToken{JTI: t.Jti, ExpiresAt: t.ExpiresAt})
+	}
+	for _, w := range watermarks {
+		out.Watermarks = append(out.Watermarks, pkg.TokenWatermark{
+			UserID:     w.ID,
+			ValidAfter: w.TokensValidAfter.Int64,
+			ExpiresAt:  w.TokensValidAfter.Int64/1000 + 1 + int64(localPkg.ACCESS_TOKEN_EXPIRATION.Seconds()),
+		})
+	}
+	return out, nil
+}
+
+// PruneRevokedTokens drops revocation entries for tokens that have expired anyway.
+func (s *authService) PruneRevokedTokens(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpiredRevokedAccessTokens(ctx, time.Now().Unix())
+}
@@ -5,35 +5,94 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"time"
 
+	"github.com/cthulhu-platform/auth/internal/mailer"
+	"github.com/cthulhu-platform/auth/internal/oauth"
 	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
 	"github.com/cthulhu-platform/auth/internal/repository"
 	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
 	"github.com/cthulhu-platform/auth/pkg"
 	"github.com/google/uuid"
-	"golang.org/x/oauth2"
 )
 
 type Service interface {
 	InitiateOAuth(ctx context.Context, provider string) (string, error)
-	HandleOAuthCallback(ctx context.Context, provider string, code string, state string) (*pkg.AuthResponse, error)
+	HandleOAuthCallback(ctx context.Context, provider string, code string, state string, client pkg.ClientInfo) (*pkg.AuthResponse, error)
 	ValidateToken(ctx context.Context, token string) (*pkg.UserInfo, error)
-	RefreshToken(ctx context.Context, refreshToken string) (*pkg.TokenPair, error)
+	RefreshToken(ctx context.Context, refreshToken string, client pkg.ClientInfo) (*pkg.TokenPair, error)
 	Logout(ctx context.Context, accessToken string) error
+	GetJWKS(ctx context.Context) (*pkg.JWKS, error)
+	LogoutAll(ctx context.Context, accessToken string) error
+	SetUserSuspended(ctx context.Context, actorID, userID string, suspended bool, client pkg.ClientInfo) error
+	GetRevocations(ctx context.Context, since int64) (*pkg.Revocations, error)
+	PruneRevokedTokens(ctx context.Context) (int64, error)
+	CreatePersonalAccessToken(ctx context.Context, accessToken string, name string, scopes []string, expiresIn time.Duration) (*pkg.CreatedPersonalAccessToken, error)
+	ListPersonalAccessTokens(ctx context.Context, accessToken string) ([]pkg.PersonalAccessToken, error)
+	RevokePersonalAccessToken(ctx context.Context, accessToken string, id string) error
+	ValidatePersonalAccessToken(ctx context.Context, token string) (*pkg.UserInfo, []string, error)
+	StartDeviceAuthorization(ctx context.Context, clientName string) (*pkg.DeviceAuthorization, error)
+	PollDeviceAuthorization(ctx context.Context, deviceCode string, client pkg.ClientInfo) (*pkg.AuthResponse, error)
+	GetDeviceAuthorization(ctx context.Context, accessToken string, userCode string) (*pkg.PendingDeviceAuthorization, error)
+	ApproveDeviceAuthorization(ctx context.Context, accessToken string, userCode string, approve bool) error
+	ListSessions(ctx context.Context, accessToken string) ([]pkg.Session, error)
+	RevokeSession(ctx context.Context, accessToken string, id string) error
+	StartLinkIdentity(ctx context.Context, accessToken string, provider string) (string, error)
+	LinkIdentity(ctx context.Context, accessToken string, provider string, code string, state string) (*pkg.Identity, error)
+	ListIdentities(ctx context.Context, accessToken string) ([]pkg.Identity, error)
+	UnlinkIdentity(ctx context.Context, accessToken string, provider string) error
+	DeleteAccount(ctx context.Context, accessToken string) error
+	ListAccountDeletions(ctx context.Context, after int64, limit int) ([]pkg.AccountDeletion, error)
+	PurgeDeletedAccounts(ctx context.Context, gracePeriod time.Duration) (int, error)
+	CleanupExpired(ctx context.Context, refreshRetention time.Duration) (MaintenanceReport, error)
+	StartMFAEnrollment(ctx context.Context, accessToken string) (*pkg.MFAEnrollment, error)
+	ConfirmMFAEnrollment(ctx context.Context, accessToken string, code string) ([]string, error)
+	DisableMFA(ctx context.Context, accessToken string, code string) error
+	GetMFAStatus(ctx context.Context, accessToken string) (*pkg.MFAStatus, error)
+	VerifyMFA(ctx context.Context, mfaToken string, code string, client pkg.ClientInfo) (*pkg.AuthResponse, error)
+	SetUserRole(ctx context.Context, actorID, userID, role string, client pkg.ClientInfo) error
+	RecordAuditEvent(ctx context.Context, event pkg.AuditEvent, client pkg.ClientInfo) error
+	ListAuditEvents(ctx context.Context, before int64, actorID, targetID, action string, limit int) ([]pkg.AuditEvent, error)
+	NotifyAbuseReporter(ctx context.Context, userID, email string, reportID int64, bucketID, outcome string) (bool, error)
+	RequestMagicLink(ctx context.Context, email string) error
+	RedeemMagicLink(ctx context.Context, token string, client pkg.ClientInfo) (*pkg.AuthResponse, error)
+	CreateOrganization(ctx context.Context, accessToken string, name string, slug string) (*pkg.Organization, error)
+	ListOrganizations(ctx context.Context, accessToken string) ([]pkg.Organization, error)
+	GetOrganization(ctx context.Context, accessToken string, orgID string) (*pkg.Organization, error)
+	UpdateOrganization(ctx context.Context, accessToken string, orgID string, update pkg.OrganizationUpdate) (*pkg.Organization, error)
+	DeleteOrganization(ctx context.Context, accessToken string, orgID string) error
+	ListOrganizationMembers(ctx context.Context, accessToken string, orgID string) ([]pkg.OrganizationMember, error)
+	AddOrganizationMember(ctx context.Context, accessToken string, orgID string, email string, role string) (*pkg.OrganizationMember, error)
+	SetOrganizationMemberRole(ctx context.Context, accessToken string, orgID string, userID string, role string) error
+	RemoveOrganizationMember(ctx context.Context, accessToken string, orgID string, userID string) error
+	GetOrganizationMembership(ctx context.Context, orgID string, userID string) (*pkg.Organization, error)
 }
 
 type authService struct {
-	repo repository.Repository
+	repo      repository.Repository
+	providers *oauth.Registry
+	keys      *KeyManager
+	mailer    mailer.Mailer
 }
 
-func NewAuthService(repo repository.Repository) Service {
-	return &authService{repo: repo}
+func NewAuthService(repo repository.Repository, providers *oauth.Registry, keys *KeyManager, mailer mailer.Mailer) Service {
+	return &authService{repo: repo, providers: providers, keys: keys, mailer: mailer}
 }
 
 func (s *authService) InitiateOAuth(ctx context.Context, provider string) (string, error) {
+	return s.beginOAuth(ctx, provider, "")
+}
+
+// beginOAuth stores a PKCE OAuth session and returns the provider's authorization URL. A
+// non-empty linkUserID makes it a flow that links the identity to that user (LinkIdentity)
+// rather than a sign-in.
+func (s *authService) beginOAuth(ctx context.Context, provider string, linkUserID string) (string, error) {
+	p, err := s.providers.Get(provider)
+	if err != nil {
+		return "", err
+	}
+
 	// Generate PKCE values
 	codeVerifier := generateCodeVerifier()
 	codeChallenge := generateCodeChallenge(codeVerifier)
@@ -44,25 +103,28 @@ func (s *authService) InitiateOAuth(ctx context.Context, provider string) (strin
 	now := time.Now()
 	expiresAt := now.Add(localPkg.OAUTH_SESSION_EXPIRATION_TIME).Unix()
 
-	// Store OAuth session (validated in HandleOAuthCallback via state)
+	// Store OAuth session (validated in completeOAuth via state)
 	session := &db.OauthSession{
 		State:         state,
 		Provider:      provider,
 		CodeVerifier:  codeVerifier,
 		CodeChallenge: codeChallenge,
-		RedirectUri:   getRedirectURI(provider),
+		RedirectUri:   p.RedirectURI(),
 		ExpiresAt:     expiresAt,
 		CreatedAt:     now.Unix(),
+		LinkUserID:    sql.NullString{String: linkUserID, Valid: linkUserID != ""},
 	}
 
 	if err := s.repo.CreateOAuthSession(ctx, session); err != nil {
 		return "", err
 	}
 
-	return buildOAuthURL(provider, state, codeChallenge), nil
+	return p.AuthCodeURL(state, codeChallenge, deriveNonce(codeVerifier)), nil
 }
 
-func (s *authService) HandleOAuthCallback(ctx context.Context, provider string, code string, state string) (*pkg.AuthResponse, error) {
+// completeOAuth checks the OAuth session named by state, which must have been started by
+// beginOAuth with the same linkUserID, and exchanges the code for the provider's user info.
+func (s *authService) completeOAuth(ctx context.Context, provider, code, state, linkUserID string) (*oauth.UserInfo, error) {
 	session, err := s.repo.GetOAuthSession(ctx, state)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get OAuth session: %w", err)
@@ -77,99 +139,76 @@ func (s *authService) HandleOAuthCallback(ctx context.Context, provider string,
 	if session.Provider != provider {
 		return nil, fmt.Errorf("provider mismatch")
 	}
+	if session.LinkUserID.String != linkUserID {
+		if linkUserID == "" {
+			return nil, fmt.Errorf("OAuth session was started to link an account")
+		}
+		return nil, fmt.Errorf("OAuth session was not started by this user")
+	}
 
-	oauthConfig := getOAuthConfig(provider)
-	if oauthConfig == nil {
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	p, err := s.providers.Get(provider)
+	if err != nil {
+		return nil, err
 	}
-	token, err := oauthConfig.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", session.CodeVerifier))
+	token, err := p.Exchange(ctx, code, session.CodeVerifier)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
 	}
 
-	userInfo, err := fetchUserInfo(provider, token.AccessToken)
+	userInfo, err := p.UserInfo(ctx, token, deriveNonce(session.CodeVerifier))
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch user info: %w", err)
 	}
 
-	existingUser, err := s.repo.GetUserByOAuthID(ctx, provider, userInfo.OAuthUserID)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("failed to check existing user: %w", err)
-	}
-
-	now := time.Now().Unix()
-	var user *db.User
+	_ = s.repo.DeleteOAuthSession(ctx, state)
+	return userInfo, nil
+}
 
-	if existingUser != nil {
-		if err := s.repo.UpdateUser(ctx, &db.User{
-			ID:        existingUser.ID,
-			Username:  ptrToNullString(userInfo.Username),
-			AvatarUrl: ptrToNullString(userInfo.AvatarURL),
-			UpdatedAt: now,
-		}); err != nil {
-			return nil, fmt.Errorf("failed to update user: %w", err)
-		}
-		user = existingUser
-		user.Username = ptrToNullString(userInfo.Username)
-		user.AvatarUrl = ptrToNullString(userInfo.AvatarURL)
-		user.UpdatedAt = now
-	} else {
-		user = &db.User{
-			ID:            uuid.New().String(),
-			OauthProvider: provider,
-			OauthUserID:   userInfo.OAuthUserID,
-			Email:         userInfo.Email,
-			Username:      ptrToNullString(userInfo.Username),
-			AvatarUrl:     ptrToNullString(userInfo.AvatarURL),
-			CreatedAt:     now,
-			UpdatedAt:     now,
-		}
-		if err := s.repo.CreateUser(ctx, user); err != nil {
-			return nil, fmt.Errorf("failed to create user: %w", err)
-		}
+func (s *authService) HandleOAuthCallback(ctx context.Context, provider string, code string, state string, client pkg.ClientInfo) (*pkg.AuthResponse, error) {
+	userInfo, err := s.completeOAuth(ctx, provider, code, state, "")
+	if err != nil {
+		return nil, err
 	}
-
-	accessToken, err := generateAccessToken(user.ID, user.Email, user.OauthProvider)
+	user, err := s.signInIdentity(ctx, provider, userInfo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		return nil, err
 	}
-	refreshPlain, refreshHash, err := generateRefreshToken()
+	return s.finishSignIn(ctx, user, client)
+}
+
+// finishSignIn starts a session for a user who has proven an identity, or an MFA challenge if
+// they enrolled in MFA.
+func (s *authService) finishSignIn(ctx context.Context, user *db.User, client pkg.ClientInfo) (*pkg.AuthResponse, error) {
+	// With MFA enabled no tokens are issued until VerifyMFA sees a code
+	mfa, err := s.enabledMFA(ctx, user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
-	}
-	refreshExpiresAt := time.Now().Add(localPkg.REFRESH_TOKEN_EXPIRATION).Unix()
-	if err := s.repo.CreateRefreshToken(ctx, &db.RefreshToken{
-		ID:        uuid.New().String(),
-		UserID:    user.ID,
-		TokenHash: refreshHash,
-		ExpiresAt: refreshExpiresAt,
-		CreatedAt: now,
-	}); err != nil {
-		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		return nil, err
+	}
+	if mfa != nil {
+		return s.startMFAChallenge(ctx, user)
 	}
 
-	_ = s.repo.DeleteOAuthSession(ctx, state)
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
 
 	return &pkg.AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshPlain,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 		User:         userToUserInfo(user),
 	}, nil
 }
 
 func (s *authService) ValidateToken(ctx context.Context, token string) (*pkg.UserInfo, error) {
-	claims, err := validateAccessToken(token)
+	_, user, err := s.validateActiveToken(ctx, token)
 	if err != nil {
 		return nil, err
 	}
-	user, err := s.repo.GetUserByID(ctx, claims.UserID)
-	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
-	}
 	return userToUserInfo(user), nil
 }
 
-func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*pkg.TokenPair, error) {
+func (s *authService) RefreshToken(ctx context.Context, refreshToken string, client pkg.ClientInfo) (*pkg.TokenPair, error) {
 	hash := sha256Hex(refreshToken)
 
 	tokenRecord, err := s.repo.GetRefreshTokenByHash(ctx, hash)
@@ -177,10 +216,10 @@ func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*p
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
 	if tokenRecord.RevokedAt.Valid {
-		return nil, fmt.Errorf("refresh token has been revoked")
+		return nil, s.rejectRevokedRefreshToken(ctx, tokenRecord)
 	}
 	if time.Now().Unix() > tokenRecord.ExpiresAt {
-		_ = s.repo.RevokeRefreshToken(ctx, tokenRecord.ID, "expired")
+		_, _ = s.repo.RevokeRefreshToken(ctx, tokenRecord.ID, "expired")
 		return nil, fmt.Errorf("refresh token expired")
 	}
 
@@ -188,43 +227,52 @@ func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*p
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
-
-	if err := s.repo.RevokeRefreshToken(ctx, tokenRecord.ID, "token_refreshed"); err != nil {
-		return nil, fmt.Errorf("failed to revoke old token: %w", err)
+	if user.SuspendedAt.Valid {
+		return nil, errUserSuspended
 	}
 
-	accessToken, err := generateAccessToken(user.ID, user.Email, user.OauthProvider)
+	rotated, err := s.repo.RevokeRefreshToken(ctx, tokenRecord.ID, "token_refreshed")
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		return nil, fmt.Errorf("failed to revoke old token: %w", err)
 	}
-	refreshPlain, refreshHash, err := generateRefreshToken()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
-	}
-	now := time.Now().Unix()
-	refreshExpiresAt := time.Now().Add(localPkg.REFRESH_TOKEN_EXPIRATION).Unix()
-	if err := s.repo.CreateRefreshToken(ctx, &db.RefreshToken{
-		ID:        uuid.New().String(),
-		UserID:    user.ID,
-		TokenHash: refreshHash,
-		ExpiresAt: refreshExpiresAt,
-		CreatedAt: now,
-	}); err != nil {
-		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	if !rotated {
+		// Revoked since it was read, by a concurrent refresh or logout
+		tokenRecord, err = s.repo.GetRefreshTokenByHash(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid refresh token: %w", err)
+		}
+		return nil, s.rejectRevokedRefreshToken(ctx, tokenRecord)
 	}
 
-	return &pkg.TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshPlain,
-	}, nil
+	return s.continueSession(ctx, user, tokenRecord.FamilyID, client)
 }
 
+// Logout revokes the presented access token and ends its session.
 func (s *authService) Logout(ctx context.Context, accessToken string) error {
-	claims, err := validateAccessToken(accessToken)
+	claims, _, err := s.validateActiveToken(ctx, accessToken)
 	if err != nil {
 		return err
 	}
-	return s.repo.RevokeAllUserTokens(ctx, claims.UserID, "user_logout")
+	if err := s.repo.RevokeAccessToken(ctx, &db.RevokedAccessToken{
+		Jti:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		RevokedAt: time.Now().Unix(),
+	}); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	if claims.SessionID == "" {
+		// issued before sessions were tracked, its refresh token cannot be told apart
+		return s.repo.RevokeAllUserTokens(ctx, claims.UserID, "user_logout")
+	}
+	if _, err := s.repo.RevokeRefreshTokenFamily(ctx, claims.SessionID, "user_logout"); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+func (s *authService) GetJWKS(ctx context.Context) (*pkg.JWKS, error) {
+	return s.keys.JWKS(), nil
 }
 
 func ptrToNullString(s *string) sql.NullString {
@@ -238,7 +286,7 @@ func userToUserInfo(u *db.User) *pkg.UserInfo {
 	if u == nil {
 		return nil
 	}
-	info := &pkg.UserInfo{ID: u.ID, Email: u.Email}
+	info := &pkg.UserInfo{ID: u.ID, Email: u.Email, Role: u.Role}
 	if u.Username.Valid {
 		info.Username = u.Username.String
 	}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+	"unicode"
+
+	localPkg "github.com/cthulhu-platform/auth/internal/pkg"
+	"github.com/cthulhu-platform/auth/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/google/uuid"
+)
+
+var errSessionNotFound = errors.New("session not found")
+
+// startSession signs a user in on a new session. The session id doubles as the family_id of
+// its refresh tokens and is carried in access tokens as the sid claim.
+func (s *authService) startSession(ctx context.Context, user *db.User, client pkg.ClientInfo) (*pkg.TokenPair, error) {
+	sessionID := uuid.New().String()
+	accessToken, claims, err := s.keys.generateAccessToken(user.ID, user.Email, user.OauthProvider, user.Role, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	client = cleanClientInfo(client)
+	now := time.Now().Unix()
+	if err := s.repo.CreateSession(ctx, &db.Session{
+		ID:                   sessionID,
+		UserID:               user.ID,
+		UserAgent:            client.UserAgent,
+		IpAddress:            client.IPAddress,
+		CreatedAt:            now,
+		LastRefreshedAt:      now,
+		AccessTokenJti:       claims.ID,
+		AccessTokenExpiresAt: claims.ExpiresAt.Unix(),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store session: %w", err)
+	}
+	refreshPlain, err := s.issueRefreshToken(ctx, user.ID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &pkg.TokenPair{AccessToken: accessToken, RefreshToken: refreshPlain}, nil
+}
+
+// continueSession issues the next token pair of a session on refresh and records where it was
+// refreshed from.
+func (s *authService) continueSession(ctx context.Context, user *db.User, sessionID string, client pkg.ClientInfo) (*pkg.TokenPair, error) {
+	accessToken, claims, err := s.keys.generateAccessToken(user.ID, user.Email, user.OauthProvider, user.Role, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	client = cleanClientInfo(client)
+	if err := s.repo.UpdateSessionRefresh(ctx, &db.Session{
+		ID:                   sessionID,
+		UserAgent:            client.UserAgent,
+		IpAddress:            client.IPAddress,
+		LastRefreshedAt:      time.Now().Unix(),
+		AccessTokenJti:       claims.ID,
+		AccessTokenExpiresAt: claims.ExpiresAt.Unix(),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+	refreshPlain, err := s.issueRefreshToken(ctx, user.ID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &pkg.TokenPair{AccessToken: accessToken, RefreshToken: refreshPlain}, nil
+}
+
+// ListSessions returns the access token's user's active sessions, most recently used first.
+func (s *authService) ListSessions(ctx context.Context, accessToken string) ([]pkg.Session, error) {
+	claims, _, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListActiveSessionsByUser(ctx, claims.UserID, time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	out := make([]pkg.Session, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, pkg.Session{
+			ID:              row.ID,
+			UserAgent:       row.UserAgent,
+			IPAddress:       row.IpAddress,
+			CreatedAt:       row.CreatedAt,
+			LastRefreshedAt: row.LastRefreshedAt,
+			Current:         row.ID == claims.SessionID,
+		})
+	}
+	return out, nil
+}
+
+// RevokeSession signs one of the access token's user's sessions out.
+func (s *authService) RevokeSession(ctx context.Context, accessToken string, id string) error {
+	claims, _, err := s.validateActiveToken(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	session, err := s.repo.GetUserSession(ctx, id, claims.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	ended, err := s.endSession(ctx, session, "session_revoked")
+	if err != nil {
+		return err
+	}
+	if !ended {
+		return errSessionNotFound
+	}
+	return nil
+}
+
+// endSession revokes a session's refresh tokens and its latest access token, and reports whether
+// the session was still active. Access tokens it was issued before its last refresh are not
+// tracked and stay valid until they expire.
+func (s *authService) endSession(ctx context.Context, session *db.Session, reason string) (bool, error) {
+	revoked, err := s.repo.RevokeRefreshTokenFamily(ctx, session.ID, reason)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session tokens: %w", err)
+	}
+	now := time.Now().Unix()
+	if session.AccessTokenJti != "" && session.AccessTokenExpiresAt > now {
+		if err := s.repo.RevokeAccessToken(ctx, &db.RevokedAccessToken{
+			Jti:       session.AccessTokenJti,
+			UserID:    session.UserID,
+			ExpiresAt: session.AccessTokenExpiresAt,
+			RevokedAt: now,
+		}); err != nil {
+			return false, fmt.Errorf("failed to revoke access token: %w", err)
+		}
+	}
+	return revoked > 0, nil
+}
+
+// cleanClientInfo bounds the client supplied user agent and drops anything that is not an IP.
+func cleanClientInfo(client pkg.ClientInfo) pkg.ClientInfo {
+	ua := strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, client.UserAgent))
+	if len(ua) > localPkg.SESSION_USER_AGENT_MAX {
+		ua = strings.ToValidUTF8(ua[:localPkg.SESSION_USER_AGENT_MAX], "")
+	}
+	ip := ""
+	if addr := net.ParseIP(strings.TrimSpace(client.IPAddress)); addr != nil {
+		ip = addr.String()
+	}
+	return pkg.ClientInfo{IPAddress: ip, UserAgent: ua}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238). They are the defaults every authenticator app supports, so the
+// otpauth URI does not need to spell them out.
+const (
+	totpPeriod     = 30 * time.Second
+	totpDigits     = 6
+	totpSkew       = 1  // steps accepted either side of the current one, for clock drift
+	totpSecretSize = 20 // bytes, the HMAC-SHA1 block recommended by RFC 4226
+
+	recoveryCodeLength = 12 // characters of userCodeAlphabet, ~52 bits of entropy
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateTOTPSecret() (string, error) {
+	b := make([]byte, totpSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// totpURI is the key URI authenticator apps import, usually from a QR code.
+func totpURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod/time.Second)
+}
+
+// totpCode computes the code of a time step (RFC 4226 section 5.3).
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
+
+// verifyTOTP returns the time step code was generated for, if it is valid within totpSkew
+// steps of now.
+func verifyTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// isTOTPCode tells a TOTP code from a recovery code.
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// generateRecoveryCode returns a code formatted as XXXX-XXXX-XXXX. Codes are compared after
+// normalizeUserCode, so case, dashes and spaces do not matter.
+func generateRecoveryCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	var b strings.Builder
+	for i := range recoveryCodeLength {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(userCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
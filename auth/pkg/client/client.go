@@ -3,11 +3,13 @@ package client
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/cthulhu-platform/auth/pkg"
 	pb "github.com/cthulhu-platform/proto/pkg/auth"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 type Client struct {
@@ -15,8 +17,11 @@ type Client struct {
 	service pb.AuthServiceClient
 }
 
-func NewClient(ctx context.Context, addr string) (*Client, error) {
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewClient creates a client for addr. Connections are plaintext unless opts carries transport
+// credentials, such as grpcauth.Config.DialOption for mutual TLS.
+func NewClient(ctx context.Context, addr string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(addr, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %v", err)
 	}
@@ -38,12 +43,17 @@ func (c *Client) InitiateOAuth(ctx context.Context, provider string) (string, er
 	return r.RedirectUrl, nil
 }
 
+// HandleOAuthCallback signs the user in. For users with MFA enabled only MFAToken and
+// MFAExpiresIn are set; pass the token to VerifyMFA.
 func (c *Client) HandleOAuthCallback(ctx context.Context, provider string, code string, state string) (*pkg.AuthResponse, error) {
 	r, err := c.service.HandleOAuthCallback(ctx, &pb.HandleOAuthCallbackRequest{Provider: provider, Code: code, State: state})
 	if err != nil {
 		return nil, fmt.Errorf("failed to handle OAuth callback: %v", err)
 	}
-	return &pkg.AuthResponse{AccessToken: r.AccessToken, RefreshToken: r.RefreshToken, User: &pkg.UserInfo{ID: r.User.Id, Email: r.User.Email, Username: r.User.Username, AvatarUrl: r.User.AvatarUrl}}, nil
+	if r.MfaToken != "" {
+		return &pkg.AuthResponse{MFAToken: r.MfaToken, MFAExpiresIn: r.MfaExpiresIn}, nil
+	}
+	return &pkg.AuthResponse{AccessToken: r.AccessToken, RefreshToken: r.RefreshToken, User: &pkg.UserInfo{ID: r.User.Id, Email: r.User.Email, Username: r.User.Username, AvatarUrl: r.User.AvatarUrl, Role: r.User.Role}}, nil
 }
 
 func (c *Client) ValidateToken(ctx context.Context, token string) (*pkg.UserInfo, error) {
@@ -51,7 +61,7 @@ func (c *Client) ValidateToken(ctx context.Context, token string) (*pkg.UserInfo
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate token: %v", err)
 	}
-	return &pkg.UserInfo{ID: r.User.Id, Email: r.User.Email, Username: r.User.Username, AvatarUrl: r.User.AvatarUrl}, nil
+	return &pkg.UserInfo{ID: r.User.Id, Email: r.User.Email, Username: r.User.Username, AvatarUrl: r.User.AvatarUrl, Role: r.User.Role}, nil
 }
 
 func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*pkg.TokenPair, error) {
@@ -62,6 +72,527 @@ func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*pkg.To
 	return &pkg.TokenPair{AccessToken: r.AccessToken, RefreshToken: r.RefreshToken}, nil
 }
 
+func (c *Client) LogoutAll(ctx context.Context, accessToken string) (bool, error) {
+	r, err := c.service.LogoutAll(ctx, &pb.LogoutAllRequest{AccessToken: accessToken})
+	if err != nil {
+		return false, fmt.Errorf("failed to logout everywhere: %v", err)
+	}
+	return r.Success, nil
+}
+
+// SetUserSuspended suspends or reinstates a user on behalf of actorID, a moderator or admin
+// whose role must be above the user's. Forward the actor's client with WithClientInfo.
+func (c *Client) SetUserSuspended(ctx context.Context, actorID string, userID string, suspended bool) (bool, error) {
+	r, err := c.service.SetUserSuspended(ctx, &pb.SetUserSuspendedRequest{ActorId: actorID, UserId: userID, Suspended: suspended})
+	if err != nil {
+		return false, fmt.Errorf("failed to set user suspended: %v", err)
+	}
+	return r.Success, nil
+}
+
+func (c *Client) GetRevocations(ctx context.Context, since int64) (*pkg.Revocations, error) {
+	r, err := c.service.GetRevocations(ctx, &pb.GetRevocationsRequest{Since: since})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revocations: %v", err)
+	}
+	out := &pkg.Revocations{Cursor: r.Cursor}
+	for _, t := range r.Tokens {
+		out.Tokens = append(out.Tokens, pkg.RevokedToken{JTI: t.Jti, ExpiresAt: t.ExpiresAt})
+	}
+	for _, w := range r.Watermarks {
+		out.Watermarks = append(out.Watermarks, pkg.TokenWatermark{UserID: w.UserId, ValidAfter: w.ValidAfter, ExpiresAt: w.ExpiresAt})
+	}
+	return out, nil
+}
+
+func (c *Client) CreatePersonalAccessToken(ctx context.Context, accessToken string, name string, scopes []string, expiresIn time.Duration) (*pkg.CreatedPersonalAccessToken, error) {
+	r, err := c.service.CreatePersonalAccessToken(ctx, &pb.CreatePersonalAccessTokenRequest{
+		AccessToken: accessToken,
+		Name:        name,
+		Scopes:      scopes,
+		ExpiresIn:   int64(expiresIn / time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create personal access token: %v", err)
+	}
+	return &pkg.CreatedPersonalAccessToken{Token: r.Token, PersonalAccessToken: personalAccessTokenFromPB(r.Details)}, nil
+}
+
+func (c *Client) ListPersonalAccessTokens(ctx context.Context, accessToken string) ([]pkg.PersonalAccessToken, error) {
+	r, err := c.service.ListPersonalAccessTokens(ctx, &pb.ListPersonalAccessTokensRequest{AccessToken: accessToken})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personal access tokens: %v", err)
+	}
+	out := make([]pkg.PersonalAccessToken, 0, len(r.Tokens))
+	for _, t := range r.Tokens {
+		out = append(out, personalAccessTokenFromPB(t))
+	}
+	return out, nil
+}
+
+func (c *Client) RevokePersonalAccessToken(ctx context.Context, accessToken string, id string) (bool, error) {
+	r, err := c.service.RevokePersonalAccessToken(ctx, &pb.RevokePersonalAccessTokenRequest{AccessToken: accessToken, Id: id})
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke personal access token: %v", err)
+	}
+	return r.Success, nil
+}
+
+// ValidatePersonalAccessToken returns the token's user and granted scopes.
+func (c *Client) ValidatePersonalAccessToken(ctx context.Context, token string) (*pkg.UserInfo, []string, error) {
+	r, err := c.service.ValidatePersonalAccessToken(ctx, &pb.ValidatePersonalAccessTokenRequest{Token: token})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to validate personal access token: %v", err)
+	}
+	return &pkg.UserInfo{ID: r.User.Id, Email: r.User.Email, Username: r.User.Username, AvatarUrl: r.User.AvatarUrl, Role: r.User.Role}, r.Scopes, nil
+}
+
+func (c *Client) StartDeviceAuthorization(ctx context.Context, clientName string) (*pkg.DeviceAuthorization, error) {
+	r, err := c.service.StartDeviceAuthorization(ctx, &pb.StartDeviceAuthorizationRequest{ClientName: clientName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device authorization: %v", err)
+	}
+	return &pkg.DeviceAuthorization{
+		DeviceCode:              r.DeviceCode,
+		UserCode:                r.UserCode,
+		VerificationURI:         r.VerificationUri,
+		VerificationURIComplete: r.VerificationUriComplete,
+		ExpiresIn:               r.ExpiresIn,
+		Interval:                r.Interval,
+	}, nil
+}
+
+// PollDeviceAuthorization returns a *pkg.DeviceFlowError until the request has been approved.
+func (c *Client) PollDeviceAuthorization(ctx context.Context, deviceCode string) (*pkg.AuthResponse, error) {
+	r, err := c.service.PollDeviceAuthorization(ctx, &pb.PollDeviceAuthorizationRequest{DeviceCode: deviceCode})
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll device authorization: %v", err)
+	}
+	if r.Error != "" {
+		return nil, &pkg.DeviceFlowError{Code: r.Error}
+	}
+	return &pkg.AuthResponse{
+		AccessToken:  r.AccessToken,
+		RefreshToken: r.RefreshToken,
+		User:         &pkg.UserInfo{ID: r.User.Id, Email: r.User.Email, Username: r.User.Username, AvatarUrl: r.User.AvatarUrl, Role: r.User.Role},
+	}, nil
+}
+
+func (c *Client) GetDeviceAuthorization(ctx context.Context, accessToken string, userCode string) (*pkg.PendingDeviceAuthorization, error) {
+	r, err := c.service.GetDeviceAuthorization(ctx, &pb.GetDeviceAuthorizationRequest{AccessToken: accessToken, UserCode: userCode})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device authorization: %v", err)
+	}
+	return &pkg.PendingDeviceAuthorization{UserCode: r.UserCode, ClientName: r.ClientName, CreatedAt: r.CreatedAt, ExpiresAt: r.ExpiresAt}, nil
+}
+
+func (c *Client) ApproveDeviceAuthorization(ctx context.Context, accessToken string, userCode string, approve bool) (bool, error) {
+	r, err := c.service.ApproveDeviceAuthorization(ctx, &pb.ApproveDeviceAuthorizationRequest{AccessToken: accessToken, UserCode: userCode, Approve: approve})
+	if err != nil {
+		return false, fmt.Errorf("failed to approve device authorization: %v", err)
+	}
+	return r.Success, nil
+}
+
+func (c *Client) ListSessions(ctx context.Context, accessToken string) ([]pkg.Session, error) {
+	r, err := c.service.ListSessions(ctx, &pb.ListSessionsRequest{AccessToken: accessToken})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %v", err)
+	}
+	sessions := make([]pkg.Session, 0, len(r.Sessions))
+	for _, s := range r.Sessions {
+		sessions = append(sessions, pkg.Session{
+			ID:              s.GetId(),
+			UserAgent:       s.GetUserAgent(),
+			IPAddress:       s.GetIpAddress(),
+			CreatedAt:       s.GetCreatedAt(),
+			LastRefreshedAt: s.GetLastRefreshedAt(),
+			Current:         s.GetCurrent(),
+		})
+	}
+	return sessions, nil
+}
+
+func (c *Client) RevokeSession(ctx context.Context, accessToken string, id string) (bool, error) {
+	r, err := c.service.RevokeSession(ctx, &pb.RevokeSessionRequest{AccessToken: accessToken, Id: id})
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %v", err)
+	}
+	return r.Success, nil
+}
+
+// StartLinkIdentity returns the provider URL that starts linking another account to the user.
+func (c *Client) StartLinkIdentity(ctx context.Context, accessToken string, provider string) (string, error) {
+	r, err := c.service.StartLinkIdentity(ctx, &pb.StartLinkIdentityRequest{AccessToken: accessToken, Provider: provider})
+	if err != nil {
+		return "", fmt.Errorf("failed to start identity link: %v", err)
+	}
+	return r.RedirectUrl, nil
+}
+
+func (c *Client) LinkIdentity(ctx context.Context, accessToken string, provider string, code string, state string) (*pkg.Identity, error) {
+	r, err := c.service.LinkIdentity(ctx, &pb.LinkIdentityRequest{AccessToken: accessToken, Provider: provider, Code: code, State: state})
+	if err != nil {
+		return nil, fmt.Errorf("failed to link identity: %v", err)
+	}
+	identity := identityFromPB(r.Identity)
+	return &identity, nil
+}
+
+func (c *Client) ListIdentities(ctx context.Context, accessToken string) ([]pkg.Identity, error) {
+	r, err := c.service.ListIdentities(ctx, &pb.ListIdentitiesRequest{AccessToken: accessToken})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %v", err)
+	}
+	identities := make([]pkg.Identity, 0, len(r.Identities))
+	for _, i := range r.Identities {
+		identities = append(identities, identityFromPB(i))
+	}
+	return identities, nil
+}
+
+func (c *Client) UnlinkIdentity(ctx context.Context, accessToken string, provider string) (bool, error) {
+	r, err := c.service.UnlinkIdentity(ctx, &pb.UnlinkIdentityRequest{AccessToken: accessToken, Provider: provider})
+	if err != nil {
+		return false, fmt.Errorf("failed to unlink identity: %v", err)
+	}
+	return r.Success, nil
+}
+
+func (c *Client) DeleteAccount(ctx context.Context, accessToken string) (bool, error) {
+	r, err := c.service.DeleteAccount(ctx, &pb.DeleteAccountRequest{AccessToken: accessToken})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete account: %v", err)
+	}
+	return r.Success, nil
+}
+
+// ListAccountDeletions returns up to limit entries of the account deletion feed after seq
+// (0 for the start, limit 0 for the server maximum).
+func (c *Client) ListAccountDeletions(ctx context.Context, after int64, limit int) ([]pkg.AccountDeletion, error) {
+	r, err := c.service.ListAccountDeletions(ctx, &pb.ListAccountDeletionsRequest{After: after, Limit: int32(limit)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account deletions: %v", err)
+	}
+	out := make([]pkg.AccountDeletion, 0, len(r.Deletions))
+	for _, d := range r.Deletions {
+		out = append(out, pkg.AccountDeletion{Seq: d.GetSeq(), UserID: d.GetUserId(), DeletedAt: d.GetDeletedAt()})
+	}
+	return out, nil
+}
+
+func (c *Client) StartMFAEnrollment(ctx context.Context, accessToken string) (*pkg.MFAEnrollment, error) {
+	r, err := c.service.StartMFAEnrollment(ctx, &pb.StartMFAEnrollmentRequest{AccessToken: accessToken})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start MFA enrollment: %v", err)
+	}
+	return &pkg.MFAEnrollment{Secret: r.Secret, OTPAuthURI: r.OtpauthUri}, nil
+}
+
+// ConfirmMFAEnrollment enables MFA and returns the recovery codes, which cannot be retrieved again.
+func (c *Client) ConfirmMFAEnrollment(ctx context.Context, accessToken string, code string) ([]string, error) {
+	r, err := c.service.ConfirmMFAEnrollment(ctx, &pb.ConfirmMFAEnrollmentRequest{AccessToken: accessToken, Code: code})
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm MFA enrollment: %v", err)
+	}
+	return r.RecoveryCodes, nil
+}
+
+func (c *Client) DisableMFA(ctx context.Context, accessToken string, code string) (bool, error) {
+	r, err := c.service.DisableMFA(ctx, &pb.DisableMFARequest{AccessToken: accessToken, Code: code})
+	if err != nil {
+		return false, fmt.Errorf("failed to disable MFA: %v", err)
+	}
+	return r.Success, nil
+}
+
+func (c *Client) GetMFAStatus(ctx context.Context, accessToken string) (*pkg.MFAStatus, error) {
+	r, err := c.service.GetMFAStatus(ctx, &pb.GetMFAStatusRequest{AccessToken: accessToken})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MFA status: %v", err)
+	}
+	return &pkg.MFAStatus{Enabled: r.Enabled, RecoveryCodesRemaining: r.RecoveryCodesRemaining}, nil
+}
+
+// VerifyMFA finishes a sign-in for which HandleOAuthCallback returned an MFA token.
+func (c *Client) VerifyMFA(ctx context.Context, mfaToken string, code string) (*pkg.AuthResponse, error) {
+	r, err := c.service.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: mfaToken, Code: code})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify MFA: %v", err)
+	}
+	return &pkg.AuthResponse{
+		AccessToken:  r.AccessToken,
+		RefreshToken: r.RefreshToken,
+		User:         &pkg.UserInfo{ID: r.User.Id, Email: r.User.Email, Username: r.User.Username, AvatarUrl: r.User.AvatarUrl, Role: r.User.Role},
+	}, nil
+}
+
+// RequestMagicLink emails a sign-in link to the address. It succeeds whether or not an account
+// uses the address.
+func (c *Client) RequestMagicLink(ctx context.Context, email string) (bool, error) {
+	r, err := c.service.RequestMagicLink(ctx, &pb.RequestMagicLinkRequest{Email: email})
+	if err != nil {
+		return false, fmt.Errorf("failed to request magic link: %v", err)
+	}
+	return r.Success, nil
+}
+
+// RedeemMagicLink signs in with the token of an emailed link, answering like HandleOAuthCallback.
+func (c *Client) RedeemMagicLink(ctx context.Context, token string) (*pkg.AuthResponse, error) {
+	r, err := c.service.RedeemMagicLink(ctx, &pb.RedeemMagicLinkRequest{Token: token})
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem magic link: %v", err)
+	}
+	if r.MfaToken != "" {
+		return &pkg.AuthResponse{MFAToken: r.MfaToken, MFAExpiresIn: r.MfaExpiresIn}, nil
+	}
+	return &pkg.AuthResponse{AccessToken: r.AccessToken, RefreshToken: r.RefreshToken, User: &pkg.UserInfo{ID: r.User.Id, Email: r.User.Email, Username: r.User.Username, AvatarUrl: r.User.AvatarUrl, Role: r.User.Role}}, nil
+}
+
+// SetUserRole changes a user's role on behalf of actorID, who must be an admin.
+func (c *Client) SetUserRole(ctx context.Context, actorID string, userID string, role string) (bool, error) {
+	r, err := c.service.SetUserRole(ctx, &pb.SetUserRoleRequest{ActorId: actorID, UserId: userID, Role: role})
+	if err != nil {
+		return false, fmt.Errorf("failed to set user role: %v", err)
+	}
+	return r.Success, nil
+}
+
+// RecordAuditEvent logs an action the caller carried out; the IP address is taken from
+// WithClientInfo.
+func (c *Client) RecordAuditEvent(ctx context.Context, event pkg.AuditEvent) (bool, error) {
+	r, err := c.service.RecordAuditEvent(ctx, &pb.RecordAuditEventRequest{Event: &pb.AuditEvent{
+		ActorId:    event.ActorID,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetId:   event.TargetID,
+		Details:    event.Details,
+	}})
+	if err != nil {
+		return false, fmt.Errorf("failed to record audit event: %v", err)
+	}
+	return r.Success, nil
+}
+
+// NotifyAbuseReporter emails the reporter of a bucket about the outcome of their report, at the
+// account's address when userID is set and at email otherwise. It returns false when there was no
+// address or mail is not configured.
+func (c *Client) NotifyAbuseReporter(ctx context.Context, userID, email string, reportID int64, bucketID, outcome string) (bool, error) {
+	r, err := c.service.NotifyAbuseReporter(ctx, &pb.NotifyAbuseReporterRequest{
+		UserId:   userID,
+		Email:    email,
+		ReportId: reportID,
+		BucketId: bucketID,
+		Outcome:  outcome,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to notify abuse reporter: %v", err)
+	}
+	return r.Success, nil
+}
+
+// ListAuditEvents returns up to limit audit events newest first, below the before id (0 for the
+// newest, limit 0 for the server maximum). Empty filters match anything.
+func (c *Client) ListAuditEvents(ctx context.Context, before int64, actorID, targetID, action string, limit int) ([]pkg.AuditEvent, error) {
+	r, err := c.service.ListAuditEvents(ctx, &pb.ListAuditEventsRequest{
+		Before:   before,
+		ActorId:  actorID,
+		TargetId: targetID,
+		Action:   action,
+		Limit:    int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %v", err)
+	}
+	out := make([]pkg.AuditEvent, 0, len(r.Events))
+	for _, e := range r.Events {
+		out = append(out, pkg.AuditEvent{
+			ID:         e.GetId(),
+			ActorID:    e.GetActorId(),
+			Action:     e.GetAction(),
+			TargetType: e.GetTargetType(),
+			TargetID:   e.GetTargetId(),
+			Details:    e.GetDetails(),
+			IPAddress:  e.GetIpAddress(),
+			CreatedAt:  e.GetCreatedAt(),
+		})
+	}
+	return out, nil
+}
+
+// CreateOrganization creates an organization owned by the access token's user. An empty slug is
+// derived from the name.
+func (c *Client) CreateOrganization(ctx context.Context, accessToken string, name string, slug string) (*pkg.Organization, error) {
+	r, err := c.service.CreateOrganization(ctx, &pb.CreateOrganizationRequest{AccessToken: accessToken, Name: name, Slug: slug})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %v", err)
+	}
+	return organizationFromPB(r.Organization), nil
+}
+
+// ListOrganizations returns the organizations of the access token's user with their role.
+func (c *Client) ListOrganizations(ctx context.Context, accessToken string) ([]pkg.Organization, error) {
+	r, err := c.service.ListOrganizations(ctx, &pb.ListOrganizationsRequest{AccessToken: accessToken})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %v", err)
+	}
+	out := make([]pkg.Organization, 0, len(r.Organizations))
+	for _, o := range r.Organizations {
+		out = append(out, *organizationFromPB(o))
+	}
+	return out, nil
+}
+
+func (c *Client) GetOrganization(ctx context.Context, accessToken string, orgID string) (*pkg.Organization, error) {
+	r, err := c.service.GetOrganization(ctx, &pb.GetOrganizationRequest{AccessToken: accessToken, OrgId: orgID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %v", err)
+	}
+	return organizationFromPB(r.Organization), nil
+}
+
+// UpdateOrganization changes the set fields of update (organization admins).
+func (c *Client) UpdateOrganization(ctx context.Context, accessToken string, orgID string, update pkg.OrganizationUpdate) (*pkg.Organization, error) {
+	r, err := c.service.UpdateOrganization(ctx, &pb.UpdateOrganizationRequest{
+		AccessToken:      accessToken,
+		OrgId:            orgID,
+		Name:             update.Name,
+		RetentionSeconds: update.RetentionSeconds,
+		QuotaBytes:       update.QuotaBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update organization: %v", err)
+	}
+	return organizationFromPB(r.Organization), nil
+}
+
+// DeleteOrganization deletes an organization (owners). Its buckets are released by the caller.
+func (c *Client) DeleteOrganization(ctx context.Context, accessToken string, orgID string) (bool, error) {
+	r, err := c.service.DeleteOrganization(ctx, &pb.DeleteOrganizationRequest{AccessToken: accessToken, OrgId: orgID})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete organization: %v", err)
+	}
+	return r.Success, nil
+}
+
+func (c *Client) ListOrganizationMembers(ctx context.Context, accessToken string, orgID string) ([]pkg.OrganizationMember, error) {
+	r, err := c.service.ListOrganizationMembers(ctx, &pb.ListOrganizationMembersRequest{AccessToken: accessToken, OrgId: orgID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %v", err)
+	}
+	out := make([]pkg.OrganizationMember, 0, len(r.Members))
+	for _, m := range r.Members {
+		out = append(out, organizationMemberFromPB(m))
+	}
+	return out, nil
+}
+
+// AddOrganizationMember adds the user with the given email, with a role up to the caller's own.
+func (c *Client) AddOrganizationMember(ctx context.Context, accessToken string, orgID string, email string, role string) (*pkg.OrganizationMember, error) {
+	r, err := c.service.AddOrganizationMember(ctx, &pb.AddOrganizationMemberRequest{AccessToken: accessToken, OrgId: orgID, Email: email, Role: role})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add organization member: %v", err)
+	}
+	member := organizationMemberFromPB(r.Member)
+	return &member, nil
+}
+
+func (c *Client) SetOrganizationMemberRole(ctx context.Context, accessToken string, orgID string, userID string, role string) (bool, error) {
+	r, err := c.service.SetOrganizationMemberRole(ctx, &pb.SetOrganizationMemberRoleRequest{AccessToken: accessToken, OrgId: orgID, UserId: userID, Role: role})
+	if err != nil {
+		return false, fmt.Errorf("failed to set organization member role: %v", err)
+	}
+	return r.Success, nil
+}
+
+// RemoveOrganizationMember removes a member, or the caller themselves when userID is their own.
+func (c *Client) RemoveOrganizationMember(ctx context.Context, accessToken string, orgID string, userID string) (bool, error) {
+	r, err := c.service.RemoveOrganizationMember(ctx, &pb.RemoveOrganizationMemberRequest{AccessToken: accessToken, OrgId: orgID, UserId: userID})
+	if err != nil {
+		return false, fmt.Errorf("failed to remove organization member: %v", err)
+	}
+	return r.Success, nil
+}
+
+// GetOrganizationMembership returns an organization with userID's role in it, empty if they are
+// not a member. It trusts the caller and is meant for other services.
+func (c *Client) GetOrganizationMembership(ctx context.Context, orgID string, userID string) (*pkg.Organization, error) {
+	r, err := c.service.GetOrganizationMembership(ctx, &pb.GetOrganizationMembershipRequest{OrgId: orgID, UserId: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization membership: %v", err)
+	}
+	return organizationFromPB(r.Organization), nil
+}
+
+func identityFromPB(i *pb.Identity) pkg.Identity {
+	return pkg.Identity{
+		Provider:      i.GetProvider(),
+		Email:         i.GetEmail(),
+		EmailVerified: i.GetEmailVerified(),
+		Primary:       i.GetPrimary(),
+		CreatedAt:     i.GetCreatedAt(),
+		LastLoginAt:   i.GetLastLoginAt(),
+	}
+}
+
+func organizationFromPB(o *pb.Organization) *pkg.Organization {
+	return &pkg.Organization{
+		ID:               o.GetId(),
+		Slug:             o.GetSlug(),
+		Name:             o.GetName(),
+		RetentionSeconds: o.GetRetentionSeconds(),
+		QuotaBytes:       o.GetQuotaBytes(),
+		Role:             o.GetRole(),
+		CreatedAt:        o.GetCreatedAt(),
+		UpdatedAt:        o.GetUpdatedAt(),
+	}
+}
+
+func organizationMemberFromPB(m *pb.OrganizationMember) pkg.OrganizationMember {
+	return pkg.OrganizationMember{
+		UserID:    m.GetUserId(),
+		Email:     m.GetEmail(),
+		Username:  m.GetUsername(),
+		AvatarUrl: m.GetAvatarUrl(),
+		Role:      m.GetRole(),
+		CreatedAt: m.GetCreatedAt(),
+	}
+}
+
+// WithClientInfo forwards the end user's IP and user agent to calls that start or refresh a
+// session (HandleOAuthCallback, VerifyMFA, RefreshToken, PollDeviceAuthorization) and to
+// administrative calls, whose audit events record the IP.
+func WithClientInfo(ctx context.Context, client pkg.ClientInfo) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		pkg.MetadataClientIP, client.IPAddress,
+		pkg.MetadataClientUserAgent, client.UserAgent,
+	)
+}
+
+func personalAccessTokenFromPB(t *pb.PersonalAccessToken) pkg.PersonalAccessToken {
+	return pkg.PersonalAccessToken{
+		ID:         t.GetId(),
+		Name:       t.GetName(),
+		Prefix:     t.GetPrefix(),
+		Scopes:     t.GetScopes(),
+		CreatedAt:  t.GetCreatedAt(),
+		ExpiresAt:  t.GetExpiresAt(),
+		LastUsedAt: t.GetLastUsedAt(),
+	}
+}
+
+func (c *Client) GetJWKS(ctx context.Context) (*pkg.JWKS, error) {
+	r, err := c.service.GetJWKS(ctx, &pb.GetJWKSRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get JWKS: %v", err)
+	}
+	jwks := &pkg.JWKS{Keys: make([]pkg.JWK, 0, len(r.Keys))}
+	for _, k := range r.Keys {
+		jwks.Keys = append(jwks.Keys, pkg.JWK{Kty: k.Kty, Kid: k.Kid, Use: k.Use, Alg: k.Alg, Crv: k.Crv, X: k.X, Y: k.GetY()})
+	}
+	return jwks, nil
+}
+
 func (c *Client) Logout(ctx context.Context, accessToken string) (bool, error) {
 	r, err := c.service.Logout(ctx, &pb.LogoutRequest{AccessToken: accessToken})
 	if err != nil {
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksMinRefresh limits refetches triggered by tokens with an unknown kid.
+const jwksMinRefresh = 30 * time.Second
+
+type verificationKey struct {
+	alg string
+	pub crypto.PublicKey
+}
+
+// TokenVerifier verifies access tokens locally against the auth service's JWKS, so callers
+// avoid a ValidateToken round trip per request. The keyset is cached for ttl and refetched
+// early when a token names a kid it has not seen (the auth service rotated keys).
+// Revocations (logout, log out everywhere, suspension, deletion) are mirrored by
+// SyncRevocations, so they take effect within one sync interval.
+type TokenVerifier struct {
+	client *Client
+	ttl    time.Duration
+
+	mu          sync.RWMutex
+	jwks        *pkg.JWKS
+	keys        map[string]verificationKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	refreshMu   sync.Mutex
+
+	revMu      sync.RWMutex
+	revoked    map[string]int64 // jti -> token exp
+	watermarks map[string]pkg.TokenWatermark
+	revCursor  int64
+}
+
+func NewTokenVerifier(client *Client, ttl time.Duration) *TokenVerifier {
+	return &TokenVerifier{
+		client:     client,
+		ttl:        ttl,
+		revoked:    make(map[string]int64),
+		watermarks: make(map[string]pkg.TokenWatermark),
+	}
+}
+
+// Verify checks the signature, algorithm and expiry of an access token and returns its claims.
+func (v *TokenVerifier) Verify(ctx context.Context, token string) (*pkg.Claims, error) {
+	tok, err := jwt.ParseWithClaims(token, &pkg.Claims{}, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := v.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != key.alg {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key.pub, nil
+	}, jwt.WithValidMethods(pkg.AccessTokenAlgorithms), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := tok.Claims.(*pkg.Claims)
+	if !ok || !tok.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return nil, fmt.Errorf("invalid token: missing jti or iat")
+	}
+	if v.isRevoked(claims) {
+		return nil, fmt.Errorf("token has been revoked")
+	}
+	return claims, nil
+}
+
+func (v *TokenVerifier) isRevoked(claims *pkg.Claims) bool {
+	v.revMu.RLock()
+	defer v.revMu.RUnlock()
+	if _, ok := v.revoked[claims.ID]; ok {
+		return true
+	}
+	w, ok := v.watermarks[claims.UserID]
+	return ok && claims.IssuedAtUnixMilli() <= w.ValidAfter
+}
+
+// SyncRevocations polls the auth service for revocations every interval until ctx is done.
+func (v *TokenVerifier) SyncRevocations(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := v.syncRevocations(ctx); err != nil {
+			slog.Warn("Revocation sync failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncRevocations merges revocations since the last cursor and drops entries that expired.
+func (v *TokenVerifier) syncRevocations(ctx context.Context) error {
+	v.revMu.RLock()
+	since := v.revCursor
+	v.revMu.RUnlock()
+
+	res, err := v.client.GetRevocations(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	v.revMu.Lock()
+	defer v.revMu.Unlock()
+	for _, t := range res.Tokens {
+		v.revoked[t.JTI] = t.ExpiresAt
+	}
+	for _, w := range res.Watermarks {
+		if cur, ok := v.watermarks[w.UserID]; !ok || w.ValidAfter > cur.ValidAfter {
+			v.watermarks[w.UserID] = w
+		}
+	}
+	for jti, exp := range v.revoked {
+		if exp < now {
+			delete(v.revoked, jti)
+		}
+	}
+	for userID, w := range v.watermarks {
+		if w.ExpiresAt < now {
+			delete(v.watermarks, userID)
+		}
+	}
+	v.revCursor = res.Cursor
+	return nil
+}
+
+// JWKS returns the cached keyset, refreshing it when older than ttl.
+func (v *TokenVerifier) JWKS(ctx context.Context) (*pkg.JWKS, error) {
+	if err := v.refreshIfStale(ctx); err != nil {
+		return nil, err
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.jwks, nil
+}
+
+func (v *TokenVerifier) key(ctx context.Context, kid string) (verificationKey, error) {
+	if err := v.refreshIfStale(ctx); err != nil {
+		return verificationKey{}, err
+	}
+	if k, ok := v.lookup(kid); ok {
+		return k, nil
+	}
+	if err := v.refresh(ctx, true); err != nil {
+		return verificationKey{}, err
+	}
+	if k, ok := v.lookup(kid); ok {
+		return k, nil
+	}
+	return verificationKey{}, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (v *TokenVerifier) lookup(kid string) (verificationKey, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	k, ok := v.keys[kid]
+	return k, ok
+}
+
+// refreshIfStale refetches an expired keyset. If the auth service is unreachable a stale
+// keyset keeps being used; only a verifier that never fetched one fails.
+func (v *TokenVerifier) refreshIfStale(ctx context.Context) error {
+	v.mu.RLock()
+	fresh := v.jwks != nil && time.Since(v.fetchedAt) < v.ttl
+	v.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	err := v.refresh(ctx, false)
+	if err != nil {
+		v.mu.RLock()
+		cached := v.jwks != nil
+		v.mu.RUnlock()
+		if cached {
+			slog.Warn("Using stale JWKS", "error", err)
+			return nil
+		}
+	}
+	return err
+}
+
+// refresh fetches the keyset. Concurrent callers wait for one fetch: a stale refresh is
+// skipped if another caller just refreshed, and a forced (unknown kid) refresh runs at most
+// once per jwksMinRefresh.
+func (v *TokenVerifier) refresh(ctx context.Context, force bool) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	v.mu.RLock()
+	skip := time.Since(v.lastAttempt) < jwksMinRefresh
+	if !force {
+		// while the auth service is unreachable, retry at most once per jwksMinRefresh
+		skip = v.jwks != nil && (time.Since(v.fetchedAt) < v.ttl || skip)
+	}
+	v.mu.RUnlock()
+	if skip {
+		return nil
+	}
+	v.mu.Lock()
+	v.lastAttempt = time.Now()
+	v.mu.Unlock()
+
+	jwks, err := v.client.GetJWKS(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]verificationKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		pub, err := k.PublicKey()
+		if err != nil {
+			slog.Warn("Skipping invalid JWK", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = verificationKey{alg: k.Alg, pub: pub}
+	}
+
+	v.mu.Lock()
+	v.jwks = jwks
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
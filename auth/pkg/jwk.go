@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JWK is the public half of an access token signing key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK builds the JWK for an ES256 (P-256) or EdDSA (Ed25519) public key. Kid is left empty.
+func NewJWK(pub crypto.PublicKey) (JWK, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return JWK{}, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+		}
+		return JWK{
+			Kty: "EC", Use: "sig", Alg: "ES256", Crv: "P-256",
+			X: base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, 32))),
+			Y: base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, 32))),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP", Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+			X: base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// PublicKey decodes the key for signature verification.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("jwk %s: invalid x: %w", k.Kid, err)
+	}
+	switch {
+	case k.Kty == "EC" && k.Crv == "P-256":
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: invalid y: %w", k.Kid, err)
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("jwk %s: point is not on P-256", k.Kid)
+		}
+		return pub, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwk %s: invalid Ed25519 key length", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("jwk %s: unsupported key type %s/%s", k.Kid, k.Kty, k.Crv)
+	}
+}
+
+// Thumbprint returns the RFC 7638 SHA-256 thumbprint, used as the kid.
+func (k JWK) Thumbprint() string {
+	// required members only, in lexicographic order
+	var canonical string
+	if k.Kty == "EC" {
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, k.Crv, k.Kty, k.X, k.Y)
+	} else {
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, k.Crv, k.Kty, k.X)
+	}
+	h := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
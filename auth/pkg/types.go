@@ -7,6 +7,11 @@ type AuthResponse struct {
 	AccessToken  string    `json:"access_token"`
 	RefreshToken string    `json:"refresh_token"`
 	User         *UserInfo `json:"user"`
+
+	// Set instead of the fields above when the user has MFA enabled: the sign-in is finished
+	// by VerifyMFA with this token and a code.
+	MFAToken     string `json:"mfa_token,omitempty"`
+	MFAExpiresIn int64  `json:"mfa_expires_in,omitempty"` // seconds
 }
 
 type UserInfo struct {
@@ -14,6 +19,36 @@ type UserInfo struct {
 	Email     string `json:"email"`
 	Username  string `json:"username"`
 	AvatarUrl string `json:"avatar_url"`
+	Role      string `json:"role"`
+}
+
+// Platform roles, in increasing order of privilege. Moderators can search and delete buckets
+// and suspend users, admins can also change roles and read the audit log.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var Roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
+// RoleRank orders roles by privilege: 0 for RoleUser (and an empty role, from tokens issued
+// before roles existed), -1 for unknown roles.
+func RoleRank(role string) int {
+	switch role {
+	case RoleUser, "":
+		return 0
+	case RoleModerator:
+		return 1
+	case RoleAdmin:
+		return 2
+	}
+	return -1
+}
+
+// HasRole reports whether role grants at least the privileges of min.
+func HasRole(role, min string) bool {
+	return RoleRank(role) >= RoleRank(min) && RoleRank(role) >= 0
 }
 
 type TokenPair struct {
@@ -21,9 +56,265 @@ type TokenPair struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// Revocations is the revocation state changed since a cursor (Unix seconds), mirrored by
+// verifiers that check access tokens locally.
+type Revocations struct {
+	Tokens     []RevokedToken
+	Watermarks []TokenWatermark
+	Cursor     int64
+}
+
+type RevokedToken struct {
+	JTI       string
+	ExpiresAt int64
+}
+
+// TokenWatermark invalidates a user's tokens issued at or before ValidAfter (Unix milliseconds).
+// ExpiresAt is in Unix seconds.
+type TokenWatermark struct {
+	UserID     string
+	ValidAfter int64
+	ExpiresAt  int64
+}
+
+// PersonalAccessTokenPrefix starts every personal access token, so they can be told apart
+// from JWT access tokens in an Authorization header.
+const PersonalAccessTokenPrefix = "cthp_"
+
+// Scopes a personal access token can be granted. Session (JWT) access tokens carry all of them.
+const (
+	ScopeFilesUpload  = "files:upload"  // upload files into new buckets
+	ScopeBucketsWrite = "buckets:write" // edit buckets the user administers
+)
+
+var PersonalAccessTokenScopes = []string{ScopeFilesUpload, ScopeBucketsWrite}
+
+// PersonalAccessToken describes a token without its secret. Times are Unix seconds, 0 if unset.
+type PersonalAccessToken struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  int64    `json:"created_at"`
+	ExpiresAt  int64    `json:"expires_at,omitempty"`
+	LastUsedAt int64    `json:"last_used_at,omitempty"`
+}
+
+// CreatedPersonalAccessToken is returned once on creation; Token is not stored in plaintext.
+type CreatedPersonalAccessToken struct {
+	Token string `json:"token"`
+	PersonalAccessToken
+}
+
+// DeviceAuthorization is the device authorization response (RFC 8628 section 3.2).
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"` // seconds
+	Interval                int64  `json:"interval"`   // seconds between polls
+}
+
+// PendingDeviceAuthorization is shown to the user before they approve a user code.
+type PendingDeviceAuthorization struct {
+	UserCode   string `json:"user_code"`
+	ClientName string `json:"client_name"`
+	CreatedAt  int64  `json:"created_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+}
+
+// Device access token error codes (RFC 8628 section 3.5, RFC 6749 section 5.2).
+const (
+	DeviceErrorAuthorizationPending = "authorization_pending"
+	DeviceErrorSlowDown             = "slow_down"
+	DeviceErrorAccessDenied         = "access_denied"
+	DeviceErrorExpiredToken         = "expired_token"
+	DeviceErrorInvalidGrant         = "invalid_grant"
+)
+
+// DeviceFlowError is returned while polling for a device access token; Code is one of the
+// DeviceError constants.
+type DeviceFlowError struct {
+	Code string
+}
+
+func (e *DeviceFlowError) Error() string { return e.Code }
+
+// Identity is an OAuth provider account linked to a user. Times are Unix seconds, 0 if unset.
+type Identity struct {
+	Provider      string `json:"provider"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Primary       bool   `json:"primary"` // the identity the account was created with, source of the profile
+	CreatedAt     int64  `json:"created_at"`
+	LastLoginAt   int64  `json:"last_login_at,omitempty"`
+}
+
+// ProviderEmail is the provider of identities proven by redeeming a magic link sent to the
+// address, which is also their provider user id.
+const ProviderEmail = "email"
+
+// gRPC metadata keys the gateway uses to forward details of the end user's client, recorded
+// on the sessions created or refreshed by the call.
+const (
+	MetadataClientIP        = "x-client-ip"
+	MetadataClientUserAgent = "x-client-user-agent"
+)
+
+// ClientInfo describes the client a session was started or refreshed from.
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// Session is a signed in device: one refresh token family. Times are Unix seconds.
+type Session struct {
+	ID              string `json:"id"`
+	UserAgent       string `json:"user_agent"`
+	IPAddress       string `json:"ip_address"`
+	CreatedAt       int64  `json:"created_at"`
+	LastRefreshedAt int64  `json:"last_refreshed_at"`
+	Current         bool   `json:"current"` // the session of the access token that listed it
+}
+
+// AccountDeletion is an entry of the account deletion feed. Services holding user data page
+// through it by Seq and clean up after the user. DeletedAt is Unix seconds.
+type AccountDeletion struct {
+	Seq       int64
+	UserID    string
+	DeletedAt int64
+}
+
+// AccessTokenAlgorithms are the JWS algorithms access tokens may be signed with.
+var AccessTokenAlgorithms = []string{"ES256", "EdDSA"}
+
 type Claims struct {
 	UserID   string `json:"user_id"`
 	Email    string `json:"email"`
 	Provider string `json:"provider"`
+	// SessionID is the session (refresh token family) the token was issued for.
+	SessionID string `json:"sid,omitempty"`
+	// Role is the user's platform role when the token was issued, omitted for RoleUser.
+	Role string `json:"role,omitempty"`
+	// IssuedAtMs is iat in Unix milliseconds, compared with token watermarks.
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
+
+// IssuedAtUnixMilli returns when the token was issued in Unix milliseconds. Tokens without
+// iat_ms count as issued at the start of their iat second.
+func (c *Claims) IssuedAtUnixMilli() int64 {
+	if c.IssuedAtMs != 0 {
+		return c.IssuedAtMs
+	}
+	if c.IssuedAt == nil {
+		return 0
+	}
+	return c.IssuedAt.Unix() * 1000
+}
+
+// MFAEnrollment is a TOTP secret waiting to be confirmed with a code from the authenticator app.
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`      // base32, for manual entry
+	OTPAuthURI string `json:"otpauth_uri"` // for a QR code
+}
+
+// MFAStatus tells whether a user has TOTP enabled.
+type MFAStatus struct {
+	Enabled                bool  `json:"enabled"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// AuditEvent is an entry of the moderation and administration audit log. ActorID is empty for
+// actions taken by operators from the auth service CLI. CreatedAt is Unix seconds.
+type AuditEvent struct {
+	ID         int64  `json:"id"`
+	ActorID    string `json:"actor_id"`
+	Action     string `json:"action"`
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Details    string `json:"details,omitempty"`
+	IPAddress  string `json:"ip_address,omitempty"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// Audit event actions.
+const (
+	AuditUserSuspend    = "user.suspend"
+	AuditUserUnsuspend  = "user.unsuspend"
+	AuditUserRole       = "user.role"
+	AuditBucketDelete   = "bucket.delete"
+	AuditBucketTakedown = "bucket.takedown"
+	AuditReportDismiss  = "report.dismiss"
+)
+
+// Audit event target types.
+const (
+	AuditTargetUser   = "user"
+	AuditTargetBucket = "bucket"
+	AuditTargetReport = "report"
+)
+
+// Abuse report outcomes a reporter is notified of.
+const (
+	AbuseReportReceived  = "received"
+	AbuseReportActioned  = "actioned"
+	AbuseReportDismissed = "dismissed"
+)
+
+// Organization roles, in increasing order of privilege. Admins manage members (below their own
+// role) and settings and administer every bucket of the organization; owners also manage
+// admins and owners and can delete the organization.
+const (
+	OrgRoleMember = "member"
+	OrgRoleAdmin  = "admin"
+	OrgRoleOwner  = "owner"
+)
+
+var OrgRoles = []string{OrgRoleMember, OrgRoleAdmin, OrgRoleOwner}
+
+// OrgRoleRank orders organization roles by privilege, -1 for unknown roles (and non-members).
+func OrgRoleRank(role string) int {
+	switch role {
+	case OrgRoleMember:
+		return 0
+	case OrgRoleAdmin:
+		return 1
+	case OrgRoleOwner:
+		return 2
+	}
+	return -1
+}
+
+// Organization is a team sharing ownership of buckets. Role is the requesting user's role, empty
+// if they are not a member. RetentionSeconds is the lifetime of its new buckets (0 for the
+// platform default) and QuotaBytes caps the total size of its files (0 for no limit). Times are
+// Unix seconds.
+type Organization struct {
+	ID               string `json:"id"`
+	Slug             string `json:"slug"`
+	Name             string `json:"name"`
+	RetentionSeconds int64  `json:"retention_seconds"`
+	QuotaBytes       int64  `json:"quota_bytes"`
+	Role             string `json:"role,omitempty"`
+	CreatedAt        int64  `json:"created_at"`
+	UpdatedAt        int64  `json:"updated_at"`
+}
+
+// OrganizationUpdate changes the set fields of an organization.
+type OrganizationUpdate struct {
+	Name             *string
+	RetentionSeconds *int64
+	QuotaBytes       *int64
+}
+
+// OrganizationMember is a user's membership. CreatedAt (Unix seconds) is when they joined.
+type OrganizationMember struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Username  string `json:"username,omitempty"`
+	AvatarUrl string `json:"avatar_url,omitempty"`
+	Role      string `json:"role"`
+	CreatedAt int64  `json:"created_at"`
+}
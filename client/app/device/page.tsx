@@ -0,0 +1,188 @@
+'use client';
+
+import { useEffect, useState, Suspense } from 'react';
+import { useRouter, useSearchParams } from 'next/navigation';
+import {
+  isAuthenticated,
+  getDeviceAuthorization,
+  approveDeviceAuthorization,
+  type PendingDeviceAuthorization,
+} from '@/lib/api';
+
+type Step = 'enter' | 'confirm' | 'approved' | 'denied';
+
+function DeviceContent() {
+  const router = useRouter();
+  const searchParams = useSearchParams();
+  const [authenticated, setAuthenticated] = useState<boolean | null>(null);
+  const [userCode, setUserCode] = useState(searchParams.get('user_code') ?? '');
+  const [pending, setPending] = useState<PendingDeviceAuthorization | null>(null);
+  const [step, setStep] = useState<Step>('enter');
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    setAuthenticated(isAuthenticated());
+  }, []);
+
+  const handleSignIn = () => {
+    const code = userCode.trim();
+    localStorage.setItem(
+      'oauth_return_url',
+      code ? `/device?user_code=${encodeURIComponent(code)}` : '/device'
+    );
+    router.push('/signin');
+  };
+
+  const handleContinue = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setLoading(true);
+    setError(null);
+    try {
+      const result = await getDeviceAuthorization(userCode.trim());
+      setPending(result);
+      setStep('confirm');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Invalid or expired code');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleDecision = async (approve: boolean) => {
+    if (!pending) return;
+    setLoading(true);
+    setError(null);
+    try {
+      await approveDeviceAuthorization(pending.user_code, approve);
+      setStep(approve ? 'approved' : 'denied');
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Invalid or expired code');
+      setStep('enter');
+      setPending(null);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-zinc-50 font-sans dark:bg-black">
+      <main className="flex min-h-screen w-full max-w-2xl flex-col items-center justify-center py-16 px-8">
+        <div className="w-full max-w-md space-y-8 rounded-lg border border-zinc-200 bg-white p-8 dark:border-zinc-800 dark:bg-zinc-900">
+          <div className="text-center">
+            <h1 className="text-3xl font-semibold text-black dark:text-zinc-50">
+              Connect a device
+            </h1>
+            <p className="mt-2 text-sm text-zinc-600 dark:text-zinc-400">
+              Enter the code shown on your device to sign it in to your account
+            </p>
+          </div>
+
+          {error && (
+            <div className="rounded-md bg-red-50 border border-red-200 p-4 dark:bg-red-900/20 dark:border-red-800">
+              <p className="text-sm text-red-800 dark:text-red-200">{error}</p>
+            </div>
+          )}
+
+          {authenticated === false && (
+            <div className="space-y-4">
+              <p className="text-sm text-zinc-600 dark:text-zinc-400 text-center">
+                You need to sign in before you can approve a device.
+              </p>
+              <button
+                onClick={handleSignIn}
+                className="w-full rounded-md bg-black px-4 py-2 text-sm font-medium text-white transition-colors hover:bg-zinc-800 dark:bg-zinc-50 dark:text-black dark:hover:bg-zinc-200"
+              >
+                Sign in
+              </button>
+            </div>
+          )}
+
+          {authenticated && step === 'enter' && (
+            <form onSubmit={handleContinue} className="space-y-4">
+              <input
+                type="text"
+                value={userCode}
+                onChange={(e) => setUserCode(e.target.value)}
+                placeholder="XXXX-XXXX"
+                autoComplete="off"
+                autoFocus
+                className="w-full rounded-md border border-zinc-300 bg-white px-4 py-3 text-center font-mono text-2xl tracking-widest uppercase text-black dark:border-zinc-700 dark:bg-zinc-800 dark:text-zinc-50"
+              />
+              <button
+                type="submit"
+                disabled={loading || !userCode.trim()}
+                className="w-full rounded-md bg-black px-4 py-2 text-sm font-medium text-white transition-colors hover:bg-zinc-800 disabled:opacity-50 disabled:cursor-not-allowed dark:bg-zinc-50 dark:text-black dark:hover:bg-zinc-200"
+              >
+                {loading ? 'Checking...' : 'Continue'}
+              </button>
+            </form>
+          )}
+
+          {authenticated && step === 'confirm' && pending && (
+            <div className="space-y-6">
+              <div className="rounded-md bg-zinc-100 dark:bg-zinc-800 p-4 space-y-2 text-sm">
+                <div className="flex justify-between">
+                  <span className="text-zinc-600 dark:text-zinc-400">Device:</span>
+                  <span className="font-medium text-black dark:text-zinc-50">{pending.client_name}</span>
+                </div>
+                <div className="flex justify-between">
+                  <span className="text-zinc-600 dark:text-zinc-400">Code:</span>
+                  <span className="font-mono text-black dark:text-zinc-50">{pending.user_code}</span>
+                </div>
+              </div>
+              <p className="text-sm text-zinc-600 dark:text-zinc-400">
+                Only approve if you started this sign-in yourself. The device will get full access
+                to your account.
+              </p>
+              <div className="flex gap-3">
+                <button
+                  onClick={() => handleDecision(false)}
+                  disabled={loading}
+                  className="flex-1 rounded-md border border-zinc-300 px-4 py-2 text-sm font-medium text-black transition-colors hover:bg-zinc-100 disabled:opacity-50 dark:border-zinc-700 dark:text-zinc-50 dark:hover:bg-zinc-800"
+                >
+                  Deny
+                </button>
+                <button
+                  onClick={() => handleDecision(true)}
+                  disabled={loading}
+                  className="flex-1 rounded-md bg-black px-4 py-2 text-sm font-medium text-white transition-colors hover:bg-zinc-800 disabled:opacity-50 dark:bg-zinc-50 dark:text-black dark:hover:bg-zinc-200"
+                >
+                  Approve
+                </button>
+              </div>
+            </div>
+          )}
+
+          {step === 'approved' && (
+            <div className="rounded-md bg-green-50 border border-green-200 p-4 dark:bg-green-900/20 dark:border-green-800">
+              <p className="text-sm font-medium text-green-800 dark:text-green-200">
+                Device approved. You can return to your device.
+              </p>
+            </div>
+          )}
+
+          {step === 'denied' && (
+            <div className="rounded-md bg-zinc-100 border border-zinc-200 p-4 dark:bg-zinc-800 dark:border-zinc-700">
+              <p className="text-sm font-medium text-zinc-800 dark:text-zinc-200">
+                Request denied. The device was not signed in.
+              </p>
+            </div>
+          )}
+        </div>
+      </main>
+    </div>
+  );
+}
+
+export default function DevicePage() {
+  return (
+    <Suspense fallback={
+      <div className="flex min-h-screen items-center justify-center bg-zinc-50 font-sans dark:bg-black">
+        <p className="text-zinc-600 dark:text-zinc-400">Loading...</p>
+      </div>
+    }>
+      <DeviceContent />
+    </Suspense>
+  );
+}
@@ -1,14 +1,53 @@
 'use client';
 
-import { useEffect, useState, Suspense } from 'react';
+import { useEffect, useState, Suspense, type FormEvent } from 'react';
 import { useSearchParams, useRouter } from 'next/navigation';
-import { handleCallback } from '@/lib/api';
+import {
+  handleCallback,
+  verifyMFA,
+  takePendingLinkProvider,
+  completeLinkIdentity,
+  type AuthResponse,
+  type MFAChallenge,
+  type LinkedIdentity,
+} from '@/lib/api';
 
 function CallbackContent() {
   const router = useRouter();
   const searchParams = useSearchParams();
-  const [status, setStatus] = useState<'loading' | 'success' | 'error'>('loading');
+  const [status, setStatus] = useState<'loading' | 'mfa' | 'success' | 'error'>('loading');
   const [error, setError] = useState<string | null>(null);
+  const [mfaToken, setMfaToken] = useState<string | null>(null);
+  const [mfaCode, setMfaCode] = useState('');
+  const [mfaError, setMfaError] = useState<string | null>(null);
+  const [verifying, setVerifying] = useState(false);
+
+  const finishSignIn = () => {
+    setStatus('success');
+    // Get the return URL from localStorage, default to home page
+    const returnUrl = localStorage.getItem('oauth_return_url') || '/';
+    localStorage.removeItem('oauth_return_url');
+    // Redirect to the original route after a short delay
+    setTimeout(() => {
+      router.push(returnUrl);
+    }, 1500);
+  };
+
+  const submitMFACode = async (e: FormEvent) => {
+    e.preventDefault();
+    if (!mfaToken) return;
+    setVerifying(true);
+    setMfaError(null);
+    try {
+      await verifyMFA(mfaToken, mfaCode);
+      finishSignIn();
+    } catch (err) {
+      setMfaError(err instanceof Error ? err.message : 'Invalid code');
+      setMfaCode('');
+    } finally {
+      setVerifying(false);
+    }
+  };
 
   useEffect(() => {
     const code = searchParams.get('code');
@@ -21,22 +60,27 @@ function CallbackContent() {
       return;
     }
 
-    // Handle OAuth callback
-    handleCallback(code, state, provider)
-      .then(() => {
-        setStatus('success');
-        // Get the return URL from localStorage, default to home page
-        const returnUrl = localStorage.getItem('oauth_return_url') || '/';
-        localStorage.removeItem('oauth_return_url');
-        // Redirect to the original route after a short delay
-        setTimeout(() => {
-          router.push(returnUrl);
-        }, 1500);
+    // Finish linking another provider to the signed in user, or sign in
+    const linkProvider = takePendingLinkProvider();
+    const finish: Promise<AuthResponse | MFAChallenge | LinkedIdentity> = linkProvider
+      ? completeLinkIdentity(linkProvider, code, state)
+      : handleCallback(code, state, provider);
+
+    finish
+      .then((result) => {
+        // Accounts with two-factor authentication need a code before tokens are issued
+        if ('mfa_required' in result) {
+          setMfaToken(result.mfa_token);
+          setStatus('mfa');
+          return;
+        }
+        finishSignIn();
       })
       .catch((err) => {
         setError(err instanceof Error ? err.message : 'Authentication failed');
         setStatus('error');
       });
+    // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [searchParams, router]);
 
   return (
@@ -76,7 +120,38 @@ function CallbackContent() {
             </div>
           )}
 
-          {status === 'success' && (
+          {status === 'mfa' && (
+            <form onSubmit={submitMFACode} className="text-center">
+              <h1 className="text-xl font-semibold text-black dark:text-zinc-50">
+                Two-factor authentication
+              </h1>
+              <p className="mt-2 text-sm text-zinc-600 dark:text-zinc-400">
+                Enter the code from your authenticator app, or one of your recovery codes.
+              </p>
+              <input
+                type="text"
+                inputMode="text"
+                autoComplete="one-time-code"
+                autoFocus
+                value={mfaCode}
+                onChange={(e) => setMfaCode(e.target.value)}
+                className="mt-6 w-full rounded-md border border-zinc-300 bg-white px-3 py-2 text-center font-mono text-lg tracking-widest text-black dark:border-zinc-700 dark:bg-zinc-950 dark:text-zinc-50"
+                placeholder="123456"
+              />
+              {mfaError && (
+                <p className="mt-2 text-sm text-red-600 dark:text-red-400">{mfaError}</p>
+              )}
+              <button
+                type="submit"
+                disabled={verifying || mfaCode.trim() === ''}
+                className="mt-6 w-full rounded-md bg-zinc-900 px-4 py-2 text-sm font-medium text-white transition-colors hover:bg-zinc-800 disabled:opacity-50 dark:bg-zinc-50 dark:text-zinc-900 dark:hover:bg-zinc-100"
+              >
+                {verifying ? 'Verifying...' : 'Verify'}
+              </button>
+            </form>
+          )}
+
+                    {status === 'success' && (
             <div className="text-center">
               <div className="mb-4 flex justify-center">
                 <svg
@@ -0,0 +1,182 @@
+'use client';
+
+import { useEffect, useRef, useState, Suspense, type FormEvent } from 'react';
+import { useSearchParams, useRouter } from 'next/navigation';
+import { redeemMagicLink, verifyMFA } from '@/lib/api';
+
+const spinner = (
+  <svg
+    className="animate-spin h-8 w-8 text-zinc-900 dark:text-zinc-50"
+    xmlns="http://www.w3.org/2000/svg"
+    fill="none"
+    viewBox="0 0 24 24"
+  >
+    <circle
+      className="opacity-25"
+      cx="12"
+      cy="12"
+      r="10"
+      stroke="currentColor"
+      strokeWidth="4"
+    />
+    <path
+      className="opacity-75"
+      fill="currentColor"
+      d="M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z"
+    />
+  </svg>
+);
+
+// Landing page of the emailed sign-in link (?token=), which works once.
+function EmailSignInContent() {
+  const router = useRouter();
+  const searchParams = useSearchParams();
+  const redeemed = useRef(false);
+  const [status, setStatus] = useState<'loading' | 'mfa' | 'success' | 'error'>('loading');
+  const [error, setError] = useState<string | null>(null);
+  const [mfaToken, setMfaToken] = useState<string | null>(null);
+  const [mfaCode, setMfaCode] = useState('');
+  const [mfaError, setMfaError] = useState<string | null>(null);
+  const [verifying, setVerifying] = useState(false);
+
+  const finishSignIn = () => {
+    setStatus('success');
+    const returnUrl = localStorage.getItem('oauth_return_url') || '/';
+    localStorage.removeItem('oauth_return_url');
+    setTimeout(() => {
+      router.push(returnUrl);
+    }, 1500);
+  };
+
+  const submitMFACode = async (e: FormEvent) => {
+    e.preventDefault();
+    if (!mfaToken) return;
+    setVerifying(true);
+    setMfaError(null);
+    try {
+      await verifyMFA(mfaToken, mfaCode);
+      finishSignIn();
+    } catch (err) {
+      setMfaError(err instanceof Error ? err.message : 'Invalid code');
+      setMfaCode('');
+    } finally {
+      setVerifying(false);
+    }
+  };
+
+  useEffect(() => {
+    // The token is single-use, so it must not be redeemed twice when the effect re-runs
+    if (redeemed.current) return;
+    redeemed.current = true;
+
+    const token = searchParams.get('token');
+    if (!token) {
+      setError('Missing token parameter');
+      setStatus('error');
+      return;
+    }
+
+    redeemMagicLink(token)
+      .then((result) => {
+        if ('mfa_required' in result) {
+          setMfaToken(result.mfa_token);
+          setStatus('mfa');
+          return;
+        }
+        finishSignIn();
+      })
+      .catch((err) => {
+        setError(err instanceof Error ? err.message : 'Sign-in failed');
+        setStatus('error');
+      });
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [searchParams, router]);
+
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-zinc-50 font-sans dark:bg-black">
+      <main className="flex min-h-screen w-full max-w-md flex-col items-center justify-center py-16 px-8">
+        <div className="w-full space-y-6 rounded-lg border border-zinc-200 bg-white p-8 dark:border-zinc-800 dark:bg-zinc-900">
+          {status === 'loading' && (
+            <div className="text-center">
+              <div className="mb-4 flex justify-center">{spinner}</div>
+              <h1 className="text-xl font-semibold text-black dark:text-zinc-50">
+                Signing you in...
+              </h1>
+            </div>
+          )}
+
+          {status === 'mfa' && (
+            <form onSubmit={submitMFACode} className="text-center">
+              <h1 className="text-xl font-semibold text-black dark:text-zinc-50">
+                Two-factor authentication
+              </h1>
+              <p className="mt-2 text-sm text-zinc-600 dark:text-zinc-400">
+                Enter the code from your authenticator app, or one of your recovery codes.
+              </p>
+              <input
+                type="text"
+                inputMode="text"
+                autoComplete="one-time-code"
+                autoFocus
+                value={mfaCode}
+                onChange={(e) => setMfaCode(e.target.value)}
+                className="mt-6 w-full rounded-md border border-zinc-300 bg-white px-3 py-2 text-center font-mono text-lg tracking-widest text-black dark:border-zinc-700 dark:bg-zinc-950 dark:text-zinc-50"
+                placeholder="123456"
+              />
+              {mfaError && (
+                <p className="mt-2 text-sm text-red-600 dark:text-red-400">{mfaError}</p>
+              )}
+              <button
+                type="submit"
+                disabled={verifying || mfaCode.trim() === ''}
+                className="mt-6 w-full rounded-md bg-zinc-900 px-4 py-2 text-sm font-medium text-white transition-colors hover:bg-zinc-800 disabled:opacity-50 dark:bg-zinc-50 dark:text-zinc-900 dark:hover:bg-zinc-100"
+              >
+                {verifying ? 'Verifying...' : 'Verify'}
+              </button>
+            </form>
+          )}
+
+          {status === 'success' && (
+            <div className="text-center">
+              <h1 className="text-xl font-semibold text-black dark:text-zinc-50">
+                Signed in!
+              </h1>
+              <p className="mt-2 text-sm text-zinc-600 dark:text-zinc-400">
+                Redirecting you back...
+              </p>
+            </div>
+          )}
+
+          {status === 'error' && (
+            <div className="text-center">
+              <h1 className="text-xl font-semibold text-black dark:text-zinc-50">
+                Sign-in failed
+              </h1>
+              <p className="mt-2 text-sm text-red-600 dark:text-red-400">
+                {error || 'This sign-in link is invalid or has expired'}
+              </p>
+              <button
+                onClick={() => router.push('/signin')}
+                className="mt-6 rounded-md bg-zinc-900 px-4 py-2 text-sm font-medium text-white transition-colors hover:bg-zinc-800 dark:bg-zinc-50 dark:text-zinc-900 dark:hover:bg-zinc-100"
+              >
+                Request a new link
+              </button>
+            </div>
+          )}
+        </div>
+      </main>
+    </div>
+  );
+}
+
+export default function EmailSignInPage() {
+  return (
+    <Suspense fallback={
+      <div className="flex min-h-screen items-center justify-center bg-zinc-50 font-sans dark:bg-black">
+        {spinner}
+      </div>
+    }>
+      <EmailSignInContent />
+    </Suspense>
+  );
+}
@@ -1,14 +1,18 @@
 'use client';
 
-import { useEffect, useState, Suspense } from 'react';
+import { useEffect, useState, Suspense, type FormEvent } from 'react';
 import { useRouter } from 'next/navigation';
-import { initiateOAuth, tokenStorage, validateToken, logout, type AuthResponse, type Claims } from '@/lib/api';
+import { initiateOAuth, requestMagicLink, tokenStorage, validateToken, logout, deleteAccount, type AuthResponse, type Claims } from '@/lib/api';
+import LinkedAccounts from '@/components/LinkedAccounts';
 
 function SignInContent() {
   const router = useRouter();
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
   const [user, setUser] = useState<Claims | null>(null);
+  const [email, setEmail] = useState('');
+  const [sendingLink, setSendingLink] = useState(false);
+  const [linkSentTo, setLinkSentTo] = useState<string | null>(null);
   const [tokens, setTokens] = useState<{ access: string | null; refresh: string | null }>({
     access: null,
     refresh: null,
@@ -45,11 +49,27 @@ function SignInContent() {
     initiateOAuth('github');
   };
 
+  const handleEmailSignIn = async (e: FormEvent) => {
+    e.preventDefault();
+    setSendingLink(true);
+    setError(null);
+    if (!localStorage.getItem('oauth_return_url')) {
+      localStorage.setItem('oauth_return_url', '/');
+    }
+    try {
+      await requestMagicLink(email.trim());
+      setLinkSentTo(email.trim());
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to send sign-in link');
+    } finally {
+      setSendingLink(false);
+    }
+  };
+
   const handleLogout = async () => {
-    const refreshToken = tokenStorage.getRefreshToken();
-    if (refreshToken) {
+    if (tokenStorage.getAccessToken()) {
       try {
-        await logout(refreshToken);
+        await logout();
       } catch (err) {
         console.error('Logout error:', err);
       }
@@ -59,6 +79,21 @@ function SignInContent() {
     router.push('/signin');
   };
 
+  const handleDeleteAccount = async () => {
+    if (!window.confirm('Delete your account? You will be signed out everywhere and buckets only you manage will no longer be yours.')) {
+      return;
+    }
+    try {
+      await deleteAccount();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Account deletion failed');
+      return;
+    }
+    setUser(null);
+    setTokens({ access: null, refresh: null });
+    router.push('/signin');
+  };
+
   return (
     <div className="flex min-h-screen items-center justify-center bg-zinc-50 font-sans dark:bg-black">
       <main className="flex min-h-screen w-full max-w-2xl flex-col items-center justify-center py-16 px-8">
@@ -68,7 +103,7 @@ function SignInContent() {
               Sign In
             </h1>
             <p className="mt-2 text-sm text-zinc-600 dark:text-zinc-400">
-              Test OAuth authentication with GitHub
+              Sign in with GitHub or a link sent to your email
             </p>
           </div>
 
@@ -129,12 +164,21 @@ function SignInContent() {
                   </div>
                 </div>
 
+                <LinkedAccounts />
+
                 <button
                   onClick={handleLogout}
                   className="w-full rounded-md bg-red-600 px-4 py-2 text-sm font-medium text-white transition-colors hover:bg-red-700"
                 >
                   Logout
                 </button>
+
+                <button
+                  onClick={handleDeleteAccount}
+                  className="w-full rounded-md border border-red-600 px-4 py-2 text-sm font-medium text-red-600 transition-colors hover:bg-red-50 dark:hover:bg-red-950"
+                >
+                  Delete account
+                </button>
               </div>
             </div>
           ) : (
@@ -181,6 +225,39 @@ function SignInContent() {
                   </>
                 )}
               </button>
+
+              <div className="flex items-center gap-3 text-xs text-zinc-500 dark:text-zinc-400">
+                <div className="h-px flex-1 bg-zinc-200 dark:bg-zinc-800" />
+                or
+                <div className="h-px flex-1 bg-zinc-200 dark:bg-zinc-800" />
+              </div>
+
+              {linkSentTo ? (
+                <div className="rounded-md bg-green-50 border border-green-200 p-4 dark:bg-green-900/20 dark:border-green-800">
+                  <p className="text-sm text-green-800 dark:text-green-200">
+                    Check {linkSentTo} for a sign-in link. It expires in 15 minutes.
+                  </p>
+                </div>
+              ) : (
+                <form onSubmit={handleEmailSignIn} className="space-y-3">
+                  <input
+                    type="email"
+                    required
+                    autoComplete="email"
+                    value={email}
+                    onChange={(e) => setEmail(e.target.value)}
+                    className="w-full rounded-md border border-zinc-300 bg-white px-3 py-2 text-sm text-black dark:border-zinc-700 dark:bg-zinc-950 dark:text-zinc-50"
+                    placeholder="you@example.com"
+                  />
+                  <button
+                    type="submit"
+                    disabled={sendingLink || email.trim() === ''}
+                    className="w-full rounded-md border border-zinc-300 px-4 py-2 text-sm font-medium text-black transition-colors hover:bg-zinc-100 disabled:opacity-50 disabled:cursor-not-allowed dark:border-zinc-700 dark:text-zinc-50 dark:hover:bg-zinc-800"
+                  >
+                    {sendingLink ? 'Sending...' : 'Email me a sign-in link'}
+                  </button>
+                </form>
+              )}
             </div>
           )}
         </div>
@@ -128,6 +128,18 @@ export default function FileContainer({ bucketId }: FileContainerProps) {
 
         {!loading && !error && files && (
           <>
+            {(files.title || files.description) && (
+              <div className="mb-4">
+                {files.title && (
+                  <h4 className="text-xl font-semibold mb-1" style={{ color: PURPLE_THEME }}>
+                    {files.title}
+                  </h4>
+                )}
+                {files.description && (
+                  <p className="text-sm text-gray-300 whitespace-pre-wrap break-words">{files.description}</p>
+                )}
+              </div>
+            )}
             {files.files && files.files.length > 0 ? (
               <div className="space-y-3">
                 {files.files.map((file, index) => (
@@ -137,6 +149,7 @@ export default function FileContainer({ bucketId }: FileContainerProps) {
                     originalName={file.original_name}
                     size={file.size}
                     bucketId={displayBucketId || ''}
+                    note={file.note}
                   />
                 ))}
               </div>
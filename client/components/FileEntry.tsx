@@ -9,9 +9,10 @@ interface FileEntryProps {
   originalName: string;
   size: number;
   bucketId: string;
+  note?: string | null;
 }
 
-export default function FileEntry({ fileName, originalName, size, bucketId }: FileEntryProps) {
+export default function FileEntry({ fileName, originalName, size, bucketId, note }: FileEntryProps) {
   const { isAdmin } = useAuth({ bucketId });
   const PURPLE_THEME = '#6A4A98';
   const PURPLE_LIGHT = '#8B6FB8';
@@ -65,6 +66,7 @@ export default function FileEntry({ fileName, originalName, size, bucketId }: Fi
             {originalName}
           </h3>
           <p className="text-sm text-gray-400">{formatFileSize(size)}</p>
+          {note && <p className="text-sm text-gray-300 whitespace-pre-wrap break-words">{note}</p>}
         </div>
       </div>
       <div className="flex gap-2 ml-4">
@@ -0,0 +1,99 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import {
+  listIdentities,
+  startLinkIdentity,
+  unlinkIdentity,
+  type LinkedIdentity,
+} from '@/lib/api';
+
+// Providers offered for linking; ones the auth service has not configured report an error.
+const PROVIDERS = ['github', 'google', 'gitlab'];
+
+export default function LinkedAccounts() {
+  const [identities, setIdentities] = useState<LinkedIdentity[]>([]);
+  const [busy, setBusy] = useState<string | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  const load = () => {
+    listIdentities()
+      .then(setIdentities)
+      .catch((err) => setError(err instanceof Error ? err.message : 'Failed to load linked accounts'));
+  };
+
+  useEffect(load, []);
+
+  const handleLink = async (provider: string) => {
+    setBusy(provider);
+    setError(null);
+    // Come back here once the provider redirects to the callback page
+    localStorage.setItem('oauth_return_url', '/signin');
+    try {
+      await startLinkIdentity(provider);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to link account');
+      setBusy(null);
+    }
+  };
+
+  const handleUnlink = async (provider: string) => {
+    setBusy(provider);
+    setError(null);
+    try {
+      await unlinkIdentity(provider);
+      load();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to unlink account');
+    } finally {
+      setBusy(null);
+    }
+  };
+
+  const linked = new Set(identities.map((identity) => identity.provider));
+
+  return (
+    <div>
+      <h2 className="text-lg font-semibold text-black dark:text-zinc-50 mb-3">
+        Linked Accounts
+      </h2>
+      {error && (
+        <p className="mb-2 text-sm text-red-800 dark:text-red-200">{error}</p>
+      )}
+      <div className="space-y-2 text-sm">
+        {identities.map((identity) => (
+          <div key={identity.provider} className="flex items-center justify-between">
+            <span className="text-zinc-600 dark:text-zinc-400">
+              {identity.provider}
+              {identity.primary && ' (primary)'}
+            </span>
+            <span className="flex items-center gap-3">
+              <span className="font-mono text-black dark:text-zinc-50">{identity.email}</span>
+              {identities.length > 1 && (
+                <button
+                  onClick={() => handleUnlink(identity.provider)}
+                  disabled={busy !== null}
+                  className="text-xs text-red-600 hover:underline disabled:opacity-50"
+                >
+                  Unlink
+                </button>
+              )}
+            </span>
+          </div>
+        ))}
+      </div>
+      <div className="mt-3 flex flex-wrap gap-2">
+        {PROVIDERS.filter((provider) => !linked.has(provider)).map((provider) => (
+          <button
+            key={provider}
+            onClick={() => handleLink(provider)}
+            disabled={busy !== null}
+            className="rounded-md border border-zinc-300 px-3 py-1 text-xs font-medium text-black transition-colors hover:bg-zinc-100 disabled:opacity-50 dark:border-zinc-700 dark:text-zinc-50 dark:hover:bg-zinc-800"
+          >
+            Link {provider}
+          </button>
+        ))}
+      </div>
+    </div>
+  );
+}
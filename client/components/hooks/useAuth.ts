@@ -61,10 +61,9 @@ export function useAuth(options: UseAuthOptions = {}): UseAuthReturn {
   }, [bucketId]);
 
   const logout = useCallback(async () => {
-    const refreshToken = tokenStorage.getRefreshToken();
-    if (refreshToken) {
+    if (tokenStorage.getAccessToken()) {
       try {
-        await apiLogout(refreshToken);
+        await apiLogout();
       } catch (error) {
         // Even if API call fails, clear tokens locally
         console.error('Logout error:', error);
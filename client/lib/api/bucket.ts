@@ -1,6 +1,6 @@
 import { API_URL } from '@/lib/config';
 import { bucketTokenStorage } from './bucketAuth';
-import { getCurrentUserId } from './userAuth';
+import { getCurrentUserId, tokenStorage, ensureValidToken } from './userAuth';
 import type { BucketMetadata, BucketAdminsResponse } from './types';
 
 export type { BucketMetadata, BucketAdminsResponse };
@@ -107,6 +107,53 @@ export const fetchBucketAdmins = async (bucketId: string): Promise<BucketAdminsR
   return response.json();
 };
 
+export type ReportReason =
+  | 'malware'
+  | 'phishing'
+  | 'illegal'
+  | 'copyright'
+  | 'harassment'
+  | 'spam'
+  | 'other';
+
+/**
+ * Reports a bucket to the moderators. Signed-in users are contacted at their account's address;
+ * anonymous reporters may leave an email to hear back.
+ */
+export const reportBucket = async (
+  bucketId: string,
+  reason: ReportReason,
+  details?: string,
+  email?: string
+): Promise<{ report_id: number }> => {
+  const headers: HeadersInit = {
+    'Content-Type': 'application/json',
+  };
+
+  const accessToken = tokenStorage.getAccessToken();
+  if (accessToken) {
+    try {
+      const validToken = await ensureValidToken();
+      (headers as Record<string, string>)['Authorization'] = `Bearer ${validToken}`;
+    } catch {
+      // Report anonymously if validation fails
+    }
+  }
+
+  const response = await fetch(`${API_URL}/files/s/${bucketId}/report`, {
+    method: 'POST',
+    headers,
+    body: JSON.stringify({ reason, details, email }),
+  });
+
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Report failed' }));
+    throw new Error(error.error || 'Report failed');
+  }
+
+  return response.json();
+};
+
 export const isBucketAdmin = async (bucketId: string): Promise<boolean> => {
   try {
     const userId = await getCurrentUserId();
@@ -20,7 +20,7 @@ export const bucketTokenStorage = {
 
 export const checkBucketProtected = async (
   bucketId: string
-): Promise<{ protected: boolean; bucket_id: string }> => {
+): Promise<{ protected: boolean; access_denied: boolean; bucket_id: string }> => {
   const response = await fetch(`${API_URL}/files/s/${bucketId}/protected`);
 
   if (!response.ok) {
@@ -0,0 +1,56 @@
+import { API_URL } from '@/lib/config';
+import { ensureValidToken } from './userAuth';
+
+export interface PendingDeviceAuthorization {
+  user_code: string;
+  client_name: string;
+  created_at: number; // Unix seconds
+  expires_at: number; // Unix seconds
+}
+
+/**
+ * Looks up a pending device sign-in (RFC 8628 device flow) by the code shown on the device.
+ */
+export const getDeviceAuthorization = async (
+  userCode: string
+): Promise<PendingDeviceAuthorization> => {
+  const accessToken = await ensureValidToken();
+  const response = await fetch(
+    `${API_URL}/auth/device/verify?user_code=${encodeURIComponent(userCode)}`,
+    {
+      headers: {
+        Authorization: `Bearer ${accessToken}`,
+      },
+    }
+  );
+
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Invalid or expired code' }));
+    throw new Error(error.error || 'Invalid or expired code');
+  }
+
+  return response.json();
+};
+
+/**
+ * Approves (signs the device in as the current user) or denies a pending device sign-in.
+ */
+export const approveDeviceAuthorization = async (
+  userCode: string,
+  approve: boolean
+): Promise<void> => {
+  const accessToken = await ensureValidToken();
+  const response = await fetch(`${API_URL}/auth/device/approve`, {
+    method: 'POST',
+    headers: {
+      Authorization: `Bearer ${accessToken}`,
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ user_code: userCode, approve }),
+  });
+
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Invalid or expired code' }));
+    throw new Error(error.error || 'Invalid or expired code');
+  }
+};
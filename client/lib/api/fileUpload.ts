@@ -8,6 +8,8 @@ export interface PrepareUploadFileMeta {
   original_name: string;
   size: number;
   content_type: string;
+  // Hex-encoded SHA-256 of the content; the presigned PUT URL only accepts that body
+  sha256: string;
 }
 
 export interface PrepareUploadRequest {
@@ -47,6 +49,57 @@ export interface ConfirmUploadResponse {
   error?: string;
 }
 
+export interface UploadChallenge {
+  type: 'none' | 'pow' | 'hcaptcha' | 'turnstile' | 'fake';
+  token?: string;
+  difficulty?: number;
+  expires_at?: string;
+  site_key?: string;
+}
+
+function leadingZeroBits(hash: Uint8Array): number {
+  let bits = 0;
+  for (const byte of hash) {
+    if (byte !== 0) return bits + Math.clz32(byte) - 24;
+    bits += 8;
+  }
+  return bits;
+}
+
+// Finds a suffix such that SHA-256("token:suffix") starts with `difficulty` zero bits.
+async function solveProofOfWork(token: string, difficulty: number): Promise<string> {
+  const encoder = new TextEncoder();
+  for (let i = 0; ; i++) {
+    const response = `${token}:${i.toString(36)}`;
+    const hash = new Uint8Array(await crypto.subtle.digest('SHA-256', encoder.encode(response)));
+    if (leadingZeroBits(hash) >= difficulty) return response;
+  }
+}
+
+// Anonymous uploads must answer the gateway's challenge in the X-Upload-Challenge header.
+async function answerUploadChallenge(): Promise<string | null> {
+  const res = await fetch(`${API_URL}/files/upload/challenge`);
+  const challenge: UploadChallenge = await res.json();
+  if (!res.ok) {
+    throw new Error('Failed to get upload challenge');
+  }
+  switch (challenge.type) {
+    case 'none':
+      return null;
+    case 'pow':
+      return solveProofOfWork(challenge.token!, challenge.difficulty!);
+    case 'fake':
+      return 'pass';
+    default:
+      throw new Error('Please sign in to upload files');
+  }
+}
+
+async function sha256Hex(file: Blob): Promise<string> {
+  const hash = new Uint8Array(await crypto.subtle.digest('SHA-256', await file.arrayBuffer()));
+  return Array.from(hash, (b) => b.toString(16).padStart(2, '0')).join('');
+}
+
 function buildUploadHeaders(): Promise<HeadersInit> {
   const headers: HeadersInit = { 'Content-Type': 'application/json' };
   const accessToken = tokenStorage.getAccessToken();
@@ -76,6 +129,7 @@ export const uploadFile = async (
       original_name: f.name,
       size: f.size,
       content_type: f.type || 'application/octet-stream',
+      sha256: await sha256Hex(f),
     });
   }
 
@@ -86,9 +140,15 @@ export const uploadFile = async (
 
   const headers = await buildUploadHeaders();
 
+  const prepareHeaders: Record<string, string> = { ...(headers as Record<string, string>) };
+  if (!prepareHeaders['Authorization']) {
+    const challengeResponse = await answerUploadChallenge();
+    if (challengeResponse) prepareHeaders['X-Upload-Challenge'] = challengeResponse;
+  }
+
   const prepareRes = await fetch(`${API_URL}/files/upload/prepare`, {
     method: 'POST',
-    headers,
+    headers: prepareHeaders,
     body: JSON.stringify(prepareBody),
   });
 
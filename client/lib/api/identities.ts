@@ -0,0 +1,78 @@
+import { API_URL } from '@/lib/config';
+import { ensureValidToken } from './userAuth';
+
+export interface LinkedIdentity {
+  provider: string;
+  email: string;
+  email_verified: boolean;
+  primary: boolean; // the provider the account was created with
+  created_at: number; // Unix seconds
+  last_login_at?: number; // Unix seconds
+}
+
+// Set while the provider round trip of a link is in flight, so the OAuth callback page links the
+// account instead of signing in.
+const LINK_PROVIDER_KEY = 'oauth_link_provider';
+
+const authorizedFetch = async (path: string, init: RequestInit = {}): Promise<Response> => {
+  const accessToken = await ensureValidToken();
+  const response = await fetch(`${API_URL}${path}`, {
+    ...init,
+    headers: {
+      ...init.headers,
+      Authorization: `Bearer ${accessToken}`,
+    },
+  });
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Request failed' }));
+    throw new Error(error.error || 'Request failed');
+  }
+  return response;
+};
+
+export const listIdentities = async (): Promise<LinkedIdentity[]> => {
+  const response = await authorizedFetch('/me/identities');
+  const data = await response.json();
+  return data.identities;
+};
+
+/**
+ * Sends the browser to the provider to link another account to the signed in user.
+ */
+export const startLinkIdentity = async (provider: string): Promise<void> => {
+  const response = await authorizedFetch(`/me/identities/${encodeURIComponent(provider)}`, {
+    method: 'POST',
+  });
+  const data = await response.json();
+  localStorage.setItem(LINK_PROVIDER_KEY, provider);
+  window.location.href = data.redirect_url;
+};
+
+/**
+ * Returns the provider of a link in progress (and forgets it), or null for a regular sign-in.
+ */
+export const takePendingLinkProvider = (): string | null => {
+  if (typeof window === 'undefined') return null;
+  const provider = localStorage.getItem(LINK_PROVIDER_KEY);
+  localStorage.removeItem(LINK_PROVIDER_KEY);
+  return provider;
+};
+
+export const completeLinkIdentity = async (
+  provider: string,
+  code: string,
+  state: string
+): Promise<LinkedIdentity> => {
+  const response = await authorizedFetch(`/me/identities/${encodeURIComponent(provider)}/callback`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ code, state }),
+  });
+  return response.json();
+};
+
+export const unlinkIdentity = async (provider: string): Promise<void> => {
+  await authorizedFetch(`/me/identities/${encodeURIComponent(provider)}`, {
+    method: 'DELETE',
+  });
+};
@@ -16,14 +16,18 @@ export {
   isAuthenticated,
   initiateOAuth,
   handleCallback,
+  verifyMFA,
+  requestMagicLink,
+  redeemMagicLink,
   validateToken,
   refreshToken,
   logout,
+  deleteAccount,
   hardLogout,
   ensureValidToken,
   getCurrentUserId,
 } from './userAuth';
-export type { AuthResponse, TokenPair, Claims } from './userAuth';
+export type { AuthResponse, MFAChallenge, TokenPair, Claims } from './userAuth';
 
 // File upload
 export {
@@ -53,5 +57,32 @@ export {
   fetchBucketAdmins,
   isBucketAdmin,
   fetchBucketLifecycle,
+  reportBucket,
 } from './bucket';
-export type { BucketLifecycleResponse } from './bucket';
+export type { BucketLifecycleResponse, ReportReason } from './bucket';
+
+// Device sign-in approval
+export {
+  getDeviceAuthorization,
+  approveDeviceAuthorization,
+} from './device';
+export type { PendingDeviceAuthorization } from './device';
+
+// Linked sign-in providers
+export {
+  listIdentities,
+  startLinkIdentity,
+  takePendingLinkProvider,
+  completeLinkIdentity,
+  unlinkIdentity,
+} from './identities';
+export type { LinkedIdentity } from './identities';
+
+// Two-factor authentication settings
+export {
+  getMFAStatus,
+  startMFAEnrollment,
+  confirmMFAEnrollment,
+  disableMFA,
+} from './mfa';
+export type { MFAStatus, MFAEnrollment } from './mfa';
@@ -0,0 +1,62 @@
+import { API_URL } from '@/lib/config';
+import { ensureValidToken } from './userAuth';
+
+export interface MFAStatus {
+  enabled: boolean;
+  recovery_codes_remaining: number;
+}
+
+export interface MFAEnrollment {
+  secret: string; // base32, for manual entry
+  otpauth_uri: string; // render as a QR code for authenticator apps
+}
+
+const authorizedFetch = async (path: string, init: RequestInit = {}): Promise<Response> => {
+  const accessToken = await ensureValidToken();
+  const response = await fetch(`${API_URL}${path}`, {
+    ...init,
+    headers: {
+      ...init.headers,
+      Authorization: `Bearer ${accessToken}`,
+    },
+  });
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Request failed' }));
+    throw new Error(error.error || 'Request failed');
+  }
+  return response;
+};
+
+export const getMFAStatus = async (): Promise<MFAStatus> => {
+  const response = await authorizedFetch('/me/mfa');
+  return response.json();
+};
+
+/**
+ * Creates a TOTP secret. MFA is enabled once confirmMFAEnrollment is called with a code from it.
+ */
+export const startMFAEnrollment = async (): Promise<MFAEnrollment> => {
+  const response = await authorizedFetch('/me/mfa', { method: 'POST' });
+  return response.json();
+};
+
+/**
+ * Enables MFA and returns the recovery codes. They are only shown this once.
+ */
+export const confirmMFAEnrollment = async (code: string): Promise<string[]> => {
+  const response = await authorizedFetch('/me/mfa/confirm', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ code }),
+  });
+  const data = await response.json();
+  return data.recovery_codes;
+};
+
+export const disableMFA = async (code: string): Promise<void> => {
+  await authorizedFetch('/me/mfa', {
+    method: 'DELETE',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ code }),
+  });
+};
@@ -4,12 +4,16 @@ export interface FileInfo {
   key: string;
   size: number;
   content_type: string;
+  note?: string | null;
 }
 
 export interface BucketMetadata {
   storage_id: string;
+  title?: string | null;
+  description?: string | null; // Markdown, rendered as plain text
   files: FileInfo[];
   total_size: number;
+  status?: string; // 'removed' once taken down for abuse (answered with 451)
 }
 
 export interface AdminInfo {
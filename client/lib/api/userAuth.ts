@@ -1,8 +1,11 @@
 import { API_URL } from '@/lib/config';
 
+// With the gateway's cookie session mode the refresh token is set as an HttpOnly cookie instead of
+// returned, and csrf_token must be sent back in X-CSRF-Token to use it.
 export interface AuthResponse {
   access_token: string;
-  refresh_token: string;
+  refresh_token?: string;
+  csrf_token?: string;
   user: {
     id: string;
     email: string;
@@ -11,9 +14,18 @@ export interface AuthResponse {
   };
 }
 
+// Returned by the OAuth callback instead of tokens when the user has MFA enabled; finish the
+// sign-in with verifyMFA.
+export interface MFAChallenge {
+  mfa_required: true;
+  mfa_token: string;
+  mfa_expires_in: number; // seconds
+}
+
 export interface TokenPair {
   access_token: string;
-  refresh_token: string;
+  refresh_token?: string;
+  csrf_token?: string;
 }
 
 export interface Claims {
@@ -33,10 +45,25 @@ export const tokenStorage = {
     return localStorage.getItem('refresh_token');
   },
 
-  setTokens: (accessToken: string, refreshToken: string): void => {
+  getCsrfToken: (): string | null => {
+    if (typeof window === 'undefined') return null;
+    return localStorage.getItem('csrf_token');
+  },
+
+  // Without a refresh token the session lives in the gateway's cookie, used with the CSRF token.
+  setTokens: (accessToken: string, refreshToken?: string, csrfToken?: string): void => {
     if (typeof window === 'undefined') return;
     localStorage.setItem('access_token', accessToken);
-    localStorage.setItem('refresh_token', refreshToken);
+    if (refreshToken) {
+      localStorage.setItem('refresh_token', refreshToken);
+    } else {
+      localStorage.removeItem('refresh_token');
+    }
+    if (csrfToken) {
+      localStorage.setItem('csrf_token', csrfToken);
+    } else {
+      localStorage.removeItem('csrf_token');
+    }
     window.dispatchEvent(new Event('auth-state-changed'));
   },
 
@@ -44,6 +71,7 @@ export const tokenStorage = {
     if (typeof window === 'undefined') return;
     localStorage.removeItem('access_token');
     localStorage.removeItem('refresh_token');
+    localStorage.removeItem('csrf_token');
     window.dispatchEvent(new Event('auth-state-changed'));
   },
 };
@@ -62,10 +90,11 @@ export const handleCallback = async (
   code: string,
   state: string,
   provider: string = 'github'
-): Promise<AuthResponse> => {
+): Promise<AuthResponse | MFAChallenge> => {
   const response = await fetch(
     `${API_URL}/auth/oauth/${provider}/callback?code=${encodeURIComponent(code)}&state=${encodeURIComponent(state)}`,
     {
+      credentials: 'include',
       headers: {
         Accept: 'application/json',
         'Content-Type': 'application/json',
@@ -78,8 +107,81 @@ export const handleCallback = async (
     throw new Error(error.error || 'Failed to authenticate');
   }
 
+  const data: AuthResponse | MFAChallenge = await response.json();
+  if ('mfa_required' in data) {
+    return data;
+  }
+  tokenStorage.setTokens(data.access_token, data.refresh_token, data.csrf_token);
+  return data;
+};
+
+/**
+ * Finishes a sign-in that needs a second factor, with a code from the authenticator app or a
+ * recovery code.
+ */
+export const verifyMFA = async (mfaToken: string, code: string): Promise<AuthResponse> => {
+  const response = await fetch(`${API_URL}/auth/mfa/verify`, {
+    method: 'POST',
+    credentials: 'include',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ mfa_token: mfaToken, code }),
+  });
+
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Invalid code' }));
+    throw new Error(error.error || 'Invalid code');
+  }
+
   const data: AuthResponse = await response.json();
-  tokenStorage.setTokens(data.access_token, data.refresh_token);
+  tokenStorage.setTokens(data.access_token, data.refresh_token, data.csrf_token);
+  return data;
+};
+
+/**
+ * Emails a single-use sign-in link to the address. The server answers the same whether or not an
+ * account uses it.
+ */
+export const requestMagicLink = async (email: string): Promise<void> => {
+  const response = await fetch(`${API_URL}/auth/magic-link`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ email }),
+  });
+
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Failed to send sign-in link' }));
+    throw new Error(error.error || 'Failed to send sign-in link');
+  }
+};
+
+/**
+ * Signs in with the token of an emailed link. Like handleCallback, returns an MFA challenge
+ * instead of storing tokens when the user has MFA enabled.
+ */
+export const redeemMagicLink = async (token: string): Promise<AuthResponse | MFAChallenge> => {
+  const response = await fetch(`${API_URL}/auth/magic-link/redeem`, {
+    method: 'POST',
+    credentials: 'include',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ token }),
+  });
+
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Invalid or expired sign-in link' }));
+    throw new Error(error.error || 'Invalid or expired sign-in link');
+  }
+
+  const data: AuthResponse | MFAChallenge = await response.json();
+  if ('mfa_required' in data) {
+    return data;
+  }
+  tokenStorage.setTokens(data.access_token, data.refresh_token, data.csrf_token);
   return data;
 };
 
@@ -100,14 +202,25 @@ export const validateToken = async (token: string): Promise<Claims> => {
   return data.claims;
 };
 
-export const refreshToken = async (refreshTokenValue: string): Promise<TokenPair> => {
-  const response = await fetch(`${API_URL}/auth/refresh`, {
-    method: 'POST',
-    headers: {
-      'Content-Type': 'application/json',
-    },
-    body: JSON.stringify({ refresh_token: refreshTokenValue }),
-  });
+/**
+ * Exchanges the refresh token for new tokens, or without one the gateway's refresh token cookie.
+ */
+export const refreshToken = async (refreshTokenValue?: string | null): Promise<TokenPair> => {
+  const response = refreshTokenValue
+    ? await fetch(`${API_URL}/auth/refresh`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ refresh_token: refreshTokenValue }),
+      })
+    : await fetch(`${API_URL}/auth/refresh`, {
+        method: 'POST',
+        credentials: 'include',
+        headers: {
+          'X-CSRF-Token': tokenStorage.getCsrfToken() ?? '',
+        },
+      });
 
   if (!response.ok) {
     const error = await response.json().catch(() => ({ error: 'Failed to refresh token' }));
@@ -115,24 +228,46 @@ export const refreshToken = async (refreshTokenValue: string): Promise<TokenPair
   }
 
   const data: TokenPair = await response.json();
-  tokenStorage.setTokens(data.access_token, data.refresh_token);
+  tokenStorage.setTokens(data.access_token, data.refresh_token, data.csrf_token);
   return data;
 };
 
-export const logout = async (refreshTokenValue: string): Promise<void> => {
+/**
+ * Ends the current session (clearing the gateway's session cookies too) and the local tokens.
+ */
+export const logout = async (): Promise<void> => {
   try {
     await fetch(`${API_URL}/auth/logout`, {
       method: 'POST',
+      credentials: 'include',
       headers: {
-        'Content-Type': 'application/json',
+        Authorization: `Bearer ${tokenStorage.getAccessToken() ?? ''}`,
       },
-      body: JSON.stringify({ refresh_token: refreshTokenValue }),
     });
   } finally {
     tokenStorage.clearTokens();
   }
 };
 
+/**
+ * Deletes the signed in user's account and clears the local tokens. The server keeps the account
+ * for a grace period before removing it for good; buckets the user owned alone are released.
+ */
+export const deleteAccount = async (): Promise<void> => {
+  const accessToken = await ensureValidToken();
+  const response = await fetch(`${API_URL}/me`, {
+    method: 'DELETE',
+    headers: {
+      Authorization: `Bearer ${accessToken}`,
+    },
+  });
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Account deletion failed' }));
+    throw new Error(error.error || 'Account deletion failed');
+  }
+  tokenStorage.clearTokens();
+};
+
 export const hardLogout = (): void => {
   tokenStorage.clearTokens();
   if (typeof window !== 'undefined') {
@@ -144,7 +279,7 @@ export const ensureValidToken = async (): Promise<string> => {
   const accessToken = tokenStorage.getAccessToken();
   const refreshTokenValue = tokenStorage.getRefreshToken();
 
-  if (!accessToken || !refreshTokenValue) {
+  if (!accessToken || (!refreshTokenValue && !tokenStorage.getCsrfToken())) {
     throw new Error('No authentication tokens available');
   }
 
@@ -0,0 +1,368 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect selects the SQL flavour and locking strategy used by a Migrator.
+// Its value is also the subdirectory name migrations are loaded from.
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+// MigrationsTable records which migrations have been applied.
+const MigrationsTable = "schema_migrations"
+
+// migrationLockID is the PostgreSQL advisory lock key held while migrating.
+const migrationLockID = 727260
+
+// Migration is a single numbered schema change with its rollback.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a known migration has been applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads NNNN_name.up.sql / NNNN_name.down.sql pairs from dir in fsys
+// (usually an embed.FS) and returns them ordered by version.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations dir %q: %w", dir, err)
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", e.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Migrator applies and rolls back migrations. Each run happens in one transaction that
+// holds a database-wide lock (BEGIN IMMEDIATE on SQLite, an advisory lock on PostgreSQL),
+// so concurrent replicas serialize and a failed run leaves the schema untouched.
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, dialect Dialect, migrations []Migration) *Migrator {
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}
+}
+
+// session is the subset of *sql.Tx and *sql.Conn used while the lock is held.
+type session interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// begin starts a locked transaction. end commits when commit is true, otherwise rolls back.
+func (m *Migrator) begin(ctx context.Context) (s session, end func(commit bool) error, err error) {
+	switch m.dialect {
+	case DialectSQLite:
+		// database/sql transactions use a deferred BEGIN; take the write lock up front instead
+		conn, err := m.db.Conn(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("acquire migration lock: %w", err)
+		}
+		return conn, func(commit bool) error {
+			defer conn.Close()
+			stmt := "ROLLBACK"
+			if commit {
+				stmt = "COMMIT"
+			}
+			_, err := conn.ExecContext(context.Background(), stmt)
+			return err
+		}, nil
+	case DialectPostgres:
+		tx, err := m.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+			tx.Rollback()
+			return nil, nil, fmt.Errorf("acquire migration lock: %w", err)
+		}
+		return tx, func(commit bool) error {
+			if commit {
+				return tx.Commit()
+			}
+			return tx.Rollback()
+		}, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported dialect %q", m.dialect)
+	}
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, s session) error {
+	_, err := s.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+MigrationsTable+` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at BIGINT NOT NULL
+	)`)
+	return err
+}
+
+// applied returns applied versions mapped to their Unix applied_at.
+func (m *Migrator) applied(ctx context.Context, s session) (map[int64]int64, error) {
+	rows, err := s.QueryContext(ctx, `SELECT version, applied_at FROM `+MigrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[int64]int64)
+	for rows.Next() {
+		var version, appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		out[version] = appliedAt
+	}
+	return out, rows.Err()
+}
+
+// Up applies all pending migrations in version order and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	s, end, err := m.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := m.up(ctx, s)
+	if err != nil {
+		end(false)
+		return 0, err
+	}
+	if err := end(true); err != nil {
+		return 0, fmt.Errorf("commit migrations: %w", err)
+	}
+	return n, nil
+}
+
+func (m *Migrator) up(ctx context.Context, s session) (int, error) {
+	if err := m.ensureTable(ctx, s); err != nil {
+		return 0, fmt.Errorf("create %s: %w", MigrationsTable, err)
+	}
+	applied, err := m.applied(ctx, s)
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", MigrationsTable, err)
+	}
+	n := 0
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if err := execStatements(ctx, s, mig.Up); err != nil {
+			return 0, fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+		}
+		if _, err := s.ExecContext(ctx, `INSERT INTO `+MigrationsTable+` (version, name, applied_at) VALUES ($1, $2, $3)`,
+			mig.Version, mig.Name, time.Now().Unix()); err != nil {
+			return 0, fmt.Errorf("record migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Down rolls back the latest steps applied migrations and returns how many were rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	s, end, err := m.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := m.down(ctx, s, steps)
+	if err != nil {
+		end(false)
+		return 0, err
+	}
+	if err := end(true); err != nil {
+		return 0, fmt.Errorf("commit migrations: %w", err)
+	}
+	return n, nil
+}
+
+func (m *Migrator) down(ctx context.Context, s session, steps int) (int, error) {
+	if err := m.ensureTable(ctx, s); err != nil {
+		return 0, fmt.Errorf("create %s: %w", MigrationsTable, err)
+	}
+	applied, err := m.applied(ctx, s)
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", MigrationsTable, err)
+	}
+	n := 0
+	for i := len(m.migrations) - 1; i >= 0 && n < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == "" {
+			return 0, fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+		}
+		if err := execStatements(ctx, s, mig.Down); err != nil {
+			return 0, fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+		}
+		if _, err := s.ExecContext(ctx, `DELETE FROM `+MigrationsTable+` WHERE version = $1`, mig.Version); err != nil {
+			return 0, fmt.Errorf("unrecord migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Status lists every known migration and when it was applied (nil if pending).
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	s, end, err := m.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(true)
+	if err := m.ensureTable(ctx, s); err != nil {
+		return nil, fmt.Errorf("create %s: %w", MigrationsTable, err)
+	}
+	applied, err := m.applied(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", MigrationsTable, err)
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := MigrationStatus{Migration: mig}
+		if at, ok := applied[mig.Version]; ok {
+			t := time.Unix(at, 0).UTC()
+			st.AppliedAt = &t
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// RunMigrateCommand implements the "migrate" subcommand shared by the service binaries:
+//
+//	migrate [up]       apply all pending migrations
+//	migrate down [n]   roll back the latest n migrations (default 1)
+//	migrate status     list migrations and whether they are applied
+func RunMigrateCommand(ctx context.Context, m *Migrator, args []string, w io.Writer) error {
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	switch cmd {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "applied %d migration(s)\n", n)
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil || v < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = v
+		}
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "rolled back %d migration(s)\n", n)
+		return nil
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range list {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d_%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected up, down [n] or status)", cmd)
+	}
+}
+
+// execStatements runs SQL statement by statement (database/sql drivers run one per Exec).
+// Statements are split on ";", so migration files must not use semicolons in comments or literals.
+func execStatements(ctx context.Context, s session, script string) error {
+	for _, stmt := range SplitStatements(script) {
+		if _, err := s.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SplitStatements splits a SQL script on ";" and drops empty statements.
+func SplitStatements(script string) []string {
+	var out []string
+	for _, s := range strings.Split(script, ";") {
+		if t := strings.TrimSpace(s); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -6,9 +6,13 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"time"
 
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
 	"github.com/cthulhu-platform/filemanager/internal/configs"
 	"github.com/cthulhu-platform/filemanager/internal/connections"
+	"github.com/cthulhu-platform/filemanager/internal/daemon"
+	"github.com/cthulhu-platform/filemanager/internal/geoip"
 	"github.com/cthulhu-platform/filemanager/internal/pkg"
 	"github.com/cthulhu-platform/filemanager/internal/repository"
 	"github.com/cthulhu-platform/filemanager/internal/server"
@@ -29,10 +33,19 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	slog.SetDefault(logger)
 
+	// "migrate [up | down [n] | status]" manages the schema and exits without starting the service
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := repository.Migrate(ctx, os.Args[2:]); err != nil {
+			slog.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create dependencies
 
-	// Create local Repository/db
-	repo, err := repository.NewSQLiteRepository(ctx)
+	// Create local Repository/db (PostgreSQL when POSTGRES_DSN is set, otherwise SQLite)
+	repo, err := repository.NewRepository(ctx)
 	if err != nil {
 		slog.Error("Failed to create repository", "error", err)
 		os.Exit(1)
@@ -52,6 +65,7 @@ func main() {
 		Region:             pkg.S3_REGION,
 		BucketName:         pkg.S3_BUCKET_NAME,
 		ForcePathStyle:     pkg.S3_FORCE_PATH_STYLE == "true",
+		PresignRoleARN:     pkg.S3_PRESIGN_ROLE_ARN,
 	})
 	if err != nil {
 		slog.Error("Failed to connect to storage", "error", err)
@@ -61,7 +75,8 @@ func main() {
 
 	// Create connections to other microservices
 	connectionPool, err := connections.NewConnectionsContainer(ctx, connections.ConnectionsConfig{
-		AuthURL: pkg.AUTH_GRPC_URL,
+		AuthURL:  pkg.AUTH_GRPC_URL,
+		GRPCAuth: grpcauth.ConfigFromEnv(),
 	})
 	if err != nil {
 		slog.Error("Failed to create connections container", "error", err)
@@ -69,12 +84,45 @@ func main() {
 	}
 	defer connectionPool.Close()
 
+	// Country database for bucket access rules (optional, country rules need it)
+	var geo *geoip.Database
+	if pkg.GEOIP_DB_FILE != "" {
+		geo, err = geoip.Open(pkg.GEOIP_DB_FILE)
+		if err != nil {
+			slog.Error("Failed to load GeoIP database", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("GeoIP database loaded", "file", pkg.GEOIP_DB_FILE, "ranges", geo.Len())
+	}
+
 	// Create Service (storage implements storage.Storage for PresignPut)
-	svc := service.NewFilemanagerService(repo, storage, connectionPool)
+	svc := service.NewFilemanagerService(repo, storage, connectionPool, geo)
+
+	// Finish bucket deletions interrupted by a crash or failed S3 purge, and purge abuse evidence
+	// past retention (ABUSE_EVIDENCE_RETENTION=0 keeps it)
+	evidenceRetention, err := time.ParseDuration(pkg.ABUSE_EVIDENCE_RETENTION)
+	if err != nil {
+		slog.Error("Invalid ABUSE_EVIDENCE_RETENTION", "value", pkg.ABUSE_EVIDENCE_RETENTION, "error", err)
+		os.Exit(1)
+	}
+	deletionDaemon := daemon.NewDeletionDaemon(svc, pkg.BUCKET_DELETION_RESUME_INTERVAL, evidenceRetention)
+	go deletionDaemon.Run(ctx)
+
+	// Periodic S3 <-> files table reconciliation (RECONCILE_INTERVAL=0 disables it)
+	reconcileInterval, err := time.ParseDuration(pkg.RECONCILE_INTERVAL)
+	if err != nil {
+		slog.Error("Invalid RECONCILE_INTERVAL", "value", pkg.RECONCILE_INTERVAL, "error", err)
+		os.Exit(1)
+	}
+	if reconcileInterval > 0 {
+		reconcileDaemon := daemon.NewReconcileDaemon(svc, reconcileInterval, pkg.RECONCILE_DRY_RUN == "true")
+		go reconcileDaemon.Run(ctx)
+	}
 
 	serverCfg := server.ServerConfig{
-		Host: pkg.APP_HOST,
-		Port: pkg.APP_PORT,
+		Host:     pkg.APP_HOST,
+		Port:     pkg.APP_PORT,
+		GRPCAuth: grpcauth.ConfigFromEnv(),
 	}
 	if err := server.ListenGRPC(ctx, serverCfg, svc); err != nil {
 		slog.Error("Failed to start gRPC server", "error", err)
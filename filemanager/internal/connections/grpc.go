@@ -6,6 +6,7 @@ import (
 	"time"
 
 	auth "github.com/cthulhu-platform/auth/pkg/client"
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
 )
 
 type ConnectionsContainer struct {
@@ -13,7 +14,8 @@ type ConnectionsContainer struct {
 }
 
 type ConnectionsConfig struct {
-	AuthURL string
+	AuthURL  string
+	GRPCAuth grpcauth.Config
 }
 
 func NewConnectionsContainer(ctx context.Context, cfg ConnectionsConfig) (*ConnectionsContainer, error) {
@@ -22,7 +24,11 @@ func NewConnectionsContainer(ctx context.Context, cfg ConnectionsConfig) (*Conne
 	defer cancel()
 
 	// Connect to Auth
-	authClient, err := auth.NewClient(ctx, cfg.AuthURL)
+	authCreds, err := cfg.GRPCAuth.DialOption(grpcauth.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load auth credentials: %v", err)
+	}
+	authClient, err := auth.NewClient(ctx, cfg.AuthURL, authCreds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create auth client: %v", err)
 	}
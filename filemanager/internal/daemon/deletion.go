@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cthulhu-platform/filemanager/internal/service"
+)
+
+// Deletion daemon that resumes bucket deletions interrupted between marking and row removal, and
+// purges buckets taken down for abuse once their evidence retention ends
+
+type DeletionDaemon struct {
+	service   service.Service
+	interval  time.Duration
+	retention time.Duration // 0 keeps removed buckets forever
+}
+
+func NewDeletionDaemon(service service.Service, interval, retention time.Duration) *DeletionDaemon {
+	return &DeletionDaemon{service: service, interval: interval, retention: retention}
+}
+
+func (d *DeletionDaemon) resume(ctx context.Context) {
+	resumed, err := d.service.ResumeBucketDeletions(ctx)
+	if err != nil {
+		slog.Error("Resuming bucket deletions failed", "error", err)
+		return
+	}
+	if resumed > 0 {
+		slog.Info("Resumed bucket deletions", "count", resumed)
+	}
+	if d.retention <= 0 {
+		return
+	}
+	purged, err := d.service.PurgeRemovedBuckets(ctx, time.Now().Add(-d.retention))
+	if err != nil {
+		slog.Error("Purging removed buckets failed", "error", err)
+		return
+	}
+	if purged > 0 {
+		slog.Info("Purged removed buckets past evidence retention", "count", purged)
+	}
+}
+
+func (d *DeletionDaemon) Run(ctx context.Context) error {
+	slog.Info("Starting deletion daemon", "interval", d.interval.String(), "evidence_retention", d.retention.String())
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	// Run immediately on startup to finish deletions cut short by the previous shutdown
+	d.resume(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.resume(ctx)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cthulhu-platform/filemanager/internal/service"
+)
+
+// Reconcile daemon that runs every interval and reconciles S3 objects with the files table
+
+type ReconcileDaemon struct {
+	service  service.Service
+	interval time.Duration
+	dryRun   bool
+}
+
+func NewReconcileDaemon(service service.Service, interval time.Duration, dryRun bool) *ReconcileDaemon {
+	return &ReconcileDaemon{service: service, interval: interval, dryRun: dryRun}
+}
+
+func (d *ReconcileDaemon) reconcile(ctx context.Context) {
+	slog.Info("Storage reconciliation started", "dry_run", d.dryRun)
+	report, err := d.service.ReconcileStorage(ctx, d.dryRun)
+	if err != nil {
+		slog.Error("Storage reconciliation failed", "error", err)
+		return
+	}
+	slog.Info("Storage reconciliation completed",
+		"dry_run", report.DryRun,
+		"objects_scanned", report.ObjectsScanned,
+		"rows_scanned", report.RowsScanned,
+		"orphaned_objects", report.OrphanedObjects,
+		"dangling_rows", report.DanglingRows,
+		"objects_deleted", report.ObjectsDeleted,
+		"rows_deleted", report.RowsDeleted,
+		"failures", report.Failures,
+	)
+}
+
+func (d *ReconcileDaemon) Run(ctx context.Context) error {
+	slog.Info("Starting reconcile daemon", "interval", d.interval.String(), "dry_run", d.dryRun)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	// Not run on startup: a full listing is expensive and restarts should not trigger one
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.reconcile(ctx)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package geoip maps IP addresses to countries from a local database file in CSV form, one range
+// per row as "first,last,country" (the DB-IP Lite country download) or "cidr,country". Countries
+// are ISO 3166-1 alpha-2 codes.
+package geoip
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Database is an in-memory table of address ranges, sorted by first address.
+type Database struct {
+	ranges []ipRange
+}
+
+type ipRange struct {
+	first, last netip.Addr
+	country     string
+}
+
+// Open loads the database at path.
+func Open(path string) (*Database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	db, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Load reads a database from r. A first row that does not start with an address is taken as a
+// header and skipped.
+func Load(r io.Reader) (*Database, error) {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	db := &Database{}
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rng, err := parseRange(rec)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		db.ranges = append(db.ranges, rng)
+	}
+	slices.SortFunc(db.ranges, func(a, b ipRange) int { return a.first.Compare(b.first) })
+	return db, nil
+}
+
+func parseRange(rec []string) (ipRange, error) {
+	switch len(rec) {
+	case 2:
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(rec[0]))
+		if err != nil {
+			return ipRange{}, err
+		}
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()).Masked()
+		return ipRange{first: prefix.Addr(), last: lastAddr(prefix), country: country(rec[1])}, nil
+	case 3:
+		first, err := netip.ParseAddr(strings.TrimSpace(rec[0]))
+		if err != nil {
+			return ipRange{}, err
+		}
+		last, err := netip.ParseAddr(strings.TrimSpace(rec[1]))
+		if err != nil {
+			return ipRange{}, err
+		}
+		first, last = first.Unmap(), last.Unmap()
+		if first.Is4() != last.Is4() || last.Less(first) {
+			return ipRange{}, fmt.Errorf("invalid range %s to %s", first, last)
+		}
+		return ipRange{first: first, last: last, country: country(rec[2])}, nil
+	}
+	return ipRange{}, fmt.Errorf("expected 2 or 3 fields, got %d", len(rec))
+}
+
+func country(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+// lastAddr returns the highest address of a masked prefix.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// Len returns the number of ranges loaded.
+func (d *Database) Len() int {
+	return len(d.ranges)
+}
+
+// Country returns the country of addr, or "" when no range holds it.
+func (d *Database) Country(addr netip.Addr) string {
+	addr = addr.Unmap()
+	// The last range starting at or before addr
+	i, found := slices.BinarySearchFunc(d.ranges, addr, func(r ipRange, a netip.Addr) int { return r.first.Compare(a) })
+	if !found {
+		i--
+	}
+	if i < 0 || d.ranges[i].last.Less(addr) || d.ranges[i].first.Is4() != addr.Is4() {
+		return ""
+	}
+	return d.ranges[i].country
+}
@@ -10,6 +10,27 @@ const (
 	DEFAULT_REPOSITORY_QUERY_TIMEOUT = 5 * time.Second
 	BUCKET_TOKEN_EXPIRATION          = 30 * time.Minute
 	PRESIGNED_URL_EXPIRATION         = 15 * time.Minute
+	// Lifetime of download URLs for buckets with access rules when S3 cannot enforce them
+	RESTRICTED_PRESIGNED_URL_EXPIRATION = time.Minute
+
+	// Reconciliation between S3 and the files table. Objects younger than the grace period
+	// are skipped so uploads between PrepareUpload and ConfirmUpload are not treated as orphans.
+	RECONCILE_PAGE_SIZE        = 500
+	RECONCILE_ORPHAN_GRACE     = 2 * PRESIGNED_URL_EXPIRATION
+	RECONCILE_REPORT_KEY_LIMIT = 100
+
+	// Buckets left in the deleting state (crash or failed purge) are resumed by the deletion daemon
+	BUCKET_DELETION_RESUME_INTERVAL = 5 * time.Minute
+	BUCKET_DELETION_RESUME_BATCH    = 100
+
+	BUCKET_LIST_PAGE_MAX = 100 // buckets returned per ListBuckets call
+
+	// Abuse reports
+	ABUSE_REPORT_PAGE_MAX      = 100  // reports returned per ListAbuseReports call
+	ABUSE_REPORT_DETAILS_MAX   = 2000 // characters
+	ABUSE_EVIDENCE_PURGE_BATCH = 100  // removed buckets purged per deletion daemon pass
+
+	ACCESS_RULE_MAX_ENTRIES = 50 // per list of a bucket's access rules
 )
 
 var (
@@ -18,6 +39,8 @@ var (
 	AUTH_GRPC_URL = env.GetEnv("AUTH_GRPC_URL", "localhost:49051")
 
 	SQLITE_DB_FILE          = env.GetEnv("SQLITE_DB_FILE", "filemanager.db")
+	POSTGRES_DSN            = env.GetEnv("POSTGRES_DSN", "")     // if set, PostgreSQL is used instead of SQLITE_DB_FILE
+	AUTO_MIGRATE            = env.GetEnv("AUTO_MIGRATE", "true") // apply pending migrations on startup
 	BUCKET_TOKEN_SECRET_KEY = env.GetEnv("BUCKET_TOKEN_SECRET_KEY", "iamasecretkey")
 
 	S3_ACCESS_KEY_ID        = env.GetEnv("S3_ACCESS_KEY_ID", "")
@@ -27,4 +50,16 @@ var (
 	S3_REGION               = env.GetEnv("S3_REGION", "")
 	S3_BUCKET_NAME          = env.GetEnv("S3_BUCKET_NAME", "")
 	S3_FORCE_PATH_STYLE     = env.GetEnv("S3_FORCE_PATH_STYLE", "true")
+	// If set, downloads of buckets with CIDR rules are presigned with credentials of this role,
+	// assumed with a session policy on aws:SourceIp so S3 itself refuses other addresses
+	S3_PRESIGN_ROLE_ARN = env.GetEnv("S3_PRESIGN_ROLE_ARN", "")
+
+	// Country database for bucket access rules (see internal/geoip), country rules need it
+	GEOIP_DB_FILE = env.GetEnv("GEOIP_DB_FILE", "")
+
+	RECONCILE_INTERVAL = env.GetEnv("RECONCILE_INTERVAL", "1h") // "0" disables the periodic job
+	RECONCILE_DRY_RUN  = env.GetEnv("RECONCILE_DRY_RUN", "true")
+
+	// How long the files of a bucket taken down for abuse are kept as evidence ("0" keeps them)
+	ABUSE_EVIDENCE_RETENTION = env.GetEnv("ABUSE_EVIDENCE_RETENTION", "2160h")
 )
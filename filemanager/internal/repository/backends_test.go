@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	commonrepo "github.com/cthulhu-platform/common/pkg/repository"
+	internalpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
+)
+
+// The repository tests run against every backend: a SQLite file in a temporary directory, and
+// PostgreSQL when TEST_POSTGRES_DSN points at a server (see the test-postgres task in mise.toml).
+// Each test gets a freshly migrated database, on PostgreSQL a schema dropped when it ends.
+
+type testBackend struct {
+	name    string
+	dialect commonrepo.Dialect
+	open    func(t *testing.T) (Repository, *sql.DB)
+}
+
+var testBackends = []testBackend{
+	{name: "sqlite", dialect: commonrepo.DialectSQLite, open: openTestSQLite},
+	{name: "postgres", dialect: commonrepo.DialectPostgres, open: openTestPostgres},
+}
+
+func openTestSQLite(t *testing.T) (Repository, *sql.DB) {
+	t.Helper()
+	internalpkg.SQLITE_DB_FILE = filepath.Join(t.TempDir(), "filemanager.db")
+	r, err := NewSQLiteRepository(context.Background())
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r, r.db
+}
+
+func openTestPostgres(t *testing.T) (Repository, *sql.DB) {
+	t.Helper()
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+	admin, err := openPostgres(ctx, dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		admin.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.ExecContext(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+		admin.Close()
+	})
+
+	r, err := NewPostgresRepository(ctx, withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("open postgres repository: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r, r.db
+}
+
+// withSearchPath points dsn, a URL or keyword/value connection string, at schema.
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
+
+// forEachBackend runs fn as a subtest per backend, each with its own database.
+func forEachBackend(t *testing.T, fn func(t *testing.T, r Repository)) {
+	for _, b := range testBackends {
+		t.Run(b.name, func(t *testing.T) {
+			r, _ := b.open(t)
+			fn(t, r)
+		})
+	}
+}
+
+func TestMigrationsUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	for _, b := range testBackends {
+		t.Run(b.name, func(t *testing.T) {
+			_, conn := b.open(t)
+			m, err := newMigrator(conn, b.dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			migrations, err := commonrepo.LoadMigrations(migrationsFS, "migrations/"+string(b.dialect))
+			if err != nil {
+				t.Fatal(err)
+			}
+			migrated := listTables(t, conn, b.dialect)
+
+			n, err := m.Down(ctx, len(migrations))
+			if err != nil {
+				t.Fatalf("down: %v", err)
+			}
+			if n != len(migrations) {
+				t.Fatalf("rolled back %d migrations, want %d", n, len(migrations))
+			}
+			if tables := listTables(t, conn, b.dialect); !slices.Equal(tables, []string{commonrepo.MigrationsTable}) {
+				t.Fatalf("tables left after rolling back every migration: %v", tables)
+			}
+
+			n, err = m.Up(ctx)
+			if err != nil {
+				t.Fatalf("up: %v", err)
+			}
+			if n != len(migrations) {
+				t.Fatalf("applied %d migrations, want %d", n, len(migrations))
+			}
+			if tables := listTables(t, conn, b.dialect); !slices.Equal(tables, migrated) {
+				t.Fatalf("tables after re-applying migrations = %v, want %v", tables, migrated)
+			}
+		})
+	}
+}
+
+// listTables returns the sorted table names of the current database or schema.
+func listTables(t *testing.T, conn *sql.DB, dialect commonrepo.Dialect) []string {
+	t.Helper()
+	query := `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`
+	if dialect == commonrepo.DialectPostgres {
+		query = `SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema()`
+	}
+	rows, err := conn.QueryContext(context.Background(), query)
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, strings.ToLower(name))
+	}
+	slices.Sort(tables)
+	return tables
+}
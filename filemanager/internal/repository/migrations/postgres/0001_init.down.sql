@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS bucket_admins;
+DROP TABLE IF EXISTS files;
+DROP TABLE IF EXISTS buckets;
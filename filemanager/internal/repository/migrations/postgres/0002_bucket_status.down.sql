@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_buckets_status;
+ALTER TABLE buckets DROP COLUMN deleting_at;
+ALTER TABLE buckets DROP COLUMN status;
@@ -0,0 +1,5 @@
+-- Bucket lifecycle state, mirrors ../sqlite/0002_bucket_status.up.sql.
+ALTER TABLE buckets ADD COLUMN status TEXT NOT NULL DEFAULT 'active';  -- 'active' or 'deleting'
+ALTER TABLE buckets ADD COLUMN deleting_at BIGINT;  -- Unix timestamp the deletion started
+
+CREATE INDEX IF NOT EXISTS idx_buckets_status ON buckets(status);
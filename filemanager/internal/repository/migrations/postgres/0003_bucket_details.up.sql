@@ -0,0 +1,12 @@
+-- Bucket title, description and file notes, mirrors ../sqlite/0003_bucket_details.up.sql
+ALTER TABLE buckets ADD COLUMN title TEXT;
+ALTER TABLE buckets ADD COLUMN description TEXT;  -- Markdown
+
+CREATE TABLE IF NOT EXISTS file_notes (
+    string_id TEXT PRIMARY KEY,  -- files.string_id (row may not exist yet)
+    bucket_id TEXT NOT NULL REFERENCES buckets(id) ON DELETE CASCADE,
+    note TEXT NOT NULL,
+    updated_at BIGINT NOT NULL  -- Unix timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_notes_bucket_id ON file_notes(bucket_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_buckets_org_id;
+ALTER TABLE buckets DROP COLUMN org_id;
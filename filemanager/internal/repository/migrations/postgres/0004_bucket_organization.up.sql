@@ -0,0 +1,4 @@
+-- Bucket organization, mirrors ../sqlite/0004_bucket_organization.up.sql
+ALTER TABLE buckets ADD COLUMN org_id TEXT;  -- Cross-db ref to auth.organizations.id, NULL if personal
+
+CREATE INDEX IF NOT EXISTS idx_buckets_org_id ON buckets(org_id);
@@ -0,0 +1,3 @@
+ALTER TABLE buckets DROP COLUMN allowed_countries;
+ALTER TABLE buckets DROP COLUMN allowed_referrers;
+ALTER TABLE buckets DROP COLUMN allowed_cidrs;
@@ -1,5 +1,5 @@
--- Enable foreign keys
-PRAGMA foreign_keys = ON;
+-- Initial schema. Foreign keys are enabled per connection via the DSN (_foreign_keys=on)
+-- since PRAGMA foreign_keys is a no-op inside the migration transaction.
 
 -- Buckets table: Storage containers for files
 CREATE TABLE IF NOT EXISTS buckets (
@@ -38,4 +38,4 @@ CREATE TABLE IF NOT EXISTS bucket_admins (
 );
 
 CREATE INDEX IF NOT EXISTS idx_bucket_admins_user_id ON bucket_admins(user_id);
-CREATE INDEX IF NOT EXISTS idx_bucket_admins_bucket_id ON bucket_admins(bucket_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_bucket_admins_bucket_id ON bucket_admins(bucket_id);
@@ -0,0 +1,6 @@
+-- Bucket lifecycle state. DeleteBucket marks a bucket 'deleting' before purging its S3 objects,
+-- so a crash mid-way leaves a marker that the deletion daemon picks up and finishes.
+ALTER TABLE buckets ADD COLUMN status TEXT NOT NULL DEFAULT 'active';  -- 'active' or 'deleting'
+ALTER TABLE buckets ADD COLUMN deleting_at INTEGER;  -- Unix timestamp the deletion started
+
+CREATE INDEX IF NOT EXISTS idx_buckets_status ON buckets(status);
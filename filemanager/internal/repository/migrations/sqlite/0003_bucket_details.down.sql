@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS file_notes;
+ALTER TABLE buckets DROP COLUMN description;
+ALTER TABLE buckets DROP COLUMN title;
@@ -0,0 +1,14 @@
+-- Uploader-supplied context shown on the bucket page
+ALTER TABLE buckets ADD COLUMN title TEXT;
+ALTER TABLE buckets ADD COLUMN description TEXT;  -- Markdown
+
+-- File notes: keyed by string_id rather than files.id because notes are supplied at
+-- PrepareUpload, before ConfirmUpload creates the files row
+CREATE TABLE IF NOT EXISTS file_notes (
+    string_id TEXT PRIMARY KEY,  -- files.string_id (row may not exist yet)
+    bucket_id TEXT NOT NULL REFERENCES buckets(id) ON DELETE CASCADE,
+    note TEXT NOT NULL,
+    updated_at INTEGER NOT NULL  -- Unix timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_notes_bucket_id ON file_notes(bucket_id);
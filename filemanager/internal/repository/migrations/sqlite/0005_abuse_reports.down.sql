@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS blocked_hashes;
+DROP TABLE IF EXISTS abuse_reports;
+DROP INDEX IF EXISTS idx_files_sha256;
+ALTER TABLE files DROP COLUMN sha256;
+ALTER TABLE buckets DROP COLUMN removal_reason;
+ALTER TABLE buckets DROP COLUMN removed_at;
@@ -0,0 +1,34 @@
+-- Abuse reports and takedowns. A taken down bucket keeps status 'removed' with its files as
+-- evidence until the retention period ends, and the content hashes of its files are blocked.
+ALTER TABLE buckets ADD COLUMN removed_at INTEGER;  -- Unix timestamp of the takedown
+ALTER TABLE buckets ADD COLUMN removal_reason TEXT;
+
+ALTER TABLE files ADD COLUMN sha256 TEXT;  -- Hex content hash, computed at ConfirmUpload (NULL for older files)
+
+CREATE INDEX IF NOT EXISTS idx_files_sha256 ON files(sha256);
+
+-- Reports from recipients, queued for platform moderators
+CREATE TABLE IF NOT EXISTS abuse_reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    bucket_id TEXT NOT NULL REFERENCES buckets(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,  -- Category, e.g. 'malware'
+    details TEXT NOT NULL DEFAULT '',
+    reporter_id TEXT,  -- Reference to users table in auth database, NULL if anonymous
+    reporter_email TEXT,  -- Contact address given by an anonymous reporter
+    status TEXT NOT NULL DEFAULT 'open',  -- 'open', 'actioned' or 'dismissed'
+    resolved_by TEXT,  -- Moderator user id
+    resolution_note TEXT,
+    created_at INTEGER NOT NULL,  -- Unix timestamp
+    resolved_at INTEGER  -- Unix timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_abuse_reports_status ON abuse_reports(status);
+CREATE INDEX IF NOT EXISTS idx_abuse_reports_bucket_id ON abuse_reports(bucket_id);
+
+-- Content refused by ConfirmUpload, kept after the removed bucket is purged
+CREATE TABLE IF NOT EXISTS blocked_hashes (
+    sha256 TEXT PRIMARY KEY,
+    bucket_id TEXT NOT NULL,  -- Bucket whose takedown blocked it (no FK, outlives the bucket)
+    reason TEXT NOT NULL,
+    created_at INTEGER NOT NULL  -- Unix timestamp
+);
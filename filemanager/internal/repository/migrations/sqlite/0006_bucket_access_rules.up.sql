@@ -0,0 +1,4 @@
+-- Optional restrictions on who may fetch a bucket, comma-separated, NULL for no restriction
+ALTER TABLE buckets ADD COLUMN allowed_cidrs TEXT;  -- e.g. '10.0.0.0/8,203.0.113.7/32'
+ALTER TABLE buckets ADD COLUMN allowed_referrers TEXT;  -- Hosts, e.g. 'intranet.example.com,*.example.org'
+ALTER TABLE buckets ADD COLUMN allowed_countries TEXT;  -- ISO 3166-1 alpha-2 codes, e.g. 'DE,FR'
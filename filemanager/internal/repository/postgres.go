@@ -0,0 +1,649 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	commonrepo "github.com/cthulhu-platform/common/pkg/repository"
+	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/pgdb"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresRepository implements Repository on PostgreSQL via the pgx database/sql driver.
+// sqlc generates pgdb models with the same shape as the SQLite db models, so rows are
+// converted directly and callers keep using the db package types.
+type postgresRepository struct {
+	db *sql.DB
+	tx *sql.Tx // set on the Repository passed to WithTx callbacks
+}
+
+func NewPostgresRepository(ctx context.Context, dsn string) (*postgresRepository, error) {
+	conn, err := openPostgres(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply pending schema migrations
+	if err := autoMigrate(ctx, conn, commonrepo.DialectPostgres); err != nil {
+		log.Printf("Failed to migrate database schema: %v\n", err)
+		conn.Close()
+		return nil, err
+	}
+
+	log.Println("PostgreSQL database initialized successfully")
+
+	r := &postgresRepository{db: conn}
+	return r, nil
+}
+
+// openPostgres opens and pings the PostgreSQL connection pool without touching the schema.
+func openPostgres(ctx context.Context, dsn string) (*sql.DB, error) {
+	// Create timeout context
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Open PostgreSQL connection pool
+	conn, err := sql.Open("pgx", dsn)
+	if err != nil {
+		log.Printf("Failed to open PostgreSQL database: %v\n", err)
+		return nil, err
+	}
+
+	// Ping database to ensure connection is established
+	if err := conn.PingContext(ctx); err != nil {
+		log.Printf("Failed to ping PostgreSQL database: %v\n", err)
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (r *postgresRepository) Close() error {
+	if r.tx != nil {
+		// The pool belongs to the outer repository
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *postgresRepository) WithTx(ctx context.Context, fn func(tx Repository) error) error {
+	return r.withTx(ctx, func(tx *postgresRepository) error { return fn(tx) })
+}
+
+// withTx runs fn on a copy of r bound to a new transaction, or on r itself when r is already
+// inside one, so nested calls join the outer transaction.
+func (r *postgresRepository) withTx(ctx context.Context, fn func(tx *postgresRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(&postgresRepository{db: r.db, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// queries returns sqlc queries bound to the current transaction, if any.
+func (r *postgresRepository) queries() *pgdb.Queries {
+	q := pgdb.New(r.db)
+	if r.tx != nil {
+		return q.WithTx(r.tx)
+	}
+	return q
+}
+
+// Implement Repository interface
+
+// Bucket operations
+func (r *postgresRepository) GetBucketByID(ctx context.Context, id string) (*db.Bucket, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	bucket, err := r.queries().GetBucketByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := db.Bucket(bucket)
+	return &out, nil
+}
+
+func (r *postgresRepository) CreateBucket(ctx context.Context, bucket *db.Bucket) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().CreateBucket(ctx, pgdb.CreateBucketParams{
+		ID:           bucket.ID,
+		PasswordHash: bucket.PasswordHash,
+		Title:        bucket.Title,
+		Description:  bucket.Description,
+		OrgID:        bucket.OrgID,
+		CreatedAt:    bucket.CreatedAt,
+		UpdatedAt:    bucket.UpdatedAt,
+	})
+}
+
+func (r *postgresRepository) UpdateBucket(ctx context.Context, bucket *db.Bucket) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().UpdateBucket(ctx, pgdb.UpdateBucketParams{
+		ID:           bucket.ID,
+		PasswordHash: bucket.PasswordHash,
+		UpdatedAt:    bucket.UpdatedAt,
+	})
+}
+
+func (r *postgresRepository) DeleteBucket(ctx context.Context, id string) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().DeleteBucket(ctx, id)
+}
+
+func (r *postgresRepository) ListBuckets(ctx context.Context, limit int, offset int) ([]*db.Bucket, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListBuckets(ctx, pgdb.ListBucketsParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pgBuckets(list), nil
+}
+
+func (r *postgresRepository) SearchBuckets(ctx context.Context, pattern, status, orgID string, limit int, offset int) ([]db.SearchBucketsRow, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().SearchBuckets(ctx, pgdb.SearchBucketsParams{
+		Pattern: pattern,
+		Status:  status,
+		OrgID:   orgID,
+		MaxRows: int32(limit),
+		Skip:    int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]db.SearchBucketsRow, len(list))
+	for i, row := range list {
+		out[i] = db.SearchBucketsRow(row)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) UpdateBucketDetails(ctx context.Context, id string, title, description sql.NullString) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().UpdateBucketDetails(ctx, pgdb.UpdateBucketDetailsParams{
+		Title:       title,
+		Description: description,
+		UpdatedAt:   time.Now().Unix(),
+		ID:          id,
+	})
+}
+
+func (r *postgresRepository) UpdateBucketAccessRules(ctx context.Context, id string, cidrs, referrers, countries sql.NullString) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().UpdateBucketAccessRules(ctx, pgdb.UpdateBucketAccessRulesParams{
+		AllowedCidrs:     cidrs,
+		AllowedReferrers: referrers,
+		AllowedCountries: countries,
+		UpdatedAt:        time.Now().Unix(),
+		ID:               id,
+	})
+}
+
+func (r *postgresRepository) MarkBucketDeleting(ctx context.Context, id, from string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	now := time.Now().Unix()
+	n, err := r.queries().MarkBucketDeleting(ctx, pgdb.MarkBucketDeletingParams{
+		DeletingAt: sql.NullInt64{Int64: now, Valid: true},
+		UpdatedAt:  now,
+		ID:         id,
+		FromStatus: from,
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) ListBucketsByStatus(ctx context.Context, status string, limit int) ([]*db.Bucket, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListBucketsByStatus(ctx, pgdb.ListBucketsByStatusParams{
+		Status: status,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pgBuckets(list), nil
+}
+
+func (r *postgresRepository) SetBucketOrganization(ctx context.Context, id string, orgID sql.NullString) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().SetBucketOrganization(ctx, pgdb.SetBucketOrganizationParams{
+		OrgID:     orgID,
+		UpdatedAt: time.Now().Unix(),
+		ID:        id,
+	})
+}
+
+func (r *postgresRepository) ClearOrganizationBuckets(ctx context.Context, orgID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().ClearOrganizationBuckets(ctx, pgdb.ClearOrganizationBucketsParams{
+		UpdatedAt: time.Now().Unix(),
+		OrgID:     sql.NullString{String: orgID, Valid: true},
+	})
+}
+
+func (r *postgresRepository) GetOrganizationUsage(ctx context.Context, orgID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().GetOrganizationUsage(ctx, sql.NullString{String: orgID, Valid: true})
+}
+
+// File operations
+func (r *postgresRepository) GetFileByID(ctx context.Context, id int64) (*db.File, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	file, err := r.queries().GetFileByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := db.File(file)
+	return &out, nil
+}
+
+func (r *postgresRepository) GetFileByStringID(ctx context.Context, stringID string) (*db.File, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	file, err := r.queries().GetFileByStringID(ctx, stringID)
+	if err != nil {
+		return nil, err
+	}
+	out := db.File(file)
+	return &out, nil
+}
+
+func (r *postgresRepository) GetFileByBucketIDAndStringID(ctx context.Context, bucketID, stringID string) (*db.File, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	file, err := r.queries().GetFileByBucketIDAndStringID(ctx, pgdb.GetFileByBucketIDAndStringIDParams{
+		BucketID: bucketID,
+		StringID: stringID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := db.File(file)
+	return &out, nil
+}
+
+func (r *postgresRepository) GetFilesByBucketID(ctx context.Context, bucketID string) ([]*db.File, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().GetFilesByBucketID(ctx, bucketID)
+	if err != nil {
+		return nil, err
+	}
+	return pgFiles(list), nil
+}
+
+func (r *postgresRepository) CreateFile(ctx context.Context, file *db.File) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	_, err := r.queries().CreateFile(ctx, pgdb.CreateFileParams{
+		StringID:     file.StringID,
+		BucketID:     file.BucketID,
+		OriginalName: file.OriginalName,
+		OwnerID:      file.OwnerID,
+		Size:         file.Size,
+		ContentType:  file.ContentType,
+		S3Key:        file.S3Key,
+		Sha256:       file.Sha256,
+		CreatedAt:    file.CreatedAt,
+	})
+	return err
+}
+
+func (r *postgresRepository) UpdateFile(ctx context.Context, file *db.File) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().UpdateFile(ctx, pgdb.UpdateFileParams{
+		OriginalName: file.OriginalName,
+		OwnerID:      file.OwnerID,
+		StringID:     file.StringID,
+	})
+}
+
+func (r *postgresRepository) DeleteFile(ctx context.Context, id int64) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	// Lookup and delete run in one transaction so a concurrent writer cannot slip in between
+	return r.withTx(ctx, func(tx *postgresRepository) error {
+		q := tx.queries()
+		file, err := q.GetFileByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return q.DeleteFile(ctx, file.StringID)
+	})
+}
+
+func (r *postgresRepository) ListFiles(ctx context.Context, limit int, offset int) ([]*db.File, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListFiles(ctx, pgdb.ListFilesParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pgFiles(list), nil
+}
+
+func (r *postgresRepository) ListFilesAfterID(ctx context.Context, afterID int64, limit int) ([]*db.File, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListFilesAfterID(ctx, pgdb.ListFilesAfterIDParams{
+		ID:    afterID,
+		Limit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pgFiles(list), nil
+}
+
+func (r *postgresRepository) GetFileByS3Key(ctx context.Context, s3Key string) (*db.File, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	file, err := r.queries().GetFileByS3Key(ctx, s3Key)
+	if err != nil {
+		return nil, err
+	}
+	out := db.File(file)
+	return &out, nil
+}
+
+func (r *postgresRepository) ListFileHashesByBucketID(ctx context.Context, bucketID string) ([]string, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListFileHashesByBucketID(ctx, bucketID)
+	if err != nil {
+		return nil, err
+	}
+	return validStrings(list), nil
+}
+
+// File note operations
+func (r *postgresRepository) SetFileNote(ctx context.Context, bucketID, stringID, note string) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	if note == "" {
+		return r.queries().DeleteFileNote(ctx, stringID)
+	}
+	return r.queries().UpsertFileNote(ctx, pgdb.UpsertFileNoteParams{
+		StringID:  stringID,
+		BucketID:  bucketID,
+		Note:      note,
+		UpdatedAt: time.Now().Unix(),
+	})
+}
+
+func (r *postgresRepository) GetFileNotesByBucketID(ctx context.Context, bucketID string) ([]*db.FileNote, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().GetFileNotesByBucketID(ctx, bucketID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*db.FileNote, 0, len(list))
+	for i := range list {
+		n := db.FileNote(list[i])
+		out = append(out, &n)
+	}
+	return out, nil
+}
+
+// Bucket admin operations
+func (r *postgresRepository) AddBucketAdmin(ctx context.Context, bucketAdmin *db.BucketAdmin) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().AddBucketAdmin(ctx, pgdb.AddBucketAdminParams{
+		UserID:    bucketAdmin.UserID,
+		BucketID:  bucketAdmin.BucketID,
+		CreatedAt: bucketAdmin.CreatedAt,
+	})
+}
+
+func (r *postgresRepository) RemoveBucketAdmin(ctx context.Context, userID string, bucketID string) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().RemoveBucketAdmin(ctx, pgdb.RemoveBucketAdminParams{
+		UserID:   userID,
+		BucketID: bucketID,
+	})
+}
+
+func (r *postgresRepository) GetBucketAdminsByBucketID(ctx context.Context, bucketID string) ([]*db.BucketAdmin, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().GetBucketAdminsByBucketID(ctx, bucketID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*db.BucketAdmin, 0, len(list))
+	for i := range list {
+		a := db.BucketAdmin(list[i])
+		out = append(out, &a)
+	}
+	return out, nil
+}
+
+func (r *postgresRepository) GetBucketsByAdminUserID(ctx context.Context, userID string) ([]*db.Bucket, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().GetBucketsByAdminUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgBuckets(list), nil
+}
+
+func (r *postgresRepository) IsBucketAdmin(ctx context.Context, userID string, bucketID string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	v, err := r.queries().IsBucketAdmin(ctx, pgdb.IsBucketAdminParams{
+		UserID:   userID,
+		BucketID: bucketID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return v == 1, nil
+}
+
+func (r *postgresRepository) ListSoleAdminBucketIDs(ctx context.Context, userID string) ([]string, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().ListSoleAdminBucketIDs(ctx, userID)
+}
+
+func (r *postgresRepository) RemoveUserBucketAdmins(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().RemoveUserBucketAdmins(ctx, userID)
+}
+
+func (r *postgresRepository) ClearFilesOwner(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().ClearFilesOwner(ctx, sql.NullString{String: userID, Valid: true})
+}
+
+// Abuse report operations
+func (r *postgresRepository) CreateAbuseReport(ctx context.Context, report *db.AbuseReport) (*db.AbuseReport, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	created, err := r.queries().CreateAbuseReport(ctx, pgdb.CreateAbuseReportParams{
+		BucketID:      report.BucketID,
+		Reason:        report.Reason,
+		Details:       report.Details,
+		ReporterID:    report.ReporterID,
+		ReporterEmail: report.ReporterEmail,
+		CreatedAt:     report.CreatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := db.AbuseReport(created)
+	return &out, nil
+}
+
+func (r *postgresRepository) GetAbuseReport(ctx context.Context, id int64) (*db.AbuseReport, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	report, err := r.queries().GetAbuseReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := db.AbuseReport(report)
+	return &out, nil
+}
+
+func (r *postgresRepository) ListAbuseReports(ctx context.Context, status, bucketID string, limit int, offset int) ([]*db.AbuseReport, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListAbuseReports(ctx, pgdb.ListAbuseReportsParams{
+		Status:   status,
+		BucketID: bucketID,
+		MaxRows:  int32(limit),
+		Skip:     int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pgReports(list), nil
+}
+
+func (r *postgresRepository) ResolveAbuseReport(ctx context.Context, id int64, status, moderatorID, note string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := r.queries().ResolveAbuseReport(ctx, pgdb.ResolveAbuseReportParams{
+		Status:         status,
+		ResolvedBy:     sql.NullString{String: moderatorID, Valid: moderatorID != ""},
+		ResolutionNote: sql.NullString{String: note, Valid: note != ""},
+		ResolvedAt:     sql.NullInt64{Int64: time.Now().Unix(), Valid: true},
+		ID:             id,
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) ResolveBucketAbuseReports(ctx context.Context, bucketID, moderatorID, note string) ([]*db.AbuseReport, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ResolveBucketAbuseReports(ctx, pgdb.ResolveBucketAbuseReportsParams{
+		ResolvedBy:     sql.NullString{String: moderatorID, Valid: moderatorID != ""},
+		ResolutionNote: sql.NullString{String: note, Valid: note != ""},
+		ResolvedAt:     sql.NullInt64{Int64: time.Now().Unix(), Valid: true},
+		BucketID:       bucketID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pgReports(list), nil
+}
+
+// Takedowns
+func (r *postgresRepository) MarkBucketRemoved(ctx context.Context, id, reason string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	now := time.Now().Unix()
+	n, err := r.queries().MarkBucketRemoved(ctx, pgdb.MarkBucketRemovedParams{
+		RemovedAt:     sql.NullInt64{Int64: now, Valid: true},
+		RemovalReason: sql.NullString{String: reason, Valid: reason != ""},
+		UpdatedAt:     now,
+		ID:            id,
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) ListRemovedBucketsBefore(ctx context.Context, before int64, limit int) ([]*db.Bucket, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListRemovedBucketsBefore(ctx, pgdb.ListRemovedBucketsBeforeParams{
+		RemovedAt: sql.NullInt64{Int64: before, Valid: true},
+		Limit:     int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pgBuckets(list), nil
+}
+
+func (r *postgresRepository) BlockHash(ctx context.Context, sha256, bucketID, reason string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := r.queries().BlockHash(ctx, pgdb.BlockHashParams{
+		Sha256:    sha256,
+		BucketID:  bucketID,
+		Reason:    reason,
+		CreatedAt: time.Now().Unix(),
+	})
+	return n > 0, err
+}
+
+func (r *postgresRepository) IsHashBlocked(ctx context.Context, sha256 string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	v, err := r.queries().IsHashBlocked(ctx, sha256)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return v == 1, nil
+}
+
+func pgBuckets(list []pgdb.Bucket) []*db.Bucket {
+	out := make([]*db.Bucket, 0, len(list))
+	for i := range list {
+		b := db.Bucket(list[i])
+		out = append(out, &b)
+	}
+	return out
+}
+
+func pgFiles(list []pgdb.File) []*db.File {
+	out := make([]*db.File, 0, len(list))
+	for i := range list {
+		f := db.File(list[i])
+		out = append(out, &f)
+	}
+	return out
+}
+
+func pgReports(list []pgdb.AbuseReport) []*db.AbuseReport {
+	out := make([]*db.AbuseReport, 0, len(list))
+	for i := range list {
+		r := db.AbuseReport(list[i])
+		out = append(out, &r)
+	}
+	return out
+}
@@ -2,19 +2,59 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 
+	internalpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
 	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/db"
 )
 
+// Bucket status values stored in buckets.status.
+const (
+	BucketStatusActive   = "active"
+	BucketStatusDeleting = "deleting" // S3 purge in progress, rows are removed once it completes
+	BucketStatusRemoved  = "removed"  // taken down for abuse, files kept as evidence until purged
+)
+
+// Abuse report status values stored in abuse_reports.status.
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusActioned  = "actioned" // the bucket was taken down
+	ReportStatusDismissed = "dismissed"
+)
+
 type Repository interface {
 	Close() error
 
+	// WithTx runs fn with a Repository bound to a single transaction, committing if fn returns
+	// nil and rolling back otherwise. Calls made on tx inside a nested WithTx join the same transaction.
+	WithTx(ctx context.Context, fn func(tx Repository) error) error
+
 	// Bucket operations
 	GetBucketByID(ctx context.Context, id string) (*db.Bucket, error)
 	CreateBucket(ctx context.Context, bucket *db.Bucket) error
 	UpdateBucket(ctx context.Context, bucket *db.Bucket) error
 	DeleteBucket(ctx context.Context, id string) error
 	ListBuckets(ctx context.Context, limit int, offset int) ([]*db.Bucket, error)
+	// SearchBuckets lists buckets newest first with their file count and size. pattern is a
+	// lowercase LIKE pattern matched against the id and title, status and orgID exact values;
+	// empty values match every bucket.
+	SearchBuckets(ctx context.Context, pattern, status, orgID string, limit int, offset int) ([]db.SearchBucketsRow, error)
+	// UpdateBucketDetails overwrites the bucket title and description (NULL clears them).
+	UpdateBucketDetails(ctx context.Context, id string, title, description sql.NullString) error
+	// UpdateBucketAccessRules overwrites the bucket's access rules (NULL removes a rule).
+	UpdateBucketAccessRules(ctx context.Context, id string, cidrs, referrers, countries sql.NullString) error
+	// MarkBucketDeleting moves a bucket in status from (BucketStatusActive or BucketStatusRemoved)
+	// to BucketStatusDeleting, returning false if it was not in that status.
+	MarkBucketDeleting(ctx context.Context, id, from string) (bool, error)
+	ListBucketsByStatus(ctx context.Context, status string, limit int) ([]*db.Bucket, error)
+
+	// Organization operations. Organizations live in the auth service, buckets only hold their id.
+	// SetBucketOrganization moves a bucket to an organization (NULL makes it personal again).
+	SetBucketOrganization(ctx context.Context, id string, orgID sql.NullString) error
+	// ClearOrganizationBuckets makes every bucket of a deleted organization personal again.
+	ClearOrganizationBuckets(ctx context.Context, orgID string) (int64, error)
+	// GetOrganizationUsage returns the total size of the files in the organization's buckets.
+	GetOrganizationUsage(ctx context.Context, orgID string) (int64, error)
 
 	// File operations
 	GetFileByID(ctx context.Context, id int64) (*db.File, error)
@@ -25,6 +65,17 @@ type Repository interface {
 	UpdateFile(ctx context.Context, file *db.File) error
 	DeleteFile(ctx context.Context, id int64) error
 	ListFiles(ctx context.Context, limit int, offset int) ([]*db.File, error)
+	// ListFilesAfterID returns up to limit files with id > afterID in id order (keyset pagination).
+	ListFilesAfterID(ctx context.Context, afterID int64, limit int) ([]*db.File, error)
+	GetFileByS3Key(ctx context.Context, s3Key string) (*db.File, error)
+
+	// ListFileHashesByBucketID returns the distinct content hashes of the bucket's files.
+	ListFileHashesByBucketID(ctx context.Context, bucketID string) ([]string, error)
+
+	// File note operations. Notes are keyed by string_id and may precede the files row
+	// (they are written at PrepareUpload). An empty note deletes it.
+	SetFileNote(ctx context.Context, bucketID, stringID, note string) error
+	GetFileNotesByBucketID(ctx context.Context, bucketID string) ([]*db.FileNote, error)
 
 	// Bucket admin operations
 	AddBucketAdmin(ctx context.Context, bucketAdmin *db.BucketAdmin) error
@@ -32,4 +83,45 @@ type Repository interface {
 	GetBucketAdminsByBucketID(ctx context.Context, bucketID string) ([]*db.BucketAdmin, error)
 	GetBucketsByAdminUserID(ctx context.Context, userID string) ([]*db.Bucket, error)
 	IsBucketAdmin(ctx context.Context, userID string, bucketID string) (bool, error)
+	// ListSoleAdminBucketIDs returns the personal buckets the user is the only admin of.
+	ListSoleAdminBucketIDs(ctx context.Context, userID string) ([]string, error)
+	RemoveUserBucketAdmins(ctx context.Context, userID string) (int64, error)
+	// ClearFilesOwner sets owner_id to NULL on the user's files.
+	ClearFilesOwner(ctx context.Context, userID string) (int64, error)
+
+	// Abuse report operations
+	CreateAbuseReport(ctx context.Context, report *db.AbuseReport) (*db.AbuseReport, error)
+	GetAbuseReport(ctx context.Context, id int64) (*db.AbuseReport, error)
+	// ListAbuseReports lists reports oldest first; empty status and bucketID match every report.
+	ListAbuseReports(ctx context.Context, status, bucketID string, limit int, offset int) ([]*db.AbuseReport, error)
+	// ResolveAbuseReport closes an open report with status, returning false if it was not open.
+	ResolveAbuseReport(ctx context.Context, id int64, status, moderatorID, note string) (bool, error)
+	// ResolveBucketAbuseReports marks the bucket's open reports actioned and returns them.
+	ResolveBucketAbuseReports(ctx context.Context, bucketID, moderatorID, note string) ([]*db.AbuseReport, error)
+
+	// Takedowns. MarkBucketRemoved moves an active bucket to BucketStatusRemoved, returning false if
+	// it was not active.
+	MarkBucketRemoved(ctx context.Context, id, reason string) (bool, error)
+	// ListRemovedBucketsBefore returns buckets taken down before the Unix time, oldest first.
+	ListRemovedBucketsBefore(ctx context.Context, before int64, limit int) ([]*db.Bucket, error)
+	// BlockHash refuses a content hash at ConfirmUpload, returning false if it already was.
+	BlockHash(ctx context.Context, sha256, bucketID, reason string) (bool, error)
+	IsHashBlocked(ctx context.Context, sha256 string) (bool, error)
+}
+
+// NewRepository returns the PostgreSQL repository when POSTGRES_DSN is set, otherwise the SQLite repository.
+func NewRepository(ctx context.Context) (Repository, error) {
+	// Return the concrete repositories only on success: a nil pointer in the interface is not nil
+	if internalpkg.POSTGRES_DSN != "" {
+		repo, err := NewPostgresRepository(ctx, internalpkg.POSTGRES_DSN)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+	repo, err := NewSQLiteRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
 }
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/db"
+)
+
+// repositoryTests are the behaviours both backends must share.
+var repositoryTests = []struct {
+	name string
+	run  func(t *testing.T, r Repository)
+}{
+	{"buckets and files", testBucketsAndFiles},
+	{"transaction commit", testWithTxCommit},
+	{"transaction rollback", testWithTxRollback},
+	{"bucket deletion states", testBucketDeletionStates},
+	{"takedown", testTakedown},
+}
+
+func TestRepository(t *testing.T) {
+	for _, tc := range repositoryTests {
+		t.Run(tc.name, func(t *testing.T) {
+			forEachBackend(t, tc.run)
+		})
+	}
+}
+
+func mustCreateBucket(t *testing.T, r Repository, id string) {
+	t.Helper()
+	now := time.Now().Unix()
+	if err := r.CreateBucket(context.Background(), &db.Bucket{ID: id, CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("create bucket %s: %v", id, err)
+	}
+}
+
+func mustCreateFile(t *testing.T, r Repository, bucketID, stringID string) {
+	t.Helper()
+	err := r.CreateFile(context.Background(), &db.File{
+		StringID:     stringID,
+		BucketID:     bucketID,
+		OriginalName: stringID + ".txt",
+		Size:         42,
+		ContentType:  "text/plain",
+		S3Key:        bucketID + "/" + stringID,
+		Sha256:       sql.NullString{String: "sha-" + stringID, Valid: true},
+		CreatedAt:    time.Now().Unix(),
+	})
+	if err != nil {
+		t.Fatalf("create file %s: %v", stringID, err)
+	}
+}
+
+func testBucketsAndFiles(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustCreateBucket(t, r, "b1")
+	b, err := r.GetBucketByID(ctx, "b1")
+	if err != nil || b.Status != BucketStatusActive || b.PasswordHash.Valid {
+		t.Fatalf("get bucket = %+v, %v", b, err)
+	}
+	if err := r.CreateBucket(ctx, &db.Bucket{ID: "b1"}); err == nil {
+		t.Fatal("creating a bucket with a taken id succeeded")
+	}
+
+	b.PasswordHash = sql.NullString{String: "hash", Valid: true}
+	b.UpdatedAt++
+	if err := r.UpdateBucket(ctx, b); err != nil {
+		t.Fatalf("update bucket: %v", err)
+	}
+	title := sql.NullString{String: "Holiday", Valid: true}
+	if err := r.UpdateBucketDetails(ctx, "b1", title, sql.NullString{}); err != nil {
+		t.Fatalf("update details: %v", err)
+	}
+	if b, _ := r.GetBucketByID(ctx, "b1"); b.PasswordHash.String != "hash" || b.Title != title {
+		t.Fatalf("after update = %+v", b)
+	}
+
+	mustCreateFile(t, r, "b1", "f1")
+	mustCreateFile(t, r, "b1", "f2")
+	files, err := r.GetFilesByBucketID(ctx, "b1")
+	if err != nil || len(files) != 2 {
+		t.Fatalf("files = %v, %v", files, err)
+	}
+	f, err := r.GetFileByBucketIDAndStringID(ctx, "b1", "f1")
+	if err != nil || f.S3Key != "b1/f1" || f.Sha256.String != "sha-f1" {
+		t.Fatalf("file = %+v, %v", f, err)
+	}
+	if hashes, err := r.ListFileHashesByBucketID(ctx, "b1"); err != nil || len(hashes) != 2 {
+		t.Fatalf("hashes = %v, %v", hashes, err)
+	}
+	if err := r.DeleteFile(ctx, f.ID); err != nil {
+		t.Fatalf("delete file: %v", err)
+	}
+	if _, err := r.GetFileByID(ctx, f.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("deleted file: err = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := r.AddBucketAdmin(ctx, &db.BucketAdmin{UserID: "u1", BucketID: "b1", CreatedAt: time.Now().Unix()}); err != nil {
+		t.Fatalf("add admin: %v", err)
+	}
+	if ok, err := r.IsBucketAdmin(ctx, "u1", "b1"); err != nil || !ok {
+		t.Fatalf("is admin = %v, %v", ok, err)
+	}
+	if ok, err := r.IsBucketAdmin(ctx, "u2", "b1"); err != nil || ok {
+		t.Fatalf("is admin of another user = %v, %v", ok, err)
+	}
+}
+
+func testWithTxCommit(t *testing.T, r Repository) {
+	ctx := context.Background()
+	err := r.WithTx(ctx, func(tx Repository) error {
+		mustCreateBucket(t, tx, "b1")
+		// A nested WithTx joins the outer transaction and sees its writes
+		return tx.WithTx(ctx, func(inner Repository) error {
+			if _, err := inner.GetBucketByID(ctx, "b1"); err != nil {
+				return err
+			}
+			mustCreateFile(t, inner, "b1", "f1")
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("with tx: %v", err)
+	}
+	if _, err := r.GetBucketByID(ctx, "b1"); err != nil {
+		t.Fatalf("bucket after commit: %v", err)
+	}
+	if files, _ := r.GetFilesByBucketID(ctx, "b1"); len(files) != 1 {
+		t.Fatalf("files after commit = %d, want 1", len(files))
+	}
+}
+
+func testWithTxRollback(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustCreateBucket(t, r, "b1")
+	errAbort := errors.New("abort")
+	err := r.WithTx(ctx, func(tx Repository) error {
+		mustCreateBucket(t, tx, "b2")
+		mustCreateFile(t, tx, "b1", "f1")
+		if _, err := tx.MarkBucketDeleting(ctx, "b1", BucketStatusActive); err != nil {
+			return err
+		}
+		return tx.WithTx(ctx, func(inner Repository) error { return errAbort })
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("with tx: err = %v, want %v", err, errAbort)
+	}
+	if _, err := r.GetBucketByID(ctx, "b2"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("bucket after rollback: err = %v, want sql.ErrNoRows", err)
+	}
+	if files, _ := r.GetFilesByBucketID(ctx, "b1"); len(files) != 0 {
+		t.Fatalf("files after rollback = %d, want 0", len(files))
+	}
+	if b, _ := r.GetBucketByID(ctx, "b1"); b.Status != BucketStatusActive {
+		t.Fatalf("status after rollback = %q", b.Status)
+	}
+}
+
+// testBucketDeletionStates walks the repository side of DeleteBucket: active or removed buckets
+// are marked deleting only from the status the caller expects, stay listed as such until their
+// rows are deleted, and the rows cascade.
+func testBucketDeletionStates(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustCreateBucket(t, r, "b1")
+	mustCreateBucket(t, r, "b2")
+	mustCreateFile(t, r, "b1", "f1")
+	if err := r.AddBucketAdmin(ctx, &db.BucketAdmin{UserID: "u1", BucketID: "b1", CreatedAt: time.Now().Unix()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An active bucket is not marked as a removed one
+	if ok, err := r.MarkBucketDeleting(ctx, "b1", BucketStatusRemoved); err != nil || ok {
+		t.Fatalf("mark active bucket deleting from removed = %v, %v", ok, err)
+	}
+	if ok, err := r.MarkBucketDeleting(ctx, "b1", BucketStatusActive); err != nil || !ok {
+		t.Fatalf("mark deleting = %v, %v", ok, err)
+	}
+	b, _ := r.GetBucketByID(ctx, "b1")
+	if b.Status != BucketStatusDeleting || !b.DeletingAt.Valid {
+		t.Fatalf("after mark = %+v", b)
+	}
+	// Marking again is refused and keeps the original time
+	if ok, err := r.MarkBucketDeleting(ctx, "b1", BucketStatusActive); err != nil || ok {
+		t.Fatalf("mark deleting again = %v, %v", ok, err)
+	}
+	if again, _ := r.GetBucketByID(ctx, "b1"); again.DeletingAt != b.DeletingAt {
+		t.Fatalf("deleting_at moved from %v to %v", b.DeletingAt, again.DeletingAt)
+	}
+	// A deleting bucket cannot be taken down
+	if ok, err := r.MarkBucketRemoved(ctx, "b1", "spam"); err != nil || ok {
+		t.Fatalf("take down deleting bucket = %v, %v", ok, err)
+	}
+
+	deleting, err := r.ListBucketsByStatus(ctx, BucketStatusDeleting, 10)
+	if err != nil || len(deleting) != 1 || deleting[0].ID != "b1" {
+		t.Fatalf("deleting buckets = %v, %v", deleting, err)
+	}
+
+	if err := r.DeleteBucket(ctx, "b1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := r.GetBucketByID(ctx, "b1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("deleted bucket: err = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := r.GetFileByS3Key(ctx, "b1/f1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("file of deleted bucket: err = %v, want sql.ErrNoRows", err)
+	}
+	if admins, _ := r.GetBucketAdminsByBucketID(ctx, "b1"); len(admins) != 0 {
+		t.Fatalf("admins of deleted bucket = %v", admins)
+	}
+	if deleting, _ := r.ListBucketsByStatus(ctx, BucketStatusDeleting, 10); len(deleting) != 0 {
+		t.Fatalf("deleting buckets after delete = %v", deleting)
+	}
+
+	// Removed buckets can be deleted too, when their evidence is purged
+	if ok, err := r.MarkBucketRemoved(ctx, "b2", "spam"); err != nil || !ok {
+		t.Fatalf("take down = %v, %v", ok, err)
+	}
+	// A bucket taken down after DeleteBucket read it as active is not marked
+	if ok, err := r.MarkBucketDeleting(ctx, "b2", BucketStatusActive); err != nil || ok {
+		t.Fatalf("mark removed bucket deleting from active = %v, %v", ok, err)
+	}
+	if ok, err := r.MarkBucketDeleting(ctx, "b2", BucketStatusRemoved); err != nil || !ok {
+		t.Fatalf("mark removed bucket deleting = %v, %v", ok, err)
+	}
+	if b, _ := r.GetBucketByID(ctx, "b2"); b.Status != BucketStatusDeleting {
+		t.Fatalf("removed bucket status after mark = %q", b.Status)
+	}
+}
+
+func testTakedown(t *testing.T, r Repository) {
+	ctx := context.Background()
+	mustCreateBucket(t, r, "b1")
+	report, err := r.CreateAbuseReport(ctx, &db.AbuseReport{
+		BucketID:  "b1",
+		Reason:    "spam",
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil || report.ID == 0 || report.Status != ReportStatusOpen {
+		t.Fatalf("create report = %+v, %v", report, err)
+	}
+
+	if ok, err := r.MarkBucketRemoved(ctx, "b1", "spam"); err != nil || !ok {
+		t.Fatalf("take down = %v, %v", ok, err)
+	}
+	if ok, err := r.MarkBucketRemoved(ctx, "b1", "spam"); err != nil || ok {
+		t.Fatalf("take down twice = %v, %v", ok, err)
+	}
+	if removed, err := r.ListRemovedBucketsBefore(ctx, time.Now().Unix()+1, 10); err != nil || len(removed) != 1 {
+		t.Fatalf("removed buckets = %v, %v", removed, err)
+	}
+
+	if ok, err := r.BlockHash(ctx, "sha", "b1", "spam"); err != nil || !ok {
+		t.Fatalf("block = %v, %v", ok, err)
+	}
+	if ok, err := r.BlockHash(ctx, "sha", "b1", "spam"); err != nil || ok {
+		t.Fatalf("block twice = %v, %v", ok, err)
+	}
+	if ok, err := r.IsHashBlocked(ctx, "sha"); err != nil || !ok {
+		t.Fatalf("is blocked = %v, %v", ok, err)
+	}
+
+	resolved, err := r.ResolveBucketAbuseReports(ctx, "b1", "mod", "removed")
+	if err != nil || len(resolved) != 1 || resolved[0].Status != ReportStatusActioned {
+		t.Fatalf("resolve = %+v, %v", resolved, err)
+	}
+	if ok, err := r.ResolveAbuseReport(ctx, report.ID, ReportStatusDismissed, "mod", ""); err != nil || ok {
+		t.Fatalf("dismiss resolved report = %v, %v", ok, err)
+	}
+}
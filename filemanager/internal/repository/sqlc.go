@@ -3,27 +3,44 @@ package repository
 import (
 	"context"
 	"database/sql"
-	_ "embed"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
+	commonrepo "github.com/cthulhu-platform/common/pkg/repository"
 	internalpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
 	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/db"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed sqlc/schema.sql
-var schemaSQL string
-
 type sqliteRepository struct {
 	db *sql.DB
+	tx *sql.Tx // set on the Repository passed to WithTx callbacks
 }
 
 func NewSQLiteRepository(ctx context.Context) (*sqliteRepository, error) {
+	db, err := openSQLite(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply pending schema migrations
+	if err := autoMigrate(ctx, db, commonrepo.DialectSQLite); err != nil {
+		log.Printf("Failed to migrate database schema: %v\n", err)
+		db.Close()
+		return nil, err
+	}
+
+	log.Println("SQLite database initialized successfully")
+
+	r := &sqliteRepository{db: db}
+	return r, nil
+}
+
+// openSQLite opens and pings the SQLite database without touching the schema.
+func openSQLite(ctx context.Context) (*sql.DB, error) {
 	// Create timeout context
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -38,8 +55,10 @@ func NewSQLiteRepository(ctx context.Context) (*sqliteRepository, error) {
 		}
 	}
 
-	// Open SQLite database connection
-	db, err := sql.Open("sqlite3", path)
+	// Open SQLite database connection. Foreign keys are a per-connection setting in SQLite,
+	// so enable them in the DSN to cover every pooled connection. Transactions take the
+	// write lock up front (BEGIN IMMEDIATE) so WithTx callers never fail on lock upgrade.
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on&_txlock=immediate")
 	if err != nil {
 		log.Printf("Failed to open SQLite database: %v\n", err)
 		return nil, err
@@ -52,21 +71,45 @@ func NewSQLiteRepository(ctx context.Context) (*sqliteRepository, error) {
 		return nil, err
 	}
 
-	// Initialize database schema
-	if err := runSchema(ctx, db, schemaSQL); err != nil {
-		log.Printf("Failed to initialize database schema: %v\n", err)
-		db.Close()
-		return nil, err
+	return db, nil
+}
+
+func (r *sqliteRepository) Close() error {
+	if r.tx != nil {
+		// The pool belongs to the outer repository
+		return nil
 	}
+	return r.db.Close()
+}
 
-	log.Println("SQLite database initialized successfully")
+func (r *sqliteRepository) WithTx(ctx context.Context, fn func(tx Repository) error) error {
+	return r.withTx(ctx, func(tx *sqliteRepository) error { return fn(tx) })
+}
 
-	r := &sqliteRepository{db: db}
-	return r, nil
+// withTx runs fn on a copy of r bound to a new transaction, or on r itself when r is already
+// inside one, so nested calls join the outer transaction.
+func (r *sqliteRepository) withTx(ctx context.Context, fn func(tx *sqliteRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(&sqliteRepository{db: r.db, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r *sqliteRepository) Close() error {
-	return r.db.Close()
+// queries returns sqlc queries bound to the current transaction, if any.
+func (r *sqliteRepository) queries() *db.Queries {
+	q := db.New(r.db)
+	if r.tx != nil {
+		return q.WithTx(r.tx)
+	}
+	return q
 }
 
 // Implement Repository interface
@@ -75,7 +118,7 @@ func (r *sqliteRepository) Close() error {
 func (r *sqliteRepository) GetBucketByID(ctx context.Context, id string) (*db.Bucket, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	bucket, err := db.New(r.db).GetBucketByID(ctx, id)
+	bucket, err := r.queries().GetBucketByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -85,9 +128,12 @@ func (r *sqliteRepository) GetBucketByID(ctx context.Context, id string) (*db.Bu
 func (r *sqliteRepository) CreateBucket(ctx context.Context, bucket *db.Bucket) error {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	return db.New(r.db).CreateBucket(ctx, db.CreateBucketParams{
+	return r.queries().CreateBucket(ctx, db.CreateBucketParams{
 		ID:           bucket.ID,
 		PasswordHash: bucket.PasswordHash,
+		Title:        bucket.Title,
+		Description:  bucket.Description,
+		OrgID:        bucket.OrgID,
 		CreatedAt:    bucket.CreatedAt,
 		UpdatedAt:    bucket.UpdatedAt,
 	})
@@ -96,7 +142,7 @@ func (r *sqliteRepository) CreateBucket(ctx context.Context, bucket *db.Bucket)
 func (r *sqliteRepository) UpdateBucket(ctx context.Context, bucket *db.Bucket) error {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	return db.New(r.db).UpdateBucket(ctx, db.UpdateBucketParams{
+	return r.queries().UpdateBucket(ctx, db.UpdateBucketParams{
 		ID:           bucket.ID,
 		PasswordHash: bucket.PasswordHash,
 		UpdatedAt:    bucket.UpdatedAt,
@@ -106,13 +152,13 @@ func (r *sqliteRepository) UpdateBucket(ctx context.Context, bucket *db.Bucket)
 func (r *sqliteRepository) DeleteBucket(ctx context.Context, id string) error {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	return db.New(r.db).DeleteBucket(ctx, id)
+	return r.queries().DeleteBucket(ctx, id)
 }
 
 func (r *sqliteRepository) ListBuckets(ctx context.Context, limit int, offset int) ([]*db.Bucket, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	list, err := db.New(r.db).ListBuckets(ctx, db.ListBucketsParams{
+	list, err := r.queries().ListBuckets(ctx, db.ListBucketsParams{
 		Limit:  int64(limit),
 		Offset: int64(offset),
 	})
@@ -127,11 +173,102 @@ func (r *sqliteRepository) ListBuckets(ctx context.Context, limit int, offset in
 	return out, nil
 }
 
+func (r *sqliteRepository) SearchBuckets(ctx context.Context, pattern, status, orgID string, limit int, offset int) ([]db.SearchBucketsRow, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().SearchBuckets(ctx, db.SearchBucketsParams{
+		Pattern: pattern,
+		Status:  status,
+		OrgID:   orgID,
+		MaxRows: int64(limit),
+		Skip:    int64(offset),
+	})
+}
+
+func (r *sqliteRepository) UpdateBucketDetails(ctx context.Context, id string, title, description sql.NullString) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().UpdateBucketDetails(ctx, db.UpdateBucketDetailsParams{
+		Title:       title,
+		Description: description,
+		UpdatedAt:   time.Now().Unix(),
+		ID:          id,
+	})
+}
+
+func (r *sqliteRepository) UpdateBucketAccessRules(ctx context.Context, id string, cidrs, referrers, countries sql.NullString) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().UpdateBucketAccessRules(ctx, db.UpdateBucketAccessRulesParams{
+		AllowedCidrs:     cidrs,
+		AllowedReferrers: referrers,
+		AllowedCountries: countries,
+		UpdatedAt:        time.Now().Unix(),
+		ID:               id,
+	})
+}
+
+func (r *sqliteRepository) MarkBucketDeleting(ctx context.Context, id, from string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	now := time.Now().Unix()
+	n, err := r.queries().MarkBucketDeleting(ctx, db.MarkBucketDeletingParams{
+		DeletingAt: sql.NullInt64{Int64: now, Valid: true},
+		UpdatedAt:  now,
+		ID:         id,
+		FromStatus: from,
+	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) ListBucketsByStatus(ctx context.Context, status string, limit int) ([]*db.Bucket, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListBucketsByStatus(ctx, db.ListBucketsByStatusParams{
+		Status: status,
+		Limit:  int64(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*db.Bucket, 0, len(list))
+	for i := range list {
+		b := list[i]
+		out = append(out, &b)
+	}
+	return out, nil
+}
+
+func (r *sqliteRepository) SetBucketOrganization(ctx context.Context, id string, orgID sql.NullString) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().SetBucketOrganization(ctx, db.SetBucketOrganizationParams{
+		OrgID:     orgID,
+		UpdatedAt: time.Now().Unix(),
+		ID:        id,
+	})
+}
+
+func (r *sqliteRepository) ClearOrganizationBuckets(ctx context.Context, orgID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().ClearOrganizationBuckets(ctx, db.ClearOrganizationBucketsParams{
+		UpdatedAt: time.Now().Unix(),
+		OrgID:     sql.NullString{String: orgID, Valid: true},
+	})
+}
+
+func (r *sqliteRepository) GetOrganizationUsage(ctx context.Context, orgID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().GetOrganizationUsage(ctx, sql.NullString{String: orgID, Valid: true})
+}
+
 // File operations
 func (r *sqliteRepository) GetFileByID(ctx context.Context, id int64) (*db.File, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	file, err := db.New(r.db).GetFileByID(ctx, id)
+	file, err := r.queries().GetFileByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +278,7 @@ func (r *sqliteRepository) GetFileByID(ctx context.Context, id int64) (*db.File,
 func (r *sqliteRepository) GetFileByStringID(ctx context.Context, stringID string) (*db.File, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	file, err := db.New(r.db).GetFileByStringID(ctx, stringID)
+	file, err := r.queries().GetFileByStringID(ctx, stringID)
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +288,7 @@ func (r *sqliteRepository) GetFileByStringID(ctx context.Context, stringID strin
 func (r *sqliteRepository) GetFileByBucketIDAndStringID(ctx context.Context, bucketID, stringID string) (*db.File, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	file, err := db.New(r.db).GetFileByBucketIDAndStringID(ctx, db.GetFileByBucketIDAndStringIDParams{
+	file, err := r.queries().GetFileByBucketIDAndStringID(ctx, db.GetFileByBucketIDAndStringIDParams{
 		BucketID: bucketID,
 		StringID: stringID,
 	})
@@ -164,7 +301,7 @@ func (r *sqliteRepository) GetFileByBucketIDAndStringID(ctx context.Context, buc
 func (r *sqliteRepository) GetFilesByBucketID(ctx context.Context, bucketID string) ([]*db.File, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	list, err := db.New(r.db).GetFilesByBucketID(ctx, bucketID)
+	list, err := r.queries().GetFilesByBucketID(ctx, bucketID)
 	if err != nil {
 		return nil, err
 	}
@@ -179,7 +316,7 @@ func (r *sqliteRepository) GetFilesByBucketID(ctx context.Context, bucketID stri
 func (r *sqliteRepository) CreateFile(ctx context.Context, file *db.File) error {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	_, err := db.New(r.db).CreateFile(ctx, db.CreateFileParams{
+	_, err := r.queries().CreateFile(ctx, db.CreateFileParams{
 		StringID:     file.StringID,
 		BucketID:     file.BucketID,
 		OriginalName: file.OriginalName,
@@ -187,6 +324,7 @@ func (r *sqliteRepository) CreateFile(ctx context.Context, file *db.File) error
 		Size:         file.Size,
 		ContentType:  file.ContentType,
 		S3Key:        file.S3Key,
+		Sha256:       file.Sha256,
 		CreatedAt:    file.CreatedAt,
 	})
 	return err
@@ -195,7 +333,7 @@ func (r *sqliteRepository) CreateFile(ctx context.Context, file *db.File) error
 func (r *sqliteRepository) UpdateFile(ctx context.Context, file *db.File) error {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	return db.New(r.db).UpdateFile(ctx, db.UpdateFileParams{
+	return r.queries().UpdateFile(ctx, db.UpdateFileParams{
 		OriginalName: file.OriginalName,
 		OwnerID:      file.OwnerID,
 		StringID:     file.StringID,
@@ -205,7 +343,7 @@ func (r *sqliteRepository) UpdateFile(ctx context.Context, file *db.File) error
 func (r *sqliteRepository) DeleteFile(ctx context.Context, id int64) error {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	q := db.New(r.db)
+	q := r.queries()
 	file, err := q.GetFileByID(ctx, id)
 	if err != nil {
 		return err
@@ -216,7 +354,7 @@ func (r *sqliteRepository) DeleteFile(ctx context.Context, id int64) error {
 func (r *sqliteRepository) ListFiles(ctx context.Context, limit int, offset int) ([]*db.File, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	list, err := db.New(r.db).ListFiles(ctx, db.ListFilesParams{
+	list, err := r.queries().ListFiles(ctx, db.ListFilesParams{
 		Limit:  int64(limit),
 		Offset: int64(offset),
 	})
@@ -231,11 +369,79 @@ func (r *sqliteRepository) ListFiles(ctx context.Context, limit int, offset int)
 	return out, nil
 }
 
+func (r *sqliteRepository) ListFilesAfterID(ctx context.Context, afterID int64, limit int) ([]*db.File, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListFilesAfterID(ctx, db.ListFilesAfterIDParams{
+		ID:    afterID,
+		Limit: int64(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*db.File, 0, len(list))
+	for i := range list {
+		f := list[i]
+		out = append(out, &f)
+	}
+	return out, nil
+}
+
+func (r *sqliteRepository) GetFileByS3Key(ctx context.Context, s3Key string) (*db.File, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	file, err := r.queries().GetFileByS3Key(ctx, s3Key)
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (r *sqliteRepository) ListFileHashesByBucketID(ctx context.Context, bucketID string) ([]string, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListFileHashesByBucketID(ctx, bucketID)
+	if err != nil {
+		return nil, err
+	}
+	return validStrings(list), nil
+}
+
+// File note operations
+func (r *sqliteRepository) SetFileNote(ctx context.Context, bucketID, stringID, note string) error {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	if note == "" {
+		return r.queries().DeleteFileNote(ctx, stringID)
+	}
+	return r.queries().UpsertFileNote(ctx, db.UpsertFileNoteParams{
+		StringID:  stringID,
+		BucketID:  bucketID,
+		Note:      note,
+		UpdatedAt: time.Now().Unix(),
+	})
+}
+
+func (r *sqliteRepository) GetFileNotesByBucketID(ctx context.Context, bucketID string) ([]*db.FileNote, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().GetFileNotesByBucketID(ctx, bucketID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*db.FileNote, 0, len(list))
+	for i := range list {
+		n := list[i]
+		out = append(out, &n)
+	}
+	return out, nil
+}
+
 // Bucket admin operations
 func (r *sqliteRepository) AddBucketAdmin(ctx context.Context, bucketAdmin *db.BucketAdmin) error {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	return db.New(r.db).AddBucketAdmin(ctx, db.AddBucketAdminParams{
+	return r.queries().AddBucketAdmin(ctx, db.AddBucketAdminParams{
 		UserID:    bucketAdmin.UserID,
 		BucketID:  bucketAdmin.BucketID,
 		CreatedAt: bucketAdmin.CreatedAt,
@@ -245,7 +451,7 @@ func (r *sqliteRepository) AddBucketAdmin(ctx context.Context, bucketAdmin *db.B
 func (r *sqliteRepository) RemoveBucketAdmin(ctx context.Context, userID string, bucketID string) error {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	return db.New(r.db).RemoveBucketAdmin(ctx, db.RemoveBucketAdminParams{
+	return r.queries().RemoveBucketAdmin(ctx, db.RemoveBucketAdminParams{
 		UserID:   userID,
 		BucketID: bucketID,
 	})
@@ -254,7 +460,7 @@ func (r *sqliteRepository) RemoveBucketAdmin(ctx context.Context, userID string,
 func (r *sqliteRepository) GetBucketAdminsByBucketID(ctx context.Context, bucketID string) ([]*db.BucketAdmin, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	list, err := db.New(r.db).GetBucketAdminsByBucketID(ctx, bucketID)
+	list, err := r.queries().GetBucketAdminsByBucketID(ctx, bucketID)
 	if err != nil {
 		return nil, err
 	}
@@ -269,7 +475,7 @@ func (r *sqliteRepository) GetBucketAdminsByBucketID(ctx context.Context, bucket
 func (r *sqliteRepository) GetBucketsByAdminUserID(ctx context.Context, userID string) ([]*db.Bucket, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	list, err := db.New(r.db).GetBucketsByAdminUserID(ctx, userID)
+	list, err := r.queries().GetBucketsByAdminUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -284,7 +490,7 @@ func (r *sqliteRepository) GetBucketsByAdminUserID(ctx context.Context, userID s
 func (r *sqliteRepository) IsBucketAdmin(ctx context.Context, userID string, bucketID string) (bool, error) {
 	ctx, cancel := defaultTimeoutContext()
 	defer cancel()
-	v, err := db.New(r.db).IsBucketAdmin(ctx, db.IsBucketAdminParams{
+	v, err := r.queries().IsBucketAdmin(ctx, db.IsBucketAdminParams{
 		UserID:   userID,
 		BucketID: bucketID,
 	})
@@ -297,21 +503,169 @@ func (r *sqliteRepository) IsBucketAdmin(ctx context.Context, userID string, buc
 	return v == 1, nil
 }
 
-// runSchema executes schema SQL statement by statement (database/sql runs one per Exec).
-func runSchema(ctx context.Context, db *sql.DB, schema string) error {
-	for _, stmt := range splitStatements(schema) {
-		if _, err := db.ExecContext(ctx, stmt); err != nil {
-			return err
+func (r *sqliteRepository) ListSoleAdminBucketIDs(ctx context.Context, userID string) ([]string, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().ListSoleAdminBucketIDs(ctx, userID)
+}
+
+func (r *sqliteRepository) RemoveUserBucketAdmins(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().RemoveUserBucketAdmins(ctx, userID)
+}
+
+func (r *sqliteRepository) ClearFilesOwner(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	return r.queries().ClearFilesOwner(ctx, sql.NullString{String: userID, Valid: true})
+}
+
+// Abuse report operations
+func (r *sqliteRepository) CreateAbuseReport(ctx context.Context, report *db.AbuseReport) (*db.AbuseReport, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	created, err := r.queries().CreateAbuseReport(ctx, db.CreateAbuseReportParams{
+		BucketID:      report.BucketID,
+		Reason:        report.Reason,
+		Details:       report.Details,
+		ReporterID:    report.ReporterID,
+		ReporterEmail: report.ReporterEmail,
+		CreatedAt:     report.CreatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (r *sqliteRepository) GetAbuseReport(ctx context.Context, id int64) (*db.AbuseReport, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	report, err := r.queries().GetAbuseReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *sqliteRepository) ListAbuseReports(ctx context.Context, status, bucketID string, limit int, offset int) ([]*db.AbuseReport, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListAbuseReports(ctx, db.ListAbuseReportsParams{
+		Status:   status,
+		BucketID: bucketID,
+		MaxRows:  int64(limit),
+		Skip:     int64(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reportPtrs(list), nil
+}
+
+func (r *sqliteRepository) ResolveAbuseReport(ctx context.Context, id int64, status, moderatorID, note string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := r.queries().ResolveAbuseReport(ctx, db.ResolveAbuseReportParams{
+		Status:         status,
+		ResolvedBy:     sql.NullString{String: moderatorID, Valid: moderatorID != ""},
+		ResolutionNote: sql.NullString{String: note, Valid: note != ""},
+		ResolvedAt:     sql.NullInt64{Int64: time.Now().Unix(), Valid: true},
+		ID:             id,
+	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) ResolveBucketAbuseReports(ctx context.Context, bucketID, moderatorID, note string) ([]*db.AbuseReport, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ResolveBucketAbuseReports(ctx, db.ResolveBucketAbuseReportsParams{
+		ResolvedBy:     sql.NullString{String: moderatorID, Valid: moderatorID != ""},
+		ResolutionNote: sql.NullString{String: note, Valid: note != ""},
+		ResolvedAt:     sql.NullInt64{Int64: time.Now().Unix(), Valid: true},
+		BucketID:       bucketID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reportPtrs(list), nil
+}
+
+// Takedowns
+func (r *sqliteRepository) MarkBucketRemoved(ctx context.Context, id, reason string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	now := time.Now().Unix()
+	n, err := r.queries().MarkBucketRemoved(ctx, db.MarkBucketRemovedParams{
+		RemovedAt:     sql.NullInt64{Int64: now, Valid: true},
+		RemovalReason: sql.NullString{String: reason, Valid: reason != ""},
+		UpdatedAt:     now,
+		ID:            id,
+	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) ListRemovedBucketsBefore(ctx context.Context, before int64, limit int) ([]*db.Bucket, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	list, err := r.queries().ListRemovedBucketsBefore(ctx, db.ListRemovedBucketsBeforeParams{
+		RemovedAt: sql.NullInt64{Int64: before, Valid: true},
+		Limit:     int64(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bucketPtrs(list), nil
+}
+
+func (r *sqliteRepository) BlockHash(ctx context.Context, sha256, bucketID, reason string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	n, err := r.queries().BlockHash(ctx, db.BlockHashParams{
+		Sha256:    sha256,
+		BucketID:  bucketID,
+		Reason:    reason,
+		CreatedAt: time.Now().Unix(),
+	})
+	return n > 0, err
+}
+
+func (r *sqliteRepository) IsHashBlocked(ctx context.Context, sha256 string) (bool, error) {
+	ctx, cancel := defaultTimeoutContext()
+	defer cancel()
+	v, err := r.queries().IsHashBlocked(ctx, sha256)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
 		}
+		return false, err
 	}
-	return nil
+	return v == 1, nil
+}
+
+func reportPtrs(list []db.AbuseReport) []*db.AbuseReport {
+	out := make([]*db.AbuseReport, 0, len(list))
+	for i := range list {
+		out = append(out, &list[i])
+	}
+	return out
+}
+
+func bucketPtrs(list []db.Bucket) []*db.Bucket {
+	out := make([]*db.Bucket, 0, len(list))
+	for i := range list {
+		out = append(out, &list[i])
+	}
+	return out
 }
 
-func splitStatements(schema string) []string {
-	var out []string
-	for _, s := range strings.Split(schema, ";") {
-		if t := strings.TrimSpace(s); t != "" {
-			out = append(out, t)
+// validStrings drops the NULLs of a nullable column.
+func validStrings(list []sql.NullString) []string {
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if v.Valid {
+			out = append(out, v.String)
 		}
 	}
 	return out
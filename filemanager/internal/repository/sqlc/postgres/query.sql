@@ -0,0 +1,189 @@
+-- PostgreSQL variant of ../query.sql; keep both files in sync so the backends stay interchangeable.
+
+-- Buckets
+
+-- name: GetBucketByID :one
+SELECT * FROM buckets WHERE id = $1 LIMIT 1;
+
+-- name: CreateBucket :exec
+INSERT INTO buckets (id, password_hash, title, description, org_id, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: UpdateBucket :exec
+UPDATE buckets SET password_hash = $1, updated_at = $2 WHERE id = $3;
+
+-- name: DeleteBucket :exec
+DELETE FROM buckets WHERE id = $1;
+
+-- name: ListBuckets :many
+SELECT * FROM buckets ORDER BY created_at DESC LIMIT $1 OFFSET $2;
+
+-- name: SearchBuckets :many
+SELECT b.*,
+    (SELECT COUNT(*) FROM files f WHERE f.bucket_id = b.id)::BIGINT AS file_count,
+    (SELECT COALESCE(SUM(f.size), 0) FROM files f WHERE f.bucket_id = b.id)::BIGINT AS total_size
+FROM buckets b
+WHERE (sqlc.arg(pattern)::TEXT = '' OR LOWER(b.id) LIKE sqlc.arg(pattern) OR LOWER(COALESCE(b.title, '')) LIKE sqlc.arg(pattern))
+    AND (sqlc.arg(status)::TEXT = '' OR b.status = sqlc.arg(status))
+    AND (sqlc.arg(org_id)::TEXT = '' OR b.org_id = sqlc.arg(org_id))
+ORDER BY b.created_at DESC
+LIMIT sqlc.arg(max_rows) OFFSET sqlc.arg(skip);
+
+-- name: MarkBucketDeleting :execrows
+UPDATE buckets SET status = 'deleting', deleting_at = $1, updated_at = $2
+WHERE id = $3 AND status = sqlc.arg(from_status);
+
+-- name: ListBucketsByStatus :many
+SELECT * FROM buckets WHERE status = $1 ORDER BY updated_at ASC LIMIT $2;
+
+-- name: UpdateBucketDetails :exec
+UPDATE buckets SET title = $1, description = $2, updated_at = $3 WHERE id = $4;
+
+-- name: UpdateBucketAccessRules :exec
+UPDATE buckets SET allowed_cidrs = $1, allowed_referrers = $2, allowed_countries = $3, updated_at = $4 WHERE id = $5;
+
+-- name: SetBucketOrganization :exec
+UPDATE buckets SET org_id = $1, updated_at = $2 WHERE id = $3;
+
+-- name: ClearOrganizationBuckets :execrows
+UPDATE buckets SET org_id = NULL, updated_at = $1 WHERE org_id = $2;
+
+-- name: MarkBucketRemoved :execrows
+UPDATE buckets SET status = 'removed', removed_at = $1, removal_reason = $2, updated_at = $3
+WHERE id = $4 AND status = 'active';
+
+-- name: ListRemovedBucketsBefore :many
+SELECT * FROM buckets WHERE status = 'removed' AND removed_at < $1 ORDER BY removed_at ASC LIMIT $2;
+
+-- name: GetOrganizationUsage :one
+SELECT COALESCE(SUM(f.size), 0)::BIGINT FROM files f
+INNER JOIN buckets b ON b.id = f.bucket_id
+WHERE b.org_id = $1;
+
+-- File notes
+
+-- name: UpsertFileNote :exec
+INSERT INTO file_notes (string_id, bucket_id, note, updated_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (string_id) DO UPDATE SET note = excluded.note, updated_at = excluded.updated_at;
+
+-- name: DeleteFileNote :exec
+DELETE FROM file_notes WHERE string_id = $1;
+
+-- name: GetFileNotesByBucketID :many
+SELECT * FROM file_notes WHERE bucket_id = $1;
+
+-- Files
+
+-- name: GetFileByStringID :one
+SELECT * FROM files WHERE string_id = $1 LIMIT 1;
+
+-- name: GetFileByBucketIDAndStringID :one
+SELECT * FROM files WHERE bucket_id = $1 AND string_id = $2 LIMIT 1;
+
+-- name: GetFileByID :one
+SELECT * FROM files WHERE id = $1 LIMIT 1;
+
+-- name: GetFilesByBucketID :many
+SELECT * FROM files WHERE bucket_id = $1 ORDER BY created_at ASC;
+
+-- name: GetFilesByOwnerID :many
+SELECT * FROM files WHERE owner_id = $1 ORDER BY created_at DESC;
+
+-- name: CreateFile :one
+INSERT INTO files (string_id, bucket_id, original_name, owner_id, size, content_type, s3_key, sha256, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: UpdateFile :exec
+UPDATE files SET original_name = $1, owner_id = $2 WHERE string_id = $3;
+
+-- name: DeleteFile :exec
+DELETE FROM files WHERE string_id = $1;
+
+-- name: CountFilesByBucketID :one
+SELECT COUNT(*) FROM files WHERE bucket_id = $1;
+
+-- name: ListFiles :many
+SELECT * FROM files ORDER BY created_at DESC LIMIT $1 OFFSET $2;
+
+-- name: ListFilesAfterID :many
+SELECT * FROM files WHERE id > $1 ORDER BY id ASC LIMIT $2;
+
+-- name: ListFileHashesByBucketID :many
+SELECT DISTINCT sha256 FROM files WHERE bucket_id = $1 AND sha256 IS NOT NULL;
+
+-- name: GetFileByS3Key :one
+SELECT * FROM files WHERE s3_key = $1 LIMIT 1;
+
+-- Bucket admins
+
+-- name: AddBucketAdmin :exec
+INSERT INTO bucket_admins (user_id, bucket_id, created_at)
+VALUES ($1, $2, $3);
+
+-- name: RemoveBucketAdmin :exec
+DELETE FROM bucket_admins WHERE user_id = $1 AND bucket_id = $2;
+
+-- name: GetBucketAdminsByBucketID :many
+SELECT * FROM bucket_admins WHERE bucket_id = $1 ORDER BY created_at ASC;
+
+-- name: GetBucketsByAdminUserID :many
+SELECT b.* FROM buckets b
+INNER JOIN bucket_admins ba ON b.id = ba.bucket_id
+WHERE ba.user_id = $1;
+
+-- name: IsBucketAdmin :one
+SELECT 1 FROM bucket_admins WHERE user_id = $1 AND bucket_id = $2 LIMIT 1;
+
+-- name: ListSoleAdminBucketIDs :many
+SELECT ba.bucket_id FROM bucket_admins ba
+WHERE ba.user_id = $1
+    AND NOT EXISTS (SELECT 1 FROM buckets b WHERE b.id = ba.bucket_id AND b.org_id IS NOT NULL)
+    AND NOT EXISTS (
+    SELECT 1 FROM bucket_admins other
+    WHERE other.bucket_id = ba.bucket_id AND other.user_id <> ba.user_id
+)
+ORDER BY ba.bucket_id;
+
+-- name: RemoveUserBucketAdmins :execrows
+DELETE FROM bucket_admins WHERE user_id = $1;
+
+-- name: ClearFilesOwner :execrows
+UPDATE files SET owner_id = NULL WHERE owner_id = $1;
+
+-- Abuse reports
+
+-- name: CreateAbuseReport :one
+INSERT INTO abuse_reports (bucket_id, reason, details, reporter_id, reporter_email, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAbuseReport :one
+SELECT * FROM abuse_reports WHERE id = $1 LIMIT 1;
+
+-- name: ListAbuseReports :many
+SELECT * FROM abuse_reports
+WHERE (sqlc.arg(status)::TEXT = '' OR status = sqlc.arg(status))
+    AND (sqlc.arg(bucket_id)::TEXT = '' OR bucket_id = sqlc.arg(bucket_id))
+ORDER BY id ASC
+LIMIT sqlc.arg(max_rows) OFFSET sqlc.arg(skip);
+
+-- name: ResolveAbuseReport :execrows
+UPDATE abuse_reports SET status = $1, resolved_by = $2, resolution_note = $3, resolved_at = $4
+WHERE id = $5 AND status = 'open';
+
+-- name: ResolveBucketAbuseReports :many
+UPDATE abuse_reports SET status = 'actioned', resolved_by = $1, resolution_note = $2, resolved_at = $3
+WHERE bucket_id = $4 AND status = 'open'
+RETURNING *;
+
+-- Blocked content hashes
+
+-- name: BlockHash :execrows
+INSERT INTO blocked_hashes (sha256, bucket_id, reason, created_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (sha256) DO NOTHING;
+
+-- name: IsHashBlocked :one
+SELECT 1 FROM blocked_hashes WHERE sha256 = $1 LIMIT 1;
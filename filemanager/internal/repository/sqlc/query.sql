@@ -4,8 +4,8 @@
 SELECT * FROM buckets WHERE id = ? LIMIT 1;
 
 -- name: CreateBucket :exec
-INSERT INTO buckets (id, password_hash, created_at, updated_at)
-VALUES (?, ?, ?, ?);
+INSERT INTO buckets (id, password_hash, title, description, org_id, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?);
 
 -- name: UpdateBucket :exec
 UPDATE buckets SET password_hash = ?, updated_at = ? WHERE id = ?;
@@ -16,6 +16,61 @@ DELETE FROM buckets WHERE id = ?;
 -- name: ListBuckets :many
 SELECT * FROM buckets ORDER BY created_at DESC LIMIT ? OFFSET ?;
 
+-- name: SearchBuckets :many
+SELECT b.*,
+    CAST((SELECT COUNT(*) FROM files f WHERE f.bucket_id = b.id) AS INTEGER) AS file_count,
+    CAST((SELECT COALESCE(SUM(f.size), 0) FROM files f WHERE f.bucket_id = b.id) AS INTEGER) AS total_size
+FROM buckets b
+WHERE (sqlc.arg(pattern) = '' OR LOWER(b.id) LIKE sqlc.arg(pattern) OR LOWER(COALESCE(b.title, '')) LIKE sqlc.arg(pattern))
+    AND (sqlc.arg(status) = '' OR b.status = sqlc.arg(status))
+    AND (sqlc.arg(org_id) = '' OR b.org_id = sqlc.arg(org_id))
+ORDER BY b.created_at DESC
+LIMIT sqlc.arg(max_rows) OFFSET sqlc.arg(skip);
+
+-- name: MarkBucketDeleting :execrows
+UPDATE buckets SET status = 'deleting', deleting_at = ?, updated_at = ?
+WHERE id = ? AND status = sqlc.arg(from_status);
+
+-- name: ListBucketsByStatus :many
+SELECT * FROM buckets WHERE status = ? ORDER BY updated_at ASC LIMIT ?;
+
+-- name: UpdateBucketDetails :exec
+UPDATE buckets SET title = ?, description = ?, updated_at = ? WHERE id = ?;
+
+-- name: UpdateBucketAccessRules :exec
+UPDATE buckets SET allowed_cidrs = ?, allowed_referrers = ?, allowed_countries = ?, updated_at = ? WHERE id = ?;
+
+-- name: SetBucketOrganization :exec
+UPDATE buckets SET org_id = ?, updated_at = ? WHERE id = ?;
+
+-- name: ClearOrganizationBuckets :execrows
+UPDATE buckets SET org_id = NULL, updated_at = ? WHERE org_id = ?;
+
+-- name: MarkBucketRemoved :execrows
+UPDATE buckets SET status = 'removed', removed_at = ?, removal_reason = ?, updated_at = ?
+WHERE id = ? AND status = 'active';
+
+-- name: ListRemovedBucketsBefore :many
+SELECT * FROM buckets WHERE status = 'removed' AND removed_at < ? ORDER BY removed_at ASC LIMIT ?;
+
+-- name: GetOrganizationUsage :one
+SELECT CAST(COALESCE(SUM(f.size), 0) AS INTEGER) FROM files f
+INNER JOIN buckets b ON b.id = f.bucket_id
+WHERE b.org_id = ?;
+
+-- File notes
+
+-- name: UpsertFileNote :exec
+INSERT INTO file_notes (string_id, bucket_id, note, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (string_id) DO UPDATE SET note = excluded.note, updated_at = excluded.updated_at;
+
+-- name: DeleteFileNote :exec
+DELETE FROM file_notes WHERE string_id = ?;
+
+-- name: GetFileNotesByBucketID :many
+SELECT * FROM file_notes WHERE bucket_id = ?;
+
 -- Files
 
 -- name: GetFileByStringID :one
@@ -34,8 +89,8 @@ SELECT * FROM files WHERE bucket_id = ? ORDER BY created_at ASC;
 SELECT * FROM files WHERE owner_id = ? ORDER BY created_at DESC;
 
 -- name: CreateFile :one
-INSERT INTO files (string_id, bucket_id, original_name, owner_id, size, content_type, s3_key, created_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+INSERT INTO files (string_id, bucket_id, original_name, owner_id, size, content_type, s3_key, sha256, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: UpdateFile :exec
@@ -50,6 +105,15 @@ SELECT COUNT(*) FROM files WHERE bucket_id = ?;
 -- name: ListFiles :many
 SELECT * FROM files ORDER BY created_at DESC LIMIT ? OFFSET ?;
 
+-- name: ListFilesAfterID :many
+SELECT * FROM files WHERE id > ? ORDER BY id ASC LIMIT ?;
+
+-- name: ListFileHashesByBucketID :many
+SELECT DISTINCT sha256 FROM files WHERE bucket_id = ? AND sha256 IS NOT NULL;
+
+-- name: GetFileByS3Key :one
+SELECT * FROM files WHERE s3_key = ? LIMIT 1;
+
 -- Bucket admins
 
 -- name: AddBucketAdmin :exec
@@ -69,3 +133,55 @@ WHERE ba.user_id = ?;
 
 -- name: IsBucketAdmin :one
 SELECT 1 FROM bucket_admins WHERE user_id = ? AND bucket_id = ? LIMIT 1;
+
+-- name: ListSoleAdminBucketIDs :many
+SELECT ba.bucket_id FROM bucket_admins ba
+WHERE ba.user_id = ?
+    AND NOT EXISTS (SELECT 1 FROM buckets b WHERE b.id = ba.bucket_id AND b.org_id IS NOT NULL)
+    AND NOT EXISTS (
+    SELECT 1 FROM bucket_admins other
+    WHERE other.bucket_id = ba.bucket_id AND other.user_id <> ba.user_id
+)
+ORDER BY ba.bucket_id;
+
+-- name: RemoveUserBucketAdmins :execrows
+DELETE FROM bucket_admins WHERE user_id = ?;
+
+-- name: ClearFilesOwner :execrows
+UPDATE files SET owner_id = NULL WHERE owner_id = ?;
+
+-- Abuse reports
+
+-- name: CreateAbuseReport :one
+INSERT INTO abuse_reports (bucket_id, reason, details, reporter_id, reporter_email, created_at)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetAbuseReport :one
+SELECT * FROM abuse_reports WHERE id = ? LIMIT 1;
+
+-- name: ListAbuseReports :many
+SELECT * FROM abuse_reports
+WHERE (sqlc.arg(status) = '' OR status = sqlc.arg(status))
+    AND (sqlc.arg(bucket_id) = '' OR bucket_id = sqlc.arg(bucket_id))
+ORDER BY id ASC
+LIMIT sqlc.arg(max_rows) OFFSET sqlc.arg(skip);
+
+-- name: ResolveAbuseReport :execrows
+UPDATE abuse_reports SET status = ?, resolved_by = ?, resolution_note = ?, resolved_at = ?
+WHERE id = ? AND status = 'open';
+
+-- name: ResolveBucketAbuseReports :many
+UPDATE abuse_reports SET status = 'actioned', resolved_by = ?, resolution_note = ?, resolved_at = ?
+WHERE bucket_id = ? AND status = 'open'
+RETURNING *;
+
+-- Blocked content hashes
+
+-- name: BlockHash :execrows
+INSERT INTO blocked_hashes (sha256, bucket_id, reason, created_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (sha256) DO NOTHING;
+
+-- name: IsHashBlocked :one
+SELECT 1 FROM blocked_hashes WHERE sha256 = ? LIMIT 1;
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
+	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
+)
+
+var gatewayOnly = []string{grpcauth.Gateway}
+
+// allowlist names the services that may call each RPC when gRPC authentication is enabled. Bucket
+// deletion also comes from lifecycle expiry, which alone releases deleted accounts' buckets.
+var allowlist = grpcauth.Allowlist{
+	pb.FilemanagerService_PrepareUpload_FullMethodName:              gatewayOnly,
+	pb.FilemanagerService_ConfirmUpload_FullMethodName:              gatewayOnly,
+	pb.FilemanagerService_PrepareDownload_FullMethodName:            gatewayOnly,
+	pb.FilemanagerService_RetrieveFileBucket_FullMethodName:         gatewayOnly,
+	pb.FilemanagerService_GetBucketAdmins_FullMethodName:            gatewayOnly,
+	pb.FilemanagerService_IsBucketProtected_FullMethodName:          gatewayOnly,
+	pb.FilemanagerService_AuthenticateBucket_FullMethodName:         gatewayOnly,
+	pb.FilemanagerService_DeleteBucket_FullMethodName:               {grpcauth.Gateway, grpcauth.Lifecycle},
+	pb.FilemanagerService_ReconcileStorage_FullMethodName:           {grpcauth.Operator},
+	pb.FilemanagerService_UpdateBucketDetails_FullMethodName:        gatewayOnly,
+	pb.FilemanagerService_ListSoleOwnedBuckets_FullMethodName:       {grpcauth.Lifecycle},
+	pb.FilemanagerService_ForgetUser_FullMethodName:                 {grpcauth.Lifecycle},
+	pb.FilemanagerService_ListBuckets_FullMethodName:                gatewayOnly,
+	pb.FilemanagerService_SetBucketOrganization_FullMethodName:      gatewayOnly,
+	pb.FilemanagerService_ReleaseOrganizationBuckets_FullMethodName: gatewayOnly,
+	pb.FilemanagerService_ReportBucket_FullMethodName:               gatewayOnly,
+	pb.FilemanagerService_ListAbuseReports_FullMethodName:           gatewayOnly,
+	pb.FilemanagerService_DismissAbuseReport_FullMethodName:         gatewayOnly,
+	pb.FilemanagerService_TakedownBucket_FullMethodName:             gatewayOnly,
+	pb.FilemanagerService_GetBucketAccessRules_FullMethodName:       gatewayOnly,
+	pb.FilemanagerService_SetBucketAccessRules_FullMethodName:       gatewayOnly,
+
+	"/grpc.reflection.v1.ServerReflection/":      {grpcauth.Operator},
+	"/grpc.reflection.v1alpha.ServerReflection/": {grpcauth.Operator},
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net"
 
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
 	internalpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
 	"github.com/cthulhu-platform/filemanager/internal/service"
 	"github.com/cthulhu-platform/filemanager/pkg"
@@ -25,8 +26,9 @@ type grpcServer struct {
 }
 
 type ServerConfig struct {
-	Host string
-	Port string
+	Host     string
+	Port     string
+	GRPCAuth grpcauth.Config
 }
 
 func ListenGRPC(ctx context.Context, cfg ServerConfig, svc service.Service) error {
@@ -35,7 +37,15 @@ func ListenGRPC(ctx context.Context, cfg ServerConfig, svc service.Service) erro
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
-	srv := grpc.NewServer()
+	opts, err := cfg.GRPCAuth.ServerOptions(allowlist)
+	if err != nil {
+		return fmt.Errorf("failed to load gRPC credentials: %v", err)
+	}
+	if !cfg.GRPCAuth.Enabled() {
+		slog.Warn("gRPC service authentication is disabled, any caller is accepted; set GRPC_TLS_CERT_FILE, GRPC_TLS_KEY_FILE and GRPC_TLS_CA_FILE")
+	}
+
+	srv := grpc.NewServer(opts...)
 	pb.RegisterFilemanagerServiceServer(srv, &grpcServer{svc: svc})
 
 	reflection.Register(srv)
@@ -87,9 +97,12 @@ func (s *grpcServer) RetrieveFileBucket(ctx context.Context, req *pb.RetrieveFil
 		return &pb.RetrieveFileBucketResponse{Error: err.Error()}, nil
 	}
 	out := &pb.RetrieveFileBucketResponse{
-		StorageId: meta.StorageID,
-		TotalSize: meta.TotalSize,
-		Files:     make([]*pb.FileInfoResult, 0, len(meta.Files)),
+		StorageId:   meta.StorageID,
+		TotalSize:   meta.TotalSize,
+		Title:       meta.Title,
+		Description: meta.Description,
+		Files:       make([]*pb.FileInfoResult, 0, len(meta.Files)),
+		Status:      meta.Status,
 	}
 	for _, f := range meta.Files {
 		out.Files = append(out.Files, &pb.FileInfoResult{
@@ -98,6 +111,7 @@ func (s *grpcServer) RetrieveFileBucket(ctx context.Context, req *pb.RetrieveFil
 			Key:          f.Key,
 			Size:         f.Size,
 			ContentType:  f.ContentType,
+			Note:         f.Note,
 		})
 	}
 	slog.Info("Retrieve file bucket response", "storage_id", req.StorageId, "files", len(out.Files), "total_size", out.TotalSize)
@@ -122,6 +136,7 @@ func (s *grpcServer) GetBucketAdmins(ctx context.Context, req *pb.GetBucketAdmin
 	}
 	out := &pb.GetBucketAdminsResponse{
 		BucketId: admins.BucketID,
+		OrgId:    admins.OrgID,
 		Admins:   make([]*pb.AdminInfo, 0, len(admins.Admins)),
 	}
 	if admins.Owner != nil {
@@ -139,8 +154,12 @@ func (s *grpcServer) IsBucketProtected(ctx context.Context, req *pb.IsBucketProt
 	if err != nil {
 		return &pb.IsBucketProtectedResponse{Error: err.Error()}, nil
 	}
-	slog.Info("Is bucket protected response", "bucket_id", req.BucketId, "protected", protected)
-	return &pb.IsBucketProtectedResponse{Protected: protected}, nil
+	allowed, err := s.svc.BucketAccessAllowed(ctx, req.BucketId)
+	if err != nil {
+		return &pb.IsBucketProtectedResponse{Error: err.Error()}, nil
+	}
+	slog.Info("Is bucket protected response", "bucket_id", req.BucketId, "protected", protected, "access_denied", !allowed)
+	return &pb.IsBucketProtectedResponse{Protected: protected, AccessDenied: !allowed}, nil
 }
 
 func (s *grpcServer) AuthenticateBucket(ctx context.Context, req *pb.AuthenticateBucketRequest) (*pb.AuthenticateBucketResponse, error) {
@@ -160,6 +179,43 @@ func (s *grpcServer) AuthenticateBucket(ctx context.Context, req *pb.Authenticat
 	return &pb.AuthenticateBucketResponse{AccessToken: token, ExpiresIn: expiresIn}, nil
 }
 
+func (s *grpcServer) UpdateBucketDetails(ctx context.Context, req *pb.UpdateBucketDetailsRequest) (*pb.UpdateBucketDetailsResponse, error) {
+	res, err := s.svc.UpdateBucketDetails(ctx, req)
+	if err != nil {
+		if res != nil && res.Error != "" {
+			return res, nil
+		}
+		return nil, status.Errorf(codes.Internal, "update bucket details: %v", err)
+	}
+	slog.Info("Update bucket details response", "bucket_id", req.BucketId, "user_id", req.UserId, "notes", len(req.Notes))
+	return res, nil
+}
+
+func (s *grpcServer) GetBucketAccessRules(ctx context.Context, req *pb.GetBucketAccessRulesRequest) (*pb.GetBucketAccessRulesResponse, error) {
+	res, err := s.svc.GetBucketAccessRules(ctx, req)
+	if err != nil {
+		if res != nil && res.Error != "" {
+			return res, nil
+		}
+		return nil, status.Errorf(codes.Internal, "get bucket access rules: %v", err)
+	}
+	slog.Info("Get bucket access rules response", "bucket_id", req.BucketId, "user_id", req.UserId)
+	return res, nil
+}
+
+func (s *grpcServer) SetBucketAccessRules(ctx context.Context, req *pb.SetBucketAccessRulesRequest) (*pb.SetBucketAccessRulesResponse, error) {
+	res, err := s.svc.SetBucketAccessRules(ctx, req)
+	if err != nil {
+		if res != nil && res.Error != "" {
+			return res, nil
+		}
+		return nil, status.Errorf(codes.Internal, "set bucket access rules: %v", err)
+	}
+	slog.Info("Set bucket access rules response", "bucket_id", req.BucketId, "user_id", req.UserId,
+		"cidrs", len(res.Rules.AllowedCidrs), "referrers", len(res.Rules.AllowedReferrers), "countries", len(res.Rules.AllowedCountries))
+	return res, nil
+}
+
 func (s *grpcServer) DeleteBucket(ctx context.Context, req *pb.DeleteBucketRequest) (*pb.DeleteBucketResponse, error) {
 	filesDeleted, err := s.svc.DeleteBucket(ctx, req.BucketId)
 	if err != nil {
@@ -168,3 +224,117 @@ func (s *grpcServer) DeleteBucket(ctx context.Context, req *pb.DeleteBucketReque
 	slog.Info("Delete bucket response", "bucket_id", req.BucketId, "files_deleted", filesDeleted)
 	return &pb.DeleteBucketResponse{Success: true, FilesDeleted: filesDeleted}, nil
 }
+
+func (s *grpcServer) ReconcileStorage(ctx context.Context, req *pb.ReconcileStorageRequest) (*pb.ReconcileStorageResponse, error) {
+	report, err := s.svc.ReconcileStorage(ctx, req.DryRun)
+	if report == nil {
+		return nil, status.Errorf(codes.Internal, "reconcile storage: %v", err)
+	}
+	out := &pb.ReconcileStorageResponse{
+		DryRun:          report.DryRun,
+		ObjectsScanned:  report.ObjectsScanned,
+		RowsScanned:     report.RowsScanned,
+		OrphanedObjects: report.OrphanedObjects,
+		DanglingRows:    report.DanglingRows,
+		ObjectsDeleted:  report.ObjectsDeleted,
+		RowsDeleted:     report.RowsDeleted,
+		Failures:        report.Failures,
+		OrphanedKeys:    report.OrphanedKeys,
+		DanglingKeys:    report.DanglingKeys,
+	}
+	if err != nil {
+		out.Error = err.Error()
+	}
+	slog.Info("Reconcile storage response", "dry_run", out.DryRun, "orphaned_objects", out.OrphanedObjects, "dangling_rows", out.DanglingRows, "failures", out.Failures)
+	return out, nil
+}
+
+func (s *grpcServer) ListSoleOwnedBuckets(ctx context.Context, req *pb.ListSoleOwnedBucketsRequest) (*pb.ListSoleOwnedBucketsResponse, error) {
+	ids, err := s.svc.ListSoleOwnedBuckets(ctx, req.UserId)
+	if err != nil {
+		return &pb.ListSoleOwnedBucketsResponse{Error: err.Error()}, nil
+	}
+	return &pb.ListSoleOwnedBucketsResponse{BucketIds: ids}, nil
+}
+
+func (s *grpcServer) ForgetUser(ctx context.Context, req *pb.ForgetUserRequest) (*pb.ForgetUserResponse, error) {
+	adminsRemoved, filesDisowned, err := s.svc.ForgetUser(ctx, req.UserId)
+	if err != nil {
+		return &pb.ForgetUserResponse{Error: err.Error()}, nil
+	}
+	slog.Info("Forget user response", "user_id", req.UserId, "admins_removed", adminsRemoved, "files_disowned", filesDisowned)
+	return &pb.ForgetUserResponse{AdminsRemoved: adminsRemoved, FilesDisowned: filesDisowned}, nil
+}
+
+func (s *grpcServer) ListBuckets(ctx context.Context, req *pb.ListBucketsRequest) (*pb.ListBucketsResponse, error) {
+	res, err := s.svc.ListBuckets(ctx, req)
+	if err != nil {
+		return &pb.ListBucketsResponse{Error: err.Error()}, nil
+	}
+	return res, nil
+}
+
+func (s *grpcServer) SetBucketOrganization(ctx context.Context, req *pb.SetBucketOrganizationRequest) (*pb.SetBucketOrganizationResponse, error) {
+	res, err := s.svc.SetBucketOrganization(ctx, req)
+	if err != nil {
+		if res != nil && res.Error != "" {
+			return res, nil
+		}
+		return nil, status.Errorf(codes.Internal, "set bucket organization: %v", err)
+	}
+	slog.Info("Set bucket organization response", "bucket_id", req.BucketId, "org_id", req.OrgId)
+	return res, nil
+}
+
+func (s *grpcServer) ReleaseOrganizationBuckets(ctx context.Context, req *pb.ReleaseOrganizationBucketsRequest) (*pb.ReleaseOrganizationBucketsResponse, error) {
+	released, err := s.svc.ReleaseOrganizationBuckets(ctx, req.OrgId)
+	if err != nil {
+		return &pb.ReleaseOrganizationBucketsResponse{Error: err.Error()}, nil
+	}
+	slog.Info("Release organization buckets response", "org_id", req.OrgId, "buckets_released", released)
+	return &pb.ReleaseOrganizationBucketsResponse{BucketsReleased: released}, nil
+}
+
+func (s *grpcServer) ReportBucket(ctx context.Context, req *pb.ReportBucketRequest) (*pb.ReportBucketResponse, error) {
+	res, err := s.svc.ReportBucket(ctx, req)
+	if err != nil {
+		if res != nil && res.Error != "" {
+			return res, nil
+		}
+		return nil, status.Errorf(codes.Internal, "report bucket: %v", err)
+	}
+	slog.Info("Report bucket response", "bucket_id", req.BucketId, "reason", req.Reason, "report_id", res.ReportId)
+	return res, nil
+}
+
+func (s *grpcServer) ListAbuseReports(ctx context.Context, req *pb.ListAbuseReportsRequest) (*pb.ListAbuseReportsResponse, error) {
+	res, err := s.svc.ListAbuseReports(ctx, req)
+	if err != nil {
+		return &pb.ListAbuseReportsResponse{Error: err.Error()}, nil
+	}
+	return res, nil
+}
+
+func (s *grpcServer) DismissAbuseReport(ctx context.Context, req *pb.DismissAbuseReportRequest) (*pb.DismissAbuseReportResponse, error) {
+	res, err := s.svc.DismissAbuseReport(ctx, req)
+	if err != nil {
+		if res != nil && res.Error != "" {
+			return res, nil
+		}
+		return nil, status.Errorf(codes.Internal, "dismiss abuse report: %v", err)
+	}
+	slog.Info("Dismiss abuse report response", "report_id", req.ReportId, "moderator_id", req.ModeratorId)
+	return res, nil
+}
+
+func (s *grpcServer) TakedownBucket(ctx context.Context, req *pb.TakedownBucketRequest) (*pb.TakedownBucketResponse, error) {
+	res, err := s.svc.TakedownBucket(ctx, req)
+	if err != nil {
+		if res != nil && res.Error != "" {
+			return res, nil
+		}
+		return nil, status.Errorf(codes.Internal, "takedown bucket: %v", err)
+	}
+	slog.Info("Takedown bucket response", "bucket_id", req.BucketId, "moderator_id", req.ModeratorId, "hashes_blocked", res.HashesBlocked, "reports_resolved", len(res.Reports))
+	return res, nil
+}
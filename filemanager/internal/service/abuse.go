@@ -0,0 +1,261 @@
+// Abuse reports and takedowns: anyone can report a bucket, platform moderators dismiss reports or
+// take the bucket down. A removed bucket keeps its files as evidence until the retention period
+// ends, and the content hashes of its files are refused by ConfirmUpload from then on.
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	localpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
+	"github.com/cthulhu-platform/filemanager/internal/repository"
+	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/filemanager/internal/storage"
+	"github.com/cthulhu-platform/filemanager/pkg"
+	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
+)
+
+var (
+	errBucketRemoved  = errors.New("bucket was removed for abuse")
+	errContentBlocked = errors.New("upload contains content that was removed for abuse")
+	// A file stored without the checksum its presigned URL was signed over
+	errChecksumMissing = errors.New("uploaded file has no SHA-256 checksum, upload it with its presigned URL")
+)
+
+func (s *filemanagerService) ReportBucket(ctx context.Context, req *pb.ReportBucketRequest) (*pb.ReportBucketResponse, error) {
+	res := &pb.ReportBucketResponse{}
+	if req == nil || req.BucketId == "" {
+		res.Error = "bucket_id is required"
+		return res, errors.New(res.Error)
+	}
+	if !slices.Contains(pkg.ReportReasons, req.Reason) {
+		res.Error = "reason must be one of " + strings.Join(pkg.ReportReasons, ", ")
+		return res, errors.New(res.Error)
+	}
+	details := strings.TrimSpace(req.Details)
+	if utf8.RuneCountInString(details) > localpkg.ABUSE_REPORT_DETAILS_MAX {
+		res.Error = fmt.Sprintf("details must be at most %d characters", localpkg.ABUSE_REPORT_DETAILS_MAX)
+		return res, errors.New(res.Error)
+	}
+	email := strings.TrimSpace(req.ReporterEmail)
+	if email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil || addr.Address != email {
+			res.Error = "invalid reporter email"
+			return res, errors.New(res.Error)
+		}
+	}
+
+	bucket, err := s.repo.GetBucketByID(ctx, req.BucketId)
+	if err != nil || bucket.Status == repository.BucketStatusDeleting {
+		res.Error = "bucket not found"
+		return res, errors.New(res.Error)
+	}
+	if bucket.Status == repository.BucketStatusRemoved {
+		res.Error = errBucketRemoved.Error()
+		return res, errBucketRemoved
+	}
+
+	report, err := s.repo.CreateAbuseReport(ctx, &db.AbuseReport{
+		BucketID:      req.BucketId,
+		Reason:        req.Reason,
+		Details:       details,
+		ReporterID:    sql.NullString{String: req.ReporterId, Valid: req.ReporterId != ""},
+		ReporterEmail: sql.NullString{String: email, Valid: email != ""},
+		CreatedAt:     time.Now().Unix(),
+	})
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	res.ReportId = report.ID
+	return res, nil
+}
+
+func (s *filemanagerService) ListAbuseReports(ctx context.Context, req *pb.ListAbuseReportsRequest) (*pb.ListAbuseReportsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > localpkg.ABUSE_REPORT_PAGE_MAX {
+		limit = localpkg.ABUSE_REPORT_PAGE_MAX
+	}
+	offset := max(int(req.Offset), 0)
+	reports, err := s.repo.ListAbuseReports(ctx, req.Status, req.BucketId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list abuse reports: %w", err)
+	}
+	out := &pb.ListAbuseReportsResponse{Reports: make([]*pb.AbuseReport, 0, len(reports))}
+	for _, r := range reports {
+		out.Reports = append(out.Reports, abuseReportToPB(r))
+	}
+	return out, nil
+}
+
+func (s *filemanagerService) DismissAbuseReport(ctx context.Context, req *pb.DismissAbuseReportRequest) (*pb.DismissAbuseReportResponse, error) {
+	res := &pb.DismissAbuseReportResponse{}
+	if req == nil || req.ReportId <= 0 || req.ModeratorId == "" {
+		res.Error = "report_id and moderator_id are required"
+		return res, errors.New(res.Error)
+	}
+	dismissed, err := s.repo.ResolveAbuseReport(ctx, req.ReportId, repository.ReportStatusDismissed, req.ModeratorId, strings.TrimSpace(req.Note))
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	if !dismissed {
+		res.Error = "report not found or already resolved"
+		return res, errors.New(res.Error)
+	}
+	report, err := s.repo.GetAbuseReport(ctx, req.ReportId)
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	res.Report = abuseReportToPB(report)
+	return res, nil
+}
+
+// TakedownBucket removes an active bucket, blocks the content hashes of its files and resolves its
+// open reports in one transaction. Files confirmed before hashes were recorded are hashed first.
+func (s *filemanagerService) TakedownBucket(ctx context.Context, req *pb.TakedownBucketRequest) (*pb.TakedownBucketResponse, error) {
+	res := &pb.TakedownBucketResponse{}
+	reason := strings.TrimSpace(req.GetReason())
+	if req == nil || req.BucketId == "" || req.ModeratorId == "" || reason == "" {
+		res.Error = "bucket_id, moderator_id and reason are required"
+		return res, errors.New(res.Error)
+	}
+	bucket, err := s.repo.GetBucketByID(ctx, req.BucketId)
+	if err != nil {
+		res.Error = "bucket not found"
+		return res, err
+	}
+	if bucket.Status != repository.BucketStatusActive {
+		res.Error = "bucket is " + bucket.Status
+		return res, errors.New(res.Error)
+	}
+
+	files, err := s.repo.GetFilesByBucketID(ctx, req.BucketId)
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	var legacyHashes []string
+	for _, f := range files {
+		if f.Sha256.Valid {
+			continue
+		}
+		sum, err := s.storage.HashObject(ctx, f.S3Key)
+		if err != nil {
+			slog.Warn("failed to hash file for takedown", "s3_key", f.S3Key, "error", err)
+			continue
+		}
+		legacyHashes = append(legacyHashes, sum)
+	}
+
+	var reports []*db.AbuseReport
+	err = s.repo.WithTx(ctx, func(tx repository.Repository) error {
+		removed, err := tx.MarkBucketRemoved(ctx, req.BucketId, reason)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return errors.New("bucket is no longer active")
+		}
+		hashes, err := tx.ListFileHashesByBucketID(ctx, req.BucketId)
+		if err != nil {
+			return err
+		}
+		for _, h := range append(hashes, legacyHashes...) {
+			blocked, err := tx.BlockHash(ctx, h, req.BucketId, reason)
+			if err != nil {
+				return err
+			}
+			if blocked {
+				res.HashesBlocked++
+			}
+		}
+		reports, err = tx.ResolveBucketAbuseReports(ctx, req.BucketId, req.ModeratorId, reason)
+		return err
+	})
+	if err != nil {
+		res.HashesBlocked = 0
+		res.Error = err.Error()
+		return res, err
+	}
+
+	res.Success = true
+	res.Reports = make([]*pb.AbuseReport, 0, len(reports))
+	for _, r := range reports {
+		res.Reports = append(res.Reports, abuseReportToPB(r))
+	}
+	return res, nil
+}
+
+// PurgeRemovedBuckets deletes the buckets taken down before the cutoff, whose evidence is past
+// retention.
+func (s *filemanagerService) PurgeRemovedBuckets(ctx context.Context, before time.Time) (purged int, err error) {
+	buckets, err := s.repo.ListRemovedBucketsBefore(ctx, before.Unix(), localpkg.ABUSE_EVIDENCE_PURGE_BATCH)
+	if err != nil {
+		return 0, fmt.Errorf("list removed buckets: %w", err)
+	}
+	for _, b := range buckets {
+		if _, err := s.purgeBucket(ctx, b, repository.BucketStatusRemoved); err != nil {
+			slog.Warn("failed to purge removed bucket", "bucket_id", b.ID, "error", err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// checkBlockedContent reads the SHA-256 checksums the storage verified when the objects were
+// uploaded, returning them by string id. It fails closed: an object without a checksum was not
+// uploaded with its presigned URL and is refused with errChecksumMissing, and a checksum that
+// cannot be read fails the upload.
+func (s *filemanagerService) checkBlockedContent(ctx context.Context, storageID string, files []*pb.FileMetaWithStringId) (map[string]string, error) {
+	hashes := make(map[string]string, len(files))
+	for _, f := range files {
+		s3Key := storageID + "/" + f.StringId
+		sum, err := s.storage.ObjectSHA256(ctx, s3Key)
+		if errors.Is(err, storage.ErrNoChecksum) {
+			slog.Warn("uploaded file has no checksum", "s3_key", s3Key)
+			return nil, errChecksumMissing
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the checksum of file %s: %w", f.StringId, err)
+		}
+		blocked, err := s.repo.IsHashBlocked(ctx, sum)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			slog.Warn("upload matches blocked content", "storage_id", storageID, "string_id", f.StringId, "sha256", sum)
+			return nil, errContentBlocked
+		}
+		hashes[f.StringId] = sum
+	}
+	return hashes, nil
+}
+
+func abuseReportToPB(r *db.AbuseReport) *pb.AbuseReport {
+	return &pb.AbuseReport{
+		Id:             r.ID,
+		BucketId:       r.BucketID,
+		Reason:         r.Reason,
+		Details:        r.Details,
+		ReporterId:     r.ReporterID.String,
+		ReporterEmail:  r.ReporterEmail.String,
+		Status:         r.Status,
+		ResolvedBy:     r.ResolvedBy.String,
+		ResolutionNote: r.ResolutionNote.String,
+		CreatedAt:      r.CreatedAt,
+		ResolvedAt:     r.ResolvedAt.Int64,
+	}
+}
@@ -0,0 +1,282 @@
+// Bucket access rules: bucket admins (and admins of the bucket's organization) can restrict a
+// bucket to client addresses in a list of CIDRs, to requests referred from a list of hosts and to
+// a list of countries. Every non-empty list must match; a bucket without rules is open. The client
+// address and referrer come from the gateway in gRPC metadata, and a request without them is
+// denied by any rule that needs them.
+// Downloads of a restricted bucket use short-lived presigned URLs, bound to the allowed CIDRs
+// where the storage supports it.
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+
+	localpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
+	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/filemanager/pkg"
+	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
+	"google.golang.org/grpc/metadata"
+)
+
+var errAccessDenied = errors.New("access denied by bucket access rules")
+
+// accessRules is the parsed form of a bucket's access rule columns.
+type accessRules struct {
+	cidrs     []netip.Prefix
+	referrers []string
+	countries []string
+}
+
+func (r accessRules) empty() bool {
+	return len(r.cidrs) == 0 && len(r.referrers) == 0 && len(r.countries) == 0
+}
+
+func (s *filemanagerService) GetBucketAccessRules(ctx context.Context, req *pb.GetBucketAccessRulesRequest) (*pb.GetBucketAccessRulesResponse, error) {
+	res := &pb.GetBucketAccessRulesResponse{}
+	if req == nil || req.BucketId == "" || req.UserId == "" {
+		res.Error = "bucket_id and user_id required"
+		return res, errors.New(res.Error)
+	}
+	bucket, err := s.managedBucket(ctx, req.BucketId, req.UserId)
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	res.Rules = &pb.BucketAccessRules{
+		AllowedCidrs:     splitRule(bucket.AllowedCidrs),
+		AllowedReferrers: splitRule(bucket.AllowedReferrers),
+		AllowedCountries: splitRule(bucket.AllowedCountries),
+	}
+	return res, nil
+}
+
+// SetBucketAccessRules replaces the bucket's access rules; empty lists lift the restriction. The
+// rules are returned in their stored, normalized form.
+func (s *filemanagerService) SetBucketAccessRules(ctx context.Context, req *pb.SetBucketAccessRulesRequest) (*pb.SetBucketAccessRulesResponse, error) {
+	res := &pb.SetBucketAccessRulesResponse{}
+	if req == nil || req.BucketId == "" || req.UserId == "" {
+		res.Error = "bucket_id and user_id required"
+		return res, errors.New(res.Error)
+	}
+	rules, err := normalizeAccessRules(req.GetRules())
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	if len(rules.AllowedCountries) > 0 && s.geo == nil {
+		res.Error = "country rules are not available: no GeoIP database is configured"
+		return res, errors.New(res.Error)
+	}
+	if _, err := s.managedBucket(ctx, req.BucketId, req.UserId); err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	if err := s.repo.UpdateBucketAccessRules(ctx, req.BucketId,
+		joinRule(rules.AllowedCidrs), joinRule(rules.AllowedReferrers), joinRule(rules.AllowedCountries)); err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	res.Rules = rules
+	return res, nil
+}
+
+func (s *filemanagerService) BucketAccessAllowed(ctx context.Context, bucketID string) (bool, error) {
+	bucket, err := s.getReadableBucket(ctx, bucketID)
+	if err != nil {
+		return false, err
+	}
+	return s.accessAllowed(ctx, bucket), nil
+}
+
+// managedBucket returns the bucket when userID may manage it.
+func (s *filemanagerService) managedBucket(ctx context.Context, bucketID, userID string) (*db.Bucket, error) {
+	bucket, err := s.repo.GetBucketByID(ctx, bucketID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("bucket not found")
+		}
+		return nil, err
+	}
+	canManage, err := s.canManageBucket(ctx, userID, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !canManage {
+		return nil, errors.New("only bucket admins can manage access rules")
+	}
+	return bucket, nil
+}
+
+// accessAllowed checks the bucket's rules against the client in the incoming metadata.
+func (s *filemanagerService) accessAllowed(ctx context.Context, bucket *db.Bucket) bool {
+	rules := parseAccessRules(bucket)
+	if rules.empty() {
+		return true
+	}
+	ip, referrer := clientInfo(ctx)
+	addr, err := netip.ParseAddr(ip)
+	if err == nil {
+		addr = addr.Unmap()
+	}
+	if len(rules.cidrs) > 0 {
+		if err != nil || !slices.ContainsFunc(rules.cidrs, func(p netip.Prefix) bool { return p.Contains(addr) }) {
+			return false
+		}
+	}
+	if len(rules.referrers) > 0 && !referrerAllowed(referrer, rules.referrers) {
+		return false
+	}
+	if len(rules.countries) > 0 {
+		if err != nil || s.geo == nil || !slices.Contains(rules.countries, s.geo.Country(addr)) {
+			return false
+		}
+	}
+	return true
+}
+
+// presignGet presigns a download of key, restricted to the bucket's CIDRs when it has access rules.
+func (s *filemanagerService) presignGet(ctx context.Context, bucket *db.Bucket, key string) (string, error) {
+	rules := parseAccessRules(bucket)
+	if rules.empty() {
+		return s.storage.PresignGet(ctx, key)
+	}
+	cidrs := make([]string, 0, len(rules.cidrs))
+	for _, p := range rules.cidrs {
+		cidrs = append(cidrs, p.String())
+	}
+	return s.storage.PresignGetRestricted(ctx, key, cidrs)
+}
+
+// clientInfo returns the client address and referrer the gateway sent in the incoming metadata.
+func clientInfo(ctx context.Context) (ip, referrer string) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", ""
+	}
+	if v := md.Get(pkg.MetadataClientIP); len(v) > 0 {
+		ip = v[0]
+	}
+	if v := md.Get(pkg.MetadataClientReferrer); len(v) > 0 {
+		referrer = v[0]
+	}
+	return ip, referrer
+}
+
+// referrerAllowed matches the host of a Referer URL against allowed hosts, where "*.example.com"
+// allows any subdomain of example.com.
+func referrerAllowed(referrer string, allowed []string) bool {
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return false
+	}
+	for _, a := range allowed {
+		if suffix, ok := strings.CutPrefix(a, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == a {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAccessRules(bucket *db.Bucket) accessRules {
+	var rules accessRules
+	for _, c := range splitRule(bucket.AllowedCidrs) {
+		// Stored rules were validated on write
+		if p, err := netip.ParsePrefix(c); err == nil {
+			rules.cidrs = append(rules.cidrs, p)
+		}
+	}
+	rules.referrers = splitRule(bucket.AllowedReferrers)
+	rules.countries = splitRule(bucket.AllowedCountries)
+	return rules
+}
+
+// normalizeAccessRules validates the rules and returns them in stored form: masked CIDRs (a bare
+// address is a single host), lowercase hosts and uppercase country codes, without duplicates.
+func normalizeAccessRules(in *pb.BucketAccessRules) (*pb.BucketAccessRules, error) {
+	out := &pb.BucketAccessRules{}
+	for _, list := range [][]string{in.GetAllowedCidrs(), in.GetAllowedReferrers(), in.GetAllowedCountries()} {
+		if len(list) > localpkg.ACCESS_RULE_MAX_ENTRIES {
+			return nil, fmt.Errorf("at most %d entries per access rule", localpkg.ACCESS_RULE_MAX_ENTRIES)
+		}
+	}
+	for _, c := range in.GetAllowedCidrs() {
+		c = strings.TrimSpace(c)
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(c)
+			if aerr != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", c)
+			}
+			addr = addr.Unmap()
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		out.AllowedCidrs = appendUnique(out.AllowedCidrs, netip.PrefixFrom(p.Addr().Unmap(), p.Bits()).Masked().String())
+	}
+	for _, r := range in.GetAllowedReferrers() {
+		host, err := normalizeReferrerHost(r)
+		if err != nil {
+			return nil, err
+		}
+		out.AllowedReferrers = appendUnique(out.AllowedReferrers, host)
+	}
+	for _, c := range in.GetAllowedCountries() {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if len(c) != 2 || c[0] < 'A' || c[0] > 'Z' || c[1] < 'A' || c[1] > 'Z' {
+			return nil, fmt.Errorf("invalid country code %q", c)
+		}
+		out.AllowedCountries = appendUnique(out.AllowedCountries, c)
+	}
+	return out, nil
+}
+
+// normalizeReferrerHost accepts a host, "*." followed by a host, or a URL whose host is taken.
+func normalizeReferrerHost(r string) (string, error) {
+	host := strings.ToLower(strings.TrimSpace(r))
+	if strings.Contains(host, "://") {
+		u, err := url.Parse(host)
+		if err != nil {
+			return "", fmt.Errorf("invalid referrer %q", r)
+		}
+		host = u.Hostname()
+	}
+	name := strings.TrimPrefix(host, "*.")
+	if name == "" || strings.ContainsAny(name, "*/:,@ ") || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return "", fmt.Errorf("invalid referrer %q", r)
+	}
+	return host, nil
+}
+
+func appendUnique(list []string, v string) []string {
+	if slices.Contains(list, v) {
+		return list
+	}
+	return append(list, v)
+}
+
+// Access rule lists are stored comma-separated, NULL when empty.
+
+func splitRule(s sql.NullString) []string {
+	if !s.Valid || s.String == "" {
+		return nil
+	}
+	return strings.Split(s.String, ",")
+}
+
+func joinRule(list []string) sql.NullString {
+	return sql.NullString{String: strings.Join(list, ","), Valid: len(list) > 0}
+}
@@ -0,0 +1,93 @@
+// Bucket details: a title, a markdown description and per-file notes that give shared files
+// context. Uploaders set them at PrepareUpload; bucket admins (and admins of the bucket's
+// organization) edit them with UpdateBucketDetails.
+// Length limits and sanitization are enforced by the gateway.
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cthulhu-platform/filemanager/internal/repository"
+	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
+)
+
+func (s *filemanagerService) UpdateBucketDetails(ctx context.Context, req *pb.UpdateBucketDetailsRequest) (*pb.UpdateBucketDetailsResponse, error) {
+	res := &pb.UpdateBucketDetailsResponse{Success: false}
+	if req == nil || req.BucketId == "" || req.UserId == "" {
+		res.Error = "bucket_id and user_id required"
+		return res, errors.New(res.Error)
+	}
+
+	bucket, err := s.repo.GetBucketByID(ctx, req.BucketId)
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	canManage, err := s.canManageBucket(ctx, req.UserId, bucket)
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	if !canManage {
+		res.Error = "only bucket admins can edit bucket details"
+		return res, errors.New(res.Error)
+	}
+
+	err = s.repo.WithTx(ctx, func(tx repository.Repository) error {
+		bucket, err := tx.GetBucketByID(ctx, req.BucketId)
+		if err != nil {
+			return err
+		}
+		// Unset fields keep their current value
+		if req.Title != nil || req.Description != nil {
+			title, description := bucket.Title, bucket.Description
+			if req.Title != nil {
+				title = nullableString(req.Title)
+			}
+			if req.Description != nil {
+				description = nullableString(req.Description)
+			}
+			if err := tx.UpdateBucketDetails(ctx, req.BucketId, title, description); err != nil {
+				return err
+			}
+		}
+		for _, n := range req.Notes {
+			if _, err := tx.GetFileByBucketIDAndStringID(ctx, req.BucketId, n.StringId); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("file %s not found in bucket", n.StringId)
+				}
+				return err
+			}
+			if err := tx.SetFileNote(ctx, req.BucketId, n.StringId, n.Note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+
+	res.Success = true
+	return res, nil
+}
+
+// nullableString maps an unset or empty optional proto field to NULL.
+func nullableString(s *string) sql.NullString {
+	if s == nil || *s == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
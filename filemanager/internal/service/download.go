@@ -1,5 +1,6 @@
 // PrepareDownload returns a presigned GET URL for direct S3 download.
 // For protected buckets, bucket_access_token (from AuthenticateBucket) is required.
+// Buckets with access rules only serve clients the rules allow.
 
 package service
 
@@ -7,6 +8,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/cthulhu-platform/filemanager/internal/repository"
 	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
 )
 
@@ -26,11 +28,16 @@ func (s *filemanagerService) PrepareDownload(ctx context.Context, req *pb.Prepar
 		return res, err
 	}
 
-	bucket, err := s.repo.GetBucketByID(ctx, storageID)
+	bucket, err := s.getReadableBucket(ctx, storageID)
 	if err != nil {
 		res.Error = err.Error()
 		return res, err
 	}
+	if bucket.Status == repository.BucketStatusRemoved {
+		res.Status = bucket.Status
+		res.Error = errBucketRemoved.Error()
+		return res, errBucketRemoved
+	}
 
 	if bucket.PasswordHash.Valid {
 		token := ""
@@ -52,7 +59,13 @@ func (s *filemanagerService) PrepareDownload(ctx context.Context, req *pb.Prepar
 		}
 	}
 
-	url, err := s.storage.PresignGet(ctx, file.S3Key)
+	if !s.accessAllowed(ctx, bucket) {
+		res.Error = errAccessDenied.Error()
+		res.AccessDenied = true
+		return res, errAccessDenied
+	}
+
+	url, err := s.presignGet(ctx, bucket, file.S3Key)
 	if err != nil {
 		res.Error = err.Error()
 		return res, err
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	localpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
+	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
+)
+
+// ListBuckets searches buckets by a case-insensitive substring of their id or title. LIKE
+// wildcards in the query are dropped rather than escaped, since SQLite LIKE has no default escape.
+func (s *filemanagerService) ListBuckets(ctx context.Context, req *pb.ListBucketsRequest) (*pb.ListBucketsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > localpkg.BUCKET_LIST_PAGE_MAX {
+		limit = localpkg.BUCKET_LIST_PAGE_MAX
+	}
+	offset := max(int(req.Offset), 0)
+	pattern := ""
+	if q := strings.ToLower(strings.NewReplacer("%", "", "_", "", "\\", "").Replace(strings.TrimSpace(req.Query))); q != "" {
+		pattern = "%" + q + "%"
+	}
+
+	rows, err := s.repo.SearchBuckets(ctx, pattern, req.Status, req.OrgId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("search buckets: %w", err)
+	}
+	out := &pb.ListBucketsResponse{Buckets: make([]*pb.BucketSummary, 0, len(rows))}
+	for _, b := range rows {
+		out.Buckets = append(out.Buckets, &pb.BucketSummary{
+			Id:        b.ID,
+			Title:     nullStringPtr(b.Title),
+			Status:    b.Status,
+			Protected: b.PasswordHash.Valid,
+			FileCount: b.FileCount,
+			TotalSize: b.TotalSize,
+			CreatedAt: b.CreatedAt,
+			UpdatedAt: b.UpdatedAt,
+			OrgId:     b.OrgID.String,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,124 @@
+// Organization buckets: a bucket may belong to an organization, kept in the auth service, whose
+// admins and owners manage it alongside the bucket admins. The organization's storage quota is
+// checked when buckets are created in or moved to it.
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	authpkg "github.com/cthulhu-platform/auth/pkg"
+	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/db"
+	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
+)
+
+var (
+	errNotOrganizationMember = errors.New("not a member of this organization")
+	errOrganizationQuota     = errors.New("organization storage quota exceeded")
+)
+
+func (s *filemanagerService) SetBucketOrganization(ctx context.Context, req *pb.SetBucketOrganizationRequest) (*pb.SetBucketOrganizationResponse, error) {
+	res := &pb.SetBucketOrganizationResponse{Success: false}
+	if req == nil || req.BucketId == "" || req.UserId == "" {
+		res.Error = "bucket_id and user_id required"
+		return res, errors.New(res.Error)
+	}
+
+	bucket, err := s.repo.GetBucketByID(ctx, req.BucketId)
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	canManage, err := s.canManageBucket(ctx, req.UserId, bucket)
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	if !canManage {
+		res.Error = "only bucket admins can change the bucket's organization"
+		return res, errors.New(res.Error)
+	}
+
+	orgID := sql.NullString{}
+	if req.OrgId != "" {
+		files, err := s.repo.GetFilesByBucketID(ctx, req.BucketId)
+		if err != nil {
+			res.Error = err.Error()
+			return res, err
+		}
+		var size int64
+		for _, f := range files {
+			size += f.Size
+		}
+		if req.OrgId != bucket.OrgID.String {
+			if err := s.checkOrganizationUpload(ctx, req.OrgId, req.UserId, size); err != nil {
+				res.Error = err.Error()
+				return res, err
+			}
+		}
+		orgID = sql.NullString{String: req.OrgId, Valid: true}
+	}
+	if err := s.repo.SetBucketOrganization(ctx, req.BucketId, orgID); err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+
+	res.Success = true
+	return res, nil
+}
+
+// ReleaseOrganizationBuckets makes the buckets of a deleted organization personal again. They
+// stay with their bucket admins.
+func (s *filemanagerService) ReleaseOrganizationBuckets(ctx context.Context, orgID string) (int64, error) {
+	if orgID == "" {
+		return 0, errors.New("org_id required")
+	}
+	released, err := s.repo.ClearOrganizationBuckets(ctx, orgID)
+	if err != nil {
+		return 0, fmt.Errorf("release organization buckets: %w", err)
+	}
+	return released, nil
+}
+
+// checkOrganizationUpload verifies that userID is a member of orgID and that adding size bytes
+// keeps the organization within its quota (0 means no limit).
+func (s *filemanagerService) checkOrganizationUpload(ctx context.Context, orgID, userID string, size int64) error {
+	org, err := s.conns.Auth.GetOrganizationMembership(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if org.Role == "" {
+		return errNotOrganizationMember
+	}
+	if org.QuotaBytes <= 0 {
+		return nil
+	}
+	used, err := s.repo.GetOrganizationUsage(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("get organization usage: %w", err)
+	}
+	if used+size > org.QuotaBytes {
+		return errOrganizationQuota
+	}
+	return nil
+}
+
+// canManageBucket reports whether userID is an admin of the bucket or an admin or owner of the
+// organization it belongs to.
+func (s *filemanagerService) canManageBucket(ctx context.Context, userID string, bucket *db.Bucket) (bool, error) {
+	isAdmin, err := s.repo.IsBucketAdmin(ctx, userID, bucket.ID)
+	if err != nil || isAdmin {
+		return isAdmin, err
+	}
+	if !bucket.OrgID.Valid {
+		return false, nil
+	}
+	org, err := s.conns.Auth.GetOrganizationMembership(ctx, bucket.OrgID.String, userID)
+	if err != nil {
+		return false, err
+	}
+	return authpkg.OrgRoleRank(org.Role) >= authpkg.OrgRoleRank(authpkg.OrgRoleAdmin), nil
+}
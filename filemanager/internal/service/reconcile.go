@@ -0,0 +1,141 @@
+// ReconcileStorage compares the S3 listing with the files table. Orphaned objects
+// (no files row) are leaked by failed deletes or abandoned uploads; dangling rows
+// (no S3 object) point at data that no longer exists. Both are repaired unless dryRun.
+// Buckets taken down for abuse are left alone: their objects and rows are evidence, kept as they
+// are until the retention purge.
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	localpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
+	"github.com/cthulhu-platform/filemanager/internal/repository"
+	"github.com/cthulhu-platform/filemanager/pkg"
+)
+
+func (s *filemanagerService) ReconcileStorage(ctx context.Context, dryRun bool) (*pkg.ReconcileReport, error) {
+	report := &pkg.ReconcileReport{DryRun: dryRun}
+	removed := make(map[string]bool)
+	if err := s.reconcileOrphanedObjects(ctx, report, removed); err != nil {
+		return report, err
+	}
+	if err := s.reconcileDanglingRows(ctx, report, removed); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// reconcileOrphanedObjects pages through the S3 listing and removes objects without a files row.
+func (s *filemanagerService) reconcileOrphanedObjects(ctx context.Context, report *pkg.ReconcileReport, removed map[string]bool) error {
+	cutoff := time.Now().Add(-localpkg.RECONCILE_ORPHAN_GRACE)
+	token := ""
+	for {
+		objects, next, err := s.storage.ListObjects(ctx, "", token, localpkg.RECONCILE_PAGE_SIZE)
+		if err != nil {
+			return fmt.Errorf("list objects: %w", err)
+		}
+		for _, o := range objects {
+			report.ObjectsScanned++
+			// Upload may still be in flight (presigned PUT issued, ConfirmUpload not called yet)
+			if o.LastModified.After(cutoff) {
+				continue
+			}
+			_, err := s.repo.GetFileByS3Key(ctx, o.Key)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("lookup file by s3 key %q: %w", o.Key, err)
+			}
+			bucketID, _, _ := strings.Cut(o.Key, "/")
+			if isRemoved, err := s.bucketRemoved(ctx, bucketID, removed); err != nil {
+				return err
+			} else if isRemoved {
+				continue
+			}
+			report.OrphanedObjects++
+			if len(report.OrphanedKeys) < localpkg.RECONCILE_REPORT_KEY_LIMIT {
+				report.OrphanedKeys = append(report.OrphanedKeys, o.Key)
+			}
+			if report.DryRun {
+				continue
+			}
+			if err := s.storage.DeleteObject(ctx, o.Key); err != nil {
+				slog.Warn("failed to delete orphaned S3 object", "s3_key", o.Key, "error", err)
+				report.Failures++
+				continue
+			}
+			report.ObjectsDeleted++
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+// reconcileDanglingRows pages through the files table and removes rows whose S3 object is gone.
+func (s *filemanagerService) reconcileDanglingRows(ctx context.Context, report *pkg.ReconcileReport, removed map[string]bool) error {
+	var afterID int64
+	for {
+		files, err := s.repo.ListFilesAfterID(ctx, afterID, localpkg.RECONCILE_PAGE_SIZE)
+		if err != nil {
+			return fmt.Errorf("list files: %w", err)
+		}
+		for _, f := range files {
+			afterID = f.ID
+			report.RowsScanned++
+			if isRemoved, err := s.bucketRemoved(ctx, f.BucketID, removed); err != nil {
+				return err
+			} else if isRemoved {
+				continue
+			}
+			exists, err := s.storage.ObjectExists(ctx, f.S3Key)
+			if err != nil {
+				slog.Warn("failed to check S3 object", "s3_key", f.S3Key, "error", err)
+				report.Failures++
+				continue
+			}
+			if exists {
+				continue
+			}
+			report.DanglingRows++
+			if len(report.DanglingKeys) < localpkg.RECONCILE_REPORT_KEY_LIMIT {
+				report.DanglingKeys = append(report.DanglingKeys, f.S3Key)
+			}
+			if report.DryRun {
+				continue
+			}
+			if err := s.repo.DeleteFile(ctx, f.ID); err != nil {
+				slog.Warn("failed to delete dangling file row", "file_id", f.ID, "s3_key", f.S3Key, "error", err)
+				report.Failures++
+				continue
+			}
+			report.RowsDeleted++
+		}
+		if len(files) < localpkg.RECONCILE_PAGE_SIZE {
+			return nil
+		}
+	}
+}
+
+// bucketRemoved reports whether the bucket was taken down for abuse, caching the answer in
+// removed for the rest of the run. Missing buckets are not removed.
+func (s *filemanagerService) bucketRemoved(ctx context.Context, bucketID string, removed map[string]bool) (bool, error) {
+	if isRemoved, ok := removed[bucketID]; ok {
+		return isRemoved, nil
+	}
+	bucket, err := s.repo.GetBucketByID(ctx, bucketID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("get bucket %q: %w", bucketID, err)
+	}
+	removed[bucketID] = err == nil && bucket.Status == repository.BucketStatusRemoved
+	return removed[bucketID], nil
+}
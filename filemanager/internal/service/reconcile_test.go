@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	localpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
+	"github.com/cthulhu-platform/filemanager/internal/repository"
+	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/db"
+	"github.com/cthulhu-platform/filemanager/internal/storage"
+)
+
+// fakeStorage keeps object listings in memory. Methods the reconciler does not call panic
+// through the nil embedded interface.
+type fakeStorage struct {
+	storage.Storage
+	objects map[string]time.Time // key -> last modified
+	deleted []string
+}
+
+func (f *fakeStorage) ListObjects(ctx context.Context, prefix, token string, pageSize int32) ([]storage.ObjectInfo, string, error) {
+	keys := make([]string, 0, len(f.objects))
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	start, _ := strconv.Atoi(token)
+	end := min(start+int(pageSize), len(keys))
+	page := make([]storage.ObjectInfo, 0, end-start)
+	for _, k := range keys[start:end] {
+		page = append(page, storage.ObjectInfo{Key: k, LastModified: f.objects[k]})
+	}
+	next := ""
+	if end < len(keys) {
+		next = strconv.Itoa(end)
+	}
+	return page, next, nil
+}
+
+func (f *fakeStorage) DeleteObject(ctx context.Context, key string) error {
+	delete(f.objects, key)
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+func (f *fakeStorage) ObjectExists(ctx context.Context, key string) (bool, error) {
+	_, ok := f.objects[key]
+	return ok, nil
+}
+
+// newReconcileFixture returns a service over a fresh SQLite database and a storage holding:
+//   - b1 (active): f1 with its object, f2 whose object is gone, an orphan past the grace period
+//     and an orphan still inside it
+//   - b2 (removed): f3 whose object is gone and an orphan past the grace period
+//   - an orphan past the grace period of a bucket that no longer exists
+func newReconcileFixture(t *testing.T) (*filemanagerService, *fakeStorage) {
+	t.Helper()
+	ctx := context.Background()
+	localpkg.SQLITE_DB_FILE = filepath.Join(t.TempDir(), "filemanager.db")
+	repo, err := repository.NewSQLiteRepository(ctx)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	now := time.Now()
+	for _, id := range []string{"b1", "b2"} {
+		if err := repo.CreateBucket(ctx, &db.Bucket{ID: id, CreatedAt: now.Unix(), UpdatedAt: now.Unix()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []struct{ bucket, id string }{{"b1", "f1"}, {"b1", "f2"}, {"b2", "f3"}} {
+		if err := repo.CreateFile(ctx, &db.File{
+			StringID:     f.id,
+			BucketID:     f.bucket,
+			OriginalName: f.id + ".txt",
+			Size:         42,
+			ContentType:  "text/plain",
+			S3Key:        f.bucket + "/" + f.id,
+			CreatedAt:    now.Unix(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ok, err := repo.MarkBucketRemoved(ctx, "b2", "spam"); err != nil || !ok {
+		t.Fatalf("take down = %v, %v", ok, err)
+	}
+
+	old := now.Add(-localpkg.RECONCILE_ORPHAN_GRACE - time.Minute)
+	stor := &fakeStorage{objects: map[string]time.Time{
+		"b1/f1":       old,
+		"b1/orphan":   old,
+		"b1/uploaded": now.Add(-time.Minute),
+		"b2/orphan":   old,
+		"gone/orphan": old,
+	}}
+	return &filemanagerService{repo: repo, storage: stor}, stor
+}
+
+func TestReconcileStorage(t *testing.T) {
+	ctx := context.Background()
+	s, stor := newReconcileFixture(t)
+
+	report, err := s.ReconcileStorage(ctx, false)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if report.ObjectsScanned != 5 || report.OrphanedObjects != 2 || report.ObjectsDeleted != 2 ||
+		report.RowsScanned != 3 || report.DanglingRows != 1 || report.RowsDeleted != 1 || report.Failures != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	slices.Sort(stor.deleted)
+	if !slices.Equal(stor.deleted, []string{"b1/orphan", "gone/orphan"}) {
+		t.Fatalf("deleted objects = %v", stor.deleted)
+	}
+	if !slices.Equal(report.DanglingKeys, []string{"b1/f2"}) {
+		t.Fatalf("dangling keys = %v", report.DanglingKeys)
+	}
+
+	// The upload in flight and the removed bucket's evidence are kept
+	for _, key := range []string{"b1/f1", "b1/uploaded", "b2/orphan"} {
+		if _, ok := stor.objects[key]; !ok {
+			t.Errorf("object %s was deleted", key)
+		}
+	}
+	if _, err := s.repo.GetFileByS3Key(ctx, "b1/f2"); err == nil {
+		t.Error("dangling row b1/f2 was kept")
+	}
+	for _, key := range []string{"b1/f1", "b2/f3"} {
+		if _, err := s.repo.GetFileByS3Key(ctx, key); err != nil {
+			t.Errorf("row %s: %v", key, err)
+		}
+	}
+}
+
+func TestReconcileStorageDryRun(t *testing.T) {
+	ctx := context.Background()
+	s, stor := newReconcileFixture(t)
+
+	report, err := s.ReconcileStorage(ctx, true)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if !report.DryRun || report.OrphanedObjects != 2 || report.DanglingRows != 1 || report.ObjectsDeleted != 0 || report.RowsDeleted != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	if len(stor.deleted) != 0 || len(stor.objects) != 5 {
+		t.Fatalf("dry run deleted objects %v", stor.deleted)
+	}
+	if _, err := s.repo.GetFileByS3Key(ctx, "b1/f2"); err != nil {
+		t.Fatalf("dry run deleted row b1/f2: %v", err)
+	}
+}
@@ -5,11 +5,17 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/cthulhu-platform/filemanager/internal/connections"
+	"github.com/cthulhu-platform/filemanager/internal/geoip"
+	localpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
 	"github.com/cthulhu-platform/filemanager/internal/repository"
+	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/db"
 	"github.com/cthulhu-platform/filemanager/internal/storage"
 	"github.com/cthulhu-platform/filemanager/pkg"
 	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
@@ -28,45 +34,118 @@ type Service interface {
 	RetrieveFileBucket(ctx context.Context, storageID string) (*pkg.BucketMetadata, error)
 	GetBucketAdmins(ctx context.Context, bucketID string) (*pkg.BucketAdminsResponse, error)
 	IsBucketProtected(ctx context.Context, bucketID string) (bool, *string, error)
+	// BucketAccessAllowed checks the bucket's access rules against the client in the incoming
+	// gRPC metadata.
+	BucketAccessAllowed(ctx context.Context, bucketID string) (bool, error)
 	AuthenticateBucket(ctx context.Context, bucketID string, password string, userID *string, authTokenID *string) (string, error)
+	// UpdateBucketDetails edits the title, description and file notes (bucket and organization admins only).
+	UpdateBucketDetails(ctx context.Context, req *pb.UpdateBucketDetailsRequest) (*pb.UpdateBucketDetailsResponse, error)
+
+	// Access rules (bucket and organization admins)
+	GetBucketAccessRules(ctx context.Context, req *pb.GetBucketAccessRulesRequest) (*pb.GetBucketAccessRulesResponse, error)
+	SetBucketAccessRules(ctx context.Context, req *pb.SetBucketAccessRulesRequest) (*pb.SetBucketAccessRulesResponse, error)
 
 	// DeleteBucket removes the bucket, its files (and S3 objects), and bucket_admins. Returns files deleted count.
+	// A bucket taken down for abuse is kept as evidence: nothing is deleted until PurgeRemovedBuckets.
 	DeleteBucket(ctx context.Context, bucketID string) (filesDeleted int64, err error)
+	// ResumeBucketDeletions finishes deletions left in the deleting state by a crash or failed purge.
+	ResumeBucketDeletions(ctx context.Context) (resumed int, err error)
+
+	// ReconcileStorage finds S3 objects without a files row and rows without an S3 object,
+	// and deletes both unless dryRun is set.
+	ReconcileStorage(ctx context.Context, dryRun bool) (*pkg.ReconcileReport, error)
+
+	// ListBuckets searches all buckets for platform moderators.
+	ListBuckets(ctx context.Context, req *pb.ListBucketsRequest) (*pb.ListBucketsResponse, error)
+
+	// Organizations: SetBucketOrganization moves a bucket into or out of an organization (bucket
+	// and organization admins); ReleaseOrganizationBuckets runs once an organization is deleted.
+	SetBucketOrganization(ctx context.Context, req *pb.SetBucketOrganizationRequest) (*pb.SetBucketOrganizationResponse, error)
+	ReleaseOrganizationBuckets(ctx context.Context, orgID string) (int64, error)
+
+	// Abuse reports and takedowns (platform moderators, except ReportBucket)
+	ReportBucket(ctx context.Context, req *pb.ReportBucketRequest) (*pb.ReportBucketResponse, error)
+	ListAbuseReports(ctx context.Context, req *pb.ListAbuseReportsRequest) (*pb.ListAbuseReportsResponse, error)
+	DismissAbuseReport(ctx context.Context, req *pb.DismissAbuseReportRequest) (*pb.DismissAbuseReportResponse, error)
+	TakedownBucket(ctx context.Context, req *pb.TakedownBucketRequest) (*pb.TakedownBucketResponse, error)
+	// PurgeRemovedBuckets deletes buckets taken down before the cutoff, ending their evidence retention.
+	PurgeRemovedBuckets(ctx context.Context, before time.Time) (purged int, err error)
+
+	// Account deletion cleanup, driven by the lifecycle service
+	ListSoleOwnedBuckets(ctx context.Context, userID string) ([]string, error)
+	// ForgetUser removes the user's bucket admin rows and file ownership.
+	ForgetUser(ctx context.Context, userID string) (adminsRemoved, filesDisowned int64, err error)
 }
 
 type filemanagerService struct {
 	repo    repository.Repository
 	storage storage.Storage
 	conns   *connections.ConnectionsContainer
+	geo     *geoip.Database // nil without GEOIP_DB_FILE
 }
 
-func NewFilemanagerService(repo repository.Repository, stor storage.Storage, conns *connections.ConnectionsContainer) Service {
+func NewFilemanagerService(repo repository.Repository, stor storage.Storage, conns *connections.ConnectionsContainer, geo *geoip.Database) Service {
 	return &filemanagerService{
 		repo:    repo,
 		storage: stor,
 		conns:   conns,
+		geo:     geo,
+	}
+}
+
+// errBucketNotFound answers reads of buckets that do not exist or are being deleted.
+var errBucketNotFound = errors.New("bucket not found")
+
+// getReadableBucket returns the bucket unless it is missing or being deleted, whose objects are
+// being purged.
+func (s *filemanagerService) getReadableBucket(ctx context.Context, id string) (*db.Bucket, error) {
+	bucket, err := s.repo.GetBucketByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && bucket.Status == repository.BucketStatusDeleting) {
+		return nil, errBucketNotFound
 	}
+	return bucket, err
 }
 
 func (s *filemanagerService) RetrieveFileBucket(ctx context.Context, storageID string) (*pkg.BucketMetadata, error) {
-	_, err := s.repo.GetBucketByID(ctx, storageID)
+	bucket, err := s.getReadableBucket(ctx, storageID)
 	if err != nil {
 		return nil, err
 	}
+	if bucket.Status == repository.BucketStatusRemoved {
+		return &pkg.BucketMetadata{StorageID: storageID, Files: []pkg.FileInfo{}, Status: bucket.Status}, nil
+	}
 	files, err := s.repo.GetFilesByBucketID(ctx, storageID)
 	if err != nil {
 		return nil, err
 	}
-	out := &pkg.BucketMetadata{StorageID: storageID, Files: make([]pkg.FileInfo, 0, len(files))}
+	notes, err := s.repo.GetFileNotesByBucketID(ctx, storageID)
+	if err != nil {
+		return nil, err
+	}
+	noteByStringID := make(map[string]string, len(notes))
+	for _, n := range notes {
+		noteByStringID[n.StringID] = n.Note
+	}
+	out := &pkg.BucketMetadata{
+		StorageID:   storageID,
+		Title:       nullStringPtr(bucket.Title),
+		Description: nullStringPtr(bucket.Description),
+		Files:       make([]pkg.FileInfo, 0, len(files)),
+		Status:      bucket.Status,
+	}
 	var totalSize int64
 	for _, f := range files {
-		out.Files = append(out.Files, pkg.FileInfo{
+		info := pkg.FileInfo{
 			OriginalName: f.OriginalName,
 			StringID:     f.StringID,
 			Key:          f.S3Key,
 			Size:         f.Size,
 			ContentType:  f.ContentType,
-		})
+		}
+		if note, ok := noteByStringID[f.StringID]; ok {
+			info.Note = &note
+		}
+		out.Files = append(out.Files, info)
 		totalSize += f.Size
 	}
 	out.TotalSize = totalSize
@@ -74,7 +153,7 @@ func (s *filemanagerService) RetrieveFileBucket(ctx context.Context, storageID s
 }
 
 func (s *filemanagerService) GetBucketAdmins(ctx context.Context, bucketID string) (*pkg.BucketAdminsResponse, error) {
-	_, err := s.repo.GetBucketByID(ctx, bucketID)
+	bucket, err := s.repo.GetBucketByID(ctx, bucketID)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +161,7 @@ func (s *filemanagerService) GetBucketAdmins(ctx context.Context, bucketID strin
 	if err != nil {
 		return nil, err
 	}
-	out := &pkg.BucketAdminsResponse{BucketID: bucketID, Admins: make([]pkg.AdminInfo, 0, len(list))}
+	out := &pkg.BucketAdminsResponse{BucketID: bucketID, OrgID: bucket.OrgID.String, Admins: make([]pkg.AdminInfo, 0, len(list))}
 	for i, a := range list {
 		info := pkg.AdminInfo{
 			UserID:    a.UserID,
@@ -101,11 +180,12 @@ func (s *filemanagerService) GetBucketAdmins(ctx context.Context, bucketID strin
 }
 
 func (s *filemanagerService) IsBucketProtected(ctx context.Context, bucketID string) (bool, *string, error) {
-	bucket, err := s.repo.GetBucketByID(ctx, bucketID)
+	bucket, err := s.getReadableBucket(ctx, bucketID)
 	if err != nil {
 		return false, nil, err
 	}
-	return bucket.PasswordHash.Valid, nil, nil
+	// A removed bucket is answered as such without asking for its password first
+	return bucket.PasswordHash.Valid && bucket.Status != repository.BucketStatusRemoved, nil, nil
 }
 
 func (s *filemanagerService) AuthenticateBucket(ctx context.Context, bucketID string, password string, userID *string, authTokenID *string) (string, error) {
@@ -122,22 +202,86 @@ func (s *filemanagerService) AuthenticateBucket(ctx context.Context, bucketID st
 	return GenerateBucketAccessToken(bucketID, userID, authTokenID, []string{"read"})
 }
 
+// DeleteBucket runs as a crash-safe state machine: mark the bucket deleting (ConfirmUpload then
+// refuses it), purge its S3 objects, then delete the rows in one transaction. If any step fails
+// the bucket stays marked deleting and a later call, or the deletion daemon, resumes it.
 func (s *filemanagerService) DeleteBucket(ctx context.Context, bucketID string) (filesDeleted int64, err error) {
-	_, err = s.repo.GetBucketByID(ctx, bucketID)
+	bucket, err := s.repo.GetBucketByID(ctx, bucketID)
 	if err != nil {
 		return 0, fmt.Errorf("bucket not found: %w", err)
 	}
+	if bucket.Status == repository.BucketStatusRemoved {
+		slog.Info("Bucket kept as abuse evidence, deletion deferred to retention purge", "bucket_id", bucketID)
+		return 0, nil
+	}
+	return s.purgeBucket(ctx, bucket, repository.BucketStatusActive)
+}
+
+// purgeBucket deletes a bucket that is in status from, or resumes the deletion of one already
+// marked deleting. The mark only applies while the bucket is still in status from, so a takedown
+// that lands after the bucket was read is not purged as an ordinary deletion.
+func (s *filemanagerService) purgeBucket(ctx context.Context, bucket *db.Bucket, from string) (filesDeleted int64, err error) {
+	bucketID := bucket.ID
+
+	// Phase 1: mark deleting
+	if bucket.Status != repository.BucketStatusDeleting {
+		marked, err := s.repo.MarkBucketDeleting(ctx, bucketID, from)
+		if err != nil {
+			return 0, fmt.Errorf("mark bucket deleting: %w", err)
+		}
+		if !marked {
+			return 0, fmt.Errorf("bucket %s is no longer %s, deletion aborted", bucketID, from)
+		}
+	}
+
+	// Phase 2: purge objects (S3 deletes are idempotent, so a resumed purge is safe)
 	files, err := s.repo.GetFilesByBucketID(ctx, bucketID)
 	if err != nil {
 		return 0, fmt.Errorf("list files: %w", err)
 	}
+	purged := make(map[string]struct{}, len(files))
 	for _, f := range files {
 		if delErr := s.storage.DeleteObject(ctx, f.S3Key); delErr != nil {
 			slog.Warn("failed to delete S3 object during bucket delete", "s3_key", f.S3Key, "error", delErr)
+			continue
 		}
+		purged[f.S3Key] = struct{}{}
+	}
+	if len(purged) != len(files) {
+		return 0, fmt.Errorf("purge bucket objects: %d of %d failed, bucket left in %s state", len(files)-len(purged), len(files), repository.BucketStatusDeleting)
 	}
-	if err := s.repo.DeleteBucket(ctx, bucketID); err != nil {
+
+	// Phase 3: delete rows (files and bucket_admins cascade)
+	err = s.repo.WithTx(ctx, func(tx repository.Repository) error {
+		// A ConfirmUpload that committed before the mark could have added files after the listing above
+		current, err := tx.GetFilesByBucketID(ctx, bucketID)
+		if err != nil {
+			return err
+		}
+		for _, f := range current {
+			if _, ok := purged[f.S3Key]; !ok {
+				return fmt.Errorf("file %s was added during deletion, retry to purge it", f.StringID)
+			}
+		}
+		return tx.DeleteBucket(ctx, bucketID)
+	})
+	if err != nil {
 		return 0, fmt.Errorf("delete bucket: %w", err)
 	}
 	return int64(len(files)), nil
 }
+
+func (s *filemanagerService) ResumeBucketDeletions(ctx context.Context) (resumed int, err error) {
+	buckets, err := s.repo.ListBucketsByStatus(ctx, repository.BucketStatusDeleting, localpkg.BUCKET_DELETION_RESUME_BATCH)
+	if err != nil {
+		return 0, fmt.Errorf("list deleting buckets: %w", err)
+	}
+	for _, b := range buckets {
+		if _, err := s.DeleteBucket(ctx, b.ID); err != nil {
+			slog.Warn("failed to resume bucket deletion", "bucket_id", b.ID, "error", err)
+			continue
+		}
+		resumed++
+	}
+	return resumed, nil
+}
@@ -1,16 +1,20 @@
-// Two-phase presigned URL upload: PrepareUpload returns presigned PUT URLs per file;
-// the client uploads each file directly to S3; ConfirmUpload persists file metadata.
+// Two-phase presigned URL upload: PrepareUpload returns presigned PUT URLs per file, signed over
+// the SHA-256 the client declares for it; the client uploads each file directly to S3, which
+// verifies the checksum; ConfirmUpload checks the stored checksums and persists file metadata.
 
 package service
 
 import (
 	"context"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"log/slog"
 	"math/rand/v2"
 	"strings"
 	"time"
 
+	"github.com/cthulhu-platform/filemanager/internal/repository"
 	"github.com/cthulhu-platform/filemanager/internal/repository/sqlc/db"
 	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
 	"github.com/google/uuid"
@@ -33,6 +37,24 @@ func (s *filemanagerService) PrepareUpload(ctx context.Context, req *pb.PrepareU
 		res.Error = "no files provided"
 		return res, errors.New(res.Error)
 	}
+	// Content taken down before is refused before anything is uploaded
+	for _, f := range req.Files {
+		if !validSHA256(f.Sha256) {
+			res.Error = "each file needs the lowercase hex-encoded SHA-256 of its content"
+			return res, errors.New(res.Error)
+		}
+		blocked, err := s.repo.IsHashBlocked(ctx, f.Sha256)
+		if err != nil {
+			res.Error = err.Error()
+			return res, err
+		}
+		if blocked {
+			slog.Warn("upload matches blocked content", "sha256", f.Sha256)
+			res.Error = errContentBlocked.Error()
+			res.Blocked = true
+			return res, errContentBlocked
+		}
+	}
 
 	storageID := generateStorageID()
 	for i := 0; i < 3; i++ {
@@ -52,6 +74,23 @@ func (s *filemanagerService) PrepareUpload(ctx context.Context, req *pb.PrepareU
 		}
 	}
 
+	orgID := sql.NullString{}
+	if req.OrgId != nil && *req.OrgId != "" {
+		if req.UserId == nil || *req.UserId == "" {
+			res.Error = "organization uploads require a signed in user"
+			return res, errors.New(res.Error)
+		}
+		var size int64
+		for _, f := range req.Files {
+			size += max(f.Size, 0)
+		}
+		if err := s.checkOrganizationUpload(ctx, *req.OrgId, *req.UserId, size); err != nil {
+			res.Error = err.Error()
+			return res, err
+		}
+		orgID = sql.NullString{String: *req.OrgId, Valid: true}
+	}
+
 	var passwordHash sql.NullString
 	if req.Password != nil && *req.Password != "" {
 		hash, err := HashBucketPassword(*req.Password)
@@ -66,6 +105,9 @@ func (s *filemanagerService) PrepareUpload(ctx context.Context, req *pb.PrepareU
 	bucket := &db.Bucket{
 		ID:           storageID,
 		PasswordHash: passwordHash,
+		Title:        nullableString(req.Title),
+		Description:  nullableString(req.Description),
+		OrgID:        orgID,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -84,8 +126,12 @@ func (s *filemanagerService) PrepareUpload(ctx context.Context, req *pb.PrepareU
 	}
 
 	slots := make([]*pb.FileUploadSlot, 0, len(req.Files))
+	notes := make(map[string]string)
 	for _, f := range req.Files {
 		stringID := uuid.New().String()
+		if f.Note != nil && *f.Note != "" {
+			notes[stringID] = *f.Note
+		}
 		s3Key := storageID + "/" + stringID
 		size := f.Size
 		if size < 0 {
@@ -95,7 +141,7 @@ func (s *filemanagerService) PrepareUpload(ctx context.Context, req *pb.PrepareU
 		if contentType == "" {
 			contentType = "application/octet-stream"
 		}
-		url, err := s.storage.PresignPut(ctx, s3Key, size, contentType)
+		url, err := s.storage.PresignPut(ctx, s3Key, size, contentType, f.Sha256)
 		if err != nil {
 			res.StorageId = storageID
 			res.Error = err.Error()
@@ -108,6 +154,23 @@ func (s *filemanagerService) PrepareUpload(ctx context.Context, req *pb.PrepareU
 		})
 	}
 
+	// Notes are stored now, keyed by string_id, and joined to the files rows once confirmed
+	if len(notes) > 0 {
+		err := s.repo.WithTx(ctx, func(tx repository.Repository) error {
+			for stringID, note := range notes {
+				if err := tx.SetFileNote(ctx, storageID, stringID, note); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			res.StorageId = storageID
+			res.Error = err.Error()
+			return res, err
+		}
+	}
+
 	res.StorageId = storageID
 	res.Slots = slots
 	return res, nil
@@ -120,46 +183,99 @@ func (s *filemanagerService) ConfirmUpload(ctx context.Context, req *pb.ConfirmU
 		return res, errors.New(res.Error)
 	}
 
-	_, err := s.repo.GetBucketByID(ctx, req.StorageId)
+	// Content taken down before is refused, as are files stored without a checksum, and the objects
+	// just uploaded with them are purged
+	hashes, err := s.checkBlockedContent(ctx, req.StorageId, req.Files)
 	if err != nil {
+		if errors.Is(err, errContentBlocked) || errors.Is(err, errChecksumMissing) {
+			s.discardUpload(ctx, req.StorageId, req.Files)
+		}
+		res.StorageId = req.StorageId
 		res.Error = err.Error()
+		res.Blocked = errors.Is(err, errContentBlocked)
 		return res, err
 	}
 
 	now := time.Now().Unix()
 	var totalSize int64
+	var orgID string
 	fileResults := make([]*pb.FileInfoResult, 0, len(req.Files))
-	for _, f := range req.Files {
-		s3Key := req.StorageId + "/" + f.StringId
-		ownerID := sql.NullString{Valid: false}
-		dbFile := &db.File{
-			StringID:     f.StringId,
-			BucketID:     req.StorageId,
-			OriginalName: f.OriginalName,
-			OwnerID:      ownerID,
-			Size:         f.Size,
-			ContentType:  f.ContentType,
-			S3Key:        s3Key,
-			CreatedAt:    now,
-		}
-		if err := s.repo.CreateFile(ctx, dbFile); err != nil {
-			res.StorageId = req.StorageId
-			res.Error = err.Error()
-			return res, err
+	// All rows are inserted in one transaction: either every file is confirmed or none is
+	err = s.repo.WithTx(ctx, func(tx repository.Repository) error {
+		bucket, err := tx.GetBucketByID(ctx, req.StorageId)
+		if err != nil {
+			return err
 		}
-		totalSize += f.Size
-		fileResults = append(fileResults, &pb.FileInfoResult{
-			OriginalName: f.OriginalName,
-			StringId:     f.StringId,
-			Key:          s3Key,
-			Size:         f.Size,
-			ContentType:  f.ContentType,
-		})
+		if bucket.Status == repository.BucketStatusDeleting {
+			return errors.New("bucket is being deleted")
+		}
+		if bucket.Status == repository.BucketStatusRemoved {
+			return errBucketRemoved
+		}
+		orgID = bucket.OrgID.String
+		for _, f := range req.Files {
+			s3Key := req.StorageId + "/" + f.StringId
+			ownerID := sql.NullString{Valid: false}
+			dbFile := &db.File{
+				StringID:     f.StringId,
+				BucketID:     req.StorageId,
+				OriginalName: f.OriginalName,
+				OwnerID:      ownerID,
+				Size:         f.Size,
+				ContentType:  f.ContentType,
+				S3Key:        s3Key,
+				Sha256:       sql.NullString{String: hashes[f.StringId], Valid: hashes[f.StringId] != ""},
+				CreatedAt:    now,
+			}
+			if err := tx.CreateFile(ctx, dbFile); err != nil {
+				return err
+			}
+			totalSize += f.Size
+			fileResults = append(fileResults, &pb.FileInfoResult{
+				OriginalName: f.OriginalName,
+				StringId:     f.StringId,
+				Key:          s3Key,
+				Size:         f.Size,
+				ContentType:  f.ContentType,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		res.StorageId = req.StorageId
+		res.Error = err.Error()
+		return res, err
 	}
 
 	res.Success = true
 	res.StorageId = req.StorageId
 	res.Files = fileResults
 	res.TotalSize = totalSize
+	res.OrgId = orgID
 	return res, nil
 }
+
+// discardUpload deletes the objects of a refused upload and, unless files were confirmed into it
+// before, its bucket.
+func (s *filemanagerService) discardUpload(ctx context.Context, storageID string, files []*pb.FileMetaWithStringId) {
+	for _, f := range files {
+		s3Key := storageID + "/" + f.StringId
+		if err := s.storage.DeleteObject(ctx, s3Key); err != nil {
+			slog.Warn("failed to delete refused upload object", "s3_key", s3Key, "error", err)
+		}
+	}
+	confirmed, err := s.repo.GetFilesByBucketID(ctx, storageID)
+	if err != nil || len(confirmed) > 0 {
+		return
+	}
+	if _, err := s.DeleteBucket(ctx, storageID); err != nil {
+		slog.Warn("failed to delete refused upload bucket", "storage_id", storageID, "error", err)
+	}
+}
+
+// validSHA256 reports whether s is a lowercase hex-encoded SHA-256, the form blocked hashes are
+// stored in.
+func validSHA256(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil && len(s) == 64 && strings.ToLower(s) == s
+}
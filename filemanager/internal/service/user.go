@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cthulhu-platform/filemanager/internal/repository"
+)
+
+// ListSoleOwnedBuckets returns the buckets a user is the only admin of. They are left without
+// an admin once the user is forgotten.
+func (s *filemanagerService) ListSoleOwnedBuckets(ctx context.Context, userID string) ([]string, error) {
+	ids, err := s.repo.ListSoleAdminBucketIDs(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list sole owned buckets: %w", err)
+	}
+	return ids, nil
+}
+
+// ForgetUser removes a deleted user's admin rows and clears them as the owner of their files.
+// Buckets shared with other admins pass to the next admin; buckets they owned alone become
+// anonymous. Running it again for the same user is a no-op.
+func (s *filemanagerService) ForgetUser(ctx context.Context, userID string) (adminsRemoved, filesDisowned int64, err error) {
+	err = s.repo.WithTx(ctx, func(tx repository.Repository) error {
+		if adminsRemoved, err = tx.RemoveUserBucketAdmins(ctx, userID); err != nil {
+			return fmt.Errorf("remove bucket admins: %w", err)
+		}
+		if filesDisowned, err = tx.ClearFilesOwner(ctx, userID); err != nil {
+			return fmt.Errorf("clear files owner: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return adminsRemoved, filesDisowned, nil
+}
@@ -2,7 +2,13 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"time"
 
@@ -10,6 +16,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	localpkg "github.com/cthulhu-platform/filemanager/internal/pkg"
 )
 
@@ -17,6 +25,11 @@ type AWSStorage struct {
 	Client       *s3.Client
 	PresignClient *s3.PresignClient
 	BucketName   string
+
+	// Source IP restricted presigning, nil without a PresignRoleARN
+	presignBase    *s3.Client
+	sts            *sts.Client
+	presignRoleARN string
 }
 
 type AWSStorageConfig struct {
@@ -27,6 +40,7 @@ type AWSStorageConfig struct {
 	Region            string
 	BucketName        string
 	ForcePathStyle    bool
+	PresignRoleARN    string // optional; role assumed to presign URLs limited to source IPs
 }
 
 func NewAWSStorage(ctx context.Context, cfg AWSStorageConfig) (*AWSStorage, error) {
@@ -36,7 +50,7 @@ func NewAWSStorage(ctx context.Context, cfg AWSStorageConfig) (*AWSStorage, erro
 
 	creds := credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, "")
 
-	makeClient := func(endpoint string) (*s3.Client, error) {
+	loadConfig := func(endpoint string) (aws.Config, error) {
 		customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
 			if endpoint != "" {
 				return aws.Endpoint{
@@ -54,7 +68,14 @@ func NewAWSStorage(ctx context.Context, cfg AWSStorageConfig) (*AWSStorage, erro
 			config.WithEndpointResolverWithOptions(customResolver),
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load AWS config: %v", err)
+			return aws.Config{}, fmt.Errorf("failed to load AWS config: %v", err)
+		}
+		return newCfg, nil
+	}
+	makeClient := func(endpoint string) (*s3.Client, error) {
+		newCfg, err := loadConfig(endpoint)
+		if err != nil {
+			return nil, err
 		}
 		return s3.NewFromConfig(newCfg, func(o *s3.Options) {
 			o.UsePathStyle = cfg.ForcePathStyle
@@ -79,19 +100,36 @@ func NewAWSStorage(ctx context.Context, cfg AWSStorageConfig) (*AWSStorage, erro
 
 	log.Println("Successfully connected to AWS S3")
 
-	return &AWSStorage{Client: client, PresignClient: presignClient, BucketName: cfg.BucketName}, nil
+	s := &AWSStorage{Client: client, PresignClient: presignClient, BucketName: cfg.BucketName}
+	if cfg.PresignRoleARN != "" {
+		stsCfg, err := loadConfig(cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		s.presignBase = presignBase
+		s.sts = sts.NewFromConfig(stsCfg)
+		s.presignRoleARN = cfg.PresignRoleARN
+	}
+	return s, nil
 }
 
 func (s *AWSStorage) Close() error {
 	return nil
 }
 
-func (s *AWSStorage) PresignPut(ctx context.Context, key string, contentLength int64, contentType string) (string, error) {
+// PresignPut signs the checksum into the URL (X-Amz-Checksum-Sha256), so S3 verifies the body
+// against it and stores it with the object.
+func (s *AWSStorage) PresignPut(ctx context.Context, key string, contentLength int64, contentType, sha256 string) (string, error) {
+	sum, err := hex.DecodeString(sha256)
+	if err != nil || len(sum) != 32 {
+		return "", fmt.Errorf("invalid SHA-256 %q", sha256)
+	}
 	input := &s3.PutObjectInput{
-		Bucket:        aws.String(s.BucketName),
-		Key:           aws.String(key),
-		ContentLength: aws.Int64(contentLength),
-		ContentType:   aws.String(contentType),
+		Bucket:         aws.String(s.BucketName),
+		Key:            aws.String(key),
+		ContentLength:  aws.Int64(contentLength),
+		ContentType:    aws.String(contentType),
+		ChecksumSHA256: aws.String(base64.StdEncoding.EncodeToString(sum)),
 	}
 	req, err := s.PresignClient.PresignPutObject(ctx, input)
 	if err != nil {
@@ -112,6 +150,57 @@ func (s *AWSStorage) PresignGet(ctx context.Context, key string) (string, error)
 	return req.URL, nil
 }
 
+// PresignGetRestricted signs with temporary credentials of the presign role, assumed with a
+// session policy that allows reading key only from sourceCIDRs (aws:SourceIp). The URL lives as
+// long as the credentials, the STS minimum of 15 minutes. Without a role the URL is unrestricted
+// and lives RESTRICTED_PRESIGNED_URL_EXPIRATION.
+func (s *AWSStorage) PresignGetRestricted(ctx context.Context, key string, sourceCIDRs []string) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	}
+	if s.sts == nil || len(sourceCIDRs) == 0 {
+		req, err := s.PresignClient.PresignGetObject(ctx, input, s3.WithPresignExpires(localpkg.RESTRICTED_PRESIGNED_URL_EXPIRATION))
+		if err != nil {
+			return "", fmt.Errorf("presign get object: %w", err)
+		}
+		return req.URL, nil
+	}
+
+	policy, err := json.Marshal(map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{{
+			"Effect":    "Allow",
+			"Action":    "s3:GetObject",
+			"Resource":  "arn:aws:s3:::" + s.BucketName + "/" + key,
+			"Condition": map[string]any{"IpAddress": map[string]any{"aws:SourceIp": sourceCIDRs}},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	out, err := s.sts.AssumeRole(ctx, &sts.AssumeRoleInput{
+		RoleArn:         aws.String(s.presignRoleARN),
+		RoleSessionName: aws.String("filemanager-presign"),
+		Policy:          aws.String(string(policy)),
+		DurationSeconds: aws.Int32(int32(localpkg.PRESIGNED_URL_EXPIRATION.Seconds())),
+	})
+	if err != nil {
+		return "", fmt.Errorf("assume presign role: %w", err)
+	}
+	c := out.Credentials
+	creds := credentials.NewStaticCredentialsProvider(aws.ToString(c.AccessKeyId), aws.ToString(c.SecretAccessKey), aws.ToString(c.SessionToken))
+	presign := s3.NewPresignClient(s.presignBase, func(o *s3.PresignOptions) {
+		o.Expires = localpkg.PRESIGNED_URL_EXPIRATION
+		o.ClientOptions = append(o.ClientOptions, func(o *s3.Options) { o.Credentials = creds })
+	})
+	req, err := presign.PresignGetObject(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("presign get object: %w", err)
+	}
+	return req.URL, nil
+}
+
 func (s *AWSStorage) DeleteObject(ctx context.Context, key string) error {
 	_, err := s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(s.BucketName),
@@ -122,3 +211,85 @@ func (s *AWSStorage) DeleteObject(ctx context.Context, key string) error {
 	}
 	return nil
 }
+
+func (s *AWSStorage) ObjectExists(ctx context.Context, key string) (bool, error) {
+	_, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &notFound) || errors.As(err, &noSuchKey) {
+			return false, nil
+		}
+		return false, fmt.Errorf("head object %q: %w", key, err)
+	}
+	return true, nil
+}
+
+func (s *AWSStorage) ObjectSHA256(ctx context.Context, key string) (string, error) {
+	out, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s.BucketName),
+		Key:          aws.String(key),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return "", fmt.Errorf("head object %q: %w", key, err)
+	}
+	// Multipart uploads have a checksum of the part checksums ("...-N"), which does not decode
+	sum, err := base64.StdEncoding.DecodeString(aws.ToString(out.ChecksumSHA256))
+	if err != nil || len(sum) != 32 {
+		return "", fmt.Errorf("object %q: %w", key, ErrNoChecksum)
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+func (s *AWSStorage) HashObject(ctx context.Context, key string) (string, error) {
+	out, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("get object %q: %w", key, err)
+	}
+	defer out.Body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, out.Body); err != nil {
+		return "", fmt.Errorf("read object %q: %w", key, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *AWSStorage) ListObjects(ctx context.Context, prefix string, continuationToken string, pageSize int32) ([]ObjectInfo, string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.BucketName),
+		MaxKeys: aws.Int32(pageSize),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+	if continuationToken != "" {
+		input.ContinuationToken = aws.String(continuationToken)
+	}
+	out, err := s.Client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, "", fmt.Errorf("list objects: %w", err)
+	}
+	objects := make([]ObjectInfo, 0, len(out.Contents))
+	for _, o := range out.Contents {
+		info := ObjectInfo{
+			Key:  aws.ToString(o.Key),
+			Size: aws.ToInt64(o.Size),
+		}
+		if o.LastModified != nil {
+			info.LastModified = *o.LastModified
+		}
+		objects = append(objects, info)
+	}
+	next := ""
+	if aws.ToBool(out.IsTruncated) {
+		next = aws.ToString(out.NextContinuationToken)
+	}
+	return objects, next, nil
+}
@@ -1,13 +1,43 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoChecksum is returned by ObjectSHA256 for an object stored without a SHA-256 checksum.
+var ErrNoChecksum = errors.New("object has no SHA-256 checksum")
+
+// ObjectInfo describes a stored object as returned by ListObjects.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
 
 type Storage interface {
 	Close() error
-	// PresignPut returns a short-lived presigned URL for uploading an object via PUT.
-	PresignPut(ctx context.Context, key string, contentLength int64, contentType string) (url string, err error)
+	// PresignPut returns a short-lived presigned URL for uploading an object via PUT. The URL is
+	// signed over sha256, the hex-encoded SHA-256 of the content: the storage refuses any other
+	// body and keeps the checksum with the object.
+	PresignPut(ctx context.Context, key string, contentLength int64, contentType, sha256 string) (url string, err error)
 	// PresignGet returns a short-lived presigned URL for downloading an object via GET.
 	PresignGet(ctx context.Context, key string) (url string, err error)
+	// PresignGetRestricted returns a presigned GET URL that only works from sourceCIDRs where the
+	// storage can enforce it, and otherwise one with a shorter lifetime.
+	PresignGetRestricted(ctx context.Context, key string, sourceCIDRs []string) (url string, err error)
 	// DeleteObject deletes an object from storage by key. NoSuchKey is treated as success.
 	DeleteObject(ctx context.Context, key string) error
+	// ObjectExists reports whether an object is stored under key.
+	ObjectExists(ctx context.Context, key string) (bool, error)
+	// ObjectSHA256 returns the hex-encoded SHA-256 checksum the storage verified when the object
+	// was uploaded, without reading it, or ErrNoChecksum.
+	ObjectSHA256(ctx context.Context, key string) (string, error)
+	// HashObject streams an object and returns its hex-encoded SHA-256, for objects stored
+	// without a checksum.
+	HashObject(ctx context.Context, key string) (string, error)
+	// ListObjects returns one page of objects under prefix. Pass the returned token back to get
+	// the next page; an empty token means the listing is complete.
+	ListObjects(ctx context.Context, prefix string, continuationToken string, pageSize int32) (objects []ObjectInfo, nextToken string, err error)
 }
@@ -7,6 +7,9 @@ import (
 	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/cthulhu-platform/filemanager/pkg"
 )
 
 // Client is a gRPC client for the filemanager service.
@@ -15,9 +18,11 @@ type Client struct {
 	service pb.FilemanagerServiceClient
 }
 
-// NewClient creates a new filemanager gRPC client.
-func NewClient(ctx context.Context, addr string) (*Client, error) {
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewClient creates a client for addr. Connections are plaintext unless opts carries transport
+// credentials, such as grpcauth.Config.DialOption for mutual TLS.
+func NewClient(ctx context.Context, addr string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(addr, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %v", err)
 	}
@@ -74,3 +79,77 @@ func (c *Client) AuthenticateBucket(ctx context.Context, req *pb.AuthenticateBuc
 func (c *Client) DeleteBucket(ctx context.Context, req *pb.DeleteBucketRequest) (*pb.DeleteBucketResponse, error) {
 	return c.service.DeleteBucket(ctx, req)
 }
+
+// ReconcileStorage compares S3 objects with the files table and repairs orphaned objects and dangling rows unless dry_run is set.
+func (c *Client) ReconcileStorage(ctx context.Context, req *pb.ReconcileStorageRequest) (*pb.ReconcileStorageResponse, error) {
+	return c.service.ReconcileStorage(ctx, req)
+}
+
+// UpdateBucketDetails edits a bucket's title, description and file notes (bucket and organization admins only).
+func (c *Client) UpdateBucketDetails(ctx context.Context, req *pb.UpdateBucketDetailsRequest) (*pb.UpdateBucketDetailsResponse, error) {
+	return c.service.UpdateBucketDetails(ctx, req)
+}
+
+// ListSoleOwnedBuckets returns the buckets the user is the only admin of.
+func (c *Client) ListSoleOwnedBuckets(ctx context.Context, req *pb.ListSoleOwnedBucketsRequest) (*pb.ListSoleOwnedBucketsResponse, error) {
+	return c.service.ListSoleOwnedBuckets(ctx, req)
+}
+
+// ForgetUser removes a deleted user's bucket admin rows and clears them as owner of their files.
+func (c *Client) ForgetUser(ctx context.Context, req *pb.ForgetUserRequest) (*pb.ForgetUserResponse, error) {
+	return c.service.ForgetUser(ctx, req)
+}
+
+// ListBuckets searches all buckets, for platform moderators.
+func (c *Client) ListBuckets(ctx context.Context, req *pb.ListBucketsRequest) (*pb.ListBucketsResponse, error) {
+	return c.service.ListBuckets(ctx, req)
+}
+
+// SetBucketOrganization moves a bucket into an organization, or out of it with an empty org_id.
+func (c *Client) SetBucketOrganization(ctx context.Context, req *pb.SetBucketOrganizationRequest) (*pb.SetBucketOrganizationResponse, error) {
+	return c.service.SetBucketOrganization(ctx, req)
+}
+
+// ReleaseOrganizationBuckets makes the buckets of a deleted organization personal again.
+func (c *Client) ReleaseOrganizationBuckets(ctx context.Context, req *pb.ReleaseOrganizationBucketsRequest) (*pb.ReleaseOrganizationBucketsResponse, error) {
+	return c.service.ReleaseOrganizationBuckets(ctx, req)
+}
+
+// ReportBucket files an abuse report against a bucket.
+func (c *Client) ReportBucket(ctx context.Context, req *pb.ReportBucketRequest) (*pb.ReportBucketResponse, error) {
+	return c.service.ReportBucket(ctx, req)
+}
+
+// ListAbuseReports lists the abuse report queue, for platform moderators.
+func (c *Client) ListAbuseReports(ctx context.Context, req *pb.ListAbuseReportsRequest) (*pb.ListAbuseReportsResponse, error) {
+	return c.service.ListAbuseReports(ctx, req)
+}
+
+// DismissAbuseReport closes an open report without action.
+func (c *Client) DismissAbuseReport(ctx context.Context, req *pb.DismissAbuseReportRequest) (*pb.DismissAbuseReportResponse, error) {
+	return c.service.DismissAbuseReport(ctx, req)
+}
+
+// TakedownBucket removes a bucket for abuse and returns the reports it resolved.
+func (c *Client) TakedownBucket(ctx context.Context, req *pb.TakedownBucketRequest) (*pb.TakedownBucketResponse, error) {
+	return c.service.TakedownBucket(ctx, req)
+}
+
+// GetBucketAccessRules returns the access rules of a bucket to one of its admins.
+func (c *Client) GetBucketAccessRules(ctx context.Context, req *pb.GetBucketAccessRulesRequest) (*pb.GetBucketAccessRulesResponse, error) {
+	return c.service.GetBucketAccessRules(ctx, req)
+}
+
+// SetBucketAccessRules replaces the access rules of a bucket.
+func (c *Client) SetBucketAccessRules(ctx context.Context, req *pb.SetBucketAccessRulesRequest) (*pb.SetBucketAccessRulesResponse, error) {
+	return c.service.SetBucketAccessRules(ctx, req)
+}
+
+// WithClientInfo attaches the requesting client's IP address and Referer to calls made with ctx,
+// for the bucket access rules checked by IsBucketProtected and PrepareDownload.
+func WithClientInfo(ctx context.Context, ip, referrer string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		pkg.MetadataClientIP, ip,
+		pkg.MetadataClientReferrer, referrer,
+	)
+}
@@ -17,17 +17,19 @@ type AdminInfo struct {
 
 type BucketAdminsResponse struct {
 	BucketID string      `json:"bucket_id"`
+	OrgID    string      `json:"org_id,omitempty"` // organization whose admins also manage the bucket
 	Owner    *AdminInfo  `json:"owner"`
 	Admins   []AdminInfo `json:"admins"`
 }
 
 // FileInfo represents a stored object.
 type FileInfo struct {
-	OriginalName string `json:"original_name"`
-	StringID     string `json:"string_id"`
-	Key          string `json:"key"`
-	Size         int64  `json:"size"`
-	ContentType  string `json:"content_type"`
+	OriginalName string  `json:"original_name"`
+	StringID     string  `json:"string_id"`
+	Key          string  `json:"key"`
+	Size         int64   `json:"size"`
+	ContentType  string  `json:"content_type"`
+	Note         *string `json:"note,omitempty"`
 }
 
 // UploadResult is returned after an upload transaction.
@@ -40,11 +42,57 @@ type UploadResult struct {
 	TotalSize     int64      `json:"total_size,omitempty"`
 }
 
+// gRPC metadata keys the gateway sets from the client's request, checked against bucket access
+// rules.
+const (
+	MetadataClientIP       = "x-client-ip"
+	MetadataClientReferrer = "x-client-referer"
+)
+
+// Abuse report reasons, the categories a bucket can be reported under.
+const (
+	ReportReasonMalware    = "malware"
+	ReportReasonPhishing   = "phishing"
+	ReportReasonIllegal    = "illegal"
+	ReportReasonCopyright  = "copyright"
+	ReportReasonHarassment = "harassment"
+	ReportReasonSpam       = "spam"
+	ReportReasonOther      = "other"
+)
+
+var ReportReasons = []string{
+	ReportReasonMalware,
+	ReportReasonPhishing,
+	ReportReasonIllegal,
+	ReportReasonCopyright,
+	ReportReasonHarassment,
+	ReportReasonSpam,
+	ReportReasonOther,
+}
+
 // BucketMetadata contains objects under a storage ID.
 type BucketMetadata struct {
-	StorageID string     `json:"storage_id"`
-	Files     []FileInfo `json:"files"`
-	TotalSize int64      `json:"total_size"`
+	StorageID   string     `json:"storage_id"`
+	Title       *string    `json:"title,omitempty"`
+	Description *string    `json:"description,omitempty"` // Markdown
+	Files       []FileInfo `json:"files"`
+	TotalSize   int64      `json:"total_size"`
+	Status      string     `json:"status"` // BucketStatus, "removed" after a takedown
+}
+
+// ReconcileReport summarizes a reconciliation pass between S3 objects and the files table.
+// OrphanedKeys and DanglingKeys are capped samples; the counters are always exact.
+type ReconcileReport struct {
+	DryRun          bool     `json:"dry_run"`
+	ObjectsScanned  int64    `json:"objects_scanned"`
+	RowsScanned     int64    `json:"rows_scanned"`
+	OrphanedObjects int64    `json:"orphaned_objects"`
+	DanglingRows    int64    `json:"dangling_rows"`
+	ObjectsDeleted  int64    `json:"objects_deleted"`
+	RowsDeleted     int64    `json:"rows_deleted"`
+	Failures        int64    `json:"failures"`
+	OrphanedKeys    []string `json:"orphaned_keys,omitempty"`
+	DanglingKeys    []string `json:"dangling_keys,omitempty"`
 }
 
 // DownloadResult wraps object body and metadata for streaming.
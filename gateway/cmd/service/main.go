@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"log/slog"
 	"os"
 	"time"
 
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
+	"github.com/cthulhu-platform/gateway/internal/challenge"
 	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
 	internalpkg "github.com/cthulhu-platform/gateway/internal/pkg"
 	"github.com/cthulhu-platform/gateway/internal/server"
 )
@@ -16,6 +21,36 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	slog.SetDefault(logger)
 
+	switch internalpkg.AUTH_COOKIE_MODE {
+	case internalpkg.AuthCookieModeOff, internalpkg.AuthCookieModeRefresh, internalpkg.AuthCookieModeAll:
+	default:
+		slog.Error("Invalid AUTH_COOKIE_MODE, expected off, refresh or all", "value", internalpkg.AUTH_COOKIE_MODE)
+		os.Exit(1)
+	}
+
+	if _, err := middleware.ParseTrustedProxies(internalpkg.TRUSTED_PROXIES); err != nil {
+		slog.Error("Invalid TRUSTED_PROXIES", "error", err)
+		os.Exit(1)
+	}
+
+	challengeSecret := []byte(internalpkg.UPLOAD_CHALLENGE_SECRET)
+	if internalpkg.UPLOAD_CHALLENGE == challenge.TypePoW && len(challengeSecret) == 0 {
+		// Tokens issued by one replica will not verify on another
+		slog.Warn("UPLOAD_CHALLENGE_SECRET is not set, using a random secret")
+		key := make([]byte, 32)
+		rand.Read(key)
+		challengeSecret = []byte(hex.EncodeToString(key))
+	}
+	allowFakeChallenge := internalpkg.UPLOAD_CHALLENGE_ALLOW_FAKE == "true"
+	if internalpkg.UPLOAD_CHALLENGE == challenge.TypeFake {
+		if !allowFakeChallenge {
+			slog.Error("UPLOAD_CHALLENGE=fake lets any anonymous client upload, set UPLOAD_CHALLENGE_ALLOW_FAKE=true to use it for local runs and tests")
+			os.Exit(1)
+		}
+		slog.Warn("!!! UPLOAD_CHALLENGE=fake: anonymous uploads are NOT protected, any client sending the fake response gets through. Never run this in production !!!",
+			"response", challenge.FakeResponse)
+	}
+
 	ctx := context.Background()
 
 	// Setup Dependencies (5s timeout for connection initialization)
@@ -23,9 +58,22 @@ func main() {
 	defer cancel()
 
 	connectionPool, err := connections.NewConnectionsContainer(initCtx, connections.ConnectionsConfig{
-		LifecycleURL:   internalpkg.LIFECYCLE_GRPC_URL,
-		AuthURL:        internalpkg.AUTH_GRPC_URL,
-		FilemanagerURL: internalpkg.FILEMANAGER_GRPC_URL,
+		LifecycleURL:      internalpkg.LIFECYCLE_GRPC_URL,
+		AuthURL:           internalpkg.AUTH_GRPC_URL,
+		FilemanagerURL:    internalpkg.FILEMANAGER_GRPC_URL,
+		JWKSCacheTTL:      internalpkg.JWKS_CACHE_TTL,
+		GRPCAuth:          grpcauth.ConfigFromEnv(),
+		RateLimitStore:    internalpkg.RATE_LIMIT_STORE,
+		RateLimitRedisURL: internalpkg.RATE_LIMIT_REDIS_URL,
+		UploadChallenge: challenge.Config{
+			Type:          internalpkg.UPLOAD_CHALLENGE,
+			Secret:        challengeSecret,
+			Difficulty:    internalpkg.UPLOAD_POW_DIFFICULTY,
+			MaxDifficulty: internalpkg.UPLOAD_POW_MAX_DIFFICULTY,
+			SiteKey:       internalpkg.CAPTCHA_SITE_KEY,
+			CaptchaSecret: internalpkg.CAPTCHA_SECRET,
+			AllowFake:     allowFakeChallenge,
+		},
 	})
 	if err != nil {
 		slog.Error("Failed to create connections container", "error", err)
@@ -33,6 +81,9 @@ func main() {
 	}
 	defer connectionPool.Close()
 
+	// Mirror access token revocations so logouts take effect without a per-request auth call
+	go connectionPool.AuthVerifier.SyncRevocations(ctx, internalpkg.REVOCATION_SYNC_INTERVAL)
+
 	serverCfg := server.FiberServerConfig{
 		Host:   internalpkg.APP_HOST,
 		Port:   internalpkg.APP_PORT,
@@ -0,0 +1,96 @@
+// Package challenge guards anonymous uploads with a challenge the client solves before
+// preparing an upload: a hashcash-style proof of work issued and verified by the gateway, or a
+// CAPTCHA (hCaptcha or Cloudflare Turnstile) verified with its provider.
+package challenge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cthulhu-platform/gateway/internal/ratelimit"
+)
+
+// Challenge types
+const (
+	TypeNone      = "none" // nothing to solve
+	TypePoW       = "pow"
+	TypeHCaptcha  = "hcaptcha"
+	TypeTurnstile = "turnstile"
+	TypeFake      = "fake" // accepts FakeResponse, for local runs and tests, only with Config.AllowFake
+)
+
+var (
+	// ErrRequired is returned for a request without a response to the challenge.
+	ErrRequired = errors.New("upload challenge required")
+	// ErrFailed is returned for a wrong, expired or reused response.
+	ErrFailed = errors.New("upload challenge failed")
+)
+
+// Challenge is what a client has to solve, as served by GET /files/upload/challenge.
+type Challenge struct {
+	Type string `json:"type"`
+	// Proof of work: find a suffix such that SHA-256(Token + ":" + suffix) starts with
+	// Difficulty zero bits, and send "Token:suffix"
+	Token      string     `json:"token,omitempty"`
+	Difficulty int        `json:"difficulty,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// CAPTCHA: the site key for the provider's widget, whose response token is sent as is
+	SiteKey string `json:"site_key,omitempty"`
+}
+
+// Challenger issues challenges to anonymous uploaders and checks their responses.
+type Challenger interface {
+	Issue(ctx context.Context, ip string) (Challenge, error)
+	// Verify returns ErrRequired or ErrFailed when the upload must be refused, or another error
+	// when the response could not be checked.
+	Verify(ctx context.Context, ip, response string) error
+}
+
+// Config selects and configures the Challenger.
+type Config struct {
+	Type string // none, pow, hcaptcha, turnstile or fake
+	// Proof of work: HMAC key of the challenge tokens (shared by all replicas) and the number of
+	// leading zero bits required of a first challenge and at most, as recent requests add bits
+	Secret        []byte
+	Difficulty    int
+	MaxDifficulty int
+	// CAPTCHA provider keys
+	SiteKey       string
+	CaptchaSecret string
+	// AllowFake must be set for the fake challenge, which lets any client through
+	AllowFake bool
+}
+
+// New returns the Challenger of cfg, or nil for none. The proof of work keeps recent activity
+// and used tokens in store (nil to scale nothing and allow reuse until expiry).
+func New(cfg Config, store ratelimit.Store) (Challenger, error) {
+	switch cfg.Type {
+	case TypeNone:
+		return nil, nil
+	case TypePoW:
+		if len(cfg.Secret) < 32 {
+			return nil, errors.New("proof of work secret must be at least 32 bytes")
+		}
+		if cfg.Difficulty < 1 || cfg.MaxDifficulty < cfg.Difficulty || cfg.MaxDifficulty > 32 {
+			return nil, fmt.Errorf("invalid proof of work difficulty %d to %d, expected 1 <= min <= max <= 32", cfg.Difficulty, cfg.MaxDifficulty)
+		}
+		return NewProofOfWork(cfg.Secret, cfg.Difficulty, cfg.MaxDifficulty, store), nil
+	case TypeHCaptcha, TypeTurnstile:
+		if cfg.SiteKey == "" || cfg.CaptchaSecret == "" {
+			return nil, fmt.Errorf("%s needs a site key and a secret", cfg.Type)
+		}
+		verifier := NewHCaptchaVerifier(cfg.CaptchaSecret)
+		if cfg.Type == TypeTurnstile {
+			verifier = NewTurnstileVerifier(cfg.CaptchaSecret)
+		}
+		return NewCaptcha(cfg.Type, cfg.SiteKey, verifier), nil
+	case TypeFake:
+		if !cfg.AllowFake {
+			return nil, errors.New("the fake upload challenge accepts any client and must be explicitly allowed")
+		}
+		return NewCaptcha(TypeFake, "", FakeVerifier{}), nil
+	}
+	return nil, fmt.Errorf("unknown upload challenge %q, use none, pow, hcaptcha, turnstile or fake", cfg.Type)
+}
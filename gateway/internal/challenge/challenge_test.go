@@ -0,0 +1,105 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        Config
+		wantType   string
+		shouldFail bool
+	}{
+		{name: "none", cfg: Config{Type: TypeNone}},
+		{name: "pow", cfg: Config{Type: TypePoW, Secret: testSecret, Difficulty: 16, MaxDifficulty: 22}, wantType: TypePoW},
+		{name: "pow with a short secret", cfg: Config{Type: TypePoW, Secret: []byte("short"), Difficulty: 16, MaxDifficulty: 22}, shouldFail: true},
+		{name: "pow with max below min", cfg: Config{Type: TypePoW, Secret: testSecret, Difficulty: 16, MaxDifficulty: 8}, shouldFail: true},
+		{name: "pow above 32 bits", cfg: Config{Type: TypePoW, Secret: testSecret, Difficulty: 16, MaxDifficulty: 33}, shouldFail: true},
+		{name: "hcaptcha", cfg: Config{Type: TypeHCaptcha, SiteKey: "site", CaptchaSecret: "secret"}, wantType: TypeHCaptcha},
+		{name: "turnstile without keys", cfg: Config{Type: TypeTurnstile}, shouldFail: true},
+		{name: "fake", cfg: Config{Type: TypeFake, AllowFake: true}, wantType: TypeFake},
+		{name: "fake not allowed", cfg: Config{Type: TypeFake}, shouldFail: true},
+		{name: "unknown", cfg: Config{Type: "riddle"}, shouldFail: true},
+	}
+	for _, tt := range tests {
+		c, err := New(tt.cfg, nil)
+		if tt.shouldFail {
+			if err == nil {
+				t.Errorf("%s: New succeeded", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.wantType == "" {
+			if c != nil {
+				t.Errorf("%s: challenger = %T, want none", tt.name, c)
+			}
+			continue
+		}
+		if got, err := c.Issue(context.Background(), "192.0.2.1"); err != nil || got.Type != tt.wantType {
+			t.Errorf("%s: issue = %+v, %v, want type %s", tt.name, got, err, tt.wantType)
+		}
+	}
+}
+
+func TestCaptchaFakeVerifier(t *testing.T) {
+	ctx := context.Background()
+	c := NewCaptcha(TypeFake, "site", FakeVerifier{})
+
+	if got, err := c.Issue(ctx, "192.0.2.1"); err != nil || got != (Challenge{Type: TypeFake, SiteKey: "site"}) {
+		t.Fatalf("issue = %+v, %v", got, err)
+	}
+	for _, tt := range []struct {
+		response string
+		want     error
+	}{
+		{response: FakeResponse},
+		{response: "", want: ErrRequired},
+		{response: "fail", want: ErrFailed},
+	} {
+		if err := c.Verify(ctx, "192.0.2.1", tt.response); !errors.Is(err, tt.want) {
+			t.Errorf("verify %q: err = %v, want %v", tt.response, err, tt.want)
+		}
+	}
+}
+
+func TestSiteVerifier(t *testing.T) {
+	ctx := context.Background()
+	var form map[string]string
+	status, body := http.StatusOK, `{"success": true}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = map[string]string{"secret": r.PostForm.Get("secret"), "response": r.PostForm.Get("response"), "remoteip": r.PostForm.Get("remoteip")}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	v := NewHCaptchaVerifier("secret")
+	v.url = srv.URL
+	c := NewCaptcha(TypeHCaptcha, "site", v)
+
+	if err := c.Verify(ctx, "192.0.2.1", "token"); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if form["secret"] != "secret" || form["response"] != "token" || form["remoteip"] != "192.0.2.1" {
+		t.Fatalf("siteverify form = %v", form)
+	}
+
+	body = `{"success": false}`
+	if err := c.Verify(ctx, "192.0.2.1", "token"); !errors.Is(err, ErrFailed) {
+		t.Fatalf("verify rejected response: err = %v, want ErrFailed", err)
+	}
+	// A provider error is reported as such, not as a failed challenge
+	status = http.StatusInternalServerError
+	if err := c.Verify(ctx, "192.0.2.1", "token"); err == nil || errors.Is(err, ErrFailed) {
+		t.Fatalf("verify with a provider error: err = %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cthulhu-platform/gateway/internal/ratelimit"
+)
+
+const (
+	// How long a proof of work token can be solved and used
+	powTTL = 5 * time.Minute
+	// Store key prefixes
+	powActivity = "challenge-activity"
+	powUsed     = "challenge-used"
+)
+
+// Challenges issued to an IP, counted in a bucket refilled over an hour: each doubling of the
+// ones taken adds a bit of difficulty
+var powActivityPolicy = ratelimit.Policy{Burst: 64, Period: time.Hour}
+
+// ProofOfWork issues hashcash-style challenges. Tokens are stateless: they carry their expiry,
+// difficulty and a nonce, and an HMAC over those and the client IP, so any replica sharing the
+// secret verifies them.
+type ProofOfWork struct {
+	secret        []byte
+	difficulty    int
+	maxDifficulty int
+	store         ratelimit.Store
+	now           func() time.Time
+}
+
+func NewProofOfWork(secret []byte, difficulty, maxDifficulty int, store ratelimit.Store) *ProofOfWork {
+	return &ProofOfWork{
+		secret:        secret,
+		difficulty:    difficulty,
+		maxDifficulty: maxDifficulty,
+		store:         store,
+		now:           time.Now,
+	}
+}
+
+func (p *ProofOfWork) Issue(ctx context.Context, ip string) (Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return Challenge{}, err
+	}
+	difficulty := p.difficultyFor(ctx, ip)
+	expiresAt := p.now().Add(powTTL).Truncate(time.Second)
+	claims := fmt.Sprintf("%d.%d.%s", expiresAt.Unix(), difficulty, base64.RawURLEncoding.EncodeToString(nonce))
+	return Challenge{
+		Type:       TypePoW,
+		Token:      claims + "." + base64.RawURLEncoding.EncodeToString(p.mac(claims, ip)),
+		Difficulty: difficulty,
+		ExpiresAt:  &expiresAt,
+	}, nil
+}
+
+// difficultyFor adds a bit to the base difficulty for each doubling of the challenges ip was
+// issued in the last hour.
+func (p *ProofOfWork) difficultyFor(ctx context.Context, ip string) int {
+	if p.store == nil {
+		return p.difficulty
+	}
+	res, err := p.store.Take(ctx, "rl:"+powActivity+":ip:"+ip, powActivityPolicy)
+	if err != nil {
+		slog.Warn("Challenge activity unavailable, using base difficulty", "error", err)
+		return p.difficulty
+	}
+	if !res.Allowed {
+		return p.maxDifficulty
+	}
+	issued := res.Limit - res.Remaining
+	return min(p.difficulty+bits.Len(uint(issued))-1, p.maxDifficulty)
+}
+
+// Verify checks a "token:suffix" response: the token was issued to ip and has not expired, the
+// hash meets its difficulty, and it was not used before.
+func (p *ProofOfWork) Verify(ctx context.Context, ip, response string) error {
+	if response == "" {
+		return ErrRequired
+	}
+	token, suffix, ok := strings.Cut(response, ":")
+	if !ok || suffix == "" {
+		return ErrFailed
+	}
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return ErrFailed
+	}
+	claims := token[:i]
+	mac, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || !hmac.Equal(mac, p.mac(claims, ip)) {
+		return ErrFailed
+	}
+	parts := strings.Split(claims, ".")
+	if len(parts) != 3 {
+		return ErrFailed
+	}
+	expiry, err1 := strconv.ParseInt(parts[0], 10, 64)
+	difficulty, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || !p.now().Before(time.Unix(expiry, 0)) {
+		return ErrFailed
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(response))) < difficulty {
+		return ErrFailed
+	}
+
+	// A token is spent by its first upload: its bucket of one refills after the token expired
+	if p.store != nil {
+		res, err := p.store.Take(ctx, "rl:"+powUsed+":"+parts[2], ratelimit.Policy{Burst: 1, Period: powTTL})
+		if err != nil {
+			slog.Warn("Challenge reuse check unavailable", "error", err)
+		} else if !res.Allowed {
+			return ErrFailed
+		}
+	}
+	return nil
+}
+
+func (p *ProofOfWork) mac(claims, ip string) []byte {
+	h := hmac.New(sha256.New, p.secret)
+	h.Write([]byte(claims + "|" + ip))
+	return h.Sum(nil)
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,148 @@
+package challenge
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cthulhu-platform/gateway/internal/ratelimit"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// newTestProofOfWork returns a proof of work whose clock only moves when the returned function
+// is called.
+func newTestProofOfWork(difficulty, maxDifficulty int, store ratelimit.Store) (*ProofOfWork, func(time.Duration)) {
+	p := NewProofOfWork(testSecret, difficulty, maxDifficulty, store)
+	now := time.Unix(1_700_000_000, 0)
+	p.now = func() time.Time { return now }
+	return p, func(d time.Duration) { now = now.Add(d) }
+}
+
+// solve returns the first response to token whose hash has enough zero bits, or with enough set
+// to false, the first one whose hash does not.
+func solve(token string, difficulty int, enough bool) string {
+	for i := 0; ; i++ {
+		response := token + ":" + strconv.Itoa(i)
+		if (leadingZeroBits(sha256.Sum256([]byte(response))) >= difficulty) == enough {
+			return response
+		}
+	}
+}
+
+func TestProofOfWorkVerify(t *testing.T) {
+	ctx := context.Background()
+	p, advance := newTestProofOfWork(8, 8, ratelimit.NewMemoryStore())
+
+	issue := func() Challenge {
+		t.Helper()
+		c, err := p.Issue(ctx, "192.0.2.1")
+		if err != nil {
+			t.Fatalf("issue: %v", err)
+		}
+		return c
+	}
+
+	c := issue()
+	if c.Type != TypePoW || c.Difficulty != 8 || !c.ExpiresAt.Equal(p.now().Add(powTTL)) {
+		t.Fatalf("challenge = %+v", c)
+	}
+	solved := solve(c.Token, c.Difficulty, true)
+	if err := p.Verify(ctx, "192.0.2.1", solved); err != nil {
+		t.Fatalf("verify solved token: %v", err)
+	}
+	// A token is spent by its first use
+	if err := p.Verify(ctx, "192.0.2.1", solved); !errors.Is(err, ErrFailed) {
+		t.Fatalf("verify reused token: err = %v, want ErrFailed", err)
+	}
+
+	c = issue()
+	solved = solve(c.Token, c.Difficulty, true)
+	expiry, _, _ := strings.Cut(c.Token, ".")
+	tests := []struct {
+		name     string
+		ip       string
+		response string
+		want     error
+	}{
+		{name: "empty", ip: "192.0.2.1", response: "", want: ErrRequired},
+		{name: "no suffix", ip: "192.0.2.1", response: c.Token, want: ErrFailed},
+		{name: "wrong ip", ip: "192.0.2.2", response: solved, want: ErrFailed},
+		{name: "too few zero bits", ip: "192.0.2.1", response: solve(c.Token, c.Difficulty, false), want: ErrFailed},
+		{name: "tampered token", ip: "192.0.2.1", response: strings.Replace(solved, expiry, expiry+"0", 1), want: ErrFailed},
+		{name: "malformed token", ip: "192.0.2.1", response: "token:0", want: ErrFailed},
+	}
+	for _, tt := range tests {
+		if err := p.Verify(ctx, tt.ip, tt.response); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// The failed attempts did not spend the token, but it expires
+	advance(powTTL)
+	if err := p.Verify(ctx, "192.0.2.1", solved); !errors.Is(err, ErrFailed) {
+		t.Fatalf("verify expired token: err = %v, want ErrFailed", err)
+	}
+	advance(-time.Second)
+	if err := p.Verify(ctx, "192.0.2.1", solved); err != nil {
+		t.Fatalf("verify token a second before expiry: %v", err)
+	}
+}
+
+func TestProofOfWorkWithoutStore(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestProofOfWork(4, 8, nil)
+
+	for range 3 {
+		c, err := p.Issue(ctx, "192.0.2.1")
+		if err != nil || c.Difficulty != 4 {
+			t.Fatalf("issue = %+v, %v, want the base difficulty", c, err)
+		}
+	}
+	// Without a store, tokens can be reused until they expire
+	c, _ := p.Issue(ctx, "192.0.2.1")
+	solved := solve(c.Token, c.Difficulty, true)
+	for i := range 2 {
+		if err := p.Verify(ctx, "192.0.2.1", solved); err != nil {
+			t.Fatalf("verify %d: %v", i, err)
+		}
+	}
+}
+
+func TestProofOfWorkDifficulty(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestProofOfWork(4, 8, ratelimit.NewMemoryStore())
+
+	// A bit per doubling of the challenges issued to the IP
+	var got []int
+	for range 8 {
+		c, err := p.Issue(ctx, "192.0.2.1")
+		if err != nil {
+			t.Fatalf("issue: %v", err)
+		}
+		got = append(got, c.Difficulty)
+	}
+	want := []int{4, 5, 5, 6, 6, 6, 6, 7}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("difficulties = %v, want %v", got, want)
+		}
+	}
+
+	// Other IPs start at the base difficulty
+	if c, _ := p.Issue(ctx, "192.0.2.2"); c.Difficulty != 4 {
+		t.Fatalf("difficulty for another ip = %d, want 4", c.Difficulty)
+	}
+
+	// The difficulty stops at the maximum, also once the activity bucket is empty
+	for range powActivityPolicy.Burst {
+		p.Issue(ctx, "192.0.2.1")
+	}
+	if c, _ := p.Issue(ctx, "192.0.2.1"); c.Difficulty != 8 {
+		t.Fatalf("difficulty after %d challenges = %d, want 8", powActivityPolicy.Burst+8, c.Difficulty)
+	}
+}
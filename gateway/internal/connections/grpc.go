@@ -3,24 +3,39 @@ package connections
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"time"
 
 	auth "github.com/cthulhu-platform/auth/pkg/client"
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
 	filemanager "github.com/cthulhu-platform/filemanager/pkg/client"
+	"github.com/cthulhu-platform/gateway/internal/challenge"
+	"github.com/cthulhu-platform/gateway/internal/ratelimit"
 	lifecycle "github.com/cthulhu-platform/lifecycle/pkg/client"
 )
 
 type ConnectionsContainer struct {
-	Lifecycle   *lifecycle.Client
-	Auth        *auth.Client
-	Filemanager *filemanager.Client
+	Lifecycle    *lifecycle.Client
+	Auth         *auth.Client
+	AuthVerifier *auth.TokenVerifier
+	Filemanager  *filemanager.Client
+	// nil when rate limiting is off
+	RateLimiter ratelimit.Store
+	// nil when anonymous uploads are not challenged
+	UploadChallenge challenge.Challenger
 }
 
 type ConnectionsConfig struct {
 	LifecycleURL   string
 	AuthURL        string
 	FilemanagerURL string
+	JWKSCacheTTL   time.Duration
+	GRPCAuth       grpcauth.Config
+	// Rate limiter: "memory", "redis" (at RateLimitRedisURL) or "off"
+	RateLimitStore    string
+	RateLimitRedisURL string
+	UploadChallenge   challenge.Config
 }
 
 func NewConnectionsContainer(ctx context.Context, cfg ConnectionsConfig) (*ConnectionsContainer, error) {
@@ -28,15 +43,49 @@ func NewConnectionsContainer(ctx context.Context, cfg ConnectionsConfig) (*Conne
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
+	var rateLimiter ratelimit.Store
+	switch cfg.RateLimitStore {
+	case "memory":
+		rateLimiter = ratelimit.NewMemoryStore()
+	case "redis":
+		store, err := ratelimit.NewRedisStore(cfg.RateLimitRedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create rate limit store: %v", err)
+		}
+		rateLimiter = store
+		slog.Info("Rate limit store created", "store", "redis", "addr", store.Addr())
+	case "off":
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.RateLimitStore)
+	}
+
+	uploadChallenge, err := challenge.New(cfg.UploadChallenge, rateLimiter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload challenge: %v", err)
+	}
+
+	lifecycleCreds, err := cfg.GRPCAuth.DialOption(grpcauth.Lifecycle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lifecycle credentials: %v", err)
+	}
+	authCreds, err := cfg.GRPCAuth.DialOption(grpcauth.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load auth credentials: %v", err)
+	}
+	filemanagerCreds, err := cfg.GRPCAuth.DialOption(grpcauth.Filemanager)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load filemanager credentials: %v", err)
+	}
+
 	// Connect to lifecycle service
-	lifecycleClient, err := lifecycle.NewClient(ctx, cfg.LifecycleURL)
+	lifecycleClient, err := lifecycle.NewClient(ctx, cfg.LifecycleURL, lifecycleCreds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create lifecycle client: %v", err)
 	}
 	slog.Info("Lifecycle client created", "url", cfg.LifecycleURL)
 
 	// Connect to auth service
-	authClient, err := auth.NewClient(ctx, cfg.AuthURL)
+	authClient, err := auth.NewClient(ctx, cfg.AuthURL, authCreds)
 	if err != nil {
 		lifecycleClient.Close()
 		return nil, fmt.Errorf("failed to create auth client: %v", err)
@@ -44,7 +93,7 @@ func NewConnectionsContainer(ctx context.Context, cfg ConnectionsConfig) (*Conne
 	slog.Info("Auth client created", "url", cfg.AuthURL)
 
 	// Connect to filemanager service
-	filemanagerClient, err := filemanager.NewClient(ctx, cfg.FilemanagerURL)
+	filemanagerClient, err := filemanager.NewClient(ctx, cfg.FilemanagerURL, filemanagerCreds)
 	if err != nil {
 		authClient.Close()
 		lifecycleClient.Close()
@@ -53,9 +102,12 @@ func NewConnectionsContainer(ctx context.Context, cfg ConnectionsConfig) (*Conne
 	slog.Info("Filemanager client created", "url", cfg.FilemanagerURL)
 
 	return &ConnectionsContainer{
-		Lifecycle:   lifecycleClient,
-		Auth:        authClient,
-		Filemanager: filemanagerClient,
+		Lifecycle:       lifecycleClient,
+		Auth:            authClient,
+		AuthVerifier:    auth.NewTokenVerifier(authClient, cfg.JWKSCacheTTL),
+		Filemanager:     filemanagerClient,
+		RateLimiter:     rateLimiter,
+		UploadChallenge: uploadChallenge,
 	}, nil
 }
 
@@ -63,4 +115,7 @@ func (c *ConnectionsContainer) Close() {
 	c.Lifecycle.Close()
 	c.Auth.Close()
 	c.Filemanager.Close()
+	if closer, ok := c.RateLimiter.(io.Closer); ok {
+		closer.Close()
+	}
 }
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
+	"github.com/cthulhu-platform/gateway/internal/models"
+	fmpb "github.com/cthulhu-platform/proto/pkg/filemanager"
+	"github.com/gofiber/fiber/v2"
+)
+
+// FileBucketAccessGet returns the bucket's access rules to its admins.
+func FileBucketAccessGet(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		bucketID := strings.TrimSpace(c.Params("id"))
+		if bucketID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket id is required"})
+		}
+		user := middleware.GetUser(c)
+		res, err := conns.Filemanager.GetBucketAccessRules(c.Context(), &fmpb.GetBucketAccessRulesRequest{
+			BucketId: bucketID,
+			UserId:   user.ID,
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.Error != "" {
+			return accessRulesError(c, res.Error)
+		}
+		return c.JSON(accessRulesJSON(bucketID, res.Rules))
+	}
+}
+
+// FileBucketAccessUpdate replaces the bucket's access rules: client CIDRs, Referer hosts
+// ("*.example.com" for subdomains) and ISO country codes. Each non-empty list must match for a
+// client to read the bucket.
+func FileBucketAccessUpdate(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		bucketID := strings.TrimSpace(c.Params("id"))
+		if bucketID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket id is required"})
+		}
+		user := middleware.GetUser(c)
+		var req models.BucketAccessRulesRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+
+		res, err := conns.Filemanager.SetBucketAccessRules(c.Context(), &fmpb.SetBucketAccessRulesRequest{
+			BucketId: bucketID,
+			UserId:   user.ID,
+			Rules: &fmpb.BucketAccessRules{
+				AllowedCidrs:     req.AllowedCIDRs,
+				AllowedReferrers: req.AllowedReferrers,
+				AllowedCountries: req.AllowedCountries,
+			},
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.Error != "" {
+			return accessRulesError(c, res.Error)
+		}
+		return c.JSON(accessRulesJSON(bucketID, res.Rules))
+	}
+}
+
+func accessRulesError(c *fiber.Ctx, msg string) error {
+	switch msg {
+	case "bucket not found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": msg})
+	case "only bucket admins can manage access rules":
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": msg})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+}
+
+func accessRulesJSON(bucketID string, r *fmpb.BucketAccessRules) fiber.Map {
+	// Lists are always arrays, never null
+	return fiber.Map{
+		"bucket_id":         bucketID,
+		"allowed_cidrs":     append([]string{}, r.GetAllowedCidrs()...),
+		"allowed_referrers": append([]string{}, r.GetAllowedReferrers()...),
+		"allowed_countries": append([]string{}, r.GetAllowedCountries()...),
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
+	fmpb "github.com/cthulhu-platform/proto/pkg/filemanager"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminBucketsList searches all buckets by id or title (q) and status, newest first.
+func AdminBucketsList(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		res, err := conns.Filemanager.ListBuckets(c.Context(), &fmpb.ListBucketsRequest{
+			Query:  c.Query("q"),
+			Status: c.Query("status"),
+			Limit:  int32(c.QueryInt("limit")),
+			Offset: int32(c.QueryInt("offset")),
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.Error != "" {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": res.Error})
+		}
+		buckets := make([]fiber.Map, 0, len(res.Buckets))
+		for _, b := range res.Buckets {
+			buckets = append(buckets, fiber.Map{
+				"id":         b.Id,
+				"title":      b.Title,
+				"status":     b.Status,
+				"protected":  b.Protected,
+				"file_count": b.FileCount,
+				"total_size": b.TotalSize,
+				"created_at": b.CreatedAt,
+				"updated_at": b.UpdatedAt,
+			})
+		}
+		return c.JSON(fiber.Map{"buckets": buckets})
+	}
+}
+
+// AdminBucketDelete force deletes a bucket and its files regardless of its admins, then drops its
+// lifecycle (expiry) and records the deletion in the audit log.
+func AdminBucketDelete(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := middleware.GetUser(c)
+		bucketID := strings.TrimSpace(c.Params("id"))
+		if bucketID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket id is required"})
+		}
+
+		res, err := conns.Filemanager.DeleteBucket(c.Context(), &fmpb.DeleteBucketRequest{BucketId: bucketID})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !res.Success {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": res.Error})
+		}
+		// the lifecycle service would otherwise try to delete the bucket again at its expiry
+		if _, err := conns.Lifecycle.DeleteLifecycle(c.Context(), bucketID); err != nil {
+			slog.Warn("Failed to delete lifecycle of force deleted bucket", "bucket_id", bucketID, "error", err)
+		}
+		if _, err := conns.Auth.RecordAuditEvent(clientContext(c), pkg.AuditEvent{
+			ActorID:    user.ID,
+			Action:     pkg.AuditBucketDelete,
+			TargetType: pkg.AuditTargetBucket,
+			TargetID:   bucketID,
+			Details:    fmt.Sprintf("%d files", res.FilesDeleted),
+		}); err != nil {
+			slog.Error("Failed to record bucket deletion in the audit log", "bucket_id", bucketID, "actor_id", user.ID, "error", err)
+		}
+
+		return c.JSON(fiber.Map{"success": true, "files_deleted": res.FilesDeleted})
+	}
+}
+
+// AdminUserSuspend suspends (logging the user out everywhere) or reinstates a user. The auth
+// service only lets users suspend those with a lower role.
+func AdminUserSuspend(conns *connections.ConnectionsContainer, suspend bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := middleware.GetUser(c)
+		userID := strings.TrimSpace(c.Params("id"))
+		if userID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user id is required"})
+		}
+
+		if _, err := conns.Auth.SetUserSuspended(clientContext(c), user.ID, userID, suspend); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"success": true, "suspended": suspend})
+	}
+}
+
+// AdminUserRole sets a user's platform role (admins only, not their own).
+func AdminUserRole(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := middleware.GetUser(c)
+		userID := strings.TrimSpace(c.Params("id"))
+		if userID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user id is required"})
+		}
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if !slices.Contains(pkg.Roles, req.Role) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "role must be one of " + strings.Join(pkg.Roles, ", "),
+			})
+		}
+
+		if _, err := conns.Auth.SetUserRole(clientContext(c), user.ID, userID, req.Role); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"success": true, "role": req.Role})
+	}
+}
+
+// AdminAuditList pages through the audit log newest first, optionally filtered by actor_id,
+// target_id and action. next_before, when set, fetches the following page.
+func AdminAuditList(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		before := int64(c.QueryInt("before"))
+		if before < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "before must be a positive event id"})
+		}
+		limit := c.QueryInt("limit")
+
+		events, err := conns.Auth.ListAuditEvents(c.Context(), before, c.Query("actor_id"), c.Query("target_id"), c.Query("action"), limit)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		out := fiber.Map{"events": events}
+		if len(events) > 0 && (limit <= 0 || len(events) >= limit) {
+			out["next_before"] = events[len(events)-1].ID
+		}
+		return c.JSON(out)
+	}
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
 	"github.com/cthulhu-platform/gateway/internal/pkg"
 	"github.com/gofiber/fiber/v2"
 )
@@ -44,13 +46,21 @@ func OAuthCallback(conns *connections.ConnectionsContainer) fiber.Handler {
 		isAPIRequest := acceptHeader == "application/json" || c.Get("Content-Type") == "application/json"
 
 		if isAPIRequest {
-			authResponse, err := conns.Auth.HandleOAuthCallback(c.Context(), provider, code, state)
+			authResponse, err := conns.Auth.HandleOAuthCallback(clientContext(c), provider, code, state)
 			if err != nil {
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 					"error": err.Error(),
 				})
 			}
-			return c.JSON(authResponse)
+			// Second factor required: the client asks for a code and posts it to /auth/mfa/verify
+			if authResponse.MFAToken != "" {
+				return c.JSON(fiber.Map{
+					"mfa_required":   true,
+					"mfa_token":      authResponse.MFAToken,
+					"mfa_expires_in": authResponse.MFAExpiresIn,
+				})
+			}
+			return signInResponse(c, authResponse)
 		}
 
 		clientCallbackURL := pkg.CORS_ORIGIN + "/signin/callback"
@@ -59,54 +69,91 @@ func OAuthCallback(conns *connections.ConnectionsContainer) fiber.Handler {
 	}
 }
 
+// TokenRefresh exchanges a refresh token for a new token pair. A refresh_token in the body gets
+// the pair in the body; in cookie mode a request without one uses the refresh token cookie (with
+// a valid CSRF token) and gets new cookies.
 func TokenRefresh(conns *connections.ConnectionsContainer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var req struct {
 			RefreshToken string `json:"refresh_token"`
 		}
 
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "invalid request body",
-			})
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "invalid request body",
+				})
+			}
 		}
 
+		fromCookie := false
+		if req.RefreshToken == "" && cookieMode() {
+			req.RefreshToken = c.Cookies(pkg.REFRESH_TOKEN_COOKIE)
+			fromCookie = req.RefreshToken != ""
+		}
 		if req.RefreshToken == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "refresh_token is required",
 			})
 		}
+		if fromCookie && !middleware.ValidCSRF(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": middleware.ErrInvalidCSRF.Error(),
+			})
+		}
 
-		tokenPair, err := conns.Auth.RefreshToken(c.Context(), req.RefreshToken)
+		tokenPair, err := conns.Auth.RefreshToken(clientContext(c), req.RefreshToken)
 		if err != nil {
+			if fromCookie {
+				clearSessionCookies(c)
+			}
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
 
-		return c.JSON(tokenPair)
+		if !fromCookie {
+			return c.JSON(tokenPair)
+		}
+		return c.JSON(setSessionCookies(c, tokenPair.AccessToken, tokenPair.RefreshToken, c.Cookies(pkg.CSRF_COOKIE)))
 	}
 }
 
-func TokenLogout(conns *connections.ConnectionsContainer) fiber.Handler {
+// CSRFToken returns the CSRF token of the session cookies, for clients that lost it (e.g. on a
+// reload), issuing one if the cookie is gone. 401 without a session cookie.
+func CSRFToken() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
+		if !cookieMode() || (c.Cookies(pkg.REFRESH_TOKEN_COOKIE) == "" && c.Cookies(pkg.ACCESS_TOKEN_COOKIE) == "") {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "authorization header is required",
+				"error": "no session cookie",
 			})
 		}
 
-		var accessToken string
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			accessToken = authHeader[7:]
-		} else {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid authorization header format",
+		csrfToken := c.Cookies(pkg.CSRF_COOKIE)
+		if csrfToken == "" {
+			csrfToken = newCSRFToken()
+			c.Cookie(sessionCookie(pkg.CSRF_COOKIE, csrfToken, pkg.REFRESH_TOKEN_COOKIE_MAX_AGE, false))
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.JSON(fiber.Map{
+			"csrf_token": csrfToken,
+		})
+	}
+}
+
+func TokenLogout(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken, status, err := requestAccessToken(c)
+		if err != nil {
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
 			})
 		}
+		// Signing out also drops the cookies, even if the session is already gone
+		clearSessionCookies(c)
 
-		_, err := conns.Auth.Logout(c.Context(), accessToken)
+		_, err = conns.Auth.Logout(c.Context(), accessToken)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
@@ -119,21 +166,51 @@ func TokenLogout(conns *connections.ConnectionsContainer) fiber.Handler {
 	}
 }
 
-func TokenValidate(conns *connections.ConnectionsContainer) fiber.Handler {
+// TokenLogoutAll invalidates every session of the token's user ("log out everywhere").
+func TokenLogoutAll(conns *connections.ConnectionsContainer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "authorization header is required",
+		accessToken, status, err := requestAccessToken(c)
+		if err != nil {
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
 			})
 		}
+		// Signing out also drops the cookies, even if the session is already gone
+		clearSessionCookies(c)
 
-		var token string
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			token = authHeader[7:]
-		} else {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid authorization header format",
+		_, err = conns.Auth.LogoutAll(c.Context(), accessToken)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "logged out of all sessions",
+		})
+	}
+}
+
+// JWKS serves the public keys access tokens are signed with, for services that verify tokens themselves.
+func JWKS(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		jwks, err := conns.AuthVerifier.JWKS(c.Context())
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(pkg.JWKS_CACHE_TTL.Seconds())))
+		return c.JSON(jwks)
+	}
+}
+
+func TokenValidate(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, status, err := requestAccessToken(c)
+		if err != nil {
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
 			})
 		}
 
@@ -150,3 +227,19 @@ func TokenValidate(conns *connections.ConnectionsContainer) fiber.Handler {
 		})
 	}
 }
+
+// requestAccessToken returns the access token of a request to a handler without RequireAuth (see
+// middleware.AccessToken), or the status and error to answer with.
+func requestAccessToken(c *fiber.Ctx) (string, int, error) {
+	token, err := middleware.AccessToken(c)
+	if errors.Is(err, middleware.ErrInvalidCSRF) {
+		return "", fiber.StatusForbidden, err
+	}
+	if err != nil {
+		return "", fiber.StatusUnauthorized, err
+	}
+	if token == "" {
+		return "", fiber.StatusUnauthorized, errors.New("authorization header is required")
+	}
+	return token, 0, nil
+}
@@ -0,0 +1,73 @@
+// Cookie session mode (AUTH_COOKIE_MODE): sign-in and cookie refresh responses move the refresh
+// token, and in "all" mode the access token, out of the JSON body into __Host- HttpOnly cookies,
+// and return a csrf_token that the client echoes in X-CSRF-Token (middleware.ValidCSRF).
+
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	authpkg "github.com/cthulhu-platform/auth/pkg"
+	"github.com/cthulhu-platform/gateway/internal/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+func cookieMode() bool {
+	return pkg.AUTH_COOKIE_MODE != pkg.AuthCookieModeOff
+}
+
+func sessionCookie(name, value string, maxAge time.Duration, httpOnly bool) *fiber.Cookie {
+	return &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   true,
+		HTTPOnly: httpOnly,
+		SameSite: pkg.AUTH_COOKIE_SAMESITE,
+	}
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// setSessionCookies sets the session cookies and returns what is left for the response body: the
+// access token unless it went into a cookie too, and the CSRF token. The CSRF cookie is readable
+// by scripts of the gateway's own origin, for clients served from it.
+func setSessionCookies(c *fiber.Ctx, accessToken, refreshToken, csrfToken string) fiber.Map {
+	body := fiber.Map{"csrf_token": csrfToken}
+	c.Cookie(sessionCookie(pkg.REFRESH_TOKEN_COOKIE, refreshToken, pkg.REFRESH_TOKEN_COOKIE_MAX_AGE, true))
+	c.Cookie(sessionCookie(pkg.CSRF_COOKIE, csrfToken, pkg.REFRESH_TOKEN_COOKIE_MAX_AGE, false))
+	if pkg.AUTH_COOKIE_MODE == pkg.AuthCookieModeAll {
+		c.Cookie(sessionCookie(pkg.ACCESS_TOKEN_COOKIE, accessToken, pkg.ACCESS_TOKEN_COOKIE_MAX_AGE, true))
+	} else {
+		body["access_token"] = accessToken
+	}
+	return body
+}
+
+func clearSessionCookies(c *fiber.Ctx) {
+	for _, name := range []string{pkg.ACCESS_TOKEN_COOKIE, pkg.REFRESH_TOKEN_COOKIE, pkg.CSRF_COOKIE} {
+		if c.Cookies(name) != "" {
+			cookie := sessionCookie(name, "", 0, true)
+			cookie.Expires = time.Unix(0, 0)
+			c.Cookie(cookie)
+		}
+	}
+}
+
+// signInResponse answers a finished sign-in with a new CSRF token in cookie mode, or with the
+// tokens in the body otherwise.
+func signInResponse(c *fiber.Ctx, authResponse *authpkg.AuthResponse) error {
+	if !cookieMode() {
+		return c.JSON(authResponse)
+	}
+	body := setSessionCookies(c, authResponse.AccessToken, authResponse.RefreshToken, newCSRFToken())
+	body["user"] = authResponse.User
+	return c.JSON(body)
+}
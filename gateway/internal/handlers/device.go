@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/gofiber/fiber/v2"
+)
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// DeviceAuthorize starts a device authorization grant (RFC 8628 section 3.1). Accepts JSON or
+// form bodies; client_name (or client_id) labels the device on the approval page.
+func DeviceAuthorize(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			ClientName string `json:"client_name" form:"client_name"`
+			ClientID   string `json:"client_id" form:"client_id"`
+		}
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "invalid request body",
+				})
+			}
+		}
+		name := req.ClientName
+		if name == "" {
+			name = req.ClientID
+		}
+
+		res, err := conns.Auth.StartDeviceAuthorization(c.Context(), name)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.JSON(res)
+	}
+}
+
+// DeviceToken is the device access token endpoint polled by the client (RFC 8628 section 3.4).
+// Until the user decides it answers 400 with an RFC error code such as authorization_pending.
+func DeviceToken(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			GrantType  string `json:"grant_type" form:"grant_type"`
+			DeviceCode string `json:"device_code" form:"device_code"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid_request",
+			})
+		}
+		if req.GrantType != deviceCodeGrantType {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "unsupported_grant_type",
+			})
+		}
+		if req.DeviceCode == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":             "invalid_request",
+				"error_description": "device_code is required",
+			})
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		res, err := conns.Auth.PollDeviceAuthorization(clientContext(c), req.DeviceCode)
+		var flowErr *pkg.DeviceFlowError
+		if errors.As(err, &flowErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": flowErr.Code,
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":             "server_error",
+				"error_description": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"access_token":  res.AccessToken,
+			"token_type":    "Bearer",
+			"refresh_token": res.RefreshToken,
+			"user":          res.User,
+		})
+	}
+}
+
+// DeviceVerify describes the pending request for a user code, shown before the user approves it.
+func DeviceVerify(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userCode := strings.TrimSpace(c.Query("user_code"))
+		if userCode == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "user_code is required",
+			})
+		}
+
+		res, err := conns.Auth.GetDeviceAuthorization(c.Context(), bearerToken(c), userCode)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "invalid or expired code",
+			})
+		}
+
+		return c.JSON(res)
+	}
+}
+
+// DeviceApprove approves or denies the request for a user code as the signed-in user.
+func DeviceApprove(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			UserCode string `json:"user_code"`
+			Approve  *bool  `json:"approve"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+		if strings.TrimSpace(req.UserCode) == "" || req.Approve == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "user_code and approve are required",
+			})
+		}
+
+		if _, err := conns.Auth.ApproveDeviceAuthorization(c.Context(), bearerToken(c), req.UserCode, *req.Approve); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "invalid or expired code",
+			})
+		}
+
+		message := "device denied"
+		if *req.Approve {
+			message = "device approved"
+		}
+		return c.JSON(fiber.Map{
+			"message": message,
+		})
+	}
+}
@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"mime/multipart"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/cthulhu-platform/gateway/internal/challenge"
 	"github.com/cthulhu-platform/gateway/internal/connections"
 	"github.com/cthulhu-platform/gateway/internal/middleware"
 	"github.com/cthulhu-platform/gateway/internal/models"
@@ -26,17 +33,28 @@ func parsePrepareUploadFromForm(c *fiber.Ctx) (*models.PrepareUploadRequest, err
 		return nil, nil
 	}
 
+	// Optional "notes" values pair with "files" by position
+	notes := form.Value["notes"]
 	files := make([]models.PrepareUploadFile, 0, len(fileHeaders))
-	for _, fh := range fileHeaders {
+	for i, fh := range fileHeaders {
 		ct := contentTypeFromHeader(fh)
 		if ct == "" {
 			ct = "application/octet-stream"
 		}
-		files = append(files, models.PrepareUploadFile{
+		sum, err := hashFormFile(fh)
+		if err != nil {
+			return nil, err
+		}
+		file := models.PrepareUploadFile{
 			OriginalName: fh.Filename,
 			Size:         fh.Size,
 			ContentType:  ct,
-		})
+			SHA256:       sum,
+		}
+		if i < len(notes) {
+			file.Note = notes[i]
+		}
+		files = append(files, file)
 	}
 
 	password := c.Get("X-Bucket-Password")
@@ -44,7 +62,40 @@ func parsePrepareUploadFromForm(c *fiber.Ctx) (*models.PrepareUploadRequest, err
 		password = vs[0]
 	}
 
-	return &models.PrepareUploadRequest{Files: files, Password: password}, nil
+	req := &models.PrepareUploadRequest{Files: files, Password: password}
+	if vs := form.Value["title"]; len(vs) > 0 {
+		req.Title = vs[0]
+	}
+	if vs := form.Value["description"]; len(vs) > 0 {
+		req.Description = vs[0]
+	}
+	if vs := form.Value["org_id"]; len(vs) > 0 {
+		req.OrgID = strings.TrimSpace(vs[0])
+	}
+	return req, nil
+}
+
+// cleanText sanitizes a user-supplied field and enforces its length limit (in characters).
+func cleanText(name, value string, multiline bool, max int) (string, error) {
+	v := gatewaypkg.SanitizeText(value, multiline)
+	if utf8.RuneCountInString(v) > max {
+		return "", fmt.Errorf("%s must be at most %d characters", name, max)
+	}
+	return v, nil
+}
+
+// hashFormFile returns the hex-encoded SHA-256 of a file sent in a multipart form.
+func hashFormFile(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 func contentTypeFromHeader(fh *multipart.FileHeader) string {
@@ -94,17 +145,52 @@ func FileUploadPrepare(conns *connections.ConnectionsContainer) fiber.Handler {
 			if ct == "" {
 				ct = "application/octet-stream"
 			}
-			pbFiles = append(pbFiles, &fmpb.FileMeta{
+			sum := strings.ToLower(strings.TrimSpace(f.SHA256))
+			if len(sum) != 64 {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "each file must have the hex-encoded sha256 of its content"})
+			}
+			meta := &fmpb.FileMeta{
 				OriginalName: f.OriginalName,
 				Size:         f.Size,
 				ContentType:  ct,
-			})
+				Sha256:       sum,
+			}
+			note, err := cleanText("note", f.Note, true, gatewaypkg.FILE_NOTE_MAX_LENGTH)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			if note != "" {
+				meta.Note = &note
+			}
+			pbFiles = append(pbFiles, meta)
+		}
+
+		title, err := cleanText("title", req.Title, false, gatewaypkg.BUCKET_TITLE_MAX_LENGTH)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		description, err := cleanText("description", req.Description, true, gatewaypkg.BUCKET_DESCRIPTION_MAX_LENGTH)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 
 		var userID *string
 		if u := middleware.GetUser(c); u != nil {
 			userID = &u.ID
 		}
+		if req.OrgID != "" && userID == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "sign in to upload to an organization"})
+		}
+		if userID == nil && conns.UploadChallenge != nil {
+			err := conns.UploadChallenge.Verify(c.Context(), middleware.ClientIP(c), c.Get(gatewaypkg.UPLOAD_CHALLENGE_HEADER))
+			if errors.Is(err, challenge.ErrRequired) || errors.Is(err, challenge.ErrFailed) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+			}
+			if err != nil {
+				slog.Error("Failed to verify upload challenge", "error", err)
+				return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "could not verify the upload challenge"})
+			}
+		}
 
 		pbReq := &fmpb.PrepareUploadRequest{
 			Files:  pbFiles,
@@ -113,6 +199,15 @@ func FileUploadPrepare(conns *connections.ConnectionsContainer) fiber.Handler {
 		if req.Password != "" {
 			pbReq.Password = &req.Password
 		}
+		if title != "" {
+			pbReq.Title = &title
+		}
+		if description != "" {
+			pbReq.Description = &description
+		}
+		if req.OrgID != "" {
+			pbReq.OrgId = &req.OrgID
+		}
 
 		res, err := conns.Filemanager.PrepareUpload(c.Context(), pbReq)
 		if err != nil {
@@ -120,6 +215,9 @@ func FileUploadPrepare(conns *connections.ConnectionsContainer) fiber.Handler {
 		}
 		if res != nil && res.Error != "" {
 			out := models.PrepareUploadResponse{Error: res.Error, StorageID: res.StorageId}
+			if res.Blocked {
+				return c.Status(fiber.StatusUnavailableForLegalReasons).JSON(out)
+			}
 			return c.Status(fiber.StatusBadRequest).JSON(out)
 		}
 
@@ -138,6 +236,21 @@ func FileUploadPrepare(conns *connections.ConnectionsContainer) fiber.Handler {
 	}
 }
 
+// FileUploadChallenge returns the challenge an anonymous client must solve before preparing an
+// upload, with the response sent in the X-Upload-Challenge header. Signed-in users get "none".
+func FileUploadChallenge(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if middleware.GetUser(c) != nil || conns.UploadChallenge == nil {
+			return c.Status(fiber.StatusOK).JSON(challenge.Challenge{Type: challenge.TypeNone})
+		}
+		ch, err := conns.UploadChallenge.Issue(c.Context(), middleware.ClientIP(c))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusOK).JSON(ch)
+	}
+}
+
 func FileUploadConfirm(conns *connections.ConnectionsContainer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var req models.ConfirmUploadRequest
@@ -176,6 +289,9 @@ func FileUploadConfirm(conns *connections.ConnectionsContainer) fiber.Handler {
 		}
 		if res != nil && res.Error != "" {
 			out := models.ConfirmUploadResponse{Success: false, Error: res.Error, StorageID: res.StorageId}
+			if res.Blocked {
+				return c.Status(fiber.StatusUnavailableForLegalReasons).JSON(out)
+			}
 			return c.Status(fiber.StatusBadRequest).JSON(out)
 		}
 
@@ -186,6 +302,15 @@ func FileUploadConfirm(conns *connections.ConnectionsContainer) fiber.Handler {
 		} else {
 			expiresAt = time.Now().UTC().Add(gatewaypkg.LifecycleTTLAnonymous)
 		}
+		// Organization buckets use the organization's retention, when it sets one
+		if res.OrgId != "" {
+			org, err := conns.Auth.GetOrganizationMembership(c.Context(), res.OrgId, "")
+			if err != nil {
+				slog.Warn("failed to get bucket organization", "storage_id", res.StorageId, "org_id", res.OrgId, "error", err)
+			} else if org.RetentionSeconds > 0 {
+				expiresAt = time.Now().UTC().Add(time.Duration(org.RetentionSeconds) * time.Second)
+			}
+		}
 		if _, err := conns.Lifecycle.PostLifecycle(c.Context(), res.StorageId, expiresAt); err != nil {
 			slog.Warn("failed to set bucket lifecycle", "storage_id", res.StorageId, "error", err)
 		}
@@ -205,6 +330,7 @@ func FileUploadConfirm(conns *connections.ConnectionsContainer) fiber.Handler {
 			StorageID: res.StorageId,
 			Files:     files,
 			TotalSize: res.TotalSize,
+			OrgID:     res.OrgId,
 		})
 	}
 }
@@ -264,6 +390,9 @@ func FileBucketGet(conns *connections.ConnectionsContainer) fiber.Handler {
 		if res != nil && res.Error != "" {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": res.Error})
 		}
+		if res.Status == bucketStatusRemoved {
+			return bucketRemoved(c)
+		}
 		files := make([]fiber.Map, 0, len(res.Files))
 		for _, f := range res.Files {
 			files = append(files, fiber.Map{
@@ -272,16 +401,74 @@ func FileBucketGet(conns *connections.ConnectionsContainer) fiber.Handler {
 				"key":           f.Key,
 				"size":          f.Size,
 				"content_type":  f.ContentType,
+				"note":          f.Note,
 			})
 		}
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"storage_id": res.StorageId,
-			"files":      files,
-			"total_size": res.TotalSize,
+			"storage_id":  res.StorageId,
+			"title":       res.Title,
+			"description": res.Description,
+			"files":       files,
+			"total_size":  res.TotalSize,
 		})
 	}
 }
 
+func FileBucketUpdate(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		bucketID := strings.TrimSpace(c.Params("id"))
+		if bucketID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket id is required"})
+		}
+		user := middleware.GetUser(c)
+		if user == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "authentication required"})
+		}
+		var req models.UpdateBucketDetailsRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+
+		pbReq := &fmpb.UpdateBucketDetailsRequest{BucketId: bucketID, UserId: user.ID}
+		if req.Title != nil {
+			title, err := cleanText("title", *req.Title, false, gatewaypkg.BUCKET_TITLE_MAX_LENGTH)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			pbReq.Title = &title
+		}
+		if req.Description != nil {
+			description, err := cleanText("description", *req.Description, true, gatewaypkg.BUCKET_DESCRIPTION_MAX_LENGTH)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			pbReq.Description = &description
+		}
+		for _, n := range req.Notes {
+			if n.StringID == "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "each note must have string_id"})
+			}
+			note, err := cleanText("note", n.Note, true, gatewaypkg.FILE_NOTE_MAX_LENGTH)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			pbReq.Notes = append(pbReq.Notes, &fmpb.FileNoteUpdate{StringId: n.StringID, Note: note})
+		}
+
+		res, err := conns.Filemanager.UpdateBucketDetails(c.Context(), pbReq)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res != nil && res.Error != "" {
+			if res.Error == "only bucket admins can edit bucket details" {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": res.Error})
+			}
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": res.Error})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"success": true})
+	}
+}
+
 func FileAdmins(conns *connections.ConnectionsContainer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		bucketID := strings.TrimSpace(c.Params("id"))
@@ -307,6 +494,9 @@ func FileAdmins(conns *connections.ConnectionsContainer) fiber.Handler {
 			})
 		}
 		out := fiber.Map{"bucket_id": res.BucketId, "admins": admins}
+		if res.OrgId != "" {
+			out["org_id"] = res.OrgId
+		}
 		if res.Owner != nil {
 			out["owner"] = fiber.Map{
 				"user_id":    res.Owner.UserId,
@@ -327,7 +517,7 @@ func FileBucketProtected(conns *connections.ConnectionsContainer) fiber.Handler
 		if bucketID == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket id is required"})
 		}
-		res, err := conns.Filemanager.IsBucketProtected(c.Context(), &fmpb.IsBucketProtectedRequest{BucketId: bucketID})
+		res, err := conns.Filemanager.IsBucketProtected(middleware.BucketClientContext(c), &fmpb.IsBucketProtectedRequest{BucketId: bucketID})
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -335,8 +525,9 @@ func FileBucketProtected(conns *connections.ConnectionsContainer) fiber.Handler
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": res.Error})
 		}
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"protected": res.Protected,
-			"bucket_id": bucketID,
+			"protected":     res.Protected,
+			"access_denied": res.AccessDenied,
+			"bucket_id":     bucketID,
 		})
 	}
 }
@@ -357,17 +548,23 @@ func FileDownload(conns *connections.ConnectionsContainer) fiber.Handler {
 			pbReq.BucketAccessToken = &token
 		}
 
-		res, err := conns.Filemanager.PrepareDownload(c.Context(), pbReq)
+		res, err := conns.Filemanager.PrepareDownload(middleware.BucketClientContext(c), pbReq)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
 		if res != nil && res.Error != "" {
+			if res.Status == bucketStatusRemoved {
+				return bucketRemoved(c)
+			}
 			if res.Error == "bucket is protected; bucket_access_token is required" || res.Error == "invalid or expired bucket token" {
 				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": res.Error})
 			}
-			if res.Error == "file not found" {
+			if res.Error == "file not found" || res.Error == "bucket not found" {
 				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": res.Error})
 			}
+			if res.AccessDenied {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": res.Error})
+			}
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": res.Error})
 		}
 
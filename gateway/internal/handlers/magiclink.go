@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/gofiber/fiber/v2"
+)
+
+// MagicLinkRequest emails a sign-in link to the address. It answers the same whether or not an
+// account uses the address.
+func MagicLinkRequest(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+		if req.Email == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "email is required",
+			})
+		}
+
+		if _, err := conns.Auth.RequestMagicLink(c.Context(), req.Email); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"success": true})
+	}
+}
+
+// MagicLinkRedeem signs in with the token of an emailed link and answers like the OAuth
+// callback, including mfa_required.
+func MagicLinkRedeem(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+		if req.Token == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "token is required",
+			})
+		}
+
+		authResponse, err := conns.Auth.RedeemMagicLink(clientContext(c), req.Token)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if authResponse.MFAToken != "" {
+			return c.JSON(fiber.Map{
+				"mfa_required":   true,
+				"mfa_token":      authResponse.MFAToken,
+				"mfa_expires_in": authResponse.MFAExpiresIn,
+			})
+		}
+
+		return signInResponse(c, authResponse)
+	}
+}
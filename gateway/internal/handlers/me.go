@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cthulhu-platform/auth/pkg"
+	auth "github.com/cthulhu-platform/auth/pkg/client"
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// bearerToken returns the access token of a request that passed RequireAuth, from the
+// Authorization header or the access token cookie.
+func bearerToken(c *fiber.Ctx) string {
+	return middleware.GetToken(c)
+}
+
+// clientContext carries the caller's IP and user agent to auth calls that start or refresh a
+// session, so they show up in /me/sessions, and to administrative calls for the audit log.
+func clientContext(c *fiber.Ctx) context.Context {
+	return auth.WithClientInfo(c.Context(), pkg.ClientInfo{
+		IPAddress: middleware.ClientIP(c),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	})
+}
+
+// PersonalAccessTokensList returns the user's active personal access tokens (without secrets).
+func PersonalAccessTokensList(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		tokens, err := conns.Auth.ListPersonalAccessTokens(c.Context(), accessToken)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"tokens": tokens,
+		})
+	}
+}
+
+// PersonalAccessTokenCreate issues a token. The plaintext token is only part of this response.
+func PersonalAccessTokenCreate(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		var req struct {
+			Name          string   `json:"name"`
+			Scopes        []string `json:"scopes"`
+			ExpiresInDays int      `json:"expires_in_days"` // 0 for a token that does not expire
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+		if req.ExpiresInDays < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "expires_in_days must not be negative",
+			})
+		}
+
+		expiresIn := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+		token, err := conns.Auth.CreatePersonalAccessToken(c.Context(), accessToken, req.Name, req.Scopes, expiresIn)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(token)
+	}
+}
+
+func PersonalAccessTokenRevoke(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		id := strings.TrimSpace(c.Params("id"))
+		if id == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "token id is required",
+			})
+		}
+
+		if _, err := conns.Auth.RevokePersonalAccessToken(c.Context(), accessToken, id); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "token revoked",
+		})
+	}
+}
+
+// SessionsList returns the user's signed in devices; the caller's own session is marked current.
+func SessionsList(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		sessions, err := conns.Auth.ListSessions(c.Context(), accessToken)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"sessions": sessions,
+		})
+	}
+}
+
+// SessionRevoke signs a single device out, ending its refresh tokens and latest access token.
+func SessionRevoke(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		id := strings.TrimSpace(c.Params("id"))
+		if id == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "session id is required",
+			})
+		}
+
+		if _, err := conns.Auth.RevokeSession(c.Context(), accessToken, id); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "session revoked",
+		})
+	}
+}
+
+// IdentitiesList returns the OAuth provider accounts linked to the user.
+func IdentitiesList(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		identities, err := conns.Auth.ListIdentities(c.Context(), accessToken)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"identities": identities,
+		})
+	}
+}
+
+// IdentityLinkStart returns the provider URL that starts linking an account. The provider
+// redirects back through the usual OAuth callback; the client then finishes the link with
+// IdentityLinkComplete instead of signing in.
+func IdentityLinkStart(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		redirectURL, err := conns.Auth.StartLinkIdentity(c.Context(), accessToken, c.Params("provider"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"redirect_url": redirectURL,
+		})
+	}
+}
+
+func IdentityLinkComplete(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		var req struct {
+			Code  string `json:"code"`
+			State string `json:"state"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+		if req.Code == "" || req.State == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "code and state are required",
+			})
+		}
+
+		identity, err := conns.Auth.LinkIdentity(c.Context(), accessToken, c.Params("provider"), req.Code, req.State)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(identity)
+	}
+}
+
+func IdentityUnlink(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		if _, err := conns.Auth.UnlinkIdentity(c.Context(), accessToken, c.Params("provider")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "identity unlinked",
+		})
+	}
+}
+
+// AccountDelete deletes the signed in user's account. Their tokens stop working right away and
+// their buckets are released by the lifecycle service; the account is purged after a grace period.
+func AccountDelete(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		if _, err := conns.Auth.DeleteAccount(c.Context(), accessToken); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "account deleted",
+		})
+	}
+}
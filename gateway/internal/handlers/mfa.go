@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/gofiber/fiber/v2"
+)
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFAVerify finishes a sign-in for which the OAuth callback answered mfa_required, with a TOTP
+// or recovery code.
+func MFAVerify(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			MFAToken string `json:"mfa_token"`
+			Code     string `json:"code"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+		if req.MFAToken == "" || req.Code == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "mfa_token and code are required",
+			})
+		}
+
+		authResponse, err := conns.Auth.VerifyMFA(clientContext(c), req.MFAToken, req.Code)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return signInResponse(c, authResponse)
+	}
+}
+
+func MFAStatus(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		status, err := conns.Auth.GetMFAStatus(c.Context(), accessToken)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(status)
+	}
+}
+
+// MFAEnrollStart returns a new TOTP secret and its otpauth URI. MFA is enabled once
+// MFAEnrollConfirm sees a code from it.
+func MFAEnrollStart(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		enrollment, err := conns.Auth.StartMFAEnrollment(c.Context(), accessToken)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.JSON(enrollment)
+	}
+}
+
+// MFAEnrollConfirm enables MFA and returns the recovery codes, shown only this once.
+func MFAEnrollConfirm(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		var req mfaCodeRequest
+		if err := c.BodyParser(&req); err != nil || req.Code == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "code is required",
+			})
+		}
+
+		recoveryCodes, err := conns.Auth.ConfirmMFAEnrollment(c.Context(), accessToken, req.Code)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.JSON(fiber.Map{
+			"recovery_codes": recoveryCodes,
+		})
+	}
+}
+
+// MFADisable turns MFA off; it takes a current TOTP or recovery code.
+func MFADisable(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		accessToken := bearerToken(c)
+
+		var req mfaCodeRequest
+		if err := c.BodyParser(&req); err != nil || req.Code == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "code is required",
+			})
+		}
+
+		if _, err := conns.Auth.DisableMFA(c.Context(), accessToken, req.Code); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "MFA disabled",
+		})
+	}
+}
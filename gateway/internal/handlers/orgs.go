@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
+	fmpb "github.com/cthulhu-platform/proto/pkg/filemanager"
+	"github.com/gofiber/fiber/v2"
+)
+
+// OrgsList returns the organizations the user belongs to, with their role in each.
+func OrgsList(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		orgs, err := conns.Auth.ListOrganizations(c.Context(), bearerToken(c))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"organizations": orgs})
+	}
+}
+
+// OrgCreate creates an organization owned by the user. The slug is derived from the name when
+// omitted.
+func OrgCreate(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			Name string `json:"name"`
+			Slug string `json:"slug"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+
+		org, err := conns.Auth.CreateOrganization(c.Context(), bearerToken(c), req.Name, strings.TrimSpace(req.Slug))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusCreated).JSON(org)
+	}
+}
+
+func OrgGet(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		org, err := conns.Auth.GetOrganization(c.Context(), bearerToken(c), c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(org)
+	}
+}
+
+// OrgUpdate changes the name and the bucket defaults of an organization (organization admins).
+// Omitted fields are left unchanged; a retention or quota of 0 removes it.
+func OrgUpdate(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			Name             *string `json:"name"`
+			RetentionSeconds *int64  `json:"retention_seconds"`
+			QuotaBytes       *int64  `json:"quota_bytes"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+
+		org, err := conns.Auth.UpdateOrganization(c.Context(), bearerToken(c), c.Params("id"), pkg.OrganizationUpdate{
+			Name:             req.Name,
+			RetentionSeconds: req.RetentionSeconds,
+			QuotaBytes:       req.QuotaBytes,
+		})
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(org)
+	}
+}
+
+// OrgDelete deletes an organization (owners), then hands its buckets back to their bucket
+// admins. Buckets whose release fails keep a dangling org_id, which grants nobody access.
+func OrgDelete(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		orgID := c.Params("id")
+		if _, err := conns.Auth.DeleteOrganization(c.Context(), bearerToken(c), orgID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		var released int64
+		res, err := conns.Filemanager.ReleaseOrganizationBuckets(c.Context(), &fmpb.ReleaseOrganizationBucketsRequest{OrgId: orgID})
+		switch {
+		case err != nil:
+			slog.Warn("Failed to release buckets of deleted organization", "org_id", orgID, "error", err)
+		case res.Error != "":
+			slog.Warn("Failed to release buckets of deleted organization", "org_id", orgID, "error", res.Error)
+		default:
+			released = res.BucketsReleased
+		}
+
+		return c.JSON(fiber.Map{"success": true, "buckets_released": released})
+	}
+}
+
+func OrgMembersList(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		members, err := conns.Auth.ListOrganizationMembers(c.Context(), bearerToken(c), c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"members": members})
+	}
+}
+
+// OrgMemberAdd adds a user who has signed in before, by email, as a member unless another role is
+// given. Admins can grant roles up to their own.
+func OrgMemberAdd(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			Email string `json:"email"`
+			Role  string `json:"role"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if strings.TrimSpace(req.Email) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email is required"})
+		}
+		if req.Role == "" {
+			req.Role = pkg.OrgRoleMember
+		}
+		if !slices.Contains(pkg.OrgRoles, req.Role) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "role must be one of " + strings.Join(pkg.OrgRoles, ", "),
+			})
+		}
+
+		member, err := conns.Auth.AddOrganizationMember(c.Context(), bearerToken(c), c.Params("id"), req.Email, req.Role)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusCreated).JSON(member)
+	}
+}
+
+// OrgMemberRole changes a member's role. An organization always keeps at least one owner.
+func OrgMemberRole(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if !slices.Contains(pkg.OrgRoles, req.Role) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "role must be one of " + strings.Join(pkg.OrgRoles, ", "),
+			})
+		}
+
+		if _, err := conns.Auth.SetOrganizationMemberRole(c.Context(), bearerToken(c), c.Params("id"), c.Params("userId"), req.Role); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true, "role": req.Role})
+	}
+}
+
+// OrgMemberRemove removes a member; members remove themselves to leave the organization.
+func OrgMemberRemove(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, err := conns.Auth.RemoveOrganizationMember(c.Context(), bearerToken(c), c.Params("id"), c.Params("userId")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	}
+}
+
+// OrgBucketsList lists the organization's buckets newest first, for its members.
+func OrgBucketsList(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		org, err := conns.Auth.GetOrganization(c.Context(), bearerToken(c), c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		res, err := conns.Filemanager.ListBuckets(c.Context(), &fmpb.ListBucketsRequest{
+			OrgId:  org.ID,
+			Limit:  int32(c.QueryInt("limit")),
+			Offset: int32(c.QueryInt("offset")),
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.Error != "" {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": res.Error})
+		}
+		buckets := make([]fiber.Map, 0, len(res.Buckets))
+		for _, b := range res.Buckets {
+			buckets = append(buckets, fiber.Map{
+				"id":         b.Id,
+				"title":      b.Title,
+				"protected":  b.Protected,
+				"file_count": b.FileCount,
+				"total_size": b.TotalSize,
+				"created_at": b.CreatedAt,
+				"updated_at": b.UpdatedAt,
+			})
+		}
+		return c.JSON(fiber.Map{"buckets": buckets})
+	}
+}
+
+// OrgBucketAdd moves a bucket the user manages into the organization, which must have room for
+// it within its quota.
+func OrgBucketAdd(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := middleware.GetUser(c)
+		bucketID := strings.TrimSpace(c.Params("bucketId"))
+		if bucketID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket id is required"})
+		}
+
+		return setBucketOrganization(c, conns, bucketID, user.ID, c.Params("id"))
+	}
+}
+
+// OrgBucketRemove makes an organization bucket personal again, managed by its bucket admins.
+func OrgBucketRemove(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := middleware.GetUser(c)
+		bucketID := strings.TrimSpace(c.Params("bucketId"))
+		if bucketID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket id is required"})
+		}
+		admins, err := conns.Filemanager.GetBucketAdmins(c.Context(), &fmpb.GetBucketAdminsRequest{BucketId: bucketID})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if admins.Error != "" || admins.OrgId != c.Params("id") {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "bucket is not in this organization"})
+		}
+
+		return setBucketOrganization(c, conns, bucketID, user.ID, "")
+	}
+}
+
+func setBucketOrganization(c *fiber.Ctx, conns *connections.ConnectionsContainer, bucketID, userID, orgID string) error {
+	res, err := conns.Filemanager.SetBucketOrganization(c.Context(), &fmpb.SetBucketOrganizationRequest{
+		BucketId: bucketID,
+		UserId:   userID,
+		OrgId:    orgID,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if res.Error != "" {
+		if res.Error == "only bucket admins can change the bucket's organization" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": res.Error})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": res.Error})
+	}
+	return c.JSON(fiber.Map{"success": true, "org_id": orgID})
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
+	"github.com/cthulhu-platform/gateway/internal/models"
+	fmpb "github.com/cthulhu-platform/proto/pkg/filemanager"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Status of a bucket taken down for abuse, in filemanager responses
+const bucketStatusRemoved = "removed"
+
+// bucketRemoved answers requests for a bucket taken down for abuse.
+func bucketRemoved(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusUnavailableForLegalReasons).JSON(fiber.Map{
+		"error":  "this bucket was removed for violating the terms of service",
+		"status": bucketStatusRemoved,
+	})
+}
+
+// FileBucketReport files an abuse report against a bucket, anonymously or as the signed in user,
+// and confirms it to a signed in reporter by email. Anonymous reporters only hear about the
+// outcome: their address is unverified, and a receipt would let anyone send mail to any address.
+func FileBucketReport(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		bucketID := strings.TrimSpace(c.Params("id"))
+		if bucketID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket id is required"})
+		}
+		var req models.ReportBucketRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+
+		pbReq := &fmpb.ReportBucketRequest{
+			BucketId: bucketID,
+			Reason:   strings.TrimSpace(req.Reason),
+			Details:  req.Details,
+		}
+		if user := middleware.GetUser(c); user != nil {
+			pbReq.ReporterId = user.ID
+		} else {
+			pbReq.ReporterEmail = strings.TrimSpace(req.Email)
+		}
+
+		res, err := conns.Filemanager.ReportBucket(c.Context(), pbReq)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.Error != "" {
+			if res.Error == "bucket not found" {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": res.Error})
+			}
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": res.Error})
+		}
+		if pbReq.ReporterId != "" {
+			notifyReporter(c, conns, pbReq.ReporterId, "", res.ReportId, bucketID, pkg.AbuseReportReceived)
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"success": true, "report_id": res.ReportId})
+	}
+}
+
+// AdminReportsList pages through abuse reports oldest first, optionally filtered by status
+// (open, actioned or dismissed) and bucket_id.
+func AdminReportsList(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		res, err := conns.Filemanager.ListAbuseReports(c.Context(), &fmpb.ListAbuseReportsRequest{
+			Status:   c.Query("status"),
+			BucketId: c.Query("bucket_id"),
+			Limit:    int32(c.QueryInt("limit")),
+			Offset:   int32(c.QueryInt("offset")),
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.Error != "" {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": res.Error})
+		}
+		reports := make([]fiber.Map, 0, len(res.Reports))
+		for _, r := range res.Reports {
+			reports = append(reports, abuseReportJSON(r))
+		}
+		return c.JSON(fiber.Map{"reports": reports})
+	}
+}
+
+// AdminReportDismiss closes an open report without action, tells the reporter and records the
+// dismissal in the audit log.
+func AdminReportDismiss(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := middleware.GetUser(c)
+		reportID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || reportID <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid report id"})
+		}
+		var req struct {
+			Note string `json:"note"`
+		}
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+			}
+		}
+
+		res, err := conns.Filemanager.DismissAbuseReport(c.Context(), &fmpb.DismissAbuseReportRequest{
+			ReportId:    reportID,
+			ModeratorId: user.ID,
+			Note:        req.Note,
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.Error != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": res.Error})
+		}
+		report := res.Report
+		if _, err := conns.Auth.RecordAuditEvent(clientContext(c), pkg.AuditEvent{
+			ActorID:    user.ID,
+			Action:     pkg.AuditReportDismiss,
+			TargetType: pkg.AuditTargetReport,
+			TargetID:   strconv.FormatInt(reportID, 10),
+			Details:    fmt.Sprintf("bucket %s: %s", report.BucketId, report.ResolutionNote),
+		}); err != nil {
+			slog.Error("Failed to record report dismissal in the audit log", "report_id", reportID, "actor_id", user.ID, "error", err)
+		}
+		notifyReporter(c, conns, report.ReporterId, report.ReporterEmail, report.Id, report.BucketId, pkg.AbuseReportDismissed)
+
+		return c.JSON(fiber.Map{"success": true, "report": abuseReportJSON(report)})
+	}
+}
+
+// AdminBucketTakedown removes a bucket for abuse: its files can no longer be downloaded and are
+// kept as evidence, and their content is refused on upload. Reporters of the bucket are told and
+// the takedown is recorded in the audit log.
+func AdminBucketTakedown(conns *connections.ConnectionsContainer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := middleware.GetUser(c)
+		bucketID := strings.TrimSpace(c.Params("id"))
+		if bucketID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket id is required"})
+		}
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if strings.TrimSpace(req.Reason) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "reason is required"})
+		}
+
+		res, err := conns.Filemanager.TakedownBucket(c.Context(), &fmpb.TakedownBucketRequest{
+			BucketId:    bucketID,
+			ModeratorId: user.ID,
+			Reason:      req.Reason,
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !res.Success {
+			if res.Error == "bucket not found" {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": res.Error})
+			}
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": res.Error})
+		}
+		if _, err := conns.Auth.RecordAuditEvent(clientContext(c), pkg.AuditEvent{
+			ActorID:    user.ID,
+			Action:     pkg.AuditBucketTakedown,
+			TargetType: pkg.AuditTargetBucket,
+			TargetID:   bucketID,
+			Details:    fmt.Sprintf("%d hashes blocked, %d reports: %s", res.HashesBlocked, len(res.Reports), strings.TrimSpace(req.Reason)),
+		}); err != nil {
+			slog.Error("Failed to record bucket takedown in the audit log", "bucket_id", bucketID, "actor_id", user.ID, "error", err)
+		}
+		for _, r := range res.Reports {
+			notifyReporter(c, conns, r.ReporterId, r.ReporterEmail, r.Id, bucketID, pkg.AbuseReportActioned)
+		}
+
+		return c.JSON(fiber.Map{"success": true, "hashes_blocked": res.HashesBlocked, "reports_resolved": len(res.Reports)})
+	}
+}
+
+// notifyReporter emails the reporter about their report. Failures are logged: the report itself
+// has been handled either way.
+func notifyReporter(c *fiber.Ctx, conns *connections.ConnectionsContainer, userID, email string, reportID int64, bucketID, outcome string) {
+	if userID == "" && email == "" {
+		return
+	}
+	if _, err := conns.Auth.NotifyAbuseReporter(c.Context(), userID, email, reportID, bucketID, outcome); err != nil {
+		slog.Warn("Failed to notify abuse reporter", "report_id", reportID, "outcome", outcome, "error", err)
+	}
+}
+
+func abuseReportJSON(r *fmpb.AbuseReport) fiber.Map {
+	out := fiber.Map{
+		"id":         r.Id,
+		"bucket_id":  r.BucketId,
+		"reason":     r.Reason,
+		"details":    r.Details,
+		"status":     r.Status,
+		"created_at": r.CreatedAt,
+	}
+	if r.ReporterId != "" {
+		out["reporter_id"] = r.ReporterId
+	}
+	if r.ReporterEmail != "" {
+		out["reporter_email"] = r.ReporterEmail
+	}
+	if r.ResolvedAt != 0 {
+		out["resolved_by"] = r.ResolvedBy
+		out["resolution_note"] = r.ResolutionNote
+		out["resolved_at"] = r.ResolvedAt
+	}
+	return out
+}
@@ -1,10 +1,15 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"slices"
 	"strings"
 
 	"github.com/cthulhu-platform/auth/pkg"
+	fmclient "github.com/cthulhu-platform/filemanager/pkg/client"
 	"github.com/cthulhu-platform/gateway/internal/connections"
+	gatewaypkg "github.com/cthulhu-platform/gateway/internal/pkg"
 	fmpb "github.com/cthulhu-platform/proto/pkg/filemanager"
 	"github.com/gofiber/fiber/v2"
 )
@@ -12,56 +17,132 @@ import (
 const (
 	LocalsKeyUserID = "user_id"
 	LocalsKeyUser   = "user"
+	LocalsKeyScopes = "scopes" // set only for personal access tokens
+	LocalsKeyToken  = "token"
 )
 
-// RequireAuth validates the Bearer token via the auth service and attaches user to context.
-// Returns 401 if the token is missing, malformed, or invalid.
+var errMalformedAuthorization = errors.New("invalid authorization header format")
+
+// AccessToken returns the request's access token: the Bearer token of the Authorization header
+// or, in the "all" cookie mode, the access token cookie, which needs a valid CSRF token on unsafe
+// requests (ErrInvalidCSRF). It returns "" and no error when the request has neither.
+func AccessToken(c *fiber.Ctx) (string, error) {
+	if authHeader := c.Get(fiber.HeaderAuthorization); authHeader != "" {
+		if len(authHeader) <= 7 || authHeader[:7] != "Bearer " {
+			return "", errMalformedAuthorization
+		}
+		return authHeader[7:], nil
+	}
+	if gatewaypkg.AUTH_COOKIE_MODE != gatewaypkg.AuthCookieModeAll {
+		return "", nil
+	}
+	token := c.Cookies(gatewaypkg.ACCESS_TOKEN_COOKIE)
+	if token != "" && !ValidCSRF(c) {
+		return "", ErrInvalidCSRF
+	}
+	return token, nil
+}
+
+// verifyToken checks the access token locally against the cached JWKS (no auth service call).
+// The user carries the ID, email and role from the token claims.
+func verifyToken(c *fiber.Ctx, conns *connections.ConnectionsContainer, token string) (*pkg.UserInfo, error) {
+	claims, err := conns.AuthVerifier.Verify(c.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	role := claims.Role
+	if role == "" {
+		role = pkg.RoleUser
+	}
+	return &pkg.UserInfo{ID: claims.UserID, Email: claims.Email, Role: role}, nil
+}
+
+// authenticate resolves a Bearer token to its user. Personal access tokens are validated by the
+// auth service and return their scopes; session access tokens are verified locally and return
+// nil scopes (all scopes).
+func authenticate(c *fiber.Ctx, conns *connections.ConnectionsContainer, token string) (*pkg.UserInfo, []string, error) {
+	if strings.HasPrefix(token, pkg.PersonalAccessTokenPrefix) {
+		user, scopes, err := conns.Auth.ValidatePersonalAccessToken(c.Context(), token)
+		if err != nil {
+			return nil, nil, err
+		}
+		if scopes == nil {
+			scopes = []string{}
+		}
+		return user, scopes, nil
+	}
+	user, err := verifyToken(c, conns, token)
+	return user, nil, err
+}
+
+func setUser(c *fiber.Ctx, token string, user *pkg.UserInfo, scopes []string) {
+	c.Locals(LocalsKeyToken, token)
+	c.Locals(LocalsKeyUserID, user.ID)
+	c.Locals(LocalsKeyUser, user)
+	if scopes != nil {
+		c.Locals(LocalsKeyScopes, scopes)
+	}
+}
+
+// RequireAuth validates the access token (session access token or personal access token, see
+// AccessToken) and attaches user to context. Returns 401 if the token is missing, malformed, or
+// invalid, and 403 for a cookie without a valid CSRF token.
 func RequireAuth(conns *connections.ConnectionsContainer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "authorization header is required"})
+		token, err := AccessToken(c)
+		if errors.Is(err, ErrInvalidCSRF) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
 		}
-		if len(authHeader) <= 7 || authHeader[:7] != "Bearer " {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid authorization header format"})
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		if token == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "authorization header is required"})
 		}
-		token := authHeader[7:]
 
-		user, err := conns.Auth.ValidateToken(c.Context(), token)
+		user, scopes, err := authenticate(c, conns, token)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or expired token"})
 		}
 
-		c.Locals(LocalsKeyUserID, user.ID)
-		c.Locals(LocalsKeyUser, user)
+		setUser(c, token, user, scopes)
 		return c.Next()
 	}
 }
 
-// OptionalAuth validates the Bearer token if present and attaches user to context.
-// If the header is missing or the token is invalid, the request continues without user in context.
+// OptionalAuth validates the access token if present and attaches user to context.
+// If the token is missing or invalid, the request continues without user in context, except for
+// invalid personal access tokens (401): scripts should fail rather than fall back to anonymous
+// access. A cookie without a valid CSRF token is rejected (403) for the same reason.
 func OptionalAuth(conns *connections.ConnectionsContainer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
-			return c.Next()
+		token, err := AccessToken(c)
+		if errors.Is(err, ErrInvalidCSRF) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
 		}
-		if len(authHeader) <= 7 || authHeader[:7] != "Bearer " {
+		if err != nil || token == "" {
 			return c.Next()
 		}
-		token := authHeader[7:]
 
-		user, err := conns.Auth.ValidateToken(c.Context(), token)
+		user, scopes, err := authenticate(c, conns, token)
 		if err != nil {
+			if strings.HasPrefix(token, pkg.PersonalAccessTokenPrefix) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or expired token"})
+			}
 			return c.Next()
 		}
 
-		c.Locals(LocalsKeyUserID, user.ID)
-		c.Locals(LocalsKeyUser, user)
+		setUser(c, token, user, scopes)
 		return c.Next()
 	}
 }
 
+// GetToken returns the access token the request was authenticated with, or "" if none.
+func GetToken(c *fiber.Ctx) string {
+	token, _ := c.Locals(LocalsKeyToken).(string)
+	return token
+}
+
 // GetUser returns the authenticated user from context, or nil if not set.
 func GetUser(c *fiber.Ctx) *pkg.UserInfo {
 	v := c.Locals(LocalsKeyUser)
@@ -72,29 +153,83 @@ func GetUser(c *fiber.Ctx) *pkg.UserInfo {
 	return u
 }
 
-// BucketAuth runs optional JWT validation (sets user if Bearer valid), then for the bucket in :id
-// calls filemanager IsBucketProtected; if protected and X-Bucket-Token is missing returns 401.
+// GetScopes returns the scopes of the personal access token that authenticated the request, or
+// nil for session access tokens (and anonymous requests).
+func GetScopes(c *fiber.Ctx) []string {
+	scopes, _ := c.Locals(LocalsKeyScopes).([]string)
+	return scopes
+}
+
+// IsPersonalAccessToken reports whether the request was authenticated with a personal access token.
+func IsPersonalAccessToken(c *fiber.Ctx) bool {
+	return GetScopes(c) != nil
+}
+
+// RequireScope rejects requests authenticated with a personal access token that was not granted
+// scope (403). Session tokens and anonymous requests pass; combine with RequireAuth to require a user.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if IsPersonalAccessToken(c) && !slices.Contains(GetScopes(c), scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "token is missing the " + scope + " scope"})
+		}
+		return c.Next()
+	}
+}
+
+// RequireSession rejects requests authenticated with a personal access token (403), for
+// endpoints that manage the account itself. Use after RequireAuth.
+func RequireSession() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if IsPersonalAccessToken(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "personal access tokens cannot be used for this endpoint"})
+		}
+		return c.Next()
+	}
+}
+
+// RequireRole rejects users whose platform role is below role (403). The role comes from the
+// access token, so a promotion applies from the next refresh (a demotion ends all sessions). The
+// auth service re-checks the current role for the actions it carries out. Use after RequireAuth.
+func RequireRole(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := GetUser(c)
+		if user == nil || !pkg.HasRole(user.Role, role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "the " + role + " role is required"})
+		}
+		return c.Next()
+	}
+}
+
+// BucketClientContext passes the client's address and Referer to filemanager, which checks them
+// against the bucket's access rules.
+func BucketClientContext(c *fiber.Ctx) context.Context {
+	return fmclient.WithClientInfo(c.Context(), ClientIP(c), c.Get(fiber.HeaderReferer))
+}
+
+// BucketAuth runs optional token validation (sets user if the access token is valid), then for the
+// bucket in :id calls filemanager IsBucketProtected; if protected and X-Bucket-Token is missing
+// returns 401.
 func BucketAuth(conns *connections.ConnectionsContainer) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authHeader := c.Get("Authorization")
-		if authHeader != "" && len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			token := authHeader[7:]
-			if user, err := conns.Auth.ValidateToken(c.Context(), token); err == nil {
-				c.Locals(LocalsKeyUserID, user.ID)
-				c.Locals(LocalsKeyUser, user)
+		if token, err := AccessToken(c); err == nil && token != "" {
+			if user, scopes, err := authenticate(c, conns, token); err == nil {
+				setUser(c, token, user, scopes)
 			}
 		}
 		bucketID := strings.TrimSpace(c.Params("id"))
 		if bucketID == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket id is required"})
 		}
-		res, err := conns.Filemanager.IsBucketProtected(c.Context(), &fmpb.IsBucketProtectedRequest{BucketId: bucketID})
+		res, err := conns.Filemanager.IsBucketProtected(BucketClientContext(c), &fmpb.IsBucketProtectedRequest{BucketId: bucketID})
 		if err != nil {
 			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
 		}
 		if res != nil && res.Error != "" {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": res.Error})
 		}
+		if res != nil && res.AccessDenied {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "access to this bucket is restricted"})
+		}
 		if res != nil && res.Protected && c.Get("X-Bucket-Token") == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "bucket is protected; X-Bucket-Token is required"})
 		}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"sync"
+
+	gatewaypkg "github.com/cthulhu-platform/gateway/internal/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ParseTrustedProxies parses a comma-separated list of proxy addresses and CIDR ranges.
+func ParseTrustedProxies(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+var trustedProxies = sync.OnceValue(func() []netip.Prefix {
+	prefixes, err := ParseTrustedProxies(gatewaypkg.TRUSTED_PROXIES)
+	if err != nil {
+		slog.Error("Ignoring TRUSTED_PROXIES", "error", err)
+	}
+	return prefixes
+})
+
+func isTrustedProxy(addr netip.Addr) bool {
+	for _, prefix := range trustedProxies() {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client's address. For requests from a trusted proxy (TRUSTED_PROXIES) it
+// is the last X-Forwarded-For hop that is not a trusted proxy itself; otherwise the header is
+// ignored, as clients can put anything in it.
+func ClientIP(c *fiber.Ctx) string {
+	remote, ok := netip.AddrFromSlice(c.Context().RemoteIP())
+	if !ok {
+		return c.IP()
+	}
+	remote = remote.Unmap()
+	if !isTrustedProxy(remote) {
+		return remote.String()
+	}
+
+	var hops []string
+	for _, header := range c.GetReqHeaders()[fiber.HeaderXForwardedFor] {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		remote = addr.Unmap()
+		if !isTrustedProxy(remote) {
+			break
+		}
+	}
+	return remote.String()
+}
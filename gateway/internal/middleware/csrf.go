@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	gatewaypkg "github.com/cthulhu-platform/gateway/internal/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+var ErrInvalidCSRF = errors.New("invalid or missing CSRF token")
+
+// ValidCSRF reports whether a request may be authenticated with the session cookies. Safe methods
+// always may; others need the X-CSRF-Token header to equal the CSRF cookie (double submit). A
+// cross-site page can make the browser send the cookies but cannot read the token to echo it.
+func ValidCSRF(c *fiber.Ctx) bool {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return true
+	}
+	cookie := c.Cookies(gatewaypkg.CSRF_COOKIE)
+	header := c.Get(gatewaypkg.CSRF_HEADER)
+	return cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
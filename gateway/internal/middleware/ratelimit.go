@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimit applies rule with the gateway's rate limiter. Requests are counted per user when an
+// earlier middleware authenticated them and per ClientIP otherwise; rules with a bucket policy
+// also count per :id bucket across callers. The RateLimit-* headers describe the tightest limit,
+// and an exhausted limit answers 429 with Retry-After. Requests go through if the limiter fails.
+func RateLimit(conns *connections.ConnectionsContainer, rule ratelimit.Rule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if conns.RateLimiter == nil {
+			return c.Next()
+		}
+
+		policy, key := rule.Anonymous, "ip:"+ClientIP(c)
+		if user := GetUser(c); user != nil {
+			key = "user:" + user.ID
+			if !rule.Authenticated.IsZero() {
+				policy = rule.Authenticated
+			}
+		}
+		type limit struct {
+			key    string
+			policy ratelimit.Policy
+		}
+		limits := []limit{{"rl:" + rule.Name + ":" + key, policy}}
+		if bucketID := c.Params("id"); bucketID != "" && !rule.Bucket.IsZero() {
+			limits = append(limits, limit{"rl:" + rule.Name + ":bucket:" + bucketID, rule.Bucket})
+		}
+
+		var tightest ratelimit.Result
+		var tightestPolicy ratelimit.Policy
+		for i, l := range limits {
+			res, err := conns.RateLimiter.Take(c.Context(), l.key, l.policy)
+			if err != nil {
+				slog.Warn("Rate limiter failed, allowing request", "rule", rule.Name, "error", err)
+				return c.Next()
+			}
+			if i == 0 || !res.Allowed || res.Remaining < tightest.Remaining {
+				tightest, tightestPolicy = res, l.policy
+			}
+			if !res.Allowed {
+				break
+			}
+		}
+
+		c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", tightestPolicy.Burst, int(tightestPolicy.Period.Seconds())))
+		c.Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(tightest.Reset.Seconds()))))
+		if !tightest.Allowed {
+			retryAfter := int(math.Ceil(tightest.RetryAfter.Seconds()))
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":       "too many requests",
+				"retry_after": retryAfter,
+			})
+		}
+		return c.Next()
+	}
+}
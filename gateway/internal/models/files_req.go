@@ -6,11 +6,17 @@ type PrepareUploadFile struct {
 	OriginalName string `json:"original_name"`
 	Size         int64  `json:"size"`
 	ContentType  string `json:"content_type"`
+	Note         string `json:"note,omitempty"`
+	// SHA256 is the hex-encoded SHA-256 of the content; the presigned PUT only accepts that body
+	SHA256 string `json:"sha256"`
 }
 
 type PrepareUploadRequest struct {
-	Files    []PrepareUploadFile `json:"files"`
-	Password string              `json:"password,omitempty"`
+	Files       []PrepareUploadFile `json:"files"`
+	Password    string              `json:"password,omitempty"`
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"` // Markdown
+	OrgID       string              `json:"org_id,omitempty"`      // organization to share the bucket with
 }
 
 // ConfirmUpload (request)
@@ -26,3 +32,32 @@ type ConfirmUploadRequest struct {
 	StorageID string              `json:"storage_id"`
 	Files     []ConfirmUploadFile `json:"files"`
 }
+
+// UpdateBucketDetails (request). Omitted fields are left unchanged; empty strings clear them.
+
+type FileNoteUpdate struct {
+	StringID string `json:"string_id"`
+	Note     string `json:"note"`
+}
+
+type UpdateBucketDetailsRequest struct {
+	Title       *string          `json:"title,omitempty"`
+	Description *string          `json:"description,omitempty"`
+	Notes       []FileNoteUpdate `json:"notes,omitempty"`
+}
+
+// ReportBucketRequest (request). Email is a contact address for anonymous reporters, told only the
+// outcome of their report; signed in reporters are contacted at their account's address.
+type ReportBucketRequest struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details,omitempty"`
+	Email   string `json:"email,omitempty"`
+}
+
+// BucketAccessRulesRequest (request) replaces a bucket's access rules; an empty or omitted list
+// lifts that restriction.
+type BucketAccessRulesRequest struct {
+	AllowedCIDRs     []string `json:"allowed_cidrs"`
+	AllowedReferrers []string `json:"allowed_referrers"`
+	AllowedCountries []string `json:"allowed_countries"`
+}
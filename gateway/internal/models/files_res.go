@@ -29,5 +29,6 @@ type ConfirmUploadResponse struct {
 	StorageID string           `json:"storage_id,omitempty"`
 	Files     []FileInfoResult `json:"files,omitempty"`
 	TotalSize int64            `json:"total_size,omitempty"`
+	OrgID     string           `json:"org_id,omitempty"`
 	Error     string           `json:"error,omitempty"`
 }
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/cthulhu-platform/common/pkg/env"
+	"github.com/cthulhu-platform/gateway/internal/ratelimit"
 )
 
 const (
@@ -13,6 +14,79 @@ const (
 	// LifecycleTTLAnonymous  = 48 * time.Hour
 	LifecycleTTLAnonymous  = 5 * time.Minute
 	LifecycleTTLAuthorized = 14 * 24 * time.Hour
+
+	// Bucket details limits, in characters
+	BUCKET_TITLE_MAX_LENGTH       = 120
+	BUCKET_DESCRIPTION_MAX_LENGTH = 4000
+	FILE_NOTE_MAX_LENGTH          = 500
+
+	// Access tokens are verified locally against the auth service's JWKS, cached this long
+	JWKS_CACHE_TTL = 5 * time.Minute
+	// How often revoked tokens and per-user token watermarks are pulled from the auth service
+	REVOCATION_SYNC_INTERVAL = 5 * time.Second
+
+	// Session cookies for AUTH_COOKIE_MODE. The __Host- prefix makes browsers require Secure and
+	// Path=/ and refuse a Domain, so other subdomains cannot set or shadow them.
+	ACCESS_TOKEN_COOKIE  = "__Host-access_token"
+	REFRESH_TOKEN_COOKIE = "__Host-refresh_token"
+	CSRF_COOKIE          = "__Host-csrf_token"
+	// Double-submit CSRF header: must equal CSRF_COOKIE on unsafe requests authenticated by cookie
+	CSRF_HEADER = "X-CSRF-Token"
+	// Cookie lifetimes, matching the auth service's access and refresh token expiration
+	ACCESS_TOKEN_COOKIE_MAX_AGE  = 15 * time.Minute
+	REFRESH_TOKEN_COOKIE_MAX_AGE = 7 * 24 * time.Hour
+
+	// Anonymous upload challenge: response header on POST /files/upload/prepare, and the leading
+	// zero bits of a proof of work, from a first challenge up to an IP that keeps asking for more
+	UPLOAD_CHALLENGE_HEADER   = "X-Upload-Challenge"
+	UPLOAD_POW_DIFFICULTY     = 16
+	UPLOAD_POW_MAX_DIFFICULTY = 22
+)
+
+// RATE_LIMIT_STORE values
+const (
+	RateLimitStoreMemory = "memory" // per gateway replica
+	RateLimitStoreRedis  = "redis"  // shared by all replicas through RATE_LIMIT_REDIS_URL
+	RateLimitStoreOff    = "off"
+)
+
+// Rate limits: token buckets of Burst requests refilled over Period, per user when signed in and
+// per client IP otherwise
+var (
+	// Every /auth route
+	RATE_LIMIT_AUTH = ratelimit.Rule{
+		Name:      "auth",
+		Anonymous: ratelimit.Policy{Burst: 30, Period: time.Minute},
+	}
+	// Sign-in steps that send mail or check codes, on top of RATE_LIMIT_AUTH
+	RATE_LIMIT_AUTH_CHALLENGE = ratelimit.Rule{
+		Name:      "auth-challenge",
+		Anonymous: ratelimit.Policy{Burst: 10, Period: 10 * time.Minute},
+	}
+	RATE_LIMIT_UPLOAD = ratelimit.Rule{
+		Name:          "upload",
+		Anonymous:     ratelimit.Policy{Burst: 10, Period: time.Hour},
+		Authenticated: ratelimit.Policy{Burst: 100, Period: time.Hour},
+	}
+	// Bucket password attempts, also limited per bucket against guessing from many addresses
+	RATE_LIMIT_BUCKET_AUTH = ratelimit.Rule{
+		Name:      "bucket-auth",
+		Anonymous: ratelimit.Policy{Burst: 10, Period: time.Minute},
+		Bucket:    ratelimit.Policy{Burst: 30, Period: time.Minute},
+	}
+	// Abuse reports, which email the reporter
+	RATE_LIMIT_REPORT = ratelimit.Rule{
+		Name:          "report",
+		Anonymous:     ratelimit.Policy{Burst: 5, Period: time.Hour},
+		Authenticated: ratelimit.Policy{Burst: 20, Period: time.Hour},
+	}
+)
+
+// AUTH_COOKIE_MODE values
+const (
+	AuthCookieModeOff     = "off"     // tokens only in response bodies (Bearer)
+	AuthCookieModeRefresh = "refresh" // refresh token in an HttpOnly cookie
+	AuthCookieModeAll     = "all"     // access token too
 )
 
 var (
@@ -23,6 +97,28 @@ var (
 
 	APP_TEST_ENV = env.GetEnv("APP_TEST_ENV", "")
 
+	// Browser sessions in HttpOnly cookies (off, refresh or all); Bearer tokens keep working
+	AUTH_COOKIE_MODE = env.GetEnv("AUTH_COOKIE_MODE", AuthCookieModeOff)
+	// SameSite attribute of the session cookies: Strict, Lax, or None for a client on another site
+	AUTH_COOKIE_SAMESITE = env.GetEnv("AUTH_COOKIE_SAMESITE", "Strict")
+
+	// Rate limiter store (memory, redis or off) and the Redis-protocol server for the redis store
+	RATE_LIMIT_STORE     = env.GetEnv("RATE_LIMIT_STORE", RateLimitStoreMemory)
+	RATE_LIMIT_REDIS_URL = env.GetEnv("RATE_LIMIT_REDIS_URL", "redis://localhost:6379")
+	// Comma-separated proxy addresses or CIDR ranges whose X-Forwarded-For is trusted for the
+	// client IP (e.g. the load balancer); empty trusts none
+	TRUSTED_PROXIES = env.GetEnv("TRUSTED_PROXIES", "")
+
+	// Challenge of anonymous uploads: none, pow (proof of work), hcaptcha, turnstile or fake
+	// (accepts "pass", for local runs, refused unless UPLOAD_CHALLENGE_ALLOW_FAKE is "true").
+	// Proof of work tokens are signed with UPLOAD_CHALLENGE_SECRET, which all replicas must share
+	// (random per process when empty).
+	UPLOAD_CHALLENGE            = env.GetEnv("UPLOAD_CHALLENGE", "pow")
+	UPLOAD_CHALLENGE_ALLOW_FAKE = env.GetEnv("UPLOAD_CHALLENGE_ALLOW_FAKE", "false")
+	UPLOAD_CHALLENGE_SECRET     = env.GetEnv("UPLOAD_CHALLENGE_SECRET", "")
+	CAPTCHA_SITE_KEY            = env.GetEnv("CAPTCHA_SITE_KEY", "")
+	CAPTCHA_SECRET              = env.GetEnv("CAPTCHA_SECRET", "")
+
 	// gRPC service URLs
 	AUTH_GRPC_URL        = env.GetEnv("AUTH_GRPC_URL", "localhost:49051")
 	FILEMANAGER_GRPC_URL = env.GetEnv("FILEMANAGER_GRPC_URL", "localhost:48051")
@@ -0,0 +1,27 @@
+package pkg
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SanitizeText cleans user-supplied text before it is stored: invalid UTF-8 and control
+// characters are dropped (newlines and tabs survive when multiline is set) and surrounding
+// whitespace is trimmed. Markdown is kept as written; the client renders it without raw HTML.
+func SanitizeText(s string, multiline bool) string {
+	s = strings.ToValidUTF8(s, "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			if multiline {
+				return r
+			}
+			return ' '
+		case unicode.IsControl(r), r == '\u2028', r == '\u2029':
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
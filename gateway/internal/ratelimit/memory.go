@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// How often idle buckets are dropped from a MemoryStore
+const memorySweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when the bucket will be full, after which it can be dropped
+}
+
+// MemoryStore keeps buckets in the gateway's memory. Each replica counts on its own.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket), now: time.Now}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, p Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(p.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	elapsed := now.Sub(b.updated)
+	b.tokens = math.Min(float64(p.Burst), b.tokens+float64(elapsed)/float64(p.perToken()))
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	res := result(p, allowed, b.tokens)
+	b.full = now.Add(res.Reset)
+	return res, nil
+}
+
+// sweep drops the buckets that have refilled, at most once per memorySweepInterval.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestMemoryStore returns a store whose clock only moves when the returned function is called.
+func newTestMemoryStore() (*MemoryStore, func(time.Duration)) {
+	s := NewMemoryStore()
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	ctx := context.Background()
+	s, advance := newTestMemoryStore()
+	p := Policy{Burst: 2, Period: 2 * time.Second}
+
+	for i, want := range []Result{
+		{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second},
+		{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second},
+		{Allowed: false, Limit: 2, Remaining: 0, Reset: 2 * time.Second, RetryAfter: time.Second},
+	} {
+		if got, err := s.Take(ctx, "k", p); err != nil || got != want {
+			t.Fatalf("take %d = %+v, %v, want %+v", i, got, err, want)
+		}
+	}
+
+	// Half a token is not enough, a whole one is
+	advance(500 * time.Millisecond)
+	if got, _ := s.Take(ctx, "k", p); got.Allowed || got.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("take after 500ms = %+v", got)
+	}
+	advance(500 * time.Millisecond)
+	if got, _ := s.Take(ctx, "k", p); !got.Allowed || got.Remaining != 0 {
+		t.Fatalf("take after 1s = %+v", got)
+	}
+
+	// Refills stop at the burst
+	advance(time.Hour)
+	if got, _ := s.Take(ctx, "k", p); !got.Allowed || got.Remaining != 1 {
+		t.Fatalf("take after an hour = %+v", got)
+	}
+
+	// Keys have their own buckets
+	if got, _ := s.Take(ctx, "other", p); !got.Allowed || got.Remaining != 1 {
+		t.Fatalf("take from another key = %+v", got)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	s, advance := newTestMemoryStore()
+	p := Policy{Burst: 10, Period: 10 * time.Minute}
+
+	s.Take(ctx, "short", Policy{Burst: 1, Period: time.Second})
+	s.Take(ctx, "long", p)
+
+	// Sweeps run once per memorySweepInterval and drop only the buckets that have refilled
+	advance(memorySweepInterval - time.Second)
+	s.Take(ctx, "long", p)
+	if len(s.buckets) != 2 {
+		t.Fatalf("buckets before the sweep interval = %d, want 2", len(s.buckets))
+	}
+	advance(time.Second)
+	s.Take(ctx, "long", p)
+	if _, ok := s.buckets["short"]; ok || len(s.buckets) != 1 {
+		t.Fatalf("buckets after the sweep = %v, want only long", s.buckets)
+	}
+
+	advance(p.Period + memorySweepInterval)
+	s.Take(ctx, "other", p)
+	if _, ok := s.buckets["long"]; ok {
+		t.Fatal("refilled bucket was not swept")
+	}
+}
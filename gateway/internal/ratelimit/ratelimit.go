@@ -0,0 +1,63 @@
+// Package ratelimit implements token bucket rate limits with stores kept in memory (one gateway
+// replica) or in a Redis-protocol server (shared by all replicas).
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Policy is a token bucket that holds up to Burst requests and refills at Burst per Period.
+type Policy struct {
+	Burst  int
+	Period time.Duration
+}
+
+func (p Policy) IsZero() bool {
+	return p.Burst <= 0 || p.Period <= 0
+}
+
+// perToken is the time it takes to refill one token.
+func (p Policy) perToken() time.Duration {
+	return p.Period / time.Duration(p.Burst)
+}
+
+// Rule is the limit of a route: per user when the request is authenticated and per client IP
+// otherwise, plus optionally one per bucket (the :id route parameter) shared by all callers.
+type Rule struct {
+	Name          string // key prefix, unique per rule
+	Anonymous     Policy
+	Authenticated Policy // zero for the anonymous policy
+	Bucket        Policy // zero for no per-bucket limit
+}
+
+// Result is the state of a bucket after taking a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token when the request was not allowed.
+	RetryAfter time.Duration
+}
+
+// Store takes tokens from buckets by key.
+type Store interface {
+	Take(ctx context.Context, key string, p Policy) (Result, error)
+}
+
+// result builds the Result for a bucket left with tokens (fractional) after the request.
+func result(p Policy, allowed bool, tokens float64) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     p.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(p.Burst) - tokens) * float64(p.perToken())),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) * float64(p.perToken()))
+	}
+	return res
+}
@@ -0,0 +1,251 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	redisPoolSize    = 16
+	redisDialTimeout = 2 * time.Second
+	// Deadline of a command when the context has none
+	redisCommandTimeout = time.Second
+)
+
+// takeScript refills and takes from the bucket in KEYS[1] (hash of tokens and updated, in ms of
+// the server's clock) and returns {allowed, tokens left * 1000}. The key expires once the bucket
+// would be full again.
+const takeScript = `
+redis.replicate_commands()
+local burst = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) * burst / period)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) * period / burst))
+return {allowed, math.floor(tokens * 1000)}
+`
+
+// RedisStore keeps buckets in a Redis-protocol server (Redis, Valkey, KeyDB, ...) shared by all
+// gateway replicas. Each take is one atomic script call.
+type RedisStore struct {
+	addr     string
+	useTLS   bool
+	username string
+	password string
+	db       int
+	pool     chan *redisConn
+}
+
+// NewRedisStore returns a store for the server at rawURL, redis://[[user]:password@]host:port[/db]
+// (rediss:// for TLS). Connections are opened on first use.
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("redis url must start with redis:// or rediss://, got %q", u.Scheme)
+	}
+	s := &RedisStore{
+		addr:   u.Host,
+		useTLS: u.Scheme == "rediss",
+		pool:   make(chan *redisConn, redisPoolSize),
+	}
+	if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		s.username = u.User.Username()
+		s.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if s.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	return s, nil
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, p Policy) (Result, error) {
+	reply, err := s.do(ctx, "EVAL", takeScript, "1", key,
+		strconv.Itoa(p.Burst), strconv.FormatInt(p.Period.Milliseconds(), 10))
+	if err != nil {
+		return Result{}, err
+	}
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected redis reply %v", reply)
+	}
+	allowed, ok1 := values[0].(int64)
+	milliTokens, ok2 := values[1].(int64)
+	if !ok1 || !ok2 {
+		return Result{}, fmt.Errorf("unexpected redis reply %v", reply)
+	}
+	return result(p, allowed == 1, float64(milliTokens)/1000), nil
+}
+
+// Addr is the server's host:port.
+func (s *RedisStore) Addr() string {
+	return s.addr
+}
+
+// Close closes the idle connections.
+func (s *RedisStore) Close() error {
+	for {
+		select {
+		case c := <-s.pool:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// redisError is an error reply of the server; the connection stays usable.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func (s *RedisStore) do(ctx context.Context, args ...string) (any, error) {
+	c, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.do(ctx, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.conn.Close()
+		return nil, err
+	}
+	select {
+	case s.pool <- c:
+	default:
+		c.conn.Close()
+	}
+	return reply, err
+}
+
+func (s *RedisStore) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-s.pool:
+		return c, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	var conn net.Conn
+	var err error
+	if s.useTLS {
+		host, _, _ := net.SplitHostPort(s.addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", s.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	if s.password != "" {
+		args := []string{"AUTH", s.password}
+		if s.username != "" {
+			args = []string{"AUTH", s.username, s.password}
+		}
+		if _, err := c.do(ctx, args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if _, err := c.do(ctx, "SELECT", strconv.Itoa(s.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) do(ctx context.Context, args ...string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisCommandTimeout)
+	}
+	c.conn.SetDeadline(deadline)
+
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read parses one RESP2 reply: strings, integers, bulk strings (nil when absent) and arrays.
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
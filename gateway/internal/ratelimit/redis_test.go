@@ -0,0 +1,216 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestRedis starts an in-process Redis with a fixed clock, which the take script reads with TIME.
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, time.Time) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	start := time.Unix(1_700_000_000, 0)
+	m.SetTime(start)
+	return m, start
+}
+
+func newTestRedisStore(t *testing.T, rawURL string) *RedisStore {
+	t.Helper()
+	s, err := NewRedisStore(rawURL)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestRedisStoreTake(t *testing.T) {
+	ctx := context.Background()
+	m, start := newTestRedis(t)
+	s := newTestRedisStore(t, "redis://"+m.Addr())
+	p := Policy{Burst: 2, Period: 2 * time.Second}
+
+	for i, want := range []Result{
+		{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second},
+		{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second},
+		{Allowed: false, Limit: 2, Remaining: 0, Reset: 2 * time.Second, RetryAfter: time.Second},
+	} {
+		if got, err := s.Take(ctx, "k", p); err != nil || got != want {
+			t.Fatalf("take %d = %+v, %v, want %+v", i, got, err, want)
+		}
+	}
+
+	// Half a token is not enough, a whole one is
+	m.SetTime(start.Add(500 * time.Millisecond))
+	if got, err := s.Take(ctx, "k", p); err != nil || got.Allowed || got.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("take after 500ms = %+v, %v", got, err)
+	}
+	m.SetTime(start.Add(time.Second))
+	if got, err := s.Take(ctx, "k", p); err != nil || !got.Allowed || got.Remaining != 0 {
+		t.Fatalf("take after 1s = %+v, %v", got, err)
+	}
+
+	// Refills stop at the burst
+	m.SetTime(start.Add(time.Hour))
+	if got, err := s.Take(ctx, "k", p); err != nil || !got.Allowed || got.Remaining != 1 {
+		t.Fatalf("take after an hour = %+v, %v", got, err)
+	}
+	if got, err := s.Take(ctx, "other", p); err != nil || !got.Allowed || got.Remaining != 1 {
+		t.Fatalf("take from another key = %+v, %v", got, err)
+	}
+}
+
+func TestRedisStoreKeyExpiry(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestRedis(t)
+	s := newTestRedisStore(t, "redis://"+m.Addr())
+	p := Policy{Burst: 4, Period: 4 * time.Second}
+
+	s.Take(ctx, "k", p)
+	s.Take(ctx, "k", p)
+	// The key lives until the bucket would be full again: two tokens at one per second
+	if ttl := m.TTL("k"); ttl != 2*time.Second {
+		t.Fatalf("ttl = %v, want 2s", ttl)
+	}
+	m.FastForward(2*time.Second - time.Millisecond)
+	if !m.Exists("k") {
+		t.Fatal("key expired before the bucket refilled")
+	}
+	m.FastForward(time.Millisecond)
+	if m.Exists("k") {
+		t.Fatal("key of a full bucket did not expire")
+	}
+}
+
+func TestRedisStoreConnect(t *testing.T) {
+	ctx := context.Background()
+	p := Policy{Burst: 1, Period: time.Second}
+
+	t.Run("password", func(t *testing.T) {
+		m, _ := newTestRedis(t)
+		m.RequireAuth("secret")
+		if _, err := newTestRedisStore(t, "redis://:secret@"+m.Addr()).Take(ctx, "k", p); err != nil {
+			t.Fatalf("take: %v", err)
+		}
+		_, err := newTestRedisStore(t, "redis://:wrong@"+m.Addr()).Take(ctx, "k", p)
+		var replyErr redisError
+		if !errors.As(err, &replyErr) {
+			t.Fatalf("take with a wrong password: err = %v, want an error reply", err)
+		}
+	})
+
+	t.Run("user and password", func(t *testing.T) {
+		m, _ := newTestRedis(t)
+		m.RequireUserAuth("gateway", "secret")
+		if _, err := newTestRedisStore(t, "redis://gateway:secret@"+m.Addr()).Take(ctx, "k", p); err != nil {
+			t.Fatalf("take: %v", err)
+		}
+		if _, err := newTestRedisStore(t, "redis://"+m.Addr()).Take(ctx, "k", p); err == nil {
+			t.Fatal("take without credentials succeeded")
+		}
+	})
+
+	t.Run("database", func(t *testing.T) {
+		m, _ := newTestRedis(t)
+		if _, err := newTestRedisStore(t, "redis://"+m.Addr()+"/3").Take(ctx, "k", p); err != nil {
+			t.Fatalf("take: %v", err)
+		}
+		if !m.DB(3).Exists("k") || m.DB(0).Exists("k") {
+			t.Fatal("bucket was not kept in database 3")
+		}
+	})
+}
+
+func TestRedisStoreErrorReply(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestRedis(t)
+	s := newTestRedisStore(t, "redis://"+m.Addr())
+	p := Policy{Burst: 1, Period: time.Second}
+
+	m.SetError("LOADING server is loading")
+	_, err := s.Take(ctx, "k", p)
+	var replyErr redisError
+	if !errors.As(err, &replyErr) || !strings.Contains(err.Error(), "LOADING") {
+		t.Fatalf("take: err = %v, want the error reply", err)
+	}
+	// The connection stays in the pool after an error reply and serves the next take
+	m.SetError("")
+	if _, err := s.Take(ctx, "k", p); err != nil {
+		t.Fatalf("take after the error: %v", err)
+	}
+	if n := m.TotalConnectionCount(); n != 1 {
+		t.Fatalf("connections opened = %d, want 1", n)
+	}
+}
+
+func TestNewRedisStore(t *testing.T) {
+	tests := []struct {
+		url                string
+		addr               string
+		user, password     string
+		db                 int
+		useTLS, shouldFail bool
+	}{
+		{url: "redis://cache", addr: "cache:6379"},
+		{url: "rediss://u:p@cache:6380/2", addr: "cache:6380", user: "u", password: "p", db: 2, useTLS: true},
+		{url: "redis://:p@[::1]", addr: "[::1]:6379", password: "p"},
+		{url: "http://cache", shouldFail: true},
+		{url: "redis://cache/x", shouldFail: true},
+	}
+	for _, tt := range tests {
+		s, err := NewRedisStore(tt.url)
+		if tt.shouldFail {
+			if err == nil {
+				t.Errorf("NewRedisStore(%q) succeeded", tt.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewRedisStore(%q): %v", tt.url, err)
+			continue
+		}
+		if s.addr != tt.addr || s.username != tt.user || s.password != tt.password || s.db != tt.db || s.useTLS != tt.useTLS {
+			t.Errorf("NewRedisStore(%q) = %+v", tt.url, s)
+		}
+	}
+}
+
+func TestRedisRead(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    any
+		wantErr bool
+	}{
+		{reply: "+OK\r\n", want: "OK"},
+		{reply: ":42\r\n", want: int64(42)},
+		{reply: "$5\r\nhe\r\nl\r\n", want: "he\r\nl"},
+		{reply: "$-1\r\n", want: nil},
+		{reply: "*2\r\n:1\r\n*1\r\n$1\r\nx\r\n", want: []any{int64(1), []any{"x"}}},
+		{reply: "*0\r\n", want: []any{}},
+		{reply: "-ERR unknown command\r\n", wantErr: true},
+		{reply: "?\r\n", wantErr: true},
+		{reply: "\r\n", wantErr: true},
+		{reply: "$5\r\nab", wantErr: true},
+		{reply: "*2\r\n:1\r\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		c := &redisConn{r: bufio.NewReader(strings.NewReader(tt.reply))}
+		got, err := c.read()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("read(%q) = %v, want an error", tt.reply, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("read(%q) = %#v, %v, want %#v", tt.reply, got, err, tt.want)
+		}
+	}
+}
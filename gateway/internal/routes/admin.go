@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/cthulhu-platform/auth/pkg"
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/handlers"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminRouter serves platform moderation, for moderators and admins signed in with a session
+// access token.
+func AdminRouter(app fiber.Router, conns *connections.ConnectionsContainer) {
+	admin := app.Group("/admin", middleware.RequireAuth(conns), middleware.RequireSession(), middleware.RequireRole(pkg.RoleModerator))
+
+	// Buckets
+	admin.Get("/buckets", handlers.AdminBucketsList(conns))
+	admin.Delete("/buckets/:id", handlers.AdminBucketDelete(conns))
+	admin.Post("/buckets/:id/takedown", handlers.AdminBucketTakedown(conns))
+
+	// Abuse reports
+	admin.Get("/reports", handlers.AdminReportsList(conns))
+	admin.Post("/reports/:id/dismiss", handlers.AdminReportDismiss(conns))
+
+	// Users
+	admin.Post("/users/:id/suspend", handlers.AdminUserSuspend(conns, true))
+	admin.Post("/users/:id/unsuspend", handlers.AdminUserSuspend(conns, false))
+	admin.Put("/users/:id/role", middleware.RequireRole(pkg.RoleAdmin), handlers.AdminUserRole(conns))
+
+	// Audit log
+	admin.Get("/audit", middleware.RequireRole(pkg.RoleAdmin), handlers.AdminAuditList(conns))
+}
@@ -3,16 +3,37 @@ package routes
 import (
 	"github.com/cthulhu-platform/gateway/internal/connections"
 	"github.com/cthulhu-platform/gateway/internal/handlers"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
+	gatewaypkg "github.com/cthulhu-platform/gateway/internal/pkg"
 	"github.com/gofiber/fiber/v2"
 )
 
 func AuthRouter(app fiber.Router, conns *connections.ConnectionsContainer) {
+	app.Use("/auth", middleware.RateLimit(conns, gatewaypkg.RATE_LIMIT_AUTH))
+	challengeLimit := middleware.RateLimit(conns, gatewaypkg.RATE_LIMIT_AUTH_CHALLENGE)
+
 	// OAuth endpoints
 	app.Get("/auth/oauth/:provider", handlers.OAuthInitiate(conns))
 	app.Get("/auth/oauth/:provider/callback", handlers.OAuthCallback(conns))
+	app.Post("/auth/mfa/verify", challengeLimit, handlers.MFAVerify(conns))
+
+	// Passwordless sign-in with a link emailed to the user
+	app.Post("/auth/magic-link", challengeLimit, handlers.MagicLinkRequest(conns))
+	app.Post("/auth/magic-link/redeem", challengeLimit, handlers.MagicLinkRedeem(conns))
 
 	// Token management
 	app.Post("/auth/refresh", handlers.TokenRefresh(conns))
 	app.Post("/auth/logout", handlers.TokenLogout(conns))
+	app.Post("/auth/logout-all", handlers.TokenLogoutAll(conns))
 	app.Post("/auth/validate", handlers.TokenValidate(conns))
+	app.Get("/auth/csrf", handlers.CSRFToken())
+
+	// Device authorization grant (RFC 8628) for headless clients; approval needs a browser session
+	app.Post("/auth/device/code", handlers.DeviceAuthorize(conns))
+	app.Post("/auth/device/token", handlers.DeviceToken(conns))
+	app.Get("/auth/device/verify", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.DeviceVerify(conns))
+	app.Post("/auth/device/approve", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.DeviceApprove(conns))
+
+	// Public signing keys (cached from the auth service)
+	app.Get("/.well-known/jwks.json", handlers.JWKS(conns))
 }
@@ -1,19 +1,27 @@
 package routes
 
 import (
+	"github.com/cthulhu-platform/auth/pkg"
 	"github.com/cthulhu-platform/gateway/internal/connections"
 	"github.com/cthulhu-platform/gateway/internal/handlers"
 	"github.com/cthulhu-platform/gateway/internal/middleware"
+	gatewaypkg "github.com/cthulhu-platform/gateway/internal/pkg"
 	"github.com/gofiber/fiber/v2"
 )
 
 func FilesRouter(app fiber.Router, conns *connections.ConnectionsContainer) {
-	app.Post("/files/upload", middleware.OptionalAuth(conns), handlers.FileUpload(conns))
-	app.Post("/files/upload/prepare", middleware.OptionalAuth(conns), handlers.FileUploadPrepare(conns))
-	app.Post("/files/upload/confirm", middleware.OptionalAuth(conns), handlers.FileUploadConfirm(conns))
-	app.Post("/files/s/:id/authenticate", middleware.OptionalAuth(conns), handlers.FileAuthenticate(conns))
+	uploadLimit := middleware.RateLimit(conns, gatewaypkg.RATE_LIMIT_UPLOAD)
+	app.Post("/files/upload", middleware.OptionalAuth(conns), middleware.RequireScope(pkg.ScopeFilesUpload), uploadLimit, handlers.FileUpload(conns))
+	app.Get("/files/upload/challenge", middleware.OptionalAuth(conns), handlers.FileUploadChallenge(conns))
+	app.Post("/files/upload/prepare", middleware.OptionalAuth(conns), middleware.RequireScope(pkg.ScopeFilesUpload), uploadLimit, handlers.FileUploadPrepare(conns))
+	app.Post("/files/upload/confirm", middleware.OptionalAuth(conns), middleware.RequireScope(pkg.ScopeFilesUpload), handlers.FileUploadConfirm(conns))
+	app.Post("/files/s/:id/authenticate", middleware.OptionalAuth(conns), middleware.RateLimit(conns, gatewaypkg.RATE_LIMIT_BUCKET_AUTH), handlers.FileAuthenticate(conns))
 	app.Get("/files/s/:id", middleware.BucketAuth(conns), handlers.FileBucketGet(conns))
+	app.Patch("/files/s/:id", middleware.RequireAuth(conns), middleware.RequireScope(pkg.ScopeBucketsWrite), handlers.FileBucketUpdate(conns))
+	app.Get("/files/s/:id/access", middleware.RequireAuth(conns), middleware.RequireScope(pkg.ScopeBucketsWrite), handlers.FileBucketAccessGet(conns))
+	app.Put("/files/s/:id/access", middleware.RequireAuth(conns), middleware.RequireScope(pkg.ScopeBucketsWrite), handlers.FileBucketAccessUpdate(conns))
 	app.Get("/files/s/:id/admins", middleware.BucketAuth(conns), handlers.FileAdmins(conns))
 	app.Get("/files/s/:id/protected", handlers.FileBucketProtected(conns))
+	app.Post("/files/s/:id/report", middleware.OptionalAuth(conns), middleware.RateLimit(conns, gatewaypkg.RATE_LIMIT_REPORT), handlers.FileBucketReport(conns))
 	app.Get("/files/s/:id/d/:filename", middleware.BucketAuth(conns), handlers.FileDownload(conns))
 }
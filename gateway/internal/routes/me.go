@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/handlers"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+func MeRouter(app fiber.Router, conns *connections.ConnectionsContainer) {
+	// Account deletion (soft delete, purged after a grace period)
+	app.Delete("/me", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.AccountDelete(conns))
+
+	// Personal access tokens, managed with a session access token only
+	app.Get("/me/tokens", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.PersonalAccessTokensList(conns))
+	app.Post("/me/tokens", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.PersonalAccessTokenCreate(conns))
+	app.Delete("/me/tokens/:id", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.PersonalAccessTokenRevoke(conns))
+
+	// Signed in devices (refresh token families)
+	app.Get("/me/sessions", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.SessionsList(conns))
+	app.Delete("/me/sessions/:id", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.SessionRevoke(conns))
+
+	// Linked OAuth provider accounts
+	app.Get("/me/identities", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.IdentitiesList(conns))
+	app.Post("/me/identities/:provider", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.IdentityLinkStart(conns))
+	app.Post("/me/identities/:provider/callback", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.IdentityLinkComplete(conns))
+	app.Delete("/me/identities/:provider", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.IdentityUnlink(conns))
+
+	// TOTP second factor
+	app.Get("/me/mfa", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.MFAStatus(conns))
+	app.Post("/me/mfa", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.MFAEnrollStart(conns))
+	app.Post("/me/mfa/confirm", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.MFAEnrollConfirm(conns))
+	app.Delete("/me/mfa", middleware.RequireAuth(conns), middleware.RequireSession(), handlers.MFADisable(conns))
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/cthulhu-platform/gateway/internal/connections"
+	"github.com/cthulhu-platform/gateway/internal/handlers"
+	"github.com/cthulhu-platform/gateway/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// OrgsRouter serves organizations, whose members share their buckets. The auth service checks
+// each caller's role in the organization.
+func OrgsRouter(app fiber.Router, conns *connections.ConnectionsContainer) {
+	orgs := app.Group("/orgs", middleware.RequireAuth(conns), middleware.RequireSession())
+
+	orgs.Get("/", handlers.OrgsList(conns))
+	orgs.Post("/", handlers.OrgCreate(conns))
+	orgs.Get("/:id", handlers.OrgGet(conns))
+	orgs.Patch("/:id", handlers.OrgUpdate(conns))
+	orgs.Delete("/:id", handlers.OrgDelete(conns))
+
+	// Members
+	orgs.Get("/:id/members", handlers.OrgMembersList(conns))
+	orgs.Post("/:id/members", handlers.OrgMemberAdd(conns))
+	orgs.Patch("/:id/members/:userId", handlers.OrgMemberRole(conns))
+	orgs.Delete("/:id/members/:userId", handlers.OrgMemberRemove(conns))
+
+	// Buckets
+	orgs.Get("/:id/buckets", handlers.OrgBucketsList(conns))
+	orgs.Put("/:id/buckets/:bucketId", handlers.OrgBucketAdd(conns))
+	orgs.Delete("/:id/buckets/:bucketId", handlers.OrgBucketRemove(conns))
+}
@@ -45,9 +45,12 @@ func (s *FiberServer) Start() {
 
 	// Setup middleware
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: pkg.CORS_ORIGIN,
-		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Bucket-Token",
+		AllowOrigins:  pkg.CORS_ORIGIN,
+		AllowMethods:  "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-Bucket-Token, " + pkg.CSRF_HEADER + ", " + pkg.UPLOAD_CHALLENGE_HEADER,
+		ExposeHeaders: "RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After",
+		// Session cookies are only sent cross-origin with credentials, which a wildcard origin forbids
+		AllowCredentials: pkg.AUTH_COOKIE_MODE != pkg.AuthCookieModeOff && pkg.CORS_ORIGIN != "*",
 	}))
 	slogCfg := slogfiber.Config{
 		WithClientIP: true,
@@ -63,6 +66,9 @@ func (s *FiberServer) Start() {
 	routes.FilesRouter(app, s.Conns)
 	routes.LifecycleRouter(app, s.Conns)
 	routes.AuthRouter(app, s.Conns)
+	routes.MeRouter(app, s.Conns)
+	routes.AdminRouter(app, s.Conns)
+	routes.OrgsRouter(app, s.Conns)
 
 	// Graceful shutdown
 	c := make(chan os.Signal, 1)
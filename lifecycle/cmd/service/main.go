@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
 	"github.com/cthulhu-platform/lifecycle/internal/connections"
 	"github.com/cthulhu-platform/lifecycle/internal/daemon"
 	internalpkg "github.com/cthulhu-platform/lifecycle/internal/pkg"
 	"github.com/cthulhu-platform/lifecycle/internal/repository"
 	"github.com/cthulhu-platform/lifecycle/internal/server"
 	"github.com/cthulhu-platform/lifecycle/internal/service"
+	"github.com/cthulhu-platform/lifecycle/pkg"
 )
 
 func main() {
@@ -22,6 +25,15 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	slog.SetDefault(logger)
 
+	// "migrate [up | down [n] | status]" manages the schema and exits without starting the service
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := repository.Migrate(ctx, os.Args[2:]); err != nil {
+			slog.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	repo, err := repository.NewSQLiteRepository(ctx)
 	if err != nil {
 		slog.Error("Failed to create repository", "error", err)
@@ -32,6 +44,8 @@ func main() {
 	// Create connections to other microservices
 	conns, err := connections.NewConnectionsContainer(ctx, connections.ConnectionsConfig{
 		FilemanagerURL: internalpkg.FILEMANAGER_GRPC_URL,
+		AuthURL:        internalpkg.AUTH_GRPC_URL,
+		GRPCAuth:       grpcauth.ConfigFromEnv(),
 	})
 	if err != nil {
 		slog.Error("Failed to create connections container", "error", err)
@@ -42,13 +56,30 @@ func main() {
 	svc := service.NewLifecycleService(repo, conns)
 
 	serverCfg := server.ServerConfig{
-		Host: internalpkg.APP_HOST,
-		Port: internalpkg.APP_PORT,
+		Host:     internalpkg.APP_HOST,
+		Port:     internalpkg.APP_PORT,
+		GRPCAuth: grpcauth.ConfigFromEnv(),
 	}
 
 	cleanupDaemon := daemon.NewCleanupDaemon(repo, svc, internalpkg.DEFAULT_CLEANUP_INTERVAL)
 	go cleanupDaemon.Run(ctx)
 
+	// Release the buckets of users deleted in auth
+	orphanTTL, err := time.ParseDuration(internalpkg.ORPHANED_BUCKET_TTL)
+	if err != nil {
+		logger.Error("Invalid ORPHANED_BUCKET_TTL", "value", internalpkg.ORPHANED_BUCKET_TTL, "error", err)
+		os.Exit(1)
+	}
+	if internalpkg.DELETED_USER_BUCKETS != "orphan" && internalpkg.DELETED_USER_BUCKETS != "delete" {
+		logger.Error("Invalid DELETED_USER_BUCKETS, expected orphan or delete", "value", internalpkg.DELETED_USER_BUCKETS)
+		os.Exit(1)
+	}
+	accountDeletionDaemon := daemon.NewAccountDeletionDaemon(svc, internalpkg.ACCOUNT_DELETION_POLL_INTERVAL, pkg.AccountCleanupPolicy{
+		DeleteSoleOwned: internalpkg.DELETED_USER_BUCKETS == "delete",
+		OrphanTTL:       orphanTTL,
+	})
+	go accountDeletionDaemon.Run(ctx)
+
 	err = server.ListenGRPC(ctx, serverCfg, svc)
 	if err != nil {
 		logger.Error("Failed to start server", "error", err)
@@ -3,6 +3,7 @@ module github.com/cthulhu-platform/lifecycle
 go 1.25.6
 
 require (
+	github.com/cthulhu-platform/auth v0.0.0
 	github.com/cthulhu-platform/common v0.0.0
 	github.com/cthulhu-platform/filemanager v0.0.0
 	github.com/cthulhu-platform/proto v0.0.0
@@ -17,6 +18,7 @@ require (
 require (
 	github.com/fsnotify/fsnotify v1.9.0 // indirect
 	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.3.1 // indirect
 	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
 	github.com/sagikazarmark/locafero v0.11.0 // indirect
 	github.com/sourcegraph/conc v0.3.1-0.20240121214520-5f936abd7ae8 // indirect
@@ -32,6 +34,8 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20251029180050-ab9386a59fda // indirect
 )
 
+replace github.com/cthulhu-platform/auth => ../auth
+
 replace github.com/cthulhu-platform/common => ../common
 
 replace github.com/cthulhu-platform/filemanager => ../filemanager
@@ -6,15 +6,20 @@ import (
 	"log/slog"
 	"time"
 
+	auth "github.com/cthulhu-platform/auth/pkg/client"
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
 	filemanager "github.com/cthulhu-platform/filemanager/pkg/client"
 )
 
 type ConnectionsContainer struct {
 	Filemanager *filemanager.Client
+	Auth        *auth.Client
 }
 
 type ConnectionsConfig struct {
 	FilemanagerURL string
+	AuthURL        string
+	GRPCAuth       grpcauth.Config
 }
 
 func NewConnectionsContainer(ctx context.Context, cfg ConnectionsConfig) (*ConnectionsContainer, error) {
@@ -23,17 +28,37 @@ func NewConnectionsContainer(ctx context.Context, cfg ConnectionsConfig) (*Conne
 	defer cancel()
 
 	// Connect to Filemanager
-	filemanagerClient, err := filemanager.NewClient(ctx, cfg.FilemanagerURL)
+	filemanagerCreds, err := cfg.GRPCAuth.DialOption(grpcauth.Filemanager)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load filemanager credentials: %v", err)
+	}
+	filemanagerClient, err := filemanager.NewClient(ctx, cfg.FilemanagerURL, filemanagerCreds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create filemanager client: %v", err)
 	}
 
 	slog.Info("Filemanager client created", "url", cfg.FilemanagerURL)
+
+	// Connect to Auth
+	authCreds, err := cfg.GRPCAuth.DialOption(grpcauth.Auth)
+	if err != nil {
+		filemanagerClient.Close()
+		return nil, fmt.Errorf("failed to load auth credentials: %v", err)
+	}
+	authClient, err := auth.NewClient(ctx, cfg.AuthURL, authCreds)
+	if err != nil {
+		filemanagerClient.Close()
+		return nil, fmt.Errorf("failed to create auth client: %v", err)
+	}
+
+	slog.Info("Auth client created", "url", cfg.AuthURL)
 	return &ConnectionsContainer{
 		Filemanager: filemanagerClient,
+		Auth:        authClient,
 	}, nil
 }
 
 func (c *ConnectionsContainer) Close() {
 	c.Filemanager.Close()
+	c.Auth.Close()
 }
@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/cthulhu-platform/lifecycle/internal/service"
+	"github.com/cthulhu-platform/lifecycle/pkg"
+)
+
+// Account deletion daemon that follows the auth account deletion feed and releases the buckets
+// of deleted users
+
+type AccountDeletionDaemon struct {
+	service  service.Service
+	interval time.Duration
+	policy   pkg.AccountCleanupPolicy
+}
+
+func NewAccountDeletionDaemon(service service.Service, interval time.Duration, policy pkg.AccountCleanupPolicy) *AccountDeletionDaemon {
+	return &AccountDeletionDaemon{service: service, interval: interval, policy: policy}
+}
+
+func (d *AccountDeletionDaemon) process(ctx context.Context) {
+	processed, err := d.service.ProcessAccountDeletions(ctx, d.policy)
+	if processed > 0 {
+		slog.Info("Cleaned up deleted accounts", "count", processed)
+	}
+	if err != nil {
+		slog.Error("Account deletion cleanup failed", "error", err)
+	}
+}
+
+func (d *AccountDeletionDaemon) Run(ctx context.Context) error {
+	slog.Info("Starting account deletion daemon", "interval", d.interval.String(), "delete_sole_owned", d.policy.DeleteSoleOwned, "orphan_ttl", d.policy.OrphanTTL.String())
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	d.process(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.process(ctx)
+		}
+	}
+}
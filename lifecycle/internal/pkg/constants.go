@@ -8,6 +8,10 @@ import (
 
 const (
 	DEFAULT_CLEANUP_INTERVAL = 6 * time.Minute
+
+	// Deleted accounts are read from the auth account deletion feed
+	ACCOUNT_DELETION_POLL_INTERVAL = 1 * time.Minute
+	ACCOUNT_DELETION_BATCH         = 100
 )
 
 var (
@@ -15,6 +19,13 @@ var (
 	APP_PORT = env.GetEnv("APP_PORT", "50051")
 
 	SQLITE_DB_FILE = env.GetEnv("SQLITE_DB_FILE", "lifecycle.db")
+	AUTO_MIGRATE   = env.GetEnv("AUTO_MIGRATE", "true") // apply pending migrations on startup
 
 	FILEMANAGER_GRPC_URL = env.GetEnv("FILEMANAGER_GRPC_URL", "localhost:48051")
+	AUTH_GRPC_URL        = env.GetEnv("AUTH_GRPC_URL", "localhost:49051")
+
+	// Buckets a deleted user was the only admin of are either deleted right away ("delete") or
+	// kept as anonymous buckets that expire within ORPHANED_BUCKET_TTL ("orphan")
+	DELETED_USER_BUCKETS = env.GetEnv("DELETED_USER_BUCKETS", "orphan")
+	ORPHANED_BUCKET_TTL  = env.GetEnv("ORPHANED_BUCKET_TTL", "48h")
 )
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"os"
+
+	commonrepo "github.com/cthulhu-platform/common/pkg/repository"
+	internalpkg "github.com/cthulhu-platform/lifecycle/internal/pkg"
+)
+
+// Numbered up/down migrations applied by the shared runner in common/pkg/repository.
+//
+//go:embed migrations
+var migrationsFS embed.FS
+
+func newMigrator(conn *sql.DB) (*commonrepo.Migrator, error) {
+	migrations, err := commonrepo.LoadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return commonrepo.NewMigrator(conn, commonrepo.DialectSQLite, migrations), nil
+}
+
+// autoMigrate applies pending migrations on startup unless AUTO_MIGRATE is disabled,
+// in which case the schema is managed with the migrate subcommand.
+func autoMigrate(ctx context.Context, conn *sql.DB) error {
+	if internalpkg.AUTO_MIGRATE != "true" {
+		return nil
+	}
+	m, err := newMigrator(conn)
+	if err != nil {
+		return err
+	}
+	_, err = m.Up(ctx)
+	return err
+}
+
+// Migrate runs the migrate subcommand (up, down [n], status) against the configured database.
+func Migrate(ctx context.Context, args []string) error {
+	conn, err := openSQLite(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	m, err := newMigrator(conn)
+	if err != nil {
+		return err
+	}
+	return commonrepo.RunMigrateCommand(ctx, m, args, os.Stdout)
+}
@@ -0,0 +1,6 @@
+-- Position in the auth account deletion feed up to which deleted users have been cleaned up.
+CREATE TABLE IF NOT EXISTS account_deletion_cursor (
+    id INTEGER PRIMARY KEY CHECK (id = 1),  -- single row
+    seq INTEGER NOT NULL,
+    updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"database/sql"
-	_ "embed"
 	"log"
 	"os"
 	"path/filepath"
@@ -16,15 +15,16 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed schemas/up.sql
-var upSQL string
-
 type Repository interface {
 	Close() error
 	PutLifecycle(ctx context.Context, lifecycle pkg.Lifecycle) (*pkg.Lifecycle, error)
 	GetLifecycle(ctx context.Context, bucketSlug string) (*pkg.Lifecycle, error)
 	DeleteLifecycle(ctx context.Context, bucketSlug string) error
 	ListExpiredLifecycles(ctx context.Context, now time.Time) ([]pkg.Lifecycle, error)
+	// GetAccountDeletionCursor returns the last processed seq of the auth account deletion
+	// feed, 0 before the first run.
+	GetAccountDeletionCursor(ctx context.Context) (int64, error)
+	SetAccountDeletionCursor(ctx context.Context, seq int64) error
 }
 
 type sqliteRepository struct {
@@ -32,6 +32,26 @@ type sqliteRepository struct {
 }
 
 func NewSQLiteRepository(ctx context.Context) (*sqliteRepository, error) {
+	db, err := openSQLite(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply pending schema migrations
+	if err := autoMigrate(ctx, db); err != nil {
+		log.Printf("Failed to migrate database schema: %v\n", err)
+		db.Close()
+		return nil, err
+	}
+
+	log.Println("SQLite database initialized successfully")
+
+	r := &sqliteRepository{db: db}
+	return r, nil
+}
+
+// openSQLite opens and pings the SQLite database without touching the schema.
+func openSQLite(ctx context.Context) (*sql.DB, error) {
 	// create timeout context
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -61,17 +81,7 @@ func NewSQLiteRepository(ctx context.Context) (*sqliteRepository, error) {
 		return nil, err
 	}
 
-	// Initialize database schema
-	if _, err := db.ExecContext(ctx, upSQL); err != nil {
-		log.Printf("Failed to initialize database schema: %v\n", err)
-		db.Close()
-		return nil, err
-	}
-
-	log.Println("SQLite database initialized successfully")
-
-	r := &sqliteRepository{db: db}
-	return r, nil
+	return db, nil
 }
 
 func (r *sqliteRepository) Close() error {
@@ -168,3 +178,31 @@ func (r *sqliteRepository) ListExpiredLifecycles(ctx context.Context, now time.T
 	}
 	return out, rows.Err()
 }
+
+func (r *sqliteRepository) GetAccountDeletionCursor(ctx context.Context) (int64, error) {
+	query := `
+		SELECT seq FROM account_deletion_cursor
+		WHERE id = 1
+	`
+	var seq int64
+	err := r.db.QueryRowContext(ctx, query).Scan(&seq)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return seq, nil
+}
+
+func (r *sqliteRepository) SetAccountDeletionCursor(ctx context.Context, seq int64) error {
+	query := `
+		INSERT INTO account_deletion_cursor (id, seq)
+		VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET
+			seq = excluded.seq,
+			updated_at = datetime('now')
+	`
+	_, err := r.db.ExecContext(ctx, query, seq)
+	return err
+}
@@ -0,0 +1,16 @@
+package server
+
+import (
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
+	"github.com/cthulhu-platform/lifecycle/pkg/pb"
+)
+
+// allowlist names the services that may call each RPC when gRPC authentication is enabled.
+var allowlist = grpcauth.Allowlist{
+	pb.LifecycleService_PostLifecycle_FullMethodName:   {grpcauth.Gateway},
+	pb.LifecycleService_GetLifecycle_FullMethodName:    {grpcauth.Gateway},
+	pb.LifecycleService_DeleteLifecycle_FullMethodName: {grpcauth.Gateway},
+
+	"/grpc.reflection.v1.ServerReflection/":      {grpcauth.Operator},
+	"/grpc.reflection.v1alpha.ServerReflection/": {grpcauth.Operator},
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net"
 
+	"github.com/cthulhu-platform/common/pkg/grpcauth"
 	"github.com/cthulhu-platform/common/pkg/strings"
 	"github.com/cthulhu-platform/lifecycle/internal/service"
 	"github.com/cthulhu-platform/lifecycle/pkg"
@@ -23,8 +24,9 @@ type grpcServer struct {
 }
 
 type ServerConfig struct {
-	Host string
-	Port string
+	Host     string
+	Port     string
+	GRPCAuth grpcauth.Config
 }
 
 func ListenGRPC(ctx context.Context, cfg ServerConfig, svc service.Service) error {
@@ -33,7 +35,15 @@ func ListenGRPC(ctx context.Context, cfg ServerConfig, svc service.Service) erro
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
-	server := grpc.NewServer()
+	opts, err := cfg.GRPCAuth.ServerOptions(allowlist)
+	if err != nil {
+		return fmt.Errorf("failed to load gRPC credentials: %v", err)
+	}
+	if !cfg.GRPCAuth.Enabled() {
+		slog.Warn("gRPC service authentication is disabled, any caller is accepted; set GRPC_TLS_CERT_FILE, GRPC_TLS_KEY_FILE and GRPC_TLS_CA_FILE")
+	}
+
+	server := grpc.NewServer(opts...)
 	pb.RegisterLifecycleServiceServer(server, &grpcServer{service: svc})
 
 	reflection.Register(server)
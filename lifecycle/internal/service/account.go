@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	internalpkg "github.com/cthulhu-platform/lifecycle/internal/pkg"
+	"github.com/cthulhu-platform/lifecycle/pkg"
+	pb "github.com/cthulhu-platform/proto/pkg/filemanager"
+)
+
+// ProcessAccountDeletions cleans up after users deleted since the last run, in feed order. The
+// buckets a user was the only admin of are deleted or orphaned (see pkg.AccountCleanupPolicy),
+// then filemanager forgets the user. Every step is safe to repeat, and the cursor only moves
+// past a user once all of them succeeded, so a failure is retried on the next run.
+func (s *lifecycleService) ProcessAccountDeletions(ctx context.Context, policy pkg.AccountCleanupPolicy) (processed int, err error) {
+	cursor, err := s.repo.GetAccountDeletionCursor(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get account deletion cursor: %w", err)
+	}
+	deletions, err := s.conns.Auth.ListAccountDeletions(ctx, cursor, internalpkg.ACCOUNT_DELETION_BATCH)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range deletions {
+		if err := s.cleanUpUser(ctx, d.UserID, policy); err != nil {
+			return processed, fmt.Errorf("clean up user %s: %w", d.UserID, err)
+		}
+		if err := s.repo.SetAccountDeletionCursor(ctx, d.Seq); err != nil {
+			return processed, fmt.Errorf("set account deletion cursor: %w", err)
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+func (s *lifecycleService) cleanUpUser(ctx context.Context, userID string, policy pkg.AccountCleanupPolicy) error {
+	owned, err := s.conns.Filemanager.ListSoleOwnedBuckets(ctx, &pb.ListSoleOwnedBucketsRequest{UserId: userID})
+	if err != nil {
+		return fmt.Errorf("list sole owned buckets: %w", err)
+	}
+	if owned.Error != "" {
+		return fmt.Errorf("list sole owned buckets: %s", owned.Error)
+	}
+	for _, bucketID := range owned.BucketIds {
+		if policy.DeleteSoleOwned {
+			err = s.deleteBucket(ctx, bucketID)
+		} else {
+			err = s.orphanBucket(ctx, bucketID, policy.OrphanTTL)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	forgot, err := s.conns.Filemanager.ForgetUser(ctx, &pb.ForgetUserRequest{UserId: userID})
+	if err != nil {
+		return fmt.Errorf("forget user: %w", err)
+	}
+	if forgot.Error != "" {
+		return fmt.Errorf("forget user: %s", forgot.Error)
+	}
+	return nil
+}
+
+func (s *lifecycleService) deleteBucket(ctx context.Context, bucketID string) error {
+	resp, err := s.conns.Filemanager.DeleteBucket(ctx, &pb.DeleteBucketRequest{BucketId: bucketID})
+	if err != nil {
+		return fmt.Errorf("delete bucket %s: %w", bucketID, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("delete bucket %s: %s", bucketID, resp.Error)
+	}
+	return s.repo.DeleteLifecycle(ctx, bucketID)
+}
+
+// orphanBucket makes the bucket expire within ttl, like an anonymous upload. An earlier expiry is kept.
+func (s *lifecycleService) orphanBucket(ctx context.Context, bucketID string, ttl time.Duration) error {
+	expiresAt := time.Now().UTC().Add(ttl)
+	current, err := s.repo.GetLifecycle(ctx, bucketID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("get lifecycle %s: %w", bucketID, err)
+	}
+	if current != nil && !current.ExpiresAt.After(expiresAt) {
+		return nil
+	}
+	if _, err := s.repo.PutLifecycle(ctx, pkg.Lifecycle{BucketSlug: bucketID, ExpiresAt: expiresAt}); err != nil {
+		return fmt.Errorf("set lifecycle %s: %w", bucketID, err)
+	}
+	return nil
+}
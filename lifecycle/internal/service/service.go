@@ -15,6 +15,8 @@ type Service interface {
 	GetLifecycle(ctx context.Context, bucketSlug string) (*pkg.Lifecycle, error)
 	DeleteLifecycle(ctx context.Context, bucketSlug string) error
 	PurgeExpiredBuckets(ctx context.Context) ([]pkg.PurgeExpiredBucketsResult, error)
+	// ProcessAccountDeletions releases the buckets of users deleted in auth since the last run.
+	ProcessAccountDeletions(ctx context.Context, policy pkg.AccountCleanupPolicy) (int, error)
 }
 
 type lifecycleService struct {
@@ -17,9 +17,11 @@ type Client struct {
 	service pb.LifecycleServiceClient
 }
 
-func NewClient(ctx context.Context, addr string) (*Client, error) {
-	// TODO: add TLS credentials when we have a certificate
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewClient creates a client for addr. Connections are plaintext unless opts carries transport
+// credentials, such as grpcauth.Config.DialOption for mutual TLS.
+func NewClient(ctx context.Context, addr string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(addr, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %v", err)
 	}
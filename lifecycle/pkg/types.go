@@ -15,3 +15,10 @@ type PurgeExpiredBucketsResult struct {
 	FilesDeleted int64
 	Success      bool
 }
+
+// AccountCleanupPolicy decides what happens to buckets whose only admin deleted their account:
+// they are deleted, or kept as anonymous buckets that expire within OrphanTTL.
+type AccountCleanupPolicy struct {
+	DeleteSoleOwned bool
+	OrphanTTL       time.Duration
+}